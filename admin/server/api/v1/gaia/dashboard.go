@@ -26,7 +26,11 @@ func (dashboardApi *DashboardApi) GetAccountQuotaRankingData(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	list, total, err := dashboardService.GetAccountQuotaRankingData(pageInfo)
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, total, err := dashboardService.GetAccountQuotaRankingData(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -56,7 +60,11 @@ func (dashboardApi *DashboardApi) GetAppQuotaRankingData(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	list, total, err := dashboardService.GetAppQuotaRankingData(pageInfo)
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, total, err := dashboardService.GetAppQuotaRankingData(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -86,7 +94,11 @@ func (dashboardApi *DashboardApi) GetAppTokenQuotaRankingData(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	list, total, err := dashboardService.GetAppTokenQuotaRankingData(pageInfo)
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, total, err := dashboardService.GetAppTokenQuotaRankingData(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -116,7 +128,11 @@ func (dashboardApi *DashboardApi) GetAppTokenDailyQuotaData(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	list, err := dashboardService.GetAppTokenDailyQuotaData(pageInfo)
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, err := dashboardService.GetAppTokenDailyQuotaData(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -145,7 +161,11 @@ func (dashboardApi *DashboardApi) GetAiImageQuotaRankingData(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	list, err := dashboardService.GetAiImageQuotaRankingData(pageInfo)
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, err := dashboardService.GetAiImageQuotaRankingData(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -0,0 +1,21 @@
+package gaia
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// tenantScope 解析当前用户角色可见的工作空间, 失败时已写入响应
+func tenantScope(c *gin.Context) (scope gaia.TenantScope, ok bool) {
+	scope, err := dataScopeService.TenantScope(utils.GetUserAuthorityId(c), utils.GetUserUuid(c).String())
+	if err != nil {
+		global.GVA_LOG.Error("获取数据范围失败!", zap.Error(err))
+		response.FailWithMessage("获取数据范围失败", c)
+		return scope, false
+	}
+	return scope, true
+}
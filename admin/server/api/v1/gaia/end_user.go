@@ -0,0 +1,130 @@
+package gaia
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type EndUserApi struct{}
+
+// GetEndUserList 分页获取终端用户
+// @Tags EndUser
+// @Summary 按工作空间、应用、会话标识与类型分页获取终端用户及调用统计
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query gaiaReq.EndUserSearch true "分页获取终端用户"
+// @Success 200 {object} response.Response{data=response.PageResult{list=[]gaiaRes.EndUserInfo},msg=string} "获取成功"
+// @Router /endUser/getEndUserList [get]
+func (endUserApi *EndUserApi) GetEndUserList(c *gin.Context) {
+	var pageInfo gaiaReq.EndUserSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, total, err := endUserService.GetEndUserList(pageInfo, scope)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// FindEndUser 获取终端用户详情
+// @Tags EndUser
+// @Summary 获取终端用户详情与最近的会话
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id query string true "终端用户ID"
+// @Success 200 {object} response.Response{data=gaiaRes.EndUserDetail,msg=string} "查询成功"
+// @Router /endUser/findEndUser [get]
+func (endUserApi *EndUserApi) FindEndUser(c *gin.Context) {
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	detail, err := endUserService.GetEndUser(c.Query("id"), scope)
+	if err != nil {
+		global.GVA_LOG.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithData(detail, c)
+}
+
+// BlockEndUser 封禁终端用户
+// @Tags EndUser
+// @Summary 封禁终端用户, gaia侧将拒绝该用户的调用
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.EndUserBlockReq true "终端用户ID, 封禁原因"
+// @Success 200 {object} response.Response{msg=string} "封禁成功"
+// @Router /endUser/blockEndUser [post]
+func (endUserApi *EndUserApi) BlockEndUser(c *gin.Context) {
+	var req gaiaReq.EndUserBlockReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.EndUserBlockVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	if err := endUserService.BlockEndUser(req, utils.GetUserID(c), utils.GetUserName(c), scope); err != nil {
+		global.GVA_LOG.Error("封禁失败!", zap.Error(err))
+		response.FailWithMessage("封禁失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("封禁成功", c)
+}
+
+// UnblockEndUser 解封终端用户
+// @Tags EndUser
+// @Summary 解封终端用户
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.EndUserBlockReq true "终端用户ID"
+// @Success 200 {object} response.Response{msg=string} "解封成功"
+// @Router /endUser/unblockEndUser [post]
+func (endUserApi *EndUserApi) UnblockEndUser(c *gin.Context) {
+	var req gaiaReq.EndUserBlockReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.EndUserBlockVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	if err := endUserService.UnblockEndUser(req.ID, scope); err != nil {
+		global.GVA_LOG.Error("解封失败!", zap.Error(err))
+		response.FailWithMessage("解封失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("解封成功", c)
+}
@@ -9,12 +9,17 @@ type ApiGroup struct {
 	SystemApi
 	TestApi
 	SystemOAuth2Api
+	EndUserApi // Extend: end user directory
 }
 
 var (
 	dashboardService        = service.ServiceGroupApp.GaiaServiceGroup.DashboardService
 	tenantsService          = service.ServiceGroupApp.GaiaServiceGroup.TenantsService
 	systemIntegratedService = service.ServiceGroupApp.GaiaServiceGroup.SystemIntegratedService
+	dingTalkService         = service.ServiceGroupApp.GaiaServiceGroup.DingTalkService
+	oauth2Service           = service.ServiceGroupApp.GaiaServiceGroup.OAuth2Service
+	endUserService          = service.ServiceGroupApp.GaiaServiceGroup.EndUserService     // Extend: end user directory
+	dataScopeService        = service.ServiceGroupApp.SystemServiceGroup.DataScopeService // Extend: data scope
 )
 var QuotaService = service.ServiceGroupApp.GaiaServiceGroup.QuotaService
 var TestService = service.ServiceGroupApp.GaiaServiceGroup.TestService
@@ -27,7 +27,11 @@ func (quotaApi *QuotaApi) QuotaManagementList(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	list, total, err := QuotaService.GetQuotaManagementData(pageInfo)
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, total, err := QuotaService.GetQuotaManagementData(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -63,8 +67,11 @@ func (quotaApi *QuotaApi) SetUserQuota(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-
-	if err = QuotaService.SetUserQuota(uid, pageInfo.Quota); err != nil {
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	if err = QuotaService.SetUserQuota(uid, pageInfo.Quota, scope); err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
 		return
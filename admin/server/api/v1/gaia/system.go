@@ -2,10 +2,13 @@ package gaia
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type SystemApi struct{}
@@ -55,3 +58,69 @@ func (systemApi *SystemApi) SetDingTalk(c *gin.Context) {
 	}
 	response.OkWithData("ok", c)
 }
+
+// SyncDingTalk 同步钉钉组织架构
+// @Tags System
+// @Summary 同步钉钉部门与用户
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {object} response.Response{data=gaia.DingTalkSyncResult,msg=string} "同步成功"
+// @Router /gaia/system/dingtalk/sync [post]
+func (systemApi *SystemApi) SyncDingTalk(c *gin.Context) {
+	result, err := dingTalkService.SyncOrganization()
+	if err != nil {
+		global.GVA_LOG.Error("同步钉钉组织失败!", zap.Error(err))
+		response.FailWithMessage("同步失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(result, "同步成功", c)
+}
+
+// GetScim 获取SCIM用户预配配置
+// @Tags System
+// @Summary 获取SCIM用户预配配置
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {object} response.Response{data=map[string]interface{},msg=string} "查询成功"
+// @Router /gaia/system/scim [get]
+func (systemApi *SystemApi) GetScim(c *gin.Context) {
+	var host string
+	if host, _ = global.GVA_Dify_REDIS.Get(context.Background(), "api_host").Result(); len(host) == 0 {
+		host = global.GVA_CONFIG.Gaia.Url
+	}
+	integrate := systemIntegratedService.GetIntegratedConfig(gaia.SystemIntegrationSCIM)
+	var config gaia.ScimConfig
+	_ = json.Unmarshal([]byte(integrate.Config), &config)
+	response.OkWithData(gin.H{
+		"host":        host,
+		"status":      integrate.Status,
+		"token":       integrate.AppSecret,
+		"authorities": config.Authorities,
+	}, c)
+}
+
+// SetScim 设置SCIM用户预配配置
+// @Tags System
+// @Summary 设置SCIM用户预配配置, 首次启用或轮换时返回新令牌(仅返回一次)
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.SystemScimRequest true "是否启用, 是否轮换令牌"
+// @Success 200 {object} response.Response{data=map[string]interface{},msg=string} "设置成功"
+// @Router /gaia/system/scim [post]
+func (systemApi *SystemApi) SetScim(c *gin.Context) {
+	var req request.SystemScimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	token, err := systemIntegratedService.SetScimConfig(req)
+	if err != nil {
+		global.GVA_LOG.Error("设置SCIM配置失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithData(gin.H{"token": token}, c)
+}
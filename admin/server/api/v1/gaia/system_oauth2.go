@@ -58,21 +58,7 @@ func (s *SystemOAuth2Api) SetOAuth2Config(c *gin.Context) {
 		return
 	}
 
-	// 序列化为JSON
-	configBytes, err := json.Marshal(&map[string]string{
-		"server_url":        req.ServerURL,
-		"authorize_url":     req.AuthorizeURL,
-		"token_url":         req.TokenURL,
-		"userinfo_url":      req.UserinfoURL,
-		"logout_url":        req.LogoutURL,
-		"discovery_url":     req.DiscoveryURL,
-		"user_name_field":   req.UserNameField,
-		"user_email_field":  req.UserEmailField,
-		"user_id_field":     req.UserIDField,
-		"scope":             req.Scope,
-		"token_auth_method": req.TokenAuthMethod,
-		"redirect_uri":      req.RedirectUri,
-	})
+	integrate, err := oauth2Integration(req)
 	if err != nil {
 		global.GVA_LOG.Error("序列化OAuth2配置失败!", zap.Error(err))
 		response.FailWithMessage("配置序列化失败", c)
@@ -80,16 +66,142 @@ func (s *SystemOAuth2Api) SetOAuth2Config(c *gin.Context) {
 	}
 
 	// 更新配置
-	if err = systemIntegratedService.SetIntegratedConfig(gaia.SystemIntegration{
-		Classify:  gaia.SystemIntegrationOAuth2,
-		Config:    string(configBytes),
-		AppSecret: req.AppSecret,
-		Status:    req.Status,
-		AppID:     req.AppID,
-	}, req.Code, req.Test); err != nil {
+	integrate.Id = 0 // 兼容旧接口, 只修改第一个提供方
+	if err = systemIntegratedService.SetIntegratedConfig(integrate, req.Code, req.Test); err != nil {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
 
 	response.OkWithData("设置成功", c)
 }
+
+// Extend Start: multiple oauth2 providers
+
+// GetOAuth2Providers
+// @Tags System
+// @Summary 获取OAuth2/OIDC提供方列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {object} response.Response{data=[]request.SystemOAuth2Request,msg=string} "获取成功"
+// @Router /gaia/system/oauth2/providers [get]
+func (s *SystemOAuth2Api) GetOAuth2Providers(c *gin.Context) {
+	list, err := oauth2Service.GetProviderList()
+	if err != nil {
+		global.GVA_LOG.Error("获取OAuth2提供方失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	providers := make([]request.SystemOAuth2Request, 0, len(list))
+	for _, v := range list {
+		var configMap request.SystemOAuth2Request
+		_ = json.Unmarshal([]byte(v.Config), &configMap)
+		configMap.Id = v.Id
+		configMap.Name = v.Name
+		configMap.Title = v.Title
+		configMap.AuthorityId = v.AuthorityId
+		configMap.EmailDomains = v.EmailDomains
+		configMap.AppID = v.AppID
+		configMap.Status = v.Status
+		configMap.Classify = v.Classify
+		configMap.AppSecret = v.AppSecret
+		providers = append(providers, configMap)
+	}
+	var host string
+	if host, _ = global.GVA_Dify_REDIS.Get(context.Background(), "api_host").Result(); len(host) == 0 {
+		host = global.GVA_CONFIG.Gaia.Url
+	}
+	response.OkWithData(gin.H{"host": host, "list": providers}, c)
+}
+
+// SetOAuth2Provider
+// @Tags System
+// @Summary 新增或修改OAuth2/OIDC提供方
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.SystemOAuth2Request true "提供方配置, id为空时新增"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"设置成功"}"
+// @Router /gaia/system/oauth2/provider [post]
+func (s *SystemOAuth2Api) SetOAuth2Provider(c *gin.Context) {
+	var req request.SystemOAuth2Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	integrate, err := oauth2Integration(req)
+	if err != nil {
+		global.GVA_LOG.Error("序列化OAuth2配置失败!", zap.Error(err))
+		response.FailWithMessage("配置序列化失败", c)
+		return
+	}
+	if err = oauth2Service.SetProvider(integrate, req.Code, req.Test); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithData("设置成功", c)
+}
+
+// DeleteOAuth2Provider
+// @Tags System
+// @Summary 删除OAuth2/OIDC提供方
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.SystemOAuth2Request true "提供方id"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /gaia/system/oauth2/provider [delete]
+func (s *SystemOAuth2Api) DeleteOAuth2Provider(c *gin.Context) {
+	var req request.SystemOAuth2Request
+	if err := c.ShouldBindJSON(&req); err != nil || req.Id == 0 {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	if err := oauth2Service.DeleteProvider(req.Id); err != nil {
+		global.GVA_LOG.Error("删除OAuth2提供方失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// Extend Stop: multiple oauth2 providers
+
+// oauth2Integration 将请求转换为集成配置, 连接参数序列化到Config字段
+func oauth2Integration(req request.SystemOAuth2Request) (gaia.SystemIntegration, error) {
+	configBytes, err := json.Marshal(&map[string]interface{}{
+		"server_url":         req.ServerURL,
+		"authorize_url":      req.AuthorizeURL,
+		"token_url":          req.TokenURL,
+		"userinfo_url":       req.UserinfoURL,
+		"logout_url":         req.LogoutURL,
+		"discovery_url":      req.DiscoveryURL,
+		"user_name_field":    req.UserNameField,
+		"user_email_field":   req.UserEmailField,
+		"user_id_field":      req.UserIDField,
+		"user_avatar_field":  req.UserAvatarField,
+		"userinfo_method":    req.UserinfoMethod,
+		"userinfo_raw_token": req.UserinfoRawToken,
+		"scope":              req.Scope,
+		"token_auth_method":  req.TokenAuthMethod,
+		"redirect_uri":       req.RedirectUri,
+
+		"user_email_verified_field": req.UserEmailVerifiedField, // Extend: oauth2 email trust
+		"trust_issuer_email":        req.TrustIssuerEmail,       // Extend: oauth2 email trust
+	})
+	if err != nil {
+		return gaia.SystemIntegration{}, err
+	}
+	return gaia.SystemIntegration{
+		Id:           req.Id,
+		Classify:     gaia.SystemIntegrationOAuth2,
+		Config:       string(configBytes),
+		AppSecret:    req.AppSecret,
+		Status:       req.Status,
+		AppID:        req.AppID,
+		Name:         req.Name,
+		Title:        req.Title,
+		AuthorityId:  req.AuthorityId,
+		EmailDomains: req.EmailDomains,
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
 	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -21,7 +22,11 @@ type TenantsApi struct{}
 // @Router /tenants/findTenants [get]
 func (tenantsApi *TenantsApi) FindTenants(c *gin.Context) {
 	id := c.Query("id")
-	retenants, err := tenantsService.GetTenants(id)
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	retenants, err := tenantsService.GetTenants(id, scope)
 	if err != nil {
 		global.GVA_LOG.Error("查询失败!", zap.Error(err))
 		response.FailWithMessage("查询失败:"+err.Error(), c)
@@ -37,7 +42,7 @@ func (tenantsApi *TenantsApi) FindTenants(c *gin.Context) {
 // @accept application/json
 // @Produce application/json
 // @Param data query gaiaReq.TenantsSearch true "分页获取tenants表列表"
-// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Success 200 {object} response.Response{data=response.PageResult{list=[]gaiaRes.TenantInfo},msg=string} "获取成功"
 // @Router /tenants/getTenantsList [get]
 func (tenantsApi *TenantsApi) GetTenantsList(c *gin.Context) {
 	var pageInfo gaiaReq.TenantsSearch
@@ -46,7 +51,11 @@ func (tenantsApi *TenantsApi) GetTenantsList(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	list, total, err := tenantsService.GetTenantsInfoList(pageInfo)
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, total, err := tenantsService.GetTenantsInfoList(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -70,7 +79,11 @@ func (tenantsApi *TenantsApi) GetTenantsList(c *gin.Context) {
 // @Success 200 {object} response.Response{data=gaia.Tenants,msg=string} "查询成功"
 // @Router /tenants/getAllTenants [get]
 func (tenantsApi *TenantsApi) GetAllTenants(c *gin.Context) {
-	retenants, err := tenantsService.GetAllTenants()
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	retenants, err := tenantsService.GetAllTenants(scope)
 	if err != nil {
 		global.GVA_LOG.Error("查询失败!", zap.Error(err))
 		response.FailWithMessage("查询失败:"+err.Error(), c)
@@ -78,3 +91,243 @@ func (tenantsApi *TenantsApi) GetAllTenants(c *gin.Context) {
 	}
 	response.OkWithData(retenants, c)
 }
+
+// Extend Start: workspace management
+
+// UpdateTenant 修改工作空间
+// @Tags Tenants
+// @Summary 修改工作空间名称、套餐与状态
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.TenantUpdateReq true "工作空间ID, 名称, 套餐, 状态"
+// @Success 200 {object} response.Response{msg=string} "修改成功"
+// @Router /tenants/updateTenant [put]
+func (tenantsApi *TenantsApi) UpdateTenant(c *gin.Context) {
+	var req gaiaReq.TenantUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.TenantUpdateVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	if err := tenantsService.UpdateTenant(req, scope); err != nil {
+		global.GVA_LOG.Error("修改失败!", zap.Error(err))
+		response.FailWithMessage("修改失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("修改成功", c)
+}
+
+// GetTenantMembers 获取工作空间成员
+// @Tags Tenants
+// @Summary 获取工作空间成员及其角色
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id query string true "工作空间ID"
+// @Success 200 {object} response.Response{data=[]gaiaRes.TenantMember,msg=string} "获取成功"
+// @Router /tenants/getTenantMembers [get]
+func (tenantsApi *TenantsApi) GetTenantMembers(c *gin.Context) {
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, err := tenantsService.GetTenantMembers(c.Query("id"), scope)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// AddTenantMember 添加工作空间成员
+// @Tags Tenants
+// @Summary 通过邮箱添加工作空间成员
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.TenantMemberReq true "工作空间ID, 邮箱, 角色"
+// @Success 200 {object} response.Response{msg=string} "添加成功"
+// @Router /tenants/addTenantMember [post]
+func (tenantsApi *TenantsApi) AddTenantMember(c *gin.Context) {
+	var req gaiaReq.TenantMemberReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.TenantMemberVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	if err := tenantsService.AddTenantMember(req, utils.GetUserID(c), utils.GetUserName(c), scope); err != nil {
+		global.GVA_LOG.Error("添加失败!", zap.Error(err))
+		response.FailWithMessage("添加失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("添加成功", c)
+}
+
+// RemoveTenantMember 移除工作空间成员
+// @Tags Tenants
+// @Summary 移除工作空间成员
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.TenantAccountReq true "工作空间ID, 账户ID"
+// @Success 200 {object} response.Response{msg=string} "移除成功"
+// @Router /tenants/removeTenantMember [delete]
+func (tenantsApi *TenantsApi) RemoveTenantMember(c *gin.Context) {
+	var req gaiaReq.TenantAccountReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.TenantAccountVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	if err := tenantsService.RemoveTenantMember(req, utils.GetUserID(c), utils.GetUserName(c), scope); err != nil {
+		global.GVA_LOG.Error("移除失败!", zap.Error(err))
+		response.FailWithMessage("移除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("移除成功", c)
+}
+
+// TransferTenantOwner 转移工作空间所有权
+// @Tags Tenants
+// @Summary 转移工作空间所有权, 原所有者降为管理员
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.TenantAccountReq true "工作空间ID, 新所有者账户ID"
+// @Success 200 {object} response.Response{msg=string} "转移成功"
+// @Router /tenants/transferTenantOwner [put]
+func (tenantsApi *TenantsApi) TransferTenantOwner(c *gin.Context) {
+	var req gaiaReq.TenantAccountReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.TenantAccountVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	if err := tenantsService.TransferTenantOwner(req, utils.GetUserID(c), utils.GetUserName(c), scope); err != nil {
+		global.GVA_LOG.Error("转移失败!", zap.Error(err))
+		response.FailWithMessage("转移失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("转移成功", c)
+}
+
+// GetAccountTenants 获取账户加入的工作空间
+// @Tags Tenants
+// @Summary 获取账户加入的工作空间及角色
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param accountId query string true "账户ID"
+// @Success 200 {object} response.Response{data=[]gaiaRes.AccountTenant,msg=string} "获取成功"
+// @Router /tenants/getAccountTenants [get]
+func (tenantsApi *TenantsApi) GetAccountTenants(c *gin.Context) {
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, err := tenantsService.GetAccountTenants(c.Query("accountId"), scope)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// UpdateMemberRoles 批量修改成员角色
+// @Tags Tenants
+// @Summary 批量修改成员角色, 修改后每个工作空间必须有且只有一个所有者
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaiaReq.TenantRoleUpdateReq true "成员角色列表, 变更原因"
+// @Success 200 {object} response.Response{msg=string} "修改成功"
+// @Router /tenants/updateMemberRoles [put]
+func (tenantsApi *TenantsApi) UpdateMemberRoles(c *gin.Context) {
+	var req gaiaReq.TenantRoleUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	for _, item := range req.Items {
+		if err := utils.Verify(item, utils.TenantRoleVerify); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	if err := tenantsService.UpdateMemberRoles(req, utils.GetUserID(c), utils.GetUserName(c), scope); err != nil {
+		global.GVA_LOG.Error("修改失败!", zap.Error(err))
+		response.FailWithMessage("修改失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("修改成功", c)
+}
+
+// GetMemberAuditList 分页获取成员变更审计
+// @Tags Tenants
+// @Summary 分页获取工作空间成员变更审计
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query gaiaReq.TenantMemberAuditSearch true "页码, 每页大小, 工作空间ID, 账户ID, 动作"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /tenants/getMemberAuditList [get]
+func (tenantsApi *TenantsApi) GetMemberAuditList(c *gin.Context) {
+	var pageInfo gaiaReq.TenantMemberAuditSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
+	list, total, err := tenantsService.GetMemberAuditList(pageInfo, scope)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// Extend Stop: workspace management
@@ -70,8 +70,12 @@ func (quotaApi *TestApi) GaiaAppRequestTestList(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
 	// list
-	lock, list, total, err := TestService.AppRequestTestList(pageInfo)
+	lock, list, total, err := TestService.AppRequestTestList(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -89,7 +93,7 @@ func (quotaApi *TestApi) GaiaAppRequestTestList(c *gin.Context) {
 	// 查询相关的app列表
 	if len(appIdList) > 0 {
 		var apps []gaia.Apps
-		if err = global.GVA_DB.Select("id", "name").Where(
+		if err = scope.Where(global.GVA_DB, "tenant_id").Select("id", "name").Where(
 			"id IN (?)", appIdList).Find(&apps).Error; err == nil {
 			for _, v := range apps {
 				appList = append(appList, map[string]string{
@@ -124,8 +128,12 @@ func (quotaApi *TestApi) GaiaAppRequestTestBatch(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
+	scope, ok := tenantScope(c) // Extend: 数据范围
+	if !ok {
+		return
+	}
 	// list
-	lock, list, total, err := TestService.AppRequestTestBatch(pageInfo)
+	lock, list, total, err := TestService.AppRequestTestBatch(pageInfo, scope)
 	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败:"+err.Error(), c)
@@ -0,0 +1,60 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PreviewGaia
+// @Tags      AutoCodeTemplate
+// @Summary   预览按 gaia 表结构生成的代码
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.AutoCodeGaia                                       true  "表名, Struct名称, 简称, 是否只读"
+// @Success   200   {object}  response.Response{data=map[string]interface{},msg=string}  "预览创建后的代码"
+// @Router    /autoCode/previewGaia [post]
+func (a *AutoCodeTemplateApi) PreviewGaia(c *gin.Context) {
+	var info request.AutoCodeGaia
+	err := c.ShouldBindJSON(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	autoCode, err := autoCodeGaiaService.Preview(c.Request.Context(), info)
+	if err != nil {
+		global.GVA_LOG.Error("预览失败!", zap.Error(err))
+		response.FailWithMessage("预览失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(gin.H{"autoCode": autoCode}, "预览成功", c)
+}
+
+// CreateGaia
+// @Tags      AutoCodeTemplate
+// @Summary   按 gaia 表结构生成代码, 可选登记api并授权给当前角色
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.AutoCodeGaia           true  "表名, Struct名称, 简称, 是否只读, 是否登记api"
+// @Success   200   {object}  response.Response{msg=string}  "创建成功"
+// @Router    /autoCode/createGaia [post]
+func (a *AutoCodeTemplateApi) CreateGaia(c *gin.Context) {
+	var info request.AutoCodeGaia
+	err := c.ShouldBindJSON(&info)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = autoCodeGaiaService.Create(c.Request.Context(), info, utils.GetUserAuthorityId(c))
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("创建成功", c)
+}
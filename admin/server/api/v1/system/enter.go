@@ -22,6 +22,17 @@ type ApiGroup struct {
 	AutoCodeHistoryApi
 	AutoCodeTemplateApi
 	SysParamsApi
+	ScimApi           // Extend: SCIM provisioning
+	SessionApi        // Extend: session registry
+	LoginSecurityApi  // Extend: login lockout
+	AccessTokenApi    // Extend: personal access token
+	OffboardApi       // Extend: offboarding
+	UserSyncApi       // Extend: two-way user sync
+	ExportJobApi      // Extend: export job queue
+	ExportScheduleApi // Extend: scheduled report
+	RbacApi           // Extend: rbac bundle
+	AuthorityGrantApi // Extend: temporary authority grant
+	PermissionApi     // Extend: permission simulator
 }
 
 var (
@@ -45,4 +56,22 @@ var (
 	autoCodePackageService  = service.ServiceGroupApp.SystemServiceGroup.AutoCodePackage
 	autoCodeHistoryService  = service.ServiceGroupApp.SystemServiceGroup.AutoCodeHistory
 	autoCodeTemplateService = service.ServiceGroupApp.SystemServiceGroup.AutoCodeTemplate
+	dingTalkService         = service.ServiceGroupApp.GaiaServiceGroup.DingTalkService         // Extend: DingTalk login
+	systemIntegratedService = service.ServiceGroupApp.GaiaServiceGroup.SystemIntegratedService // Extend: DingTalk login
+	oauth2Service           = service.ServiceGroupApp.GaiaServiceGroup.OAuth2Service           // Extend: OIDC login
+	scimService             = service.ServiceGroupApp.SystemServiceGroup.ScimService           // Extend: SCIM provisioning
+	credentialService       = service.ServiceGroupApp.GaiaServiceGroup.CredentialService       // Extend: unified password
+	mfaService              = service.ServiceGroupApp.SystemServiceGroup.MfaService            // Extend: TOTP mfa
+	sessionService          = service.ServiceGroupApp.SystemServiceGroup.SessionService        // Extend: session registry
+	lockoutService          = service.ServiceGroupApp.SystemServiceGroup.LockoutService        // Extend: login lockout
+	accessTokenService      = service.ServiceGroupApp.SystemServiceGroup.AccessTokenService    // Extend: personal access token
+	offboardService         = service.ServiceGroupApp.SystemServiceGroup.OffboardService       // Extend: offboarding
+	userSyncService         = service.ServiceGroupApp.SystemServiceGroup.UserSyncService       // Extend: two-way user sync
+	exportJobService        = service.ServiceGroupApp.SystemServiceGroup.ExportJobService      // Extend: export job queue
+	exportScheduleService   = service.ServiceGroupApp.SystemServiceGroup.ExportScheduleService // Extend: scheduled report
+	dataScopeService        = service.ServiceGroupApp.SystemServiceGroup.DataScopeService      // Extend: data scope
+	rbacService             = service.ServiceGroupApp.SystemServiceGroup.RbacService           // Extend: rbac bundle
+	authorityGrantService   = service.ServiceGroupApp.SystemServiceGroup.AuthorityGrantService // Extend: temporary authority grant
+	permissionService       = service.ServiceGroupApp.SystemServiceGroup.PermissionService     // Extend: permission simulator
+	autoCodeGaiaService     = service.ServiceGroupApp.SystemServiceGroup.AutoCodeGaia          // Extend: gaia auto code
 )
@@ -0,0 +1,269 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ScimApi struct{}
+
+// ServiceProviderConfig
+// @Tags     SCIM
+// @Summary  SCIM服务能力说明
+// @Produce  application/scim+json
+// @Router   /scim/v2/ServiceProviderConfig [get]
+func (s *ScimApi) ServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{systemRes.ScimSchemaSPConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": 100},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type": "oauthbearertoken", "name": "OAuth Bearer Token", "primary": true,
+			"description": "在应用集成配置中生成的SCIM令牌",
+		}},
+	})
+}
+
+// ListUsers
+// @Tags     SCIM
+// @Summary  查询用户
+// @Produce  application/scim+json
+// @Param    filter      query  string  false  "过滤条件, 如 userName eq \"a@b.com\""
+// @Param    startIndex  query  int     false  "起始位置, 从1开始"
+// @Param    count       query  int     false  "每页数量"
+// @Router   /scim/v2/Users [get]
+func (s *ScimApi) ListUsers(c *gin.Context) {
+	startIndex, count := scimPageQuery(c)
+	res, err := scimService.ListUsers(c.Query("filter"), startIndex, count)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, res)
+}
+
+// GetUser
+// @Tags     SCIM
+// @Summary  获取用户
+// @Produce  application/scim+json
+// @Router   /scim/v2/Users/{id} [get]
+func (s *ScimApi) GetUser(c *gin.Context) {
+	res, err := scimService.GetUser(c.Param("id"))
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, res)
+}
+
+// CreateUser
+// @Tags     SCIM
+// @Summary  创建用户
+// @Produce  application/scim+json
+// @Param    data  body  systemReq.ScimUser  true  "SCIM用户"
+// @Router   /scim/v2/Users [post]
+func (s *ScimApi) CreateUser(c *gin.Context) {
+	var req systemReq.ScimUser
+	if !scimBind(c, &req) {
+		return
+	}
+	res, err := scimService.CreateUser(req)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusCreated, res)
+}
+
+// ReplaceUser
+// @Tags     SCIM
+// @Summary  全量更新用户
+// @Produce  application/scim+json
+// @Param    data  body  systemReq.ScimUser  true  "SCIM用户"
+// @Router   /scim/v2/Users/{id} [put]
+func (s *ScimApi) ReplaceUser(c *gin.Context) {
+	var req systemReq.ScimUser
+	if !scimBind(c, &req) {
+		return
+	}
+	res, err := scimService.ReplaceUser(c.Param("id"), req)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, res)
+}
+
+// PatchUser
+// @Tags     SCIM
+// @Summary  部分更新用户, 包括启用与停用
+// @Produce  application/scim+json
+// @Param    data  body  systemReq.ScimPatchOp  true  "PATCH操作"
+// @Router   /scim/v2/Users/{id} [patch]
+func (s *ScimApi) PatchUser(c *gin.Context) {
+	var req systemReq.ScimPatchOp
+	if !scimBind(c, &req) {
+		return
+	}
+	res, err := scimService.PatchUser(c.Param("id"), req)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, res)
+}
+
+// DeleteUser
+// @Tags     SCIM
+// @Summary  删除用户并关闭gaia账户
+// @Router   /scim/v2/Users/{id} [delete]
+func (s *ScimApi) DeleteUser(c *gin.Context) {
+	if err := scimService.DeleteUser(c.Param("id")); err != nil {
+		scimFail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups
+// @Tags     SCIM
+// @Summary  查询分组(角色与工作空间)
+// @Produce  application/scim+json
+// @Param    filter              query  string  false  "过滤条件, 如 displayName eq \"研发\""
+// @Param    excludedAttributes  query  string  false  "为 members 时不返回成员"
+// @Router   /scim/v2/Groups [get]
+func (s *ScimApi) ListGroups(c *gin.Context) {
+	startIndex, count := scimPageQuery(c)
+	withMembers := !strings.Contains(c.Query("excludedAttributes"), "members")
+	res, err := scimService.ListGroups(c.Query("filter"), startIndex, count, withMembers)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, res)
+}
+
+// GetGroup
+// @Tags     SCIM
+// @Summary  获取分组
+// @Produce  application/scim+json
+// @Router   /scim/v2/Groups/{id} [get]
+func (s *ScimApi) GetGroup(c *gin.Context) {
+	res, err := scimService.GetGroup(c.Param("id"))
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, res)
+}
+
+// CreateGroup
+// @Tags     SCIM
+// @Summary  关联已存在的同名角色或工作空间
+// @Produce  application/scim+json
+// @Param    data  body  systemReq.ScimGroup  true  "SCIM分组"
+// @Router   /scim/v2/Groups [post]
+func (s *ScimApi) CreateGroup(c *gin.Context) {
+	var req systemReq.ScimGroup
+	if !scimBind(c, &req) {
+		return
+	}
+	res, err := scimService.CreateGroup(req)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusCreated, res)
+}
+
+// ReplaceGroup
+// @Tags     SCIM
+// @Summary  覆盖分组成员
+// @Produce  application/scim+json
+// @Param    data  body  systemReq.ScimGroup  true  "SCIM分组"
+// @Router   /scim/v2/Groups/{id} [put]
+func (s *ScimApi) ReplaceGroup(c *gin.Context) {
+	var req systemReq.ScimGroup
+	if !scimBind(c, &req) {
+		return
+	}
+	res, err := scimService.ReplaceGroup(c.Param("id"), req)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, res)
+}
+
+// PatchGroup
+// @Tags     SCIM
+// @Summary  增删分组成员
+// @Produce  application/scim+json
+// @Param    data  body  systemReq.ScimPatchOp  true  "PATCH操作"
+// @Router   /scim/v2/Groups/{id} [patch]
+func (s *ScimApi) PatchGroup(c *gin.Context) {
+	var req systemReq.ScimPatchOp
+	if !scimBind(c, &req) {
+		return
+	}
+	res, err := scimService.PatchGroup(c.Param("id"), req)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, res)
+}
+
+// DeleteGroup
+// @Tags     SCIM
+// @Summary  角色与工作空间不允许通过SCIM删除
+// @Router   /scim/v2/Groups/{id} [delete]
+func (s *ScimApi) DeleteGroup(c *gin.Context) {
+	scimFail(c, systemRes.NewScimError(http.StatusForbidden, "mutability", "角色与工作空间不允许通过SCIM删除"))
+}
+
+// scimJSON SCIM接口统一使用 application/scim+json
+func scimJSON(c *gin.Context, status int, obj interface{}) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		scimFail(c, err)
+		return
+	}
+	c.Data(status, "application/scim+json; charset=utf-8", body)
+}
+
+func scimFail(c *gin.Context, err error) {
+	var scimErr *systemRes.ScimError
+	if !errors.As(err, &scimErr) {
+		global.GVA_LOG.Error("SCIM请求失败!", zap.Error(err))
+		scimErr = systemRes.NewScimError(http.StatusInternalServerError, "", err.Error())
+	}
+	scimJSON(c, scimErr.Code, scimErr)
+}
+
+func scimBind(c *gin.Context, obj interface{}) bool {
+	if err := json.NewDecoder(c.Request.Body).Decode(obj); err != nil {
+		scimFail(c, systemRes.NewScimError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return false
+	}
+	return true
+}
+
+func scimPageQuery(c *gin.Context) (startIndex, count int) {
+	startIndex, _ = strconv.Atoi(c.Query("startIndex"))
+	count, _ = strconv.Atoi(c.Query("count"))
+	return startIndex, count
+}
@@ -0,0 +1,98 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AccessTokenApi struct{}
+
+// GetAccessTokenList
+// @Tags      AccessToken
+// @Summary   获取自己的个人访问令牌
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=[]system.SysAccessToken,msg=string}  "个人访问令牌"
+// @Router    /accessToken/list [get]
+func (a *AccessTokenApi) GetAccessTokenList(c *gin.Context) {
+	list, err := accessTokenService.GetList(utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// GetAccessTokenScopes
+// @Tags      AccessToken
+// @Summary   获取可授予令牌的接口, 即当前角色的接口权限
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=[]request.CasbinInfo,msg=string}  "可选接口"
+// @Router    /accessToken/scopes [get]
+func (a *AccessTokenApi) GetAccessTokenScopes(c *gin.Context) {
+	response.OkWithDetailed(casbinService.GetPolicyPathByAuthorityId(utils.GetUserAuthorityId(c)), "获取成功", c)
+}
+
+// CreateAccessToken
+// @Tags      AccessToken
+// @Summary   创建个人访问令牌, 令牌明文只返回一次
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.AccessTokenCreateReq                                      true  "名称, 有效天数, 接口范围, IP白名单"
+// @Success   200   {object}  response.Response{data=systemRes.AccessTokenCreateResponse,msg=string}  "令牌明文与令牌信息"
+// @Router    /accessToken/create [post]
+func (a *AccessTokenApi) CreateAccessToken(c *gin.Context) {
+	if _, ok := c.Get(system.AccessTokenScopesKey); ok {
+		response.FailWithMessage("访问令牌不能用于创建访问令牌", c)
+		return
+	}
+	var req systemReq.AccessTokenCreateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.AccessTokenCreateVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	res, err := accessTokenService.Create(utils.GetUserID(c), utils.GetUserAuthorityId(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "创建成功, 请妥善保存令牌, 关闭后将无法再次查看", c)
+}
+
+// RevokeAccessToken
+// @Tags      AccessToken
+// @Summary   吊销个人访问令牌
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "令牌ID"
+// @Success   200   {object}  response.Response{msg=string}  "吊销成功"
+// @Router    /accessToken/revoke [delete]
+func (a *AccessTokenApi) RevokeAccessToken(c *gin.Context) {
+	var req request.GetById
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.IdVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := accessTokenService.Revoke(req.Uint(), utils.GetUserID(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("吊销成功", c)
+}
@@ -0,0 +1,62 @@
+package system
+
+import (
+	"strconv"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetDataScope
+// @Tags      Authority
+// @Summary   获取角色数据范围, 未设置时为全部工作空间
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     authorityId  query     int                                                             true  "角色ID"
+// @Success   200          {object}  response.Response{data=system.SysAuthorityDataScope,msg=string}  "角色数据范围"
+// @Router    /authority/getDataScope [get]
+func (a *AuthorityApi) GetDataScope(c *gin.Context) {
+	authorityID, err := strconv.ParseUint(c.Query("authorityId"), 10, 64)
+	if err != nil {
+		response.FailWithMessage("角色ID错误", c)
+		return
+	}
+	scope, err := dataScopeService.GetDataScope(uint(authorityID))
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(scope, "获取成功", c)
+}
+
+// SetDataScope
+// @Tags      Authority
+// @Summary   设置角色数据范围: 全部工作空间, 用户所在工作空间或指定工作空间
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.SetDataScopeReq      true  "角色ID, 数据范围, 指定的工作空间"
+// @Success   200   {object}  response.Response{msg=string}  "设置成功"
+// @Router    /authority/setDataScope [post]
+func (a *AuthorityApi) SetDataScope(c *gin.Context) {
+	var req systemReq.SetDataScopeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := authorityService.CheckAuthorityIDAuth(utils.GetUserAuthorityId(c), req.AuthorityId); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := dataScopeService.SetDataScope(req); err != nil {
+		global.GVA_LOG.Error("设置失败!", zap.Error(err))
+		response.FailWithMessage("设置失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("设置成功", c)
+}
@@ -0,0 +1,138 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AuthorityGrantApi struct{}
+
+// CreateAuthorityGrant
+// @Tags      AuthorityGrant
+// @Summary   为用户临时授予角色, 到期自动收回
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.AuthorityGrantReq                                 true  "用户, 角色, 开始结束时间, 原因, 审批人"
+// @Success   200   {object}  response.Response{data=system.SysAuthorityGrant,msg=string}  "授权成功"
+// @Router    /authorityGrant/createAuthorityGrant [post]
+func (a *AuthorityGrantApi) CreateAuthorityGrant(c *gin.Context) {
+	var req systemReq.AuthorityGrantReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	grant, err := authorityGrantService.CreateGrant(utils.GetUserAuthorityId(c), req, utils.GetUserID(c), utils.GetUserName(c))
+	if err != nil {
+		global.GVA_LOG.Error("授权失败!", zap.Error(err))
+		response.FailWithMessage("授权失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(grant, "授权成功", c)
+}
+
+// RevokeAuthorityGrant
+// @Tags      AuthorityGrant
+// @Summary   提前收回临时角色
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.AuthorityGrantRevokeReq  true  "授权ID, 收回原因"
+// @Success   200   {object}  response.Response{msg=string}      "收回成功"
+// @Router    /authorityGrant/revokeAuthorityGrant [post]
+func (a *AuthorityGrantApi) RevokeAuthorityGrant(c *gin.Context) {
+	var req systemReq.AuthorityGrantRevokeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := authorityGrantService.RevokeGrant(req, utils.GetUserID(c), utils.GetUserName(c)); err != nil {
+		global.GVA_LOG.Error("收回失败!", zap.Error(err))
+		response.FailWithMessage("收回失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("收回成功", c)
+}
+
+// ApproveAuthorityGrant
+// @Tags      AuthorityGrant
+// @Summary   审批人审批临时角色授权, 通过后才生效
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.AuthorityGrantApproveReq  true  "授权ID, 是否通过, 审批意见"
+// @Success   200   {object}  response.Response{msg=string}       "审批成功"
+// @Router    /authorityGrant/approveAuthorityGrant [post]
+func (a *AuthorityGrantApi) ApproveAuthorityGrant(c *gin.Context) {
+	var req systemReq.AuthorityGrantApproveReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := authorityGrantService.ApproveGrant(req, utils.GetUserID(c), utils.GetUserName(c)); err != nil {
+		global.GVA_LOG.Error("审批失败!", zap.Error(err))
+		response.FailWithMessage("审批失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("审批成功", c)
+}
+
+// GetAuthorityGrantList
+// @Tags      AuthorityGrant
+// @Summary   分页获取临时角色授权
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  query     systemReq.AuthorityGrantSearch                         true  "页码, 每页大小, 用户, 角色, 状态"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取临时角色授权"
+// @Router    /authorityGrant/getAuthorityGrantList [get]
+func (a *AuthorityGrantApi) GetAuthorityGrantList(c *gin.Context) {
+	var pageInfo systemReq.AuthorityGrantSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := authorityGrantService.GetGrantList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// GetAuthorityGrantLogList
+// @Tags      AuthorityGrant
+// @Summary   分页获取临时角色授权与收回记录
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  query     systemReq.AuthorityGrantLogSearch                      true  "页码, 每页大小, 授权, 用户, 动作"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取授权记录"
+// @Router    /authorityGrant/getAuthorityGrantLogList [get]
+func (a *AuthorityGrantApi) GetAuthorityGrantLogList(c *gin.Context) {
+	var pageInfo systemReq.AuthorityGrantLogSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := authorityGrantService.GetGrantLogList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
@@ -0,0 +1,173 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemService "github.com/flipped-aurora/gin-vue-admin/server/service/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ExportJobApi struct{}
+
+// CreateExportJob
+// @Tags      ExportJob
+// @Summary   创建导出任务, 在后台生成文件
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.ExportJobCreate                                  true  "任务类型, 导出格式, 任务参数"
+// @Success   200   {object}  response.Response{data=system.SysExportJob,msg=string}  "导出任务"
+// @Router    /exportJob/createExportJob [post]
+func (e *ExportJobApi) CreateExportJob(c *gin.Context) {
+	var req systemReq.ExportJobCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// 模板导出需要有模板所在数据库的权限
+	if req.Kind == system.ExportKindTemplate || req.Kind == system.ExportKindTemplateFile {
+		var params systemService.TemplateExportParams
+		_ = json.Unmarshal(req.Params, &params)
+		if err := sysExportTemplateService.CheckTemplateAuthority(params.TemplateID, utils.GetUserAuthorityId(c)); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+	}
+	job, err := exportJobService.Enqueue(req.Kind, req.Format, req.Params, utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("创建导出任务失败!", zap.Error(err))
+		response.FailWithMessage("创建导出任务失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(job, "已加入导出队列", c)
+}
+
+// GetExportJobList
+// @Tags      ExportJob
+// @Summary   分页获取自己的导出历史
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  query     systemReq.SysExportJobSearch                            true  "页码, 每页大小, 任务类型, 任务状态"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "导出历史"
+// @Router    /exportJob/getExportJobList [get]
+func (e *ExportJobApi) GetExportJobList(c *gin.Context) {
+	var pageInfo systemReq.SysExportJobSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := exportJobService.GetExportJobList(pageInfo, utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// FindExportJob
+// @Tags      ExportJob
+// @Summary   查询自己的导出任务状态与进度
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     id   query     int                                                     true  "任务ID"
+// @Success   200  {object}  response.Response{data=system.SysExportJob,msg=string}  "导出任务"
+// @Router    /exportJob/findExportJob [get]
+func (e *ExportJobApi) FindExportJob(c *gin.Context) {
+	var req request.GetById
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	job, err := exportJobService.GetExportJob(req.Uint(), utils.GetUserID(c))
+	if err != nil {
+		response.FailWithMessage("导出任务不存在", c)
+		return
+	}
+	response.OkWithData(job, c)
+}
+
+// GetDownloadLink
+// @Tags      ExportJob
+// @Summary   获取已完成导出任务的限时下载链接
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     id   query     int                                                           true  "任务ID"
+// @Success   200  {object}  response.Response{data=systemRes.ExportJobLink,msg=string}  "下载链接"
+// @Router    /exportJob/getDownloadLink [get]
+func (e *ExportJobApi) GetDownloadLink(c *gin.Context) {
+	var req request.GetById
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	link, err := exportJobService.GetDownloadLink(req.Uint(), utils.GetUserID(c))
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithData(link, c)
+}
+
+// DeleteExportJob
+// @Tags      ExportJob
+// @Summary   删除自己的导出任务及文件
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                 true  "任务ID"
+// @Success   200   {object}  response.Response{msg=string}  "删除成功"
+// @Router    /exportJob/deleteExportJob [delete]
+func (e *ExportJobApi) DeleteExportJob(c *gin.Context) {
+	var req request.GetById
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := exportJobService.DeleteExportJob(req.Uint(), utils.GetUserID(c)); err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// DownloadExportJob
+// @Tags      ExportJob
+// @Summary   通过签名链接下载导出文件, 不需要登录
+// @Produce   application/octet-stream
+// @Param     data  query     systemReq.ExportJobDownload  true  "任务ID, 过期时间, 签名"
+// @Success   200   {file}    file                         "导出文件"
+// @Router    /exportJob/download [get]
+func (e *ExportJobApi) DownloadExportJob(c *gin.Context) {
+	var req systemReq.ExportJobDownload
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	job, file, err := exportJobService.OpenDownload(req)
+	if err != nil {
+		global.GVA_LOG.Error("下载导出文件失败!", zap.Uint("job", req.ID), zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	defer file.Close()
+	c.DataFromReader(http.StatusOK, job.Size, systemService.ExportContentType(job.Format), file, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%s", url.PathEscape(job.Name)),
+	})
+}
@@ -0,0 +1,211 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ExportScheduleApi struct{}
+
+// CreateExportSchedule
+// @Tags      ExportSchedule
+// @Summary   创建定时报表
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysExportSchedule                                    true  "模板标识, 查询参数(可使用相对日期), cron表达式, 格式, 收件人, 发送方式"
+// @Success   200   {object}  response.Response{data=system.SysExportSchedule,msg=string}  "定时报表"
+// @Router    /exportSchedule/createExportSchedule [post]
+func (e *ExportScheduleApi) CreateExportSchedule(c *gin.Context) {
+	var schedule system.SysExportSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := sysExportTemplateService.CheckTemplateAuthority(schedule.TemplateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	schedule.CreatedBy = utils.GetUserID(c)
+	if err := exportScheduleService.CreateExportSchedule(&schedule); err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(schedule, "创建成功", c)
+}
+
+// UpdateExportSchedule
+// @Tags      ExportSchedule
+// @Summary   更新定时报表
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysExportSchedule       true  "定时报表"
+// @Success   200   {object}  response.Response{msg=string}  "更新成功"
+// @Router    /exportSchedule/updateExportSchedule [put]
+func (e *ExportScheduleApi) UpdateExportSchedule(c *gin.Context) {
+	var schedule system.SysExportSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// 原模板与新模板所在的数据库都需要有权限
+	old, err := exportScheduleService.GetExportSchedule(schedule.ID)
+	if err != nil {
+		response.FailWithMessage("定时报表不存在", c)
+		return
+	}
+	for _, templateID := range []string{old.TemplateID, schedule.TemplateID} {
+		if err = sysExportTemplateService.CheckTemplateAuthority(templateID, utils.GetUserAuthorityId(c)); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+	}
+	if err = exportScheduleService.UpdateExportSchedule(schedule); err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteExportSchedule
+// @Tags      ExportSchedule
+// @Summary   删除定时报表及执行记录
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "定时报表ID"
+// @Success   200   {object}  response.Response{msg=string}  "删除成功"
+// @Router    /exportSchedule/deleteExportSchedule [delete]
+func (e *ExportScheduleApi) DeleteExportSchedule(c *gin.Context) {
+	var req request.GetById
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := exportScheduleService.DeleteExportSchedule(req.Uint()); err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// RunExportSchedule
+// @Tags      ExportSchedule
+// @Summary   立即执行一次定时报表, 在后台导出并发送
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                                                true  "定时报表ID"
+// @Success   200   {object}  response.Response{data=system.SysExportScheduleRun,msg=string}  "执行记录"
+// @Router    /exportSchedule/runExportSchedule [post]
+func (e *ExportScheduleApi) RunExportSchedule(c *gin.Context) {
+	var req request.GetById
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	schedule, err := exportScheduleService.GetExportSchedule(req.Uint())
+	if err != nil {
+		response.FailWithMessage("定时报表不存在", c)
+		return
+	}
+	if err = sysExportTemplateService.CheckTemplateAuthority(schedule.TemplateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	run, err := exportScheduleService.RunExportSchedule(schedule.ID)
+	if err != nil {
+		global.GVA_LOG.Error("执行失败!", zap.Error(err))
+		response.FailWithMessage("执行失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(run, "已开始执行", c)
+}
+
+// FindExportSchedule
+// @Tags      ExportSchedule
+// @Summary   获取定时报表
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     id   query     int                                                         true  "定时报表ID"
+// @Success   200  {object}  response.Response{data=system.SysExportSchedule,msg=string}  "定时报表"
+// @Router    /exportSchedule/findExportSchedule [get]
+func (e *ExportScheduleApi) FindExportSchedule(c *gin.Context) {
+	var req request.GetById
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	schedule, err := exportScheduleService.GetExportSchedule(req.Uint())
+	if err != nil {
+		response.FailWithMessage("定时报表不存在", c)
+		return
+	}
+	response.OkWithData(schedule, c)
+}
+
+// GetExportScheduleList
+// @Tags      ExportSchedule
+// @Summary   分页获取定时报表, 含最近执行状态与下次执行时间
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  query     systemReq.SysExportScheduleSearch                       true  "页码, 每页大小, 名称, 模板标识"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "定时报表列表"
+// @Router    /exportSchedule/getExportScheduleList [get]
+func (e *ExportScheduleApi) GetExportScheduleList(c *gin.Context) {
+	var pageInfo systemReq.SysExportScheduleSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := exportScheduleService.GetExportScheduleList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// GetExportScheduleRunList
+// @Tags      ExportSchedule
+// @Summary   分页获取定时报表的执行记录, 包含失败原因
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  query     systemReq.SysExportScheduleRunSearch                    true  "页码, 每页大小, 定时报表ID, 执行状态"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "执行记录"
+// @Router    /exportSchedule/getExportScheduleRunList [get]
+func (e *ExportScheduleApi) GetExportScheduleRunList(c *gin.Context) {
+	var pageInfo systemReq.SysExportScheduleRunSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := exportScheduleService.GetExportScheduleRunList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
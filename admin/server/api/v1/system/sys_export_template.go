@@ -3,13 +3,16 @@ package system
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
 	"github.com/flipped-aurora/gin-vue-admin/server/service"
+	systemService "github.com/flipped-aurora/gin-vue-admin/server/service/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -43,6 +46,16 @@ func (sysExportTemplateApi *SysExportTemplateApi) CreateSysExportTemplate(c *gin
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
+	// Extend Start: export template sql safety
+	if err := sysExportTemplateService.CheckDBAuthority(sysExportTemplate.DBName, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := sysExportTemplateService.ValidateSysExportTemplate(&sysExportTemplate, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// Extend Stop: export template sql safety
 	if err := sysExportTemplateService.CreateSysExportTemplate(&sysExportTemplate); err != nil {
 		global.GVA_LOG.Error("创建失败!", zap.Error(err))
 		response.FailWithMessage("创建失败", c)
@@ -67,6 +80,11 @@ func (sysExportTemplateApi *SysExportTemplateApi) DeleteSysExportTemplate(c *gin
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
+	// Extend: export template sql safety
+	if err := sysExportTemplateService.CheckTemplateIDsAuthority([]int{int(sysExportTemplate.ID)}, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
 	if err := sysExportTemplateService.DeleteSysExportTemplate(sysExportTemplate); err != nil {
 		global.GVA_LOG.Error("删除失败!", zap.Error(err))
 		response.FailWithMessage("删除失败", c)
@@ -91,6 +109,11 @@ func (sysExportTemplateApi *SysExportTemplateApi) DeleteSysExportTemplateByIds(c
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
+	// Extend: export template sql safety
+	if err := sysExportTemplateService.CheckTemplateIDsAuthority(IDS.Ids, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
 	if err := sysExportTemplateService.DeleteSysExportTemplateByIds(IDS); err != nil {
 		global.GVA_LOG.Error("批量删除失败!", zap.Error(err))
 		response.FailWithMessage("批量删除失败", c)
@@ -122,6 +145,20 @@ func (sysExportTemplateApi *SysExportTemplateApi) UpdateSysExportTemplate(c *gin
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
+	// Extend Start: export template sql safety
+	if err := sysExportTemplateService.CheckTemplateIDsAuthority([]int{int(sysExportTemplate.ID)}, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := sysExportTemplateService.CheckDBAuthority(sysExportTemplate.DBName, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := sysExportTemplateService.ValidateSysExportTemplate(&sysExportTemplate, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// Extend Stop: export template sql safety
 	if err := sysExportTemplateService.UpdateSysExportTemplate(sysExportTemplate); err != nil {
 		global.GVA_LOG.Error("更新失败!", zap.Error(err))
 		response.FailWithMessage("更新失败", c)
@@ -185,10 +222,14 @@ func (sysExportTemplateApi *SysExportTemplateApi) GetSysExportTemplateList(c *gi
 
 // ExportExcel 导出表格
 // @Tags SysExportTemplate
-// @Summary 导出表格
+// @Summary 导出表格, 支持 xlsx/csv/jsonl 流式导出, 行数超过阈值或 async=true 时转为异步任务
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
+// @Param templateID query string true "模板标识"
+// @Param format query string false "导出格式 xlsx(默认)/csv/jsonl"
+// @Param async query bool false "是否强制异步导出"
+// @Success 200 {object} response.Response{data=system.SysExportJob,msg=string} "异步导出时返回导出任务, 通过 /exportJob 查询进度并下载"
 // @Router /sysExportTemplate/exportExcel [get]
 func (sysExportTemplateApi *SysExportTemplateApi) ExportExcel(c *gin.Context) {
 	templateID := c.Query("templateID")
@@ -197,14 +238,52 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportExcel(c *gin.Context) {
 		response.FailWithMessage("模板ID不能为空", c)
 		return
 	}
-	if file, name, err := sysExportTemplateService.ExportExcel(templateID, queryParams); err != nil {
+	// Extend Start: export template sql safety
+	if err := sysExportTemplateService.CheckTemplateAuthority(templateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// Extend Stop: export template sql safety
+	// Extend Start: streaming export
+	format := c.DefaultQuery("format", system.ExportFormatXlsx)
+	if !systemService.ValidExportFormat(format) {
+		response.FailWithMessage("不支持的导出格式", c)
+		return
+	}
+	plan, err := sysExportTemplateService.PrepareExport(templateID, queryParams)
+	if err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败", c)
-	} else {
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name+utils.RandomString(6)+".xlsx")) // 对下载的文件重命名
-		c.Header("success", "true")
-		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", file.Bytes())
+		return
+	}
+	total, err := plan.Count()
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
 	}
+	if c.Query("async") == "true" || total > systemService.ExportAsyncThreshold() {
+		job, err := exportJobService.Enqueue(system.ExportKindTemplate, format, systemService.TemplateExportParams{
+			TemplateID: templateID,
+			Query:      queryParams.Encode(),
+		}, utils.GetUserID(c))
+		if err != nil {
+			global.GVA_LOG.Error("创建导出任务失败!", zap.Error(err))
+			response.FailWithMessage("创建导出任务失败", c)
+			return
+		}
+		response.OkWithDetailed(job, fmt.Sprintf("共%d条数据, 已转为后台导出, 完成后可下载", total), c)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", url.PathEscape(plan.FileName(format)))) // 对下载的文件重命名
+	c.Header("Content-Type", systemService.ExportContentType(format))
+	c.Header("success", "true")
+	c.Status(http.StatusOK)
+	if _, err = plan.Write(format, c.Writer, nil); err != nil {
+		// 响应已开始写出, 只能记录日志
+		global.GVA_LOG.Error("导出失败!", zap.String("templateID", templateID), zap.Error(err))
+	}
+	// Extend Stop: streaming export
 }
 
 // ExportTemplate 导出表格模板
@@ -213,6 +292,7 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportExcel(c *gin.Context) {
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
+// @Param async query bool false "是否加入导出队列"
 // @Router /sysExportTemplate/exportExcel [get]
 func (sysExportTemplateApi *SysExportTemplateApi) ExportTemplate(c *gin.Context) {
 	templateID := c.Query("templateID")
@@ -220,6 +300,24 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportTemplate(c *gin.Context)
 		response.FailWithMessage("模板ID不能为空", c)
 		return
 	}
+	// Extend Start: export template sql safety
+	if err := sysExportTemplateService.CheckTemplateAuthority(templateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// Extend Stop: export template sql safety
+	// Extend Start: export job queue
+	if c.Query("async") == "true" {
+		job, err := exportJobService.Enqueue(system.ExportKindTemplateFile, system.ExportFormatXlsx, systemService.TemplateExportParams{TemplateID: templateID}, utils.GetUserID(c))
+		if err != nil {
+			global.GVA_LOG.Error("创建导出任务失败!", zap.Error(err))
+			response.FailWithMessage("创建导出任务失败", c)
+			return
+		}
+		response.OkWithDetailed(job, "已加入导出队列", c)
+		return
+	}
+	// Extend Stop: export job queue
 	if file, name, err := sysExportTemplateService.ExportTemplate(templateID); err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
 		response.FailWithMessage("获取失败", c)
@@ -234,25 +332,50 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportTemplate(c *gin.Context)
 // @Tags SysImportTemplate
 // @Summary 导入表格
 // @Security ApiKeyAuth
-// @accept application/json
+// @accept multipart/form-data
 // @Produce application/json
+// @Param templateID query string true "模板标识"
+// @Param mode query string false "导入模式 insert(默认)/upsert/update"
+// @Param dryRun query bool false "只校验不写入"
+// @Param sheet query string false "工作表, 默认 Sheet1"
+// @Param file formData file true "导入的表格"
+// @Success 200 {object} response.Response{data=systemRes.ImportExcelResult,msg=string} "导入结果"
 // @Router /sysExportTemplate/importExcel [post]
 func (sysExportTemplateApi *SysExportTemplateApi) ImportExcel(c *gin.Context) {
-	templateID := c.Query("templateID")
-	if templateID == "" {
+	// Extend Start: import validation
+	var req systemReq.ImportExcelReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.TemplateID == "" {
 		response.FailWithMessage("模板ID不能为空", c)
 		return
 	}
+	// Extend Stop: import validation
+	// Extend Start: export template sql safety
+	if err := sysExportTemplateService.CheckTemplateAuthority(req.TemplateID, utils.GetUserAuthorityId(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// Extend Stop: export template sql safety
 	file, err := c.FormFile("file")
 	if err != nil {
 		global.GVA_LOG.Error("文件获取失败!", zap.Error(err))
 		response.FailWithMessage("文件获取失败", c)
 		return
 	}
-	if err := sysExportTemplateService.ImportExcel(templateID, file); err != nil {
+	// Extend Start: import validation
+	var result systemRes.ImportExcelResult
+	if result, err = sysExportTemplateService.ImportExcel(req, file, utils.GetUserID(c)); err != nil {
 		global.GVA_LOG.Error(err.Error(), zap.Error(err))
 		response.FailWithMessage(err.Error(), c)
-	} else {
-		response.OkWithMessage("导入成功", c)
+		return
+	}
+	if result.DryRun {
+		response.OkWithDetailed(result, fmt.Sprintf("校验完成, 可导入%d条, 失败%d条", result.Inserted+result.Updated, result.Failed), c)
+		return
 	}
+	response.OkWithDetailed(result, fmt.Sprintf("导入完成, 成功%d条, 失败%d条", result.Inserted+result.Updated, result.Failed), c)
+	// Extend Stop: import validation
 }
@@ -28,6 +28,10 @@ func (j *JwtApi) JsonInBlacklist(c *gin.Context) {
 		response.FailWithMessage("jwt作废失败", c)
 		return
 	}
+	// Extend: session registry
+	if claims, cErr := utils.GetClaims(c); cErr == nil && len(claims.RegisteredClaims.ID) > 0 {
+		_ = sessionService.Revoke(claims.RegisteredClaims.ID, 0, system.SessionRevokeLogout)
+	}
 	utils.ClearToken(c)
 	response.OkWithMessage("jwt作废成功", c)
 }
@@ -0,0 +1,104 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// loginMethodKey 登录方式, 由各登录入口写入, TokenNext 记录登录历史时读取
+const loginMethodKey = "loginMethod"
+
+type LoginSecurityApi struct{}
+
+// GetLockList
+// @Tags      LoginSecurity
+// @Summary   获取当前生效的账号与IP锁定
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=[]systemRes.LoginLock,msg=string}  "锁定列表"
+// @Router    /loginSecurity/lockList [get]
+func (s *LoginSecurityApi) GetLockList(c *gin.Context) {
+	list, err := lockoutService.GetLockList()
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// Unlock
+// @Tags      LoginSecurity
+// @Summary   解除账号或IP锁定
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.LoginUnlockReq       true  "账号或IP"
+// @Success   200   {object}  response.Response{msg=string}  "解锁成功"
+// @Router    /loginSecurity/unlock [post]
+func (s *LoginSecurityApi) Unlock(c *gin.Context) {
+	var req systemReq.LoginUnlockReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := lockoutService.Unlock(req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("解锁成功", c)
+}
+
+// GetLoginHistoryList
+// @Tags      LoginSecurity
+// @Summary   分页获取登录历史
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.SysLoginHistorySearch                         true  "页码, 每页大小, 筛选条件"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取登录历史"
+// @Router    /loginSecurity/getLoginHistoryList [post]
+func (s *LoginSecurityApi) GetLoginHistoryList(c *gin.Context) {
+	var pageInfo systemReq.SysLoginHistorySearch
+	if err := c.ShouldBindJSON(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	s.loginHistoryList(c, pageInfo)
+}
+
+// GetMyLoginHistory
+// @Tags      LoginSecurity
+// @Summary   分页获取自己的登录历史
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  query     systemReq.SysLoginHistorySearch                         true  "页码, 每页大小"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取登录历史"
+// @Router    /loginSecurity/mine [get]
+func (s *LoginSecurityApi) GetMyLoginHistory(c *gin.Context) {
+	var pageInfo systemReq.SysLoginHistorySearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	pageInfo.UserID = utils.GetUserID(c)
+	pageInfo.Account = ""
+	s.loginHistoryList(c, pageInfo)
+}
+
+func (s *LoginSecurityApi) loginHistoryList(c *gin.Context, pageInfo systemReq.SysLoginHistorySearch) {
+	list, total, err := lockoutService.GetLoginHistoryList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
@@ -0,0 +1,36 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type PermissionApi struct{}
+
+// SimulatePermission
+// @Tags      Permission
+// @Summary   模拟用户或角色的有效权限: 接口是否放行及命中的规则, 可见菜单与按钮, 数据范围
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.PermissionSimulateReq                                     true  "用户或角色, 接口路径与方法, 菜单"
+// @Success   200   {object}  response.Response{data=systemRes.PermissionSimulation,msg=string}  "模拟结果"
+// @Router    /permission/simulate [post]
+func (p *PermissionApi) SimulatePermission(c *gin.Context) {
+	var req systemReq.PermissionSimulateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	res, err := permissionService.Simulate(utils.GetUserAuthorityId(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("模拟失败!", zap.Error(err))
+		response.FailWithMessage("模拟失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "模拟成功", c)
+}
@@ -0,0 +1,105 @@
+package system
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RbacApi struct{}
+
+// ExportRbac
+// @Tags      Rbac
+// @Summary   导出角色、菜单、按钮、接口与 casbin 规则的配置包
+// @Security  ApiKeyAuth
+// @Produce   application/octet-stream
+// @Param     format  query  string  false  "json(默认) 或 yaml"
+// @Success   200
+// @Router    /rbac/exportRbac [get]
+func (r *RbacApi) ExportRbac(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	bundle, err := rbacService.ExportRbac()
+	if err != nil {
+		global.GVA_LOG.Error("导出失败!", zap.Error(err))
+		response.FailWithMessage("导出失败", c)
+		return
+	}
+	data, err := rbacService.EncodeRbac(bundle, format)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	contentType := "application/json"
+	if format != "json" {
+		contentType = "application/yaml"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=rbac-%s.%s", bundle.ExportedAt.Format("20060102150405"), format))
+	c.Header("success", "true")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// PreviewRbac
+// @Tags      Rbac
+// @Summary   预览导入配置包后的差异, 不写入
+// @Security  ApiKeyAuth
+// @accept    multipart/form-data
+// @Produce   application/json
+// @Param     file   formData  file                                                 true   "配置包, .json/.yaml/.yml"
+// @Param     prune  query     bool                                                 false  "删除配置包中没有的角色、菜单与接口"
+// @Success   200    {object}  response.Response{data=systemRes.RbacDiff,msg=string}  "差异"
+// @Router    /rbac/previewRbac [post]
+func (r *RbacApi) PreviewRbac(c *gin.Context) {
+	r.importRbac(c, true)
+}
+
+// ImportRbac
+// @Tags      Rbac
+// @Summary   在一个事务中导入配置包, 返回导入前后的差异
+// @Security  ApiKeyAuth
+// @accept    multipart/form-data
+// @Produce   application/json
+// @Param     file   formData  file                                                 true   "配置包, .json/.yaml/.yml"
+// @Param     prune  query     bool                                                 false  "删除配置包中没有的角色、菜单与接口"
+// @Success   200    {object}  response.Response{data=systemRes.RbacDiff,msg=string}  "差异"
+// @Router    /rbac/importRbac [post]
+func (r *RbacApi) ImportRbac(c *gin.Context) {
+	r.importRbac(c, false)
+}
+
+func (r *RbacApi) importRbac(c *gin.Context, dryRun bool) {
+	var req systemReq.RbacImportReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.FailWithMessage("文件获取失败", c)
+		return
+	}
+	bundle, err := rbacService.DecodeRbac(file)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	start := time.Now()
+	var diff systemRes.RbacDiff
+	if diff, err = rbacService.ImportRbac(bundle, req.Prune, dryRun); err != nil {
+		global.GVA_LOG.Error("导入失败!", zap.Error(err))
+		response.FailWithMessage("导入失败: "+err.Error(), c)
+		return
+	}
+	if dryRun {
+		response.OkWithDetailed(diff, "预览完成", c)
+		return
+	}
+	global.GVA_LOG.Info("权限配置包已导入", zap.String("file", file.Filename), zap.Bool("prune", req.Prune), zap.Duration("cost", time.Since(start)))
+	response.OkWithDetailed(diff, "导入成功", c)
+}
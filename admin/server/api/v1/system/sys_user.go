@@ -1,11 +1,11 @@
 package system
 
 import (
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common"
-	"github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
@@ -13,6 +13,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	systemService "github.com/flipped-aurora/gin-vue-admin/server/service/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 
 	"github.com/gin-gonic/gin"
@@ -41,6 +42,14 @@ func (b *BaseApi) Login(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
+	// Extend Start: login lockout
+	account := lockoutService.AccountKey(l.Username)
+	if err = lockoutService.Check(account, key); err != nil {
+		lockoutService.Record(0, account, system.LoginMethodPassword, key, c.Request.UserAgent(), err.Error())
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// Extend Stop: login lockout
 
 	// 判断验证码是否开启
 	openCaptcha := global.GVA_CONFIG.Captcha.OpenCaptcha               // 是否开启防爆次数
@@ -59,6 +68,11 @@ func (b *BaseApi) Login(c *gin.Context) {
 			global.GVA_LOG.Error("登陆失败! 用户名不存在或者密码错误!", zap.Error(err))
 			// 验证码次数+1
 			global.BlackCache.Increment(key, 1)
+			// Extend: login lockout 账号与IP失败次数+1
+			if lockoutService.Fail(0, account, system.LoginMethodPassword, key, c.Request.UserAgent(), err.Error()) {
+				response.FailWithMessage("登录失败次数过多, 已被锁定", c)
+				return
+			}
 			response.FailWithMessage("用户名不存在或者密码错误", c)
 			return
 		}
@@ -66,10 +80,11 @@ func (b *BaseApi) Login(c *gin.Context) {
 			global.GVA_LOG.Error("登陆失败! 用户被禁止登录!")
 			// 验证码次数+1
 			global.BlackCache.Increment(key, 1)
+			lockoutService.Record(user.ID, account, system.LoginMethodPassword, key, c.Request.UserAgent(), "用户被禁止登录") // Extend: login lockout
 			response.FailWithMessage("用户被禁止登录", c)
 			return
 		}
-		b.TokenNext(c, *user)
+		b.loginNext(c, *user) // Extend: TOTP mfa 密码校验通过后先完成二次验证
 		return
 	}
 	// 验证码次数+1
@@ -85,6 +100,19 @@ func (b *BaseApi) TokenNext(c *gin.Context, user system.SysUser) {
 		response.FailWithMessage("获取token失败", c)
 		return
 	}
+	// Extend Start: session registry
+	if err = sessionService.Create(claims, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		global.GVA_LOG.Error("登记会话失败!", zap.Error(err))
+		response.FailWithMessage("登记会话失败", c)
+		return
+	}
+	// Extend Stop: session registry
+	// Extend: login lockout 登录成功, 记录历史并检测新IP/新设备
+	method := c.GetString(loginMethodKey)
+	if len(method) == 0 {
+		method = system.LoginMethodPassword
+	}
+	lockoutService.Succeed(user, method, c.ClientIP(), c.Request.UserAgent())
 	if !global.GVA_CONFIG.System.UseMultipoint {
 		utils.SetToken(c, token, int(claims.RegisteredClaims.ExpiresAt.Unix()-time.Now().Unix()))
 		response.OkWithDetailed(systemRes.LoginResponse{
@@ -117,6 +145,10 @@ func (b *BaseApi) TokenNext(c *gin.Context, user system.SysUser) {
 			response.FailWithMessage("jwt作废失败", c)
 			return
 		}
+		// Extend: session registry 旧会话被新登录顶替
+		if oldClaims, pErr := utils.NewJWT().ParseToken(jwtStr); pErr == nil && len(oldClaims.RegisteredClaims.ID) > 0 {
+			_ = sessionService.Revoke(oldClaims.RegisteredClaims.ID, 0, system.SessionRevokeReplaced)
+		}
 		if err := jwtService.SetRedisJWT(token, user.GetUsername()); err != nil {
 			response.FailWithMessage("设置登录状态失败", c)
 			return
@@ -149,7 +181,7 @@ func (b *BaseApi) Register(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	if err = gaia.IsUserPasswordValid(r.Password); err != nil {
+	if err = credentialService.ValidatePolicy(r.Password); err != nil {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
@@ -165,6 +197,12 @@ func (b *BaseApi) Register(c *gin.Context) {
 	userReturn, err := userService.Register(*user, token)
 	if err != nil {
 		global.GVA_LOG.Error("注册失败!", zap.Error(err))
+		// Extend Start: Gaia Register User
+		if errors.Is(err, systemService.ErrGaiaAccountExists) {
+			response.FailWithDetailed(systemRes.SysUserResponse{User: userReturn}, err.Error(), c)
+			return
+		}
+		// Extend Stop: Gaia Register User
 		response.FailWithDetailed(systemRes.SysUserResponse{User: userReturn}, "注册失败", c)
 		return
 	}
@@ -192,7 +230,8 @@ func (b *BaseApi) ChangePassword(c *gin.Context) {
 		return
 	}
 	uid := utils.GetUserID(c)
-	if err = gaia.IsUserPasswordValid(req.Password); err != nil {
+	// Extend: unified password 校验的是新密码
+	if err = credentialService.ValidatePolicy(req.NewPassword); err != nil {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
@@ -487,7 +526,7 @@ func (b *BaseApi) ResetPassword(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	if err = gaia.IsUserPasswordValid(user.Password); err != nil {
+	if err = credentialService.ValidatePolicy(user.Password); err != nil {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
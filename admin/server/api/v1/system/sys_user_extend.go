@@ -5,15 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
-	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	systemService "github.com/flipped-aurora/gin-vue-admin/server/service/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -36,129 +37,133 @@ func (b *BaseApi) SyncUser(c *gin.Context) {
 
 // OaLogin
 // @Tags     Base
-// @Summary  用户登录
+// @Summary  OA登录, 已统一为OAuth2登录流程, 需先通过 /base/oauth2/authorize 获取state
 // @Produce   application/json
-// @Param    data  body      systemReq.Login                                             true  "用户名, 密码, 验证码"
+// @Param    data  body      systemReq.OaLoginReq                                        true  "授权码, state"
 // @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
 // @Router   /base/oaLogin [post]
 func (b *BaseApi) OaLogin(c *gin.Context) {
 	var l systemReq.OaLoginReq
 	err := c.ShouldBindJSON(&l)
-
 	if err != nil {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = utils.Verify(l, utils.OaLoginVerify)
-	if err != nil {
+	if err = utils.Verify(l, utils.OaLoginVerify); err != nil {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	/**
-	请求获取accessToken
-	*/
-	clientGetToken := resty.New().R()
-	var accessTokenResponse *resty.Response
-	getTokenUrl := fmt.Sprintf("%s%s", global.GVA_CONFIG.OaLogin.Url, global.GVA_CONFIG.OaLogin.GetTokenByCodeApiPath)
-	var postParams = map[string]string{
-		"client_id":     global.GVA_CONFIG.OaLogin.Oauth2ClientId,
-		"client_secret": global.GVA_CONFIG.OaLogin.Oauth2ClientSecret,
-		"code":          l.AuthorizeCode,
-		"grant_type":    "authorization_code",
-		"redirect_uri":  "",
-	}
-	accessTokenResponse, err = clientGetToken.
-		SetFormData(postParams).
-		Post(getTokenUrl)
+	b.oauth2Login(c, l.AuthorizeCode, l.State)
+}
+
+// Extend Start: DingTalk login
+
+// DingTalkLoginConfig
+// @Tags     Base
+// @Summary  获取钉钉扫码登录配置
+// @Produce   application/json
+// @Success  200   {object}  response.Response{data=map[string]interface{},msg=string}  "返回是否启用以及client_id"
+// @Router   /base/dingtalk/config [get]
+func (b *BaseApi) DingTalkLoginConfig(c *gin.Context) {
+	integrate, err := systemIntegratedService.GetEnabledIntegrate(gaia.SystemIntegrationDingTalk)
 	if err != nil {
-		global.GVA_LOG.Error("请求OA用户信息失败,响应数据为：", zap.Error(errors.New(accessTokenResponse.String())))
-		response.FailWithMessage("请求OA用户信息失败："+err.Error(), c)
+		response.OkWithData(gin.H{"status": false}, c)
 		return
 	}
-	tokenRes := accessTokenResponse.String()
-	var oaAccessToken systemRes.OaAccessTokenRes
-	err = json.Unmarshal([]byte(tokenRes), &oaAccessToken)
+	response.OkWithData(gin.H{"status": true, "client_id": integrate.AppKey}, c)
+}
+
+// DingTalkLogin
+// @Tags     Base
+// @Summary  钉钉扫码登录
+// @Produce   application/json
+// @Param    data  body      systemReq.DingTalkLoginReq                                  true  "钉钉authCode"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/dingtalkLogin [post]
+func (b *BaseApi) DingTalkLogin(c *gin.Context) {
+	var l systemReq.DingTalkLoginReq
+	err := c.ShouldBindJSON(&l)
 	if err != nil {
-		global.GVA_LOG.Error("解析OA AccessToken接口返回数据失败,响应数据为：", zap.Error(errors.New(accessTokenResponse.String())))
-		response.FailWithMessage("解析OA AccessToken接口返回数据失败："+err.Error(), c)
+		response.FailWithMessage(err.Error(), c)
 		return
 	}
-
-	/**
-	请求OA，返回用户信息
-	*/
-	getUserInfoUrl := fmt.Sprintf("%s%s", global.GVA_CONFIG.OaLogin.Url, global.GVA_CONFIG.OaLogin.GetUserApiPath)
-	clientGetUser := resty.New().R()
-	var userInfoResponse *resty.Response
-	userInfoResponse, err = clientGetUser.SetHeader("Authorization", oaAccessToken.AccessToken).Post(getUserInfoUrl)
-	if err != nil {
-		global.GVA_LOG.Error("请求OA用户信息失败,响应数据为：", zap.Error(errors.New(userInfoResponse.String())))
-		response.FailWithMessage("请求OA用户信息失败："+err.Error(), c)
+	if err = utils.Verify(l, utils.DingTalkLoginVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	userInfoRes := userInfoResponse.String()
-	var oaUserInfo systemRes.OaUserInfoRes
-	err = json.Unmarshal([]byte(userInfoRes), &oaUserInfo)
+	c.Set(loginMethodKey, system.LoginMethodDingTalk) // Extend: login lockout
+	dingUser, err := dingTalkService.GetUserByAuthCode(l.AuthCode)
 	if err != nil {
-		global.GVA_LOG.Error("解析OA用户信息接口返回数据失败,响应数据为：", zap.Error(errors.New(userInfoRes)))
-		global.GVA_LOG.Error("解析OA用户信息接口返回数据失败,请求Token为：", zap.String("", oaAccessToken.AccessToken))
-		global.GVA_LOG.Error("解析OA用户信息接口返回数据失败,请求Token为：", zap.String("", accessTokenResponse.String()))
-		response.FailWithMessage("解析OA用户信息接口返回数据失败："+err.Error(), c)
+		global.GVA_LOG.Error("钉钉登录失败!", zap.Error(err))
+		response.FailWithMessage("钉钉登录失败："+err.Error(), c)
 		return
 	}
-
-	// 查询数据库数据
-	sysUser := &system.SysUser{}
-	err = global.GVA_DB.Where("email", oaUserInfo.Data.Email).First(&sysUser).Error
-	if err != nil && err != gorm.ErrRecordNotFound {
+	sysUser, err := dingTalkService.FindSysUser(dingUser)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		response.FailWithMessage("查询数据库信息失败："+err.Error(), c)
 		return
 	}
 	// 判断是否需要注册
 	if sysUser.ID == 0 {
-		// TODO 从ldap中获取用户详细数据
-
-		// 注册用户
-		sysUser = &system.SysUser{
-			Username:    oaUserInfo.Data.Username,
-			NickName:    oaUserInfo.Data.Username,
-			HeaderImg:   "https://hn1.oss-cn-shenzhen.aliyuncs.com/w.jpg",
+		mail := dingUser.GetMail()
+		if !strings.Contains(mail, "@") {
+			response.FailWithMessage("钉钉账号未设置邮箱，无法自动注册，请联系管理员", c)
+			return
+		}
+		var username string
+		if username, err = userExtendService.UsernameForEmail(mail); err != nil {
+			response.FailWithMessage("注册失败："+err.Error(), c)
+			return
+		}
+		register := system.SysUser{
+			Username:    username,
+			NickName:    dingUser.Name,
+			HeaderImg:   dingUser.Avatar,
 			AuthorityId: system.NormalAuthorityId,
 			Authorities: []system.SysAuthority{{AuthorityId: system.NormalAuthorityId}},
-			Enable:      1,
-			//Phone:       r.Phone, // TODO 手机需要从ldap中获取用户详细数据
-			Email:    oaUserInfo.Data.Email,
-			Password: utils.RandomString(16),
+			Enable:      system.UserActive,
+			Phone:       dingUser.Mobile,
+			Email:       mail,
+			Password:    utils.RandomString(16),
+		}
+		// 企业邮箱属于集成配置的域名时, 邮箱对应的gaia账户已存在则直接关联; 否则只能新建账户, 邮箱已被使用时拒绝
+		if dingTalkService.TrustMail(dingUser) {
+			sysUser, _, err = userService.RegisterLinked(register, "")
+		} else {
+			sysUser, err = userService.Register(register, "")
 		}
-		var userReturn system.SysUser
-		userReturn, err = userService.Register(*sysUser, "")
 		if err != nil {
 			global.GVA_LOG.Error("注册失败!", zap.Error(err))
-			response.FailWithDetailed(systemRes.SysUserResponse{User: userReturn}, "注册失败", c)
+			if errors.Is(err, systemService.ErrGaiaAccountExists) {
+				response.FailWithMessage("该钉钉账号的邮箱已存在账户, 请联系管理员关联钉钉账号", c)
+				return
+			}
+			response.FailWithMessage("注册失败", c)
 			return
-		} else {
-			global.GVA_LOG.Info("注册成功！", zap.Any("username", userReturn.Username))
 		}
-		sysUser = &userReturn
+		// 注册后写入钉钉关联信息
+		if err = dingTalkService.LinkCreated(sysUser.UUID, dingUser); err != nil {
+			global.GVA_LOG.Error("钉钉关联信息写入失败!", zap.Error(err))
+		}
 	}
 
 	var user *system.SysUser
-	user, err = userExtendService.OaLogin(sysUser) // 注意这个方法不检查密码
+	user, err = userExtendService.OaLogin(&sysUser) // 注意这个方法不检查密码
 	if err != nil {
 		global.GVA_LOG.Error("登陆失败! 用户名不存在!", zap.Error(err))
 		response.FailWithMessage("用户名不存在", c)
 		return
 	}
-	if sysUser.Enable != 1 {
+	if user.Enable != system.UserActive {
 		global.GVA_LOG.Error("登陆失败! 用户被禁止登录!")
 		response.FailWithMessage("用户被禁止登录", c)
 		return
 	}
-	b.TokenNext(c, *user)
-	return
-
+	b.loginNext(c, *user) // Extend: TOTP mfa 与密码登录使用同一二次验证
 }
 
+// Extend Stop: DingTalk login
+
 // Extend Start: oAuth2 callback verification
 
 // OAuth2Callback
@@ -177,10 +182,234 @@ func (b *BaseApi) OAuth2Callback(c *gin.Context) {
 		return
 	}
 
-	// 返回HTML内容，通过BroadcastChannel将授权码传递给前端
-	htmlContent := fmt.Sprintf(`<html><body><script>const channel = new BroadcastChannel('oAuth2');channel.postMessage({code: '%s', timestamp: Date.now() });channel.close();window.close();</script></body></html>`, code)
+	// 返回HTML内容，通过BroadcastChannel将授权码与state传递给前端
+	message, _ := json.Marshal(gin.H{"code": code, "state": c.Request.URL.Query().Get("state")})
+	htmlContent := fmt.Sprintf(`<html><body><script>const channel = new BroadcastChannel('oAuth2');channel.postMessage(Object.assign(%s, {timestamp: Date.now()}));channel.close();window.close();</script></body></html>`, message)
 	c.Header("Content-Type", "text/html; charset=utf-8")
 	c.String(http.StatusOK, htmlContent)
 }
 
 // Extend Stop: oAuth2 callback verification
+
+// Extend Start: OIDC login
+
+// OAuth2Providers
+// @Tags     Base
+// @Summary  获取已启用的OAuth2/OIDC提供方
+// @Produce   application/json
+// @Param    email  query    string  false  "邮箱, 传入时按域名路由"
+// @Success  200   {object}  response.Response{data=[]response.OAuth2Provider,msg=string}  "返回提供方列表"
+// @Router   /base/oauth2/providers [get]
+func (b *BaseApi) OAuth2Providers(c *gin.Context) {
+	list, err := oauth2Service.GetEnabledProviders(strings.TrimSpace(c.Query("email")))
+	if err != nil {
+		global.GVA_LOG.Error("获取提供方失败!", zap.Error(err))
+		response.FailWithMessage("获取提供方失败", c)
+		return
+	}
+	response.OkWithData(list, c)
+}
+
+// OAuth2Authorize
+// @Tags     Base
+// @Summary  获取OAuth2/OIDC授权地址
+// @Produce   application/json
+// @Param    provider      query    string  false  "提供方标识, 为空时使用第一个已启用的提供方"
+// @Success  200   {object}  response.Response{data=map[string]string,msg=string}  "返回授权地址与state, 校验信息写入HttpOnly cookie"
+// @Router   /base/oauth2/authorize [get]
+func (b *BaseApi) OAuth2Authorize(c *gin.Context) {
+	link, state, binding, err := oauth2Service.Authorize(c.Query("provider"))
+	if err != nil {
+		global.GVA_LOG.Error("获取授权地址失败!", zap.Error(err))
+		response.FailWithMessage("获取授权地址失败："+err.Error(), c)
+		return
+	}
+	setOAuth2Binding(c, binding, oauth2BindingMaxAge)
+	response.OkWithData(gin.H{"url": link, "state": state}, c)
+}
+
+const (
+	oauth2BindingCookie = "oauth2-binding" // 保存state、nonce与PKCE verifier的cookie
+	oauth2BindingMaxAge = 600              // 秒, 与授权请求有效期一致
+)
+
+// setOAuth2Binding 写入或清除(maxAge<0)授权绑定cookie
+func setOAuth2Binding(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauth2BindingCookie, value, maxAge, "/", "", secure, true)
+}
+
+// OAuth2Login
+// @Tags     Base
+// @Summary  OAuth2/OIDC登录
+// @Produce   application/json
+// @Param    data  body      systemReq.OAuth2LoginReq                                    true  "授权码, state"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/oauth2Login [post]
+func (b *BaseApi) OAuth2Login(c *gin.Context) {
+	var l systemReq.OAuth2LoginReq
+	err := c.ShouldBindJSON(&l)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = utils.Verify(l, utils.OAuth2LoginVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	b.oauth2Login(c, l.Code, l.State)
+}
+
+// oauth2Login 校验state与授权码, 关联或注册用户后签发token
+func (b *BaseApi) oauth2Login(c *gin.Context, code, state string) {
+	// Extend Start: login lockout
+	c.Set(loginMethodKey, system.LoginMethodOAuth2)
+	ip, userAgent := c.ClientIP(), c.Request.UserAgent()
+	if err := lockoutService.Check("", ip); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// Extend Stop: login lockout
+	// 授权绑定只能使用一次
+	cookie, _ := c.Cookie(oauth2BindingCookie)
+	setOAuth2Binding(c, "", -1)
+	provider, info, token, err := oauth2Service.Callback(code, state, cookie)
+	if err != nil {
+		lockoutService.Fail(0, "", system.LoginMethodOAuth2, ip, userAgent, err.Error()) // Extend: login lockout
+		global.GVA_LOG.Error("OAuth2登录失败!", zap.Error(err))
+		response.FailWithMessage("OAuth2登录失败："+err.Error(), c)
+		return
+	}
+	sysUser, err := userExtendService.OAuth2User(info, provider)
+	if err != nil {
+		lockoutService.Fail(0, info.Email, system.LoginMethodOAuth2, ip, userAgent, err.Error()) // Extend: login lockout
+		global.GVA_LOG.Error("OAuth2用户关联失败!", zap.Error(err))
+		response.FailWithMessage("OAuth2用户关联失败："+err.Error(), c)
+		return
+	}
+
+	var user *system.SysUser
+	user, err = userExtendService.OaLogin(&sysUser) // 注意这个方法不检查密码
+	if err != nil {
+		global.GVA_LOG.Error("登陆失败! 用户名不存在!", zap.Error(err))
+		response.FailWithMessage("用户名不存在", c)
+		return
+	}
+	if user.Enable != system.UserActive {
+		lockoutService.Record(user.ID, user.Email, system.LoginMethodOAuth2, ip, userAgent, "用户被禁止登录") // Extend: login lockout
+		global.GVA_LOG.Error("登陆失败! 用户被禁止登录!")
+		response.FailWithMessage("用户被禁止登录", c)
+		return
+	}
+	// 保存id_token, 退出登录时使用
+	dr, _ := utils.ParseDuration(global.GVA_CONFIG.JWT.ExpiresTime)
+	oauth2Service.SaveIDToken(user.UUID.String(), provider.ProviderName(), token.IDToken, dr)
+	b.loginNext(c, *user) // Extend: TOTP mfa 与密码登录使用同一二次验证
+}
+
+// OAuth2Logout
+// @Tags     Base
+// @Summary  获取OIDC单点退出地址
+// @Produce   application/json
+// @Param    post_logout_redirect_uri  query    string  false  "退出后跳转地址"
+// @Success  200   {object}  response.Response{data=map[string]string,msg=string}  "返回退出地址, 为空时仅需本地退出"
+// @Router   /base/oauth2/logout [get]
+func (b *BaseApi) OAuth2Logout(c *gin.Context) {
+	claims, err := utils.GetClaims(c)
+	if err != nil {
+		response.OkWithData(gin.H{"url": ""}, c)
+		return
+	}
+	link, err := oauth2Service.LogoutURL(claims.UUID.String(), c.Query("post_logout_redirect_uri"))
+	if err != nil {
+		response.OkWithData(gin.H{"url": ""}, c)
+		return
+	}
+	response.OkWithData(gin.H{"url": link}, c)
+}
+
+// Extend Stop: OIDC login
+
+// Extend Start: oauth2 email trust
+
+// GetUserIntegrates
+// @Tags      SysUser
+// @Summary   获取用户关联的第三方身份
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     ID  query     int                                                    true  "用户ID"
+// @Success   200 {object}  response.Response{data=[]gaia.AccountIntegrate,msg=string}  "获取成功"
+// @Router    /user/integrates [get]
+func (b *BaseApi) GetUserIntegrates(c *gin.Context) {
+	var req systemReq.UserIntegrateReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.IdVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, err := userExtendService.GetUserIntegrates(req.ID)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// LinkUserIntegrate
+// @Tags      SysUser
+// @Summary   管理员为用户关联第三方身份
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.UserIntegrateReq     true  "用户ID, 提供方标识, 身份标识"
+// @Success   200   {object}  response.Response{msg=string}  "关联成功"
+// @Router    /user/integrate/link [post]
+func (b *BaseApi) LinkUserIntegrate(c *gin.Context) {
+	var req systemReq.UserIntegrateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.IdVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := userExtendService.LinkIntegrate(req); err != nil {
+		global.GVA_LOG.Error("关联失败!", zap.Error(err))
+		response.FailWithMessage("关联失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("关联成功", c)
+}
+
+// UnlinkUserIntegrate
+// @Tags      SysUser
+// @Summary   管理员解除用户的第三方身份关联
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.UserIntegrateReq     true  "用户ID, 提供方标识"
+// @Success   200   {object}  response.Response{msg=string}  "解除成功"
+// @Router    /user/integrate/unlink [post]
+func (b *BaseApi) UnlinkUserIntegrate(c *gin.Context) {
+	var req systemReq.UserIntegrateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.IdVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := userExtendService.UnlinkIntegrate(req); err != nil {
+		global.GVA_LOG.Error("解除失败!", zap.Error(err))
+		response.FailWithMessage("解除失败", c)
+		return
+	}
+	response.OkWithMessage("解除成功", c)
+}
+
+// Extend Stop: oauth2 email trust
@@ -0,0 +1,280 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	systemService "github.com/flipped-aurora/gin-vue-admin/server/service/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// loginNext 身份校验通过后, 需要二次验证的用户下发二次验证凭证, 否则直接签发jwt; 密码与单点登录共用
+func (b *BaseApi) loginNext(c *gin.Context, user system.SysUser) {
+	if mfaService.Required(user) || mfaService.Enabled(user.ID) {
+		b.mfaChallenge(c, user)
+		return
+	}
+	b.TokenNext(c, user)
+}
+
+// mfaChallenge 第一步登录校验通过后下发二次验证凭证, 代替直接签发jwt
+func (b *BaseApi) mfaChallenge(c *gin.Context, user system.SysUser) {
+	method := c.GetString(loginMethodKey)
+	if len(method) == 0 {
+		method = system.LoginMethodPassword
+	}
+	token, expiresAt, err := mfaService.CreateChallenge(user.ID, method)
+	if err != nil {
+		global.GVA_LOG.Error("生成二次验证凭证失败!", zap.Error(err))
+		response.FailWithMessage("生成二次验证凭证失败", c)
+		return
+	}
+	response.OkWithDetailed(systemRes.MfaChallengeResponse{
+		NeedMfa:    true,
+		NeedEnroll: !mfaService.Enabled(user.ID),
+		MfaToken:   token,
+		ExpiresAt:  expiresAt.Unix() * 1000,
+	}, "请完成二次验证", c)
+}
+
+// challengeUser 根据二次验证凭证获取用户与第一步的登录方式
+func (b *BaseApi) challengeUser(mfaToken string) (user system.SysUser, challenge systemService.MfaChallenge, err error) {
+	if challenge, err = mfaService.ResolveChallenge(mfaToken); err != nil {
+		return user, challenge, err
+	}
+	var u *system.SysUser
+	if u, err = userService.FindUserById(int(challenge.UserID)); err != nil {
+		return user, challenge, err
+	}
+	user, err = userService.GetUserInfo(u.UUID)
+	return user, challenge, err
+}
+
+// MfaLoginEnroll
+// @Tags     Base
+// @Summary  登录时强制绑定TOTP, 获取绑定二维码与恢复码
+// @Produce  application/json
+// @Param    data  body      systemReq.MfaChallengeReq                                       true  "二次验证凭证"
+// @Success  200   {object}  response.Response{data=systemRes.MfaEnrollResponse,msg=string}  "返回密钥、otpauth地址与恢复码"
+// @Router   /base/mfa/enroll [post]
+func (b *BaseApi) MfaLoginEnroll(c *gin.Context) {
+	var req systemReq.MfaChallengeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	user, _, err := b.challengeUser(req.MfaToken)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	res, err := mfaService.Enroll(user)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// MfaLogin
+// @Tags     Base
+// @Summary  登录二次验证, 未绑定时校验通过即完成绑定
+// @Produce  application/json
+// @Param    data  body      systemReq.MfaChallengeReq                                   true  "二次验证凭证, 验证码或恢复码"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/mfa/login [post]
+func (b *BaseApi) MfaLogin(c *gin.Context) {
+	var req systemReq.MfaChallengeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.MfaChallengeVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	user, challenge, err := b.challengeUser(req.MfaToken)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// 二次验证失败按账号计入登录锁定, 锁定期间不再校验验证码
+	ip, userAgent := c.ClientIP(), c.Request.UserAgent()
+	if err = lockoutService.Check(lockoutService.AccountKey(user.Username), ""); err != nil {
+		mfaService.FinishChallenge(req.MfaToken)
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if mfaService.Enabled(user.ID) {
+		err = mfaService.Verify(user.ID, req.Code)
+	} else {
+		err = mfaService.Activate(user.ID, req.Code)
+	}
+	if err != nil {
+		global.GVA_LOG.Error("二次验证失败!", zap.String("username", user.Username), zap.Error(err))
+		if mfaService.FailChallenge(req.MfaToken, challenge, user, ip, userAgent, err.Error()) {
+			response.FailWithMessage("二次验证失败次数过多, 已被锁定", c)
+			return
+		}
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	mfaService.FinishChallenge(req.MfaToken)
+	if user.Enable != 1 {
+		response.FailWithMessage("用户被禁止登录", c)
+		return
+	}
+	c.Set(loginMethodKey, challenge.Method)
+	b.TokenNext(c, user)
+}
+
+// GetMfaStatus
+// @Tags      SysUser
+// @Summary   获取自身二次验证状态
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=systemRes.MfaStatusResponse,msg=string}  "二次验证状态"
+// @Router    /user/mfa [get]
+func (b *BaseApi) GetMfaStatus(c *gin.Context) {
+	user, err := userService.GetUserInfo(utils.GetUserUuid(c))
+	if err != nil {
+		response.FailWithMessage("获取用户失败", c)
+		return
+	}
+	res, err := mfaService.Status(user)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// MfaEnroll
+// @Tags      SysUser
+// @Summary   绑定TOTP, 获取绑定二维码与恢复码
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=systemRes.MfaEnrollResponse,msg=string}  "返回密钥、otpauth地址与恢复码"
+// @Router    /user/mfa/enroll [post]
+func (b *BaseApi) MfaEnroll(c *gin.Context) {
+	user, err := userService.GetUserInfo(utils.GetUserUuid(c))
+	if err != nil {
+		response.FailWithMessage("获取用户失败", c)
+		return
+	}
+	res, err := mfaService.Enroll(user)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// MfaActivate
+// @Tags      SysUser
+// @Summary   校验验证码完成TOTP绑定
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.MfaCodeReq           true  "验证码"
+// @Success   200   {object}  response.Response{msg=string}  "开启成功"
+// @Router    /user/mfa/activate [post]
+func (b *BaseApi) MfaActivate(c *gin.Context) {
+	var req systemReq.MfaCodeReq
+	if !b.bindMfaCode(c, &req) {
+		return
+	}
+	if err := mfaService.Activate(utils.GetUserID(c), req.Code); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("开启成功", c)
+}
+
+// MfaDisable
+// @Tags      SysUser
+// @Summary   关闭二次验证
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.MfaCodeReq           true  "验证码或恢复码"
+// @Success   200   {object}  response.Response{msg=string}  "关闭成功"
+// @Router    /user/mfa/disable [post]
+func (b *BaseApi) MfaDisable(c *gin.Context) {
+	var req systemReq.MfaCodeReq
+	if !b.bindMfaCode(c, &req) {
+		return
+	}
+	user, err := userService.GetUserInfo(utils.GetUserUuid(c))
+	if err != nil {
+		response.FailWithMessage("获取用户失败", c)
+		return
+	}
+	if err = mfaService.Disable(user, req.Code); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("关闭成功", c)
+}
+
+// MfaRecoveryCodes
+// @Tags      SysUser
+// @Summary   重新生成恢复码
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.MfaCodeReq                         true  "验证码"
+// @Success   200   {object}  response.Response{data=[]string,msg=string}  "新的恢复码"
+// @Router    /user/mfa/recoveryCodes [post]
+func (b *BaseApi) MfaRecoveryCodes(c *gin.Context) {
+	var req systemReq.MfaCodeReq
+	if !b.bindMfaCode(c, &req) {
+		return
+	}
+	codes, err := mfaService.RegenerateRecoveryCodes(utils.GetUserID(c), req.Code)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(codes, "生成成功", c)
+}
+
+// ResetMfa
+// @Tags      SysUser
+// @Summary   管理员重置用户的二次验证
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.MfaResetReq          true  "用户ID"
+// @Success   200   {object}  response.Response{msg=string}  "重置成功"
+// @Router    /user/mfa/reset [post]
+func (b *BaseApi) ResetMfa(c *gin.Context) {
+	var req systemReq.MfaResetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.IdVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := mfaService.Reset(req.ID); err != nil {
+		global.GVA_LOG.Error("重置失败!", zap.Error(err))
+		response.FailWithMessage("重置失败", c)
+		return
+	}
+	response.OkWithMessage("重置成功", c)
+}
+
+func (b *BaseApi) bindMfaCode(c *gin.Context, req *systemReq.MfaCodeReq) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return false
+	}
+	if err := utils.Verify(*req, utils.MfaCodeVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type OffboardApi struct{}
+
+// PreviewOffboard
+// @Tags      Offboard
+// @Summary   预览离职交接
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.OffboardReq                                          true  "离职用户, 接任人, 密钥处理方式"
+// @Success   200   {object}  response.Response{data=systemRes.OffboardPreview,msg=string}  "将转移的工作空间、应用与密钥"
+// @Router    /offboard/preview [post]
+func (o *OffboardApi) PreviewOffboard(c *gin.Context) {
+	var req systemReq.OffboardReq
+	if !o.bindOffboard(c, &req) {
+		return
+	}
+	res, err := offboardService.Preview(req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// ExecuteOffboard
+// @Tags      Offboard
+// @Summary   执行离职交接, 轮换后的新密钥只返回一次
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.OffboardReq                                         true  "离职用户, 接任人, 密钥处理方式, 原因"
+// @Success   200   {object}  response.Response{data=systemRes.OffboardResult,msg=string}  "交接报告"
+// @Router    /offboard/execute [post]
+func (o *OffboardApi) ExecuteOffboard(c *gin.Context) {
+	var req systemReq.OffboardReq
+	if !o.bindOffboard(c, &req) {
+		return
+	}
+	if req.UserID == utils.GetUserID(c) {
+		response.FailWithMessage("不能对自己执行离职交接", c)
+		return
+	}
+	res, err := offboardService.Execute(req, utils.GetUserID(c), utils.GetUserName(c))
+	if err != nil {
+		global.GVA_LOG.Error("离职交接失败!", zap.Error(err))
+		response.FailWithMessage("离职交接失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "交接完成", c)
+}
+
+// DeactivateUser
+// @Tags      Offboard
+// @Summary   停用用户, 同时禁用gaia账户并注销在线会话
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.UserStatusReq        true  "用户ID"
+// @Success   200   {object}  response.Response{msg=string}  "停用成功"
+// @Router    /offboard/deactivate [post]
+func (o *OffboardApi) DeactivateUser(c *gin.Context) {
+	var req systemReq.UserStatusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.UserID == utils.GetUserID(c) {
+		response.FailWithMessage("不能停用自己", c)
+		return
+	}
+	if err := offboardService.SetActive(req.UserID, false); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("停用成功", c)
+}
+
+// ReactivateUser
+// @Tags      Offboard
+// @Summary   恢复用户, 同时启用gaia账户
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.UserStatusReq        true  "用户ID"
+// @Success   200   {object}  response.Response{msg=string}  "恢复成功"
+// @Router    /offboard/reactivate [post]
+func (o *OffboardApi) ReactivateUser(c *gin.Context) {
+	var req systemReq.UserStatusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := offboardService.SetActive(req.UserID, true); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("恢复成功", c)
+}
+
+// GetOffboardList
+// @Tags      Offboard
+// @Summary   分页获取交接报告
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.SysUserOffboardSearch                         true  "页码, 每页大小, 用户ID"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "交接报告"
+// @Router    /offboard/getOffboardList [post]
+func (o *OffboardApi) GetOffboardList(c *gin.Context) {
+	var pageInfo systemReq.SysUserOffboardSearch
+	if err := c.ShouldBindJSON(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := offboardService.GetOffboardList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+func (o *OffboardApi) bindOffboard(c *gin.Context, req *systemReq.OffboardReq) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return false
+	}
+	if err := utils.Verify(*req, utils.OffboardVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SessionApi struct{}
+
+// GetMySessions
+// @Tags      Session
+// @Summary   获取自己的在线会话
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=[]system.SysUserSession,msg=string}  "在线会话"
+// @Router    /session/mine [get]
+func (s *SessionApi) GetMySessions(c *gin.Context) {
+	claims := utils.GetUserInfo(c)
+	if claims == nil {
+		response.FailWithMessage("获取用户失败", c)
+		return
+	}
+	list, err := sessionService.GetUserSessions(claims.BaseClaims.ID, claims.RegisteredClaims.ID)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// RevokeMySession
+// @Tags      Session
+// @Summary   注销自己的某个会话
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.SessionRevokeReq     true  "会话ID"
+// @Success   200   {object}  response.Response{msg=string}  "注销成功"
+// @Router    /session/mine [delete]
+func (s *SessionApi) RevokeMySession(c *gin.Context) {
+	var req systemReq.SessionRevokeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := sessionService.Revoke(req.SessionID, utils.GetUserID(c), system.SessionRevokeManual); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("注销成功", c)
+}
+
+// GetSessionList
+// @Tags      Session
+// @Summary   分页获取全部会话
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.SysUserSessionSearch                         true  "页码, 每页大小, 用户, 是否只看在线"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取会话"
+// @Router    /session/getSessionList [post]
+func (s *SessionApi) GetSessionList(c *gin.Context) {
+	var pageInfo systemReq.SysUserSessionSearch
+	if err := c.ShouldBindJSON(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := sessionService.GetSessionList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// RevokeSession
+// @Tags      Session
+// @Summary   管理员注销任意会话
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.SessionRevokeReq     true  "会话ID"
+// @Success   200   {object}  response.Response{msg=string}  "注销成功"
+// @Router    /session/revoke [delete]
+func (s *SessionApi) RevokeSession(c *gin.Context) {
+	var req systemReq.SessionRevokeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := sessionService.Revoke(req.SessionID, 0, system.SessionRevokeManual); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("注销成功", c)
+}
+
+// RevokeUserSessions
+// @Tags      Session
+// @Summary   管理员注销用户的全部会话
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.SessionRevokeUserReq  true  "用户ID"
+// @Success   200   {object}  response.Response{msg=string}   "注销成功"
+// @Router    /session/revokeUser [delete]
+func (s *SessionApi) RevokeUserSessions(c *gin.Context) {
+	var req systemReq.SessionRevokeUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.SessionUserVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := sessionService.RevokeUser(req.UserID, system.SessionRevokeManual); err != nil {
+		global.GVA_LOG.Error("注销失败!", zap.Error(err))
+		response.FailWithMessage("注销失败", c)
+		return
+	}
+	response.OkWithMessage("注销成功", c)
+}
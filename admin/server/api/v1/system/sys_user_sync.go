@@ -0,0 +1,83 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type UserSyncApi struct{}
+
+// GetUserSyncStatus
+// @Tags      UserSync
+// @Summary   获取用户同步状态与最近一次运行统计
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=systemRes.UserSyncStatus,msg=string}  "同步状态"
+// @Router    /userSync/status [get]
+func (u *UserSyncApi) GetUserSyncStatus(c *gin.Context) {
+	res, err := userSyncService.Status()
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// GetSyncConflictList
+// @Tags      UserSync
+// @Summary   分页获取用户同步冲突
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.SysUserSyncConflictSearch                     true  "页码, 每页大小, 冲突类型, 处理状态"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "同步冲突"
+// @Router    /userSync/getConflictList [post]
+func (u *UserSyncApi) GetSyncConflictList(c *gin.Context) {
+	var pageInfo systemReq.SysUserSyncConflictSearch
+	if err := c.ShouldBindJSON(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := userSyncService.GetConflictList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// ResolveSyncConflict
+// @Tags      UserSync
+// @Summary   处理用户同步冲突
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.SyncConflictResolveReq  true  "冲突ID, 处理方式, 新用户名"
+// @Success   200   {object}  response.Response{msg=string}     "处理成功"
+// @Router    /userSync/resolveConflict [post]
+func (u *UserSyncApi) ResolveSyncConflict(c *gin.Context) {
+	var req systemReq.SyncConflictResolveReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := utils.Verify(req, utils.SyncConflictResolveVerify); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := userSyncService.ResolveConflict(req, utils.GetUserName(c)); err != nil {
+		global.GVA_LOG.Error("处理失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("处理成功", c)
+}
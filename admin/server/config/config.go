@@ -36,9 +36,15 @@ type Server struct {
 	// 跨域配置
 	Cors CORS `mapstructure:"cors" json:"cors" yaml:"cors"`
 
-	// 对接OA登录oauth2.0
+	// 对接OA登录oauth2.0, 已废弃: 启动时迁移为名为 oa 的OAuth2提供方
 	OaLogin OaLogin `mapstructure:"oa-login" json:"oa-login" yaml:"oa-login"`
 
 	// 对接Gaia平台相关配置
 	Gaia Gaia `mapstructure:"gaia" json:"gaia" yaml:"gaia"`
+
+	// Extend: TOTP 二次验证
+	Mfa Mfa `mapstructure:"mfa" json:"mfa" yaml:"mfa"`
+
+	// Extend: 登录锁定与异常登录检测
+	Lockout Lockout `mapstructure:"lockout" json:"lockout" yaml:"lockout"`
 }
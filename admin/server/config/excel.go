@@ -2,4 +2,16 @@ package config
 
 type Excel struct {
 	Dir string `mapstructure:"dir" json:"dir" yaml:"dir"`
+	// Extend Start: streaming export
+	BatchSize      int `mapstructure:"batch-size" json:"batch-size" yaml:"batch-size"`                // 流式导出每批查询的行数, 默认1000
+	AsyncThreshold int `mapstructure:"async-threshold" json:"async-threshold" yaml:"async-threshold"` // 导出行数超过该值时转为异步任务并上传至OSS, 默认50000
+	// Extend Stop: streaming export
+	// Extend Start: export job queue
+	Retention  int `mapstructure:"retention" json:"retention" yaml:"retention"`       // 导出文件保留天数, 过期后由定时任务清理, 默认7
+	LinkExpire int `mapstructure:"link-expire" json:"link-expire" yaml:"link-expire"` // 签名下载链接的有效分钟数, 默认30
+	// Extend Stop: export job queue
+	// Extend Start: scheduled report
+	SiteURL     string `mapstructure:"site-url" json:"site-url" yaml:"site-url"`             // 管理后台后端的访问地址, 用于邮件中的下载链接, 如 https://admin.example.com/api
+	AttachLimit int    `mapstructure:"attach-limit" json:"attach-limit" yaml:"attach-limit"` // 定时报表附件大小上限(MB), 超过时改为发送下载链接, 默认10
+	// Extend Stop: scheduled report
 }
@@ -5,4 +5,11 @@ type Gaia struct {
 	LoginMaxErrorLimit  int    `mapstructure:"login_max_error_limit" json:"login_max_error_limit" yaml:"login_max_error_limit"`
 	SuperAdminAccountId string `mapstructure:"SUPER_ADMIN_ACCOUNT_ID" json:"SUPER_ADMIN_ACCOUNT_ID" yaml:"SUPER_ADMIN_ACCOUNT_ID"` // 超级管理员账号
 	SuperAdminTenantId  string `mapstructure:"SUPER_ADMIN_TENANT_ID" json:"SUPER_ADMIN_TENANT_ID" yaml:"SUPER_ADMIN_TENANT_ID"`    // 系统默认工作区
+	// Extend Start: default workspace policy
+	DefaultTenantIds  []string `mapstructure:"default_tenant_ids" json:"default_tenant_ids" yaml:"default_tenant_ids"`    // 同步用户时自动加入的工作空间
+	DefaultTenantRole string   `mapstructure:"default_tenant_role" json:"default_tenant_role" yaml:"default_tenant_role"` // 自动加入时的角色, 为空时为 normal
+	// Extend Stop: default workspace policy
+	// Extend Start: usd cost
+	RmbExchangeRate float64 `mapstructure:"rmb_exchange_rate" json:"rmb_exchange_rate" yaml:"rmb_exchange_rate"` // 花费统计中 RMB 折算为美元的汇率, 为空时为 7.26
+	// Extend Stop: usd cost
 }
@@ -0,0 +1,9 @@
+package config
+
+type Lockout struct {
+	IpMaxErrorLimit int    `mapstructure:"ip-max-error-limit" json:"ip-max-error-limit" yaml:"ip-max-error-limit"` // 同一IP失败次数上限, 0 为不限制; 账号上限使用 gaia.login_max_error_limit
+	Window          string `mapstructure:"window" json:"window" yaml:"window"`                                     // 失败次数统计窗口, 默认15m
+	Duration        string `mapstructure:"duration" json:"duration" yaml:"duration"`                               // 首次锁定时长, 默认15m, 此后每次锁定翻倍
+	MaxDuration     string `mapstructure:"max-duration" json:"max-duration" yaml:"max-duration"`                   // 最长锁定时长, 默认24h
+	NotifyAdmin     bool   `mapstructure:"notify-admin" json:"notify-admin" yaml:"notify-admin"`                   // 锁定与可疑登录时邮件通知超级管理员
+}
@@ -0,0 +1,6 @@
+package config
+
+type Mfa struct {
+	Issuer              string `mapstructure:"issuer" json:"issuer" yaml:"issuer"`                                           // 验证器中显示的发行方名称
+	RequiredAuthorities []uint `mapstructure:"required-authorities" json:"required-authorities" yaml:"required-authorities"` // 强制开启二次验证的角色ID, 超级管理员角色始终强制
+}
@@ -5,6 +5,7 @@ import (
 	cron "github.com/flipped-aurora/gin-vue-admin/server/corn"
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/initialize"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia" // Extend: end user block
 	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
 	"go.uber.org/zap"
 )
@@ -30,6 +31,18 @@ func RunWindowsServer() {
 	// 从db加载jwt数据
 	if global.GVA_DB != nil {
 		system.LoadAll()
+		system.LoadRevokedSessions()   // Extend: session registry
+		system.StartExportJobWorkers() // Extend: export job queue
+		// Extend Start: export template sql safety
+		if err := system.SysExportTemplateServiceApp.SyncExportDBApis(); err != nil {
+			zap.L().Error("登记导出数据库权限失败", zap.Error(err))
+		}
+		// Extend Stop: export template sql safety
+		// Extend Start: end user block
+		if err := (&serviceGaia.EndUserService{}).SyncEndUserBlocks(); err != nil {
+			zap.L().Error("重建终端用户封禁标记失败", zap.Error(err))
+		}
+		// Extend Stop: end user block
 	}
 
 	Router := initialize.Routers()
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	gaiaModel "github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
 	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
 	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
@@ -34,8 +35,7 @@ func Corn() {
 		}
 		lock = true
 		user := system.UserExtendService{}
-		user.SyncUser()
-		gaia.SyncUserStatus()
+		user.SyncUser() // Extend: two-way user sync, 状态已在同步中双向处理
 		lock = false
 	}); err != nil {
 		global.GVA_LOG.Fatal("Start Cron Error:" + err.Error())
@@ -44,6 +44,19 @@ func Corn() {
 	}
 	global.GVA_LOG.Info("【定时任务-每1分钟执行1次】同步用户列表任务，已启动！")
 
+	// 每小时同步一次钉钉组织架构
+	if _, err := c.AddFunc("0 30 */1 * * *", func() {
+		if global.GVA_DB == nil {
+			global.GVA_LOG.Info("【定时任务-每1小时执行1次】同步钉钉组织架构任务，数据库没有初始化，暂未开始同步")
+			return
+		}
+		gaia.SyncDingTalk()
+	}); err != nil {
+		global.GVA_LOG.Fatal("每1小时同步一次钉钉组织架构 出错:" + err.Error())
+		return
+	}
+	global.GVA_LOG.Info("【定时任务-每1小时执行1次】同步钉钉组织架构任务，已启动！")
+
 	// 每10分钟同步一次【应用使用分析数据】
 	if _, err := c.AddFunc("0 */10 * * * *", func() {
 		if global.GVA_DB == nil {
@@ -60,11 +73,11 @@ func Corn() {
 				},
 			}
 			// 先删除缓存
-			cacheKey := fmt.Sprintf("app_token_quota_ranking:%d:%d", i, 10)
+			cacheKey := fmt.Sprintf("app_token_quota_ranking:all:%d:%d", i, 10) // Extend: 缓存按数据范围区分, 这里只预热全部范围
 			global.GVA_REDIS.Del(context.Background(), cacheKey)
 
 			// 再获取数据
-			_, _, err := dashService.GetAppQuotaRankingData(req)
+			_, _, err := dashService.GetAppQuotaRankingData(req, gaiaModel.AllTenants)
 			if err != nil {
 				global.GVA_LOG.Error("每10分钟同步一次应用使用分析 获取信息出错:" + err.Error())
 				return
@@ -78,5 +91,22 @@ func Corn() {
 	}
 	global.GVA_LOG.Info("【定时任务-每6分钟执行1次】同步应用使用分析数据任务，已启动！")
 
+	// Extend Start: end user block
+	// 每10分钟按 end_user_block_extend 重建一次终端用户封禁标记, 防止Redis重启或淘汰后封禁失效
+	if _, err := c.AddFunc("0 5/10 * * * *", func() {
+		if global.GVA_DB == nil {
+			global.GVA_LOG.Info("【定时任务-每10分钟执行1次】重建终端用户封禁标记任务，数据库没有初始化，暂未开始同步")
+			return
+		}
+		if err := (&gaia.EndUserService{}).SyncEndUserBlocks(); err != nil {
+			global.GVA_LOG.Error("每10分钟重建终端用户封禁标记 出错:" + err.Error())
+		}
+	}); err != nil {
+		global.GVA_LOG.Fatal("每10分钟重建终端用户封禁标记 出错:" + err.Error())
+		return
+	}
+	global.GVA_LOG.Info("【定时任务-每10分钟执行1次】重建终端用户封禁标记任务，已启动！")
+	// Extend Stop: end user block
+
 	c.Start()
 }
@@ -63,10 +63,26 @@ func (e *ensureTables) MigrateTable(ctx context.Context) (context.Context, error
 
 		// Extend gaia model
 		gaia.AccountDingTalkExtend{},
+		gaia.DingTalkDepartment{}, // Extend DingTalk organization
 		gaia.AppRequestTestBatch{},
 		gaia.AppRequestTest{},
-		gaia.SystemIntegration{},     // Extend System Integration
-		sysModel.SysUserGlobalCode{}, // Extend Global Code
+		gaia.SystemIntegration{},         // Extend System Integration
+		sysModel.SysUserGlobalCode{},     // Extend Global Code
+		sysModel.SysUserMfa{},            // Extend TOTP mfa
+		sysModel.SysUserSession{},        // Extend session registry
+		sysModel.SysLoginHistory{},       // Extend login lockout
+		sysModel.SysAccessToken{},        // Extend personal access token
+		gaia.TenantMemberAudit{},         // Extend workspace member audit
+		sysModel.SysUserOffboard{},       // Extend offboarding
+		sysModel.SysUserSyncState{},      // Extend two-way user sync
+		sysModel.SysUserSyncConflict{},   // Extend two-way user sync
+		gaia.EndUserBlock{},              // Extend end user directory
+		sysModel.SysExportJob{},          // Extend streaming export
+		sysModel.SysExportSchedule{},     // Extend scheduled report
+		sysModel.SysExportScheduleRun{},  // Extend scheduled report
+		sysModel.SysAuthorityDataScope{}, // Extend data scope
+		sysModel.SysAuthorityGrant{},     // Extend temporary authority grant
+		sysModel.SysAuthorityGrantLog{},  // Extend temporary authority grant
 		// Extend gaia model
 	}
 	for _, t := range tables {
@@ -109,6 +125,7 @@ func (e *ensureTables) TableCreated(ctx context.Context) bool {
 
 		// Extend gaia model
 		gaia.AccountDingTalkExtend{},
+		gaia.DingTalkDepartment{}, // Extend DingTalk organization
 		gaia.AppRequestTestBatch{},
 		gaia.AppRequestTest{},
 		gaia.SystemIntegration{},     // Extend System Integration
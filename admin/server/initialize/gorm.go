@@ -7,6 +7,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/model/example"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -65,10 +66,26 @@ func RegisterTables() {
 
 		// Extend gaia model
 		gaia.AccountDingTalkExtend{},
+		gaia.DingTalkDepartment{}, // Extend DingTalk organization
 		gaia.AppRequestTestBatch{},
 		gaia.AppRequestTest{},
-		gaia.SystemIntegration{},   // Extend System Integration
-		system.SysUserGlobalCode{}, // Extend Global Code
+		gaia.SystemIntegration{},       // Extend System Integration
+		system.SysUserGlobalCode{},     // Extend Global Code
+		system.SysUserMfa{},            // Extend TOTP mfa
+		system.SysUserSession{},        // Extend session registry
+		system.SysLoginHistory{},       // Extend login lockout
+		system.SysAccessToken{},        // Extend personal access token
+		gaia.TenantMemberAudit{},       // Extend workspace member audit
+		system.SysUserOffboard{},       // Extend offboarding
+		system.SysUserSyncState{},      // Extend two-way user sync
+		system.SysUserSyncConflict{},   // Extend two-way user sync
+		gaia.EndUserBlock{},            // Extend end user directory
+		system.SysExportJob{},          // Extend streaming export
+		system.SysExportSchedule{},     // Extend scheduled report
+		system.SysExportScheduleRun{},  // Extend scheduled report
+		system.SysAuthorityDataScope{}, // Extend data scope
+		system.SysAuthorityGrant{},     // Extend temporary authority grant
+		system.SysAuthorityGrantLog{},  // Extend temporary authority grant
 		// Extend gaia model
 	)
 	if err != nil {
@@ -83,4 +100,17 @@ func RegisterTables() {
 		os.Exit(0)
 	}
 	global.GVA_LOG.Info("register table success")
+
+	// Extend Start: migrate oa-login
+	var oauth2Service serviceGaia.OAuth2Service
+	if err = oauth2Service.MigrateOaLogin(); err != nil {
+		global.GVA_LOG.Error("migrate oa-login failed", zap.Error(err))
+	}
+	// Extend Stop: migrate oa-login
+
+	// Extend Start: unified password
+	if err = serviceGaia.CredentialServiceApp.RetireBcrypt(); err != nil {
+		global.GVA_LOG.Error("retire bcrypt password failed", zap.Error(err))
+	}
+	// Extend Stop: unified password
 }
@@ -74,6 +74,7 @@ func Routers() *gin.Engine {
 	{
 		systemRouter.InitBaseRouter(PublicGroup) // 注册基础功能路由 不做鉴权
 		systemRouter.InitInitRouter(PublicGroup) // 自动初始化相关
+		systemRouter.InitScimRouter(PublicGroup) // Extend: SCIM 2.0 用户预配, 使用SCIM令牌鉴权
 	}
 
 	{
@@ -92,6 +93,16 @@ func Routers() *gin.Engine {
 		systemRouter.InitAuthorityBtnRouterRouter(PrivateGroup)     // 按钮权限管理
 		systemRouter.InitSysExportTemplateRouter(PrivateGroup)      // 导出模板
 		systemRouter.InitSysParamsRouter(PrivateGroup, PublicGroup) // 参数管理
+		systemRouter.InitSessionRouter(PrivateGroup)                // Extend: 会话管理
+		systemRouter.InitLoginSecurityRouter(PrivateGroup)          // Extend: 登录锁定与登录历史
+		systemRouter.InitAccessTokenRouter(PrivateGroup)            // Extend: 个人访问令牌
+		systemRouter.InitOffboardRouter(PrivateGroup)               // Extend: 离职交接
+		systemRouter.InitUserSyncRouter(PrivateGroup)               // Extend: 用户同步
+		systemRouter.InitExportJobRouter(PrivateGroup, PublicGroup) // Extend: 导出任务队列
+		systemRouter.InitExportScheduleRouter(PrivateGroup)         // Extend: 定时报表
+		systemRouter.InitRbacRouter(PrivateGroup)                   // Extend: 权限配置包
+		systemRouter.InitAuthorityGrantRouter(PrivateGroup)         // Extend: 临时角色
+		systemRouter.InitPermissionRouter(PrivateGroup)             // Extend: 权限模拟
 		exampleRouter.InitCustomerRouter(PrivateGroup)              // 客户路由
 		exampleRouter.InitFileUploadAndDownloadRouter(PrivateGroup) // 文件上传下载功能路由
 
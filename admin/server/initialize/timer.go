@@ -7,6 +7,7 @@ import (
 	"github.com/robfig/cron/v3"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/service/system" // Extend: export job queue
 )
 
 func Timer() {
@@ -24,6 +25,42 @@ func Timer() {
 			fmt.Println("add timer error:", err)
 		}
 
+		// Extend Start: export job queue
+		_, err = global.GVA_Timer.AddTaskByFunc("ClearExportJob", "@hourly", func() {
+			if global.GVA_DB == nil {
+				return
+			}
+			if err := system.ExportJobServiceApp.ClearExpired(); err != nil {
+				fmt.Println("timer error:", err)
+			}
+		}, "定时清理过期的导出文件与中断的导出任务", option...)
+		if err != nil {
+			fmt.Println("add timer error:", err)
+		}
+		// Extend Stop: export job queue
+
+		// Extend Start: scheduled report
+		if global.GVA_DB != nil {
+			if err := system.ExportScheduleServiceApp.LoadExportSchedules(); err != nil {
+				fmt.Println("load export schedule error:", err)
+			}
+		}
+		// Extend Stop: scheduled report
+
+		// Extend Start: temporary authority grant
+		_, err = global.GVA_Timer.AddTaskByFunc("ExpireAuthorityGrant", "@every 1m", func() {
+			if global.GVA_DB == nil {
+				return
+			}
+			if err := system.AuthorityGrantServiceApp.ExpireGrants(); err != nil {
+				fmt.Println("timer error:", err)
+			}
+		}, "临时角色到期收回", option...)
+		if err != nil {
+			fmt.Println("add timer error:", err)
+		}
+		// Extend Stop: temporary authority grant
+
 		// 其他定时任务定在这里 参考上方使用方法
 
 		//_, err := global.GVA_Timer.AddTaskByFunc("定时任务标识", "corn表达式", func() {
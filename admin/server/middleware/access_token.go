@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var accessTokenService = service.ServiceGroupApp.SystemServiceGroup.AccessTokenService
+
+// getAccessToken 从 Authorization: Bearer 或 x-token 请求头中获取个人访问令牌
+func getAccessToken(c *gin.Context) string {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if !strings.HasPrefix(token, system.AccessTokenPrefix) {
+		token = c.GetHeader("x-token")
+	}
+	if !strings.HasPrefix(token, system.AccessTokenPrefix) {
+		return ""
+	}
+	return token
+}
+
+// accessTokenAuth 校验个人访问令牌, 以令牌所属用户的身份继续请求
+func accessTokenAuth(c *gin.Context, raw string) {
+	token, user, err := accessTokenService.Authenticate(raw, c.ClientIP())
+	if err != nil {
+		global.GVA_LOG.Warn("访问令牌鉴权失败!", zap.String("ip", c.ClientIP()), zap.Error(err))
+		response.NoAuth(err.Error(), c)
+		c.Abort()
+		return
+	}
+	claims := &systemReq.CustomClaims{
+		BaseClaims: systemReq.BaseClaims{
+			UUID:        user.UUID,
+			ID:          user.ID,
+			Username:    user.Username,
+			NickName:    user.NickName,
+			AuthorityId: user.AuthorityId,
+			Email:       user.Email,
+		},
+		Email: user.Email,
+		Exp:   token.ExpiresAt.Unix(),
+	}
+	var account gaia.Account
+	if err = global.GVA_DB.Select("id").Where("email = ?", user.Email).First(&account).Error; err == nil {
+		claims.UserId = account.ID.String()
+		claims.BaseClaims.UserId = claims.UserId
+	}
+	c.Set("claims", claims)
+	c.Set(system.AccessTokenScopesKey, token.Scopes)
+	c.Next()
+}
+
+// accessTokenAllowed 通过个人访问令牌鉴权的请求只能访问令牌范围内的接口
+func accessTokenAllowed(c *gin.Context, path, method string) bool {
+	scopes, exists := c.Get(system.AccessTokenScopesKey)
+	if !exists {
+		return true
+	}
+	list, _ := scopes.([]system.AccessTokenScope)
+	return accessTokenService.Allowed(list, path, method)
+}
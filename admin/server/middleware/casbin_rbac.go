@@ -26,6 +26,13 @@ func CasbinHandler() gin.HandlerFunc {
 		sub := strconv.Itoa(int(waitUse.AuthorityId))
 		e := casbinService.Casbin() // 判断策略中是否存在
 		success, _ := e.Enforce(sub, obj, act)
+		// Extend Start: personal access token 令牌只能访问授予的接口
+		if success && !accessTokenAllowed(c, obj, act) {
+			response.FailWithDetailed(gin.H{}, "访问令牌无权访问该接口", c)
+			c.Abort()
+			return
+		}
+		// Extend Stop: personal access token
 		if !success {
 			response.FailWithDetailed(gin.H{}, "权限不足", c)
 			c.Abort()
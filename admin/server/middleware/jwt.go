@@ -1,9 +1,7 @@
 package middleware
 
 import (
-	"context"
 	"errors"
-	"fmt"
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/golang-jwt/jwt/v4"
@@ -17,9 +15,16 @@ import (
 )
 
 var jwtService = service.ServiceGroupApp.SystemServiceGroup.JwtService
+var sessionService = service.ServiceGroupApp.SystemServiceGroup.SessionService // Extend: session registry
 
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Extend Start: personal access token 脚本调用使用个人访问令牌, 不走jwt
+		if accessToken := getAccessToken(c); accessToken != "" {
+			accessTokenAuth(c, accessToken)
+			return
+		}
+		// Extend Stop: personal access token
 		// 我们这里jwt鉴权取头部信息 x-token 登录时回返回token信息 这里前端需要把token存储到cookie或者本地localStorage中 不过需要跟后端协商过期时间 可以约定刷新令牌或者重新登录
 		token := utils.GetToken(c)
 		if token == "" {
@@ -49,6 +54,15 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
+		// Extend Start: session registry 会话被注销后令牌立即失效
+		if !sessionService.Active(claims.RegisteredClaims.ID) {
+			response.NoAuth("会话已失效, 请重新登录", c)
+			utils.ClearToken(c)
+			c.Abort()
+			return
+		}
+		// Extend Stop: session registry
+
 		// 已登录用户被管理员禁用 需要使该用户的jwt失效 此处比较消耗性能 如果需要 请自行打开
 		// 用户被删除的逻辑 需要优化 此处比较消耗性能 如果需要 请自行打开
 
@@ -58,15 +72,8 @@ func JWTAuth() gin.HandlerFunc {
 		//	c.Abort()
 		//}
 		// Extend Start: Gaia 的黑名单
-		if global.GVA_CONFIG.System.UseMultipoint || global.GVA_CONFIG.System.UseRedis {
-			// 查询是否有黑名单
-			// Check if the user is disabled
-			if count, iErr := global.GVA_REDIS.Get(context.Background(), fmt.Sprintf(
-				"login_error_rate_limit:%s", claims.Email)).Int(); iErr == nil && count >= global.GVA_CONFIG.Gaia.LoginMaxErrorLimit {
-				response.FailWithDetailed(gin.H{"reload": true}, err.Error(), c)
-				c.Abort()
-			}
-		}
+		// 登录失败计数只限制新的登录, 不再使已签发的令牌失效, 避免他人反复输错密码把用户踢下线
+		// 用户被禁用时已通过 session registry 注销其全部会话
 		// Extend Stop: Gaia 的黑名单
 		c.Set("claims", claims)
 		if claims.Exp-time.Now().Unix() < claims.BufferTime {
@@ -82,6 +89,12 @@ func JWTAuth() gin.HandlerFunc {
 				// 记录新的活跃jwt
 				_ = jwtService.SetRedisJWT(newToken, newClaims.Username)
 			}
+			// Extend: session registry
+			if newClaims != nil {
+				sessionService.Refresh(*newClaims)
+			}
+		} else {
+			sessionService.Touch(*claims) // Extend: session registry
 		}
 		c.Next()
 
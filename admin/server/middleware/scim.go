@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+var systemIntegratedService = service.ServiceGroupApp.GaiaServiceGroup.SystemIntegratedService
+
+// ScimAuth SCIM接口使用集成配置中的Bearer Token鉴权
+func ScimAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		integrate, err := systemIntegratedService.GetEnabledIntegrate(gaia.SystemIntegrationSCIM)
+		if err != nil || len(integrate.AppSecret) == 0 || len(token) == 0 ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(integrate.AppSecret)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, systemRes.NewScimError(http.StatusUnauthorized, "", "未授权"))
+			return
+		}
+		c.Next()
+	}
+}
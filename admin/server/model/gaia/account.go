@@ -59,14 +59,29 @@ type TenantAccountJoin struct {
 
 // AccountDingTalkExtend gaia钉钉关联表
 type AccountDingTalkExtend struct {
-	ID       uuid.UUID `json:"id" gorm:"primaryKey;comment:账户唯一标识符"`
-	DingTalk string    `json:"ding_talk" gorm:"index:account_ding_talk_idx;comment:关联钉钉id"`
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;comment:账户唯一标识符"`
+	DingTalk  string    `json:"ding_talk" gorm:"index:account_ding_talk_idx;comment:关联钉钉id"`
+	UnionID   string    `json:"union_id" gorm:"index:account_ding_talk_union_idx;comment:钉钉unionId"`
+	Name      string    `json:"name" gorm:"comment:钉钉姓名"`
+	Mobile    string    `json:"mobile" gorm:"comment:钉钉手机号"`
+	DeptIDs   string    `json:"dept_ids" gorm:"comment:所属部门id列表,逗号分隔"`
+	Departed  bool      `json:"departed" gorm:"default:false;comment:是否已离职(由组织同步停用)"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"comment:最后同步时间"`
+}
+
+// DingTalkDepartment 钉钉部门表
+type DingTalkDepartment struct {
+	DeptID    int64     `json:"dept_id" gorm:"primaryKey;autoIncrement:false;comment:钉钉部门id"`
+	ParentID  int64     `json:"parent_id" gorm:"index;comment:上级部门id"`
+	Name      string    `json:"name" gorm:"comment:部门名称"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"comment:最后同步时间"`
 }
 
 func (Account) TableName() string               { return "accounts" }
 func (AccountIntegrate) TableName() string      { return "account_integrates" }
 func (TenantAccountJoin) TableName() string     { return "tenant_account_joins" }
 func (AccountDingTalkExtend) TableName() string { return "account_ding_talk_extend" }
+func (DingTalkDepartment) TableName() string    { return "ding_talk_department_extend" }
 
 // GetAccount
 // @description: Get user information through the user provider relationship table
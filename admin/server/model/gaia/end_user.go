@@ -20,3 +20,25 @@ type EndUser struct {
 }
 
 func (EndUser) TableName() string { return "end_users" }
+
+// Extend Start: end user directory
+
+// EndUserBlockedKey 终端用户封禁标记, gaia的 service_api 与 web 鉴权时读取
+const EndUserBlockedKey = "end_user_blocked:%s"
+
+// EndUserBlock 终端用户封禁记录, Redis中的标记以此为准
+type EndUserBlock struct {
+	Id         uint      `json:"id" gorm:"primarykey;column:id;comment:id"`
+	EndUserID  string    `json:"end_user_id" gorm:"uniqueIndex;comment:终端用户ID"`
+	TenantID   string    `json:"tenant_id" gorm:"index;comment:工作空间ID"`
+	AppID      string    `json:"app_id" gorm:"index;comment:应用ID"`
+	Reason     string    `json:"reason" gorm:"comment:封禁原因"`
+	OperatorID uint      `json:"operator_id" gorm:"comment:操作人ID"`
+	Operator   string    `json:"operator" gorm:"comment:操作人"`
+	CreatedAt  time.Time `json:"created_at" gorm:"comment:封禁时间"`
+}
+
+// TableName end_user_block_extend表
+func (EndUserBlock) TableName() string { return "end_user_block_extend" }
+
+// Extend Stop: end user directory
@@ -0,0 +1,21 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// EndUserSearch 终端用户查询
+type EndUserSearch struct {
+	request.PageInfo
+	TenantID  string `json:"tenant_id" form:"tenant_id"`   // 工作空间ID
+	AppID     string `json:"app_id" form:"app_id"`         // 应用ID
+	SessionID string `json:"session_id" form:"session_id"` // 会话标识, 即调用方传入的 user
+	Type      string `json:"type" form:"type"`             // 用户类型 service_api/browser 等
+	Blocked   *bool  `json:"blocked" form:"blocked"`       // 是否已封禁
+}
+
+// EndUserBlockReq 封禁或解封终端用户
+type EndUserBlockReq struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
@@ -6,24 +6,44 @@ type SystemOAuth2Error struct {
 	Info string `json:"info" gorm:"comment:错误详情"` // 错误详情
 }
 
+// SystemScimRequest SCIM 用户预配配置
+type SystemScimRequest struct {
+	Status      bool   `json:"status"`      // 是否启用
+	Rotate      bool   `json:"rotate"`      // 是否重新生成令牌
+	Authorities []uint `json:"authorities"` // 允许IdP管理成员的角色ID
+}
+
 // SystemOAuth2Request OAuth2 集成配置
 type SystemOAuth2Request struct {
-	Classify        uint   `json:"classify" gorm:"comment:分类"`                // 分类
-	Status          bool   `json:"status" gorm:"comment:状态"`                  // 状态
-	ServerURL       string `json:"server_url" gorm:"comment:服务器地址"`           // OAuth2 服务器地址
-	AuthorizeURL    string `json:"authorize_url" gorm:"comment:申请认证的URL"`     // 申请认证的URL
-	TokenURL        string `json:"token_url" gorm:"comment:获取Token的URL"`      // 获取Token的URL
-	UserinfoURL     string `json:"userinfo_url" gorm:"comment:获取用户信息URL"`     // 获取用户信息的URL
-	LogoutURL       string `json:"logout_url" gorm:"comment:退出登录回调URL"`       // 退出登录回调URL
-	DiscoveryURL    string `json:"discovery_url" gorm:"comment:OIDC发现配置URL"`  // OIDC 发现配置URL
-	AppID           string `json:"app_id" gorm:"comment:Client ID"`           // Client ID
-	AppSecret       string `json:"app_secret" gorm:"comment:Client Secret"`   // Client Secret
-	UserNameField   string `json:"user_name_field" gorm:"comment:用户名字段"`      // 用户名字段
-	UserEmailField  string `json:"user_email_field" gorm:"comment:邮箱字段"`      // 邮箱字段
-	UserIDField     string `json:"user_id_field" gorm:"comment:用户唯一标识字段"`     // 用户唯一标识字段
-	Scope           string `json:"scope" gorm:"comment:授权范围scope"`            // 授权范围
-	TokenAuthMethod string `json:"token_auth_method" gorm:"comment:令牌端点认证方式"` // client_secret_post|client_secret_basic
-	RedirectUri     string `json:"redirect_uri" gorm:"comment:测试用回调地址"`       // 测试用回调地址
-	Test            bool   `json:"test" gorm:"default:0;comment:是否测试链接联通性"`   // 是否测试链接联通性
-	Code            string `json:"code" gorm:"default:0;comment:code代码"`      // code代码
+	Id               uint   `json:"id" gorm:"comment:id"`                           // 提供方ID, 为空时新增
+	Name             string `json:"name" gorm:"comment:提供方标识"`                      // 提供方标识
+	Title            string `json:"title" gorm:"comment:登录页显示名称"`                   // 登录页显示名称
+	AuthorityId      uint   `json:"authority_id" gorm:"comment:新用户默认角色"`            // 新用户默认角色
+	EmailDomains     string `json:"email_domains" gorm:"comment:邮箱域名路由"`            // 邮箱域名路由, 逗号分隔
+	Classify         uint   `json:"classify" gorm:"comment:分类"`                     // 分类
+	Status           bool   `json:"status" gorm:"comment:状态"`                       // 状态
+	ServerURL        string `json:"server_url" gorm:"comment:服务器地址"`                // OAuth2 服务器地址
+	AuthorizeURL     string `json:"authorize_url" gorm:"comment:申请认证的URL"`          // 申请认证的URL
+	TokenURL         string `json:"token_url" gorm:"comment:获取Token的URL"`           // 获取Token的URL
+	UserinfoURL      string `json:"userinfo_url" gorm:"comment:获取用户信息URL"`          // 获取用户信息的URL
+	LogoutURL        string `json:"logout_url" gorm:"comment:退出登录回调URL"`            // 退出登录回调URL
+	DiscoveryURL     string `json:"discovery_url" gorm:"comment:OIDC发现配置URL"`       // OIDC 发现配置URL
+	AppID            string `json:"app_id" gorm:"comment:Client ID"`                // Client ID
+	AppSecret        string `json:"app_secret" gorm:"comment:Client Secret"`        // Client Secret
+	UserNameField    string `json:"user_name_field" gorm:"comment:用户名字段"`           // 用户名字段
+	UserEmailField   string `json:"user_email_field" gorm:"comment:邮箱字段"`           // 邮箱字段
+	UserIDField      string `json:"user_id_field" gorm:"comment:用户唯一标识字段"`          // 用户唯一标识字段
+	UserAvatarField  string `json:"user_avatar_field" gorm:"comment:头像字段"`          // 头像字段
+	UserinfoMethod   string `json:"userinfo_method" gorm:"comment:用户信息请求方式"`        // GET|POST
+	UserinfoRawToken bool   `json:"userinfo_raw_token" gorm:"comment:令牌不带Bearer前缀"` // 兼容旧OA系统
+	Scope            string `json:"scope" gorm:"comment:授权范围scope"`                 // 授权范围
+	TokenAuthMethod  string `json:"token_auth_method" gorm:"comment:令牌端点认证方式"`      // client_secret_post|client_secret_basic
+	RedirectUri      string `json:"redirect_uri" gorm:"comment:测试用回调地址"`            // 测试用回调地址
+	Test             bool   `json:"test" gorm:"default:0;comment:是否测试链接联通性"`        // 是否测试链接联通性
+	Code             string `json:"code" gorm:"default:0;comment:code代码"`           // code代码
+
+	// Extend Start: oauth2 email trust
+	UserEmailVerifiedField string `json:"user_email_verified_field" gorm:"comment:邮箱已验证字段"` // 邮箱已验证标记字段, 默认 email_verified
+	TrustIssuerEmail       bool   `json:"trust_issuer_email" gorm:"comment:信任提供方返回的邮箱"`     // 提供方返回的邮箱均已验证且由管理员维护, 可按邮箱关联已有用户
+	// Extend Stop: oauth2 email trust
 }
@@ -1,11 +1,60 @@
-
 package request
 
 import (
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
-	
 )
 
-type TenantsSearch struct{
-    request.PageInfo
+type TenantsSearch struct {
+	request.PageInfo
+	// Extend Start: workspace management
+	Name   string `json:"name" form:"name"`     // 工作空间名称
+	Plan   string `json:"plan" form:"plan"`     // 套餐类型
+	Status string `json:"status" form:"status"` // 工作空间状态
+	// Extend Stop: workspace management
+}
+
+// Extend Start: workspace management
+
+// TenantUpdateReq 修改工作空间名称、套餐与状态, 为空的字段不修改
+type TenantUpdateReq struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Plan   string `json:"plan"`
+	Status string `json:"status"`
+}
+
+// TenantMemberReq 添加工作空间成员
+type TenantMemberReq struct {
+	TenantID string `json:"tenantId"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+// TenantAccountReq 移除成员或转移所有权
+type TenantAccountReq struct {
+	TenantID  string `json:"tenantId"`
+	AccountID string `json:"accountId"`
 }
+
+// TenantRoleItem 单个成员的角色变更
+type TenantRoleItem struct {
+	TenantID  string `json:"tenantId"`
+	AccountID string `json:"accountId"`
+	Role      string `json:"role"`
+}
+
+// TenantRoleUpdateReq 批量修改成员角色, 同一工作空间变更后必须有且只有一个所有者
+type TenantRoleUpdateReq struct {
+	Items  []TenantRoleItem `json:"items"`
+	Reason string           `json:"reason"`
+}
+
+// TenantMemberAuditSearch 成员变更审计查询
+type TenantMemberAuditSearch struct {
+	request.PageInfo
+	TenantID  string `json:"tenantId" form:"tenantId"`
+	AccountID string `json:"accountId" form:"accountId"`
+	Action    string `json:"action" form:"action"`
+}
+
+// Extend Stop: workspace management
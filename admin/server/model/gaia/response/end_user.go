@@ -0,0 +1,39 @@
+package response
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// EndUserInfo 终端用户及其调用统计
+type EndUserInfo struct {
+	gaia.EndUser
+	AppName      string    `json:"app_name" gorm:"column:app_name"`
+	TenantName   string    `json:"tenant_name" gorm:"column:tenant_name"`
+	Blocked      bool      `json:"blocked" gorm:"column:blocked"`
+	FirstSeen    time.Time `json:"first_seen" gorm:"-"`    // 首次出现时间
+	LastSeen     time.Time `json:"last_seen" gorm:"-"`     // 最近一次调用时间
+	MessageCount int64     `json:"message_count" gorm:"-"` // 消息数
+	WorkflowRuns int64     `json:"workflow_runs" gorm:"-"` // 工作流运行次数
+	TotalCost    float64   `json:"total_cost" gorm:"-"`    // 消息总花费(USD)
+}
+
+// EndUserConversation 终端用户的会话
+type EndUserConversation struct {
+	ID            string    `json:"id" gorm:"column:id"`
+	Name          string    `json:"name" gorm:"column:name"`
+	FromSource    string    `json:"from_source" gorm:"column:from_source"`
+	MessageCount  int64     `json:"message_count" gorm:"column:message_count"`
+	TotalCost     float64   `json:"total_cost" gorm:"column:total_cost"`
+	LastMessageAt time.Time `json:"last_message_at" gorm:"column:last_message_at"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// EndUserDetail 终端用户详情
+type EndUserDetail struct {
+	EndUserInfo
+	Block         *gaia.EndUserBlock    `json:"block"`         // 封禁记录, 未封禁时为空
+	Conversations []EndUserConversation `json:"conversations"` // 最近的会话
+}
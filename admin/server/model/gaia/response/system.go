@@ -0,0 +1,7 @@
+package response
+
+// OAuth2Provider 登录页展示的OAuth2/OIDC提供方
+type OAuth2Provider struct {
+	Name  string `json:"name" gorm:"comment:提供方标识"`
+	Title string `json:"title" gorm:"comment:显示名称"`
+}
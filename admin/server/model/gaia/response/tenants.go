@@ -0,0 +1,45 @@
+package response
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+)
+
+// TenantUsage 工作空间用量统计
+type TenantUsage struct {
+	MemberCount  int64   `json:"member_count" gorm:"column:member_count"`   // 成员数
+	AppCount     int64   `json:"app_count" gorm:"column:app_count"`         // 应用数
+	MessageCount int64   `json:"message_count" gorm:"column:message_count"` // 消息数
+	TotalCost    float64 `json:"total_cost" gorm:"column:total_cost"`       // 消息总花费(USD)
+}
+
+// TenantInfo 工作空间及其所有者与用量
+type TenantInfo struct {
+	gaia.Tenants
+	TenantUsage
+	OwnerName  string `json:"owner_name"`  // 所有者名称
+	OwnerEmail string `json:"owner_email"` // 所有者邮箱
+}
+
+// TenantMember 工作空间成员
+type TenantMember struct {
+	AccountID string    `json:"account_id" gorm:"column:account_id"`
+	Name      string    `json:"name" gorm:"column:name"`
+	Email     string    `json:"email" gorm:"column:email"`
+	Status    string    `json:"status" gorm:"column:status"` // 账户状态
+	Role      string    `json:"role" gorm:"column:role"`
+	Current   bool      `json:"current" gorm:"column:current"` // 是否为该账户当前工作空间
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// AccountTenant 账户加入的工作空间
+type AccountTenant struct {
+	TenantID   string    `json:"tenant_id" gorm:"column:tenant_id"`
+	TenantName string    `json:"tenant_name" gorm:"column:tenant_name"`
+	Plan       string    `json:"plan" gorm:"column:plan"`
+	Status     string    `json:"status" gorm:"column:status"` // 工作空间状态
+	Role       string    `json:"role" gorm:"column:role"`
+	Current    bool      `json:"current" gorm:"column:current"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+}
@@ -1,9 +1,15 @@
 package gaia
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 const SystemIntegrationDingTalk = uint(1) // 钉钉集成
 const SystemIntegrationWeiXin = uint(2)   // 微信集成
 const SystemIntegrationFeiShu = uint(3)   // 飞书集成
 const SystemIntegrationOAuth2 = uint(4)   // OAuth2集成
+const SystemIntegrationSCIM = uint(5)     // SCIM用户预配, AppSecret 保存 Bearer Token
 
 // SystemIntegration 系统集成表
 type SystemIntegration struct {
@@ -17,9 +23,68 @@ type SystemIntegration struct {
 	AppSecret string `json:"app_secret" gorm:"default:;comment:加密密钥"`
 	Test      bool   `json:"test" gorm:"default:0;comment:是否测试链接联通性"`
 	Config    string `json:"config" gorm:"type:text;default:;comment:其他配置"`
+	// Extend Start: multiple oauth2 providers
+	Name         string `json:"name" gorm:"default:;comment:提供方标识"`
+	Title        string `json:"title" gorm:"default:;comment:登录页显示名称"`
+	AuthorityId  uint   `json:"authority_id" gorm:"default:0;comment:新用户默认角色"`
+	EmailDomains string `json:"email_domains" gorm:"default:;comment:邮箱域名路由,逗号分隔"`
+	// Extend Stop: multiple oauth2 providers
+}
+
+// ScimConfig SCIM集成保存在 Config 中的配置
+type ScimConfig struct {
+	Authorities []uint `json:"authorities"` // 允许IdP管理成员的角色ID, 超级管理员角色始终不受SCIM管理
 }
 
 // TableName system_integration_extend表 SystemIntegration自定义表名 system_integration_extend
 func (SystemIntegration) TableName() string {
 	return "system_integration_extend"
 }
+
+// ProviderName 提供方标识, 同时作为 account_integrates.provider 的值
+// 早期只有一个OAuth2集成且未填写名称, 沿用默认的 oauth2 以保持已有关联
+func (s SystemIntegration) ProviderName() string {
+	if len(s.Name) == 0 {
+		return DefaultProviderType
+	}
+	return s.Name
+}
+
+// Domains 邮箱域名路由列表
+func (s SystemIntegration) Domains() (domains []string) {
+	for _, v := range strings.Split(s.EmailDomains, ",") {
+		if v = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(v), "@")); len(v) > 0 {
+			domains = append(domains, v)
+		}
+	}
+	return domains
+}
+
+// MatchEmail 邮箱是否属于该提供方, 未配置域名的提供方不限制
+func (s SystemIntegration) MatchEmail(email string) bool {
+	domains := s.Domains()
+	if len(domains) == 0 {
+		return true
+	}
+	email = strings.ToLower(email)
+	for _, v := range domains {
+		if strings.HasSuffix(email, "@"+v) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustEmail 邮箱属于该提供方, 且提供方配置了邮箱域名或声明信任其返回的邮箱, 只有此时才能按邮箱关联已有用户
+func (s SystemIntegration) TrustEmail(email string) bool {
+	return s.MatchEmail(email) && (len(s.Domains()) > 0 || s.TrustIssuerEmail())
+}
+
+// TrustIssuerEmail 提供方配置中是否声明其返回的邮箱均已验证, 用于不返回 email_verified 的自建身份源
+func (s SystemIntegration) TrustIssuerEmail() bool {
+	var config struct {
+		TrustIssuerEmail bool `json:"trust_issuer_email"`
+	}
+	_ = json.Unmarshal([]byte(s.Config), &config)
+	return config.TrustIssuerEmail
+}
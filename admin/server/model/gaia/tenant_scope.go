@@ -0,0 +1,46 @@
+package gaia
+
+import (
+	"errors"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// ErrTenantOutOfScope 工作空间不在当前角色的数据范围内
+var ErrTenantOutOfScope = errors.New("没有该工作空间的数据权限")
+
+// TenantScope 角色可见的工作空间范围, 由后台角色的数据范围解析而来, gaia 的服务按此过滤数据
+type TenantScope struct {
+	All       bool     // 不限制
+	TenantIDs []string // All 为 false 时可见的工作空间, 为空时看不到任何数据
+}
+
+// AllTenants 不限制数据范围, 用于系统内部调用
+var AllTenants = TenantScope{All: true}
+
+// Contains 工作空间是否在范围内
+func (s TenantScope) Contains(tenantID string) bool {
+	return s.All || slices.Contains(s.TenantIDs, tenantID)
+}
+
+// Check 工作空间不在范围内时返回 ErrTenantOutOfScope
+func (s TenantScope) Check(tenantIDs ...string) error {
+	for _, tenantID := range tenantIDs {
+		if !s.Contains(tenantID) {
+			return ErrTenantOutOfScope
+		}
+	}
+	return nil
+}
+
+// Where 按工作空间字段过滤, column 为带表名的工作空间ID字段
+func (s TenantScope) Where(db *gorm.DB, column string) *gorm.DB {
+	if s.All {
+		return db
+	}
+	if len(s.TenantIDs) == 0 {
+		return db.Where("1 = 0")
+	}
+	return db.Where(column+" IN ?", s.TenantIDs)
+}
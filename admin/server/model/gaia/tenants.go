@@ -46,3 +46,56 @@ type TenantAccountJoins struct {
 func (TenantAccountJoins) TableName() string {
 	return "tenant_account_joins"
 }
+
+// Extend Start: workspace management
+// 与 Dify 的 TenantStatus / TenantAccountRole 保持一致
+const (
+	TenantStatusNormal  = "normal"
+	TenantStatusArchive = "archive" // 归档后成员无法再进入该工作空间
+
+	TenantRoleOwner           = "owner"
+	TenantRoleAdmin           = "admin"
+	TenantRoleEditor          = "editor"
+	TenantRoleNormal          = "normal"
+	TenantRoleDatasetOperator = "dataset_operator"
+)
+
+// TenantPlans 可设置的工作空间套餐
+var TenantPlans = []string{"basic", "sandbox", "professional", "team"}
+
+// TenantMemberRoles 可直接分配的成员角色, 所有者只能通过转移所有权变更
+var TenantMemberRoles = []string{TenantRoleAdmin, TenantRoleEditor, TenantRoleNormal, TenantRoleDatasetOperator}
+
+// 成员变更审计的动作与来源
+const (
+	TenantAuditAdd      = "add"
+	TenantAuditRemove   = "remove"
+	TenantAuditRole     = "role"
+	TenantAuditTransfer = "transfer"
+
+	TenantAuditSourceAdmin    = "admin"
+	TenantAuditSourceSync     = "sync"
+	TenantAuditSourceOffboard = "offboard"
+)
+
+// TenantMemberAudit 工作空间成员变更审计
+type TenantMemberAudit struct {
+	Id         uint      `json:"id" gorm:"primarykey;column:id;comment:id"`
+	TenantID   string    `json:"tenant_id" gorm:"index;comment:工作空间ID"`
+	AccountID  string    `json:"account_id" gorm:"index;comment:账户ID"`
+	Action     string    `json:"action" gorm:"comment:动作 add/remove/role/transfer"`
+	OldRole    string    `json:"old_role" gorm:"comment:变更前角色"`
+	NewRole    string    `json:"new_role" gorm:"comment:变更后角色"`
+	Source     string    `json:"source" gorm:"comment:来源 admin/sync"`
+	Reason     string    `json:"reason" gorm:"comment:变更原因"`
+	OperatorID uint      `json:"operator_id" gorm:"comment:操作人ID, 自动同步时为0"`
+	Operator   string    `json:"operator" gorm:"comment:操作人"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index;comment:变更时间"`
+}
+
+// TableName tenant_member_audit_extend表
+func (TenantMemberAudit) TableName() string {
+	return "tenant_member_audit_extend"
+}
+
+// Extend Stop: workspace management
@@ -2,4 +2,16 @@ package request
 
 type OaLoginReq struct {
 	AuthorizeCode string `json:"authorize_code" form:"authorize_code"` // OA返回的授权验证码，用于请求用户信息
+	State         string `json:"state" form:"state"`                   // 发起授权时返回的state
+}
+
+// DingTalkLoginReq 钉钉扫码登录
+type DingTalkLoginReq struct {
+	AuthCode string `json:"auth_code" form:"auth_code"` // 钉钉登录回调返回的authCode
+}
+
+// OAuth2LoginReq OAuth2/OIDC 授权回调登录
+type OAuth2LoginReq struct {
+	Code  string `json:"code" form:"code"`   // 授权码
+	State string `json:"state" form:"state"` // 发起授权时返回的state
 }
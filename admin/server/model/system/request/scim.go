@@ -0,0 +1,117 @@
+package request
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+const (
+	ScimSchemaUser    = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup   = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaPatchOp = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+)
+
+// ScimName 用户姓名
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// ScimMultiValue 邮箱、手机号、分组成员等多值属性
+type ScimMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// ScimMeta 资源元数据
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// ScimUser SCIM用户, 对应 SysUser 与 gaia Account
+type ScimUser struct {
+	Schemas      []string         `json:"schemas"`
+	ID           string           `json:"id,omitempty"`
+	ExternalID   string           `json:"externalId,omitempty"`
+	UserName     string           `json:"userName"`
+	Name         ScimName         `json:"name"`
+	DisplayName  string           `json:"displayName,omitempty"`
+	Active       *ScimBool        `json:"active,omitempty"`
+	Emails       []ScimMultiValue `json:"emails,omitempty"`
+	PhoneNumbers []ScimMultiValue `json:"phoneNumbers,omitempty"`
+	Groups       []ScimMultiValue `json:"groups,omitempty"` // 只读
+	Meta         *ScimMeta        `json:"meta,omitempty"`
+}
+
+// PrimaryEmail 主邮箱, 未提供邮箱且 userName 为邮箱时使用 userName
+func (u ScimUser) PrimaryEmail() string {
+	for _, v := range u.Emails {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return u.UserName
+}
+
+// Nickname 显示名称
+func (u ScimUser) Nickname() string {
+	switch {
+	case len(u.DisplayName) > 0:
+		return u.DisplayName
+	case len(u.Name.Formatted) > 0:
+		return u.Name.Formatted
+	default:
+		return u.Name.FamilyName + u.Name.GivenName
+	}
+}
+
+// ScimGroup SCIM分组, 对应后台角色或工作空间
+type ScimGroup struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id,omitempty"`
+	DisplayName string           `json:"displayName"`
+	Members     []ScimMultiValue `json:"members"`
+	Meta        *ScimMeta        `json:"meta,omitempty"`
+}
+
+// ScimPatchOp PATCH请求
+type ScimPatchOp struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations"`
+}
+
+// ScimPatchOperation 单个PATCH操作, value 的结构取决于 path
+type ScimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ScimBool 兼容部分IdP将布尔值以 "True"/"False" 字符串发送
+type ScimBool bool
+
+func (b *ScimBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case bool:
+		*b = ScimBool(val)
+	case string:
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		*b = ScimBool(parsed)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package request
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+
+type AccessTokenCreateReq struct {
+	Name       string                    `json:"name"`       // 令牌名称
+	ExpireDays int                       `json:"expireDays"` // 有效天数, 1-365
+	AllowedIPs []string                  `json:"allowedIps"` // IP白名单, 支持CIDR, 为空不限制
+	Scopes     []system.AccessTokenScope `json:"scopes"`     // 允许访问的接口, 须为当前用户权限的子集
+}
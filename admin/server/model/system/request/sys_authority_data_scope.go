@@ -0,0 +1,8 @@
+package request
+
+// SetDataScopeReq 设置角色数据范围
+type SetDataScopeReq struct {
+	AuthorityId uint     `json:"authorityId" binding:"required"` // 角色ID
+	Scope       string   `json:"scope" binding:"required"`       // 数据范围 all/own/custom
+	TenantIDs   []string `json:"tenantIds"`                      // 指定的工作空间, 仅 custom 使用
+}
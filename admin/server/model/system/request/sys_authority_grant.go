@@ -0,0 +1,46 @@
+package request
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// AuthorityGrantReq 创建临时角色授权
+type AuthorityGrantReq struct {
+	UserID      uint       `json:"userId" binding:"required"`      // 用户ID
+	AuthorityId uint       `json:"authorityId" binding:"required"` // 角色ID
+	StartAt     *time.Time `json:"startAt"`                        // 开始时间, 为空立即生效
+	EndAt       time.Time  `json:"endAt" binding:"required"`       // 结束时间
+	Reason      string     `json:"reason" binding:"required"`      // 申请原因
+	ApproverID  uint       `json:"approverId"`                     // 审批人ID, 可选
+}
+
+// AuthorityGrantRevokeReq 提前收回临时角色
+type AuthorityGrantRevokeReq struct {
+	ID     uint   `json:"id" binding:"required"` // 授权ID
+	Reason string `json:"reason"`                // 收回原因
+}
+
+// AuthorityGrantApproveReq 审批临时角色授权
+type AuthorityGrantApproveReq struct {
+	ID       uint   `json:"id" binding:"required"` // 授权ID
+	Approved bool   `json:"approved"`              // 是否通过
+	Reason   string `json:"reason"`                // 审批意见
+}
+
+// AuthorityGrantSearch 临时角色授权查询
+type AuthorityGrantSearch struct {
+	request.PageInfo
+	UserID      uint   `json:"userId" form:"userId"`
+	AuthorityId uint   `json:"authorityId" form:"authorityId"`
+	Status      string `json:"status" form:"status"`
+}
+
+// AuthorityGrantLogSearch 临时角色授权记录查询
+type AuthorityGrantLogSearch struct {
+	request.PageInfo
+	GrantID uint   `json:"grantId" form:"grantId"`
+	UserID  uint   `json:"userId" form:"userId"`
+	Action  string `json:"action" form:"action"`
+}
@@ -0,0 +1,234 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	model "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/pkg/errors"
+)
+
+// AutoCodeGaiaPackage gaia 表生成的代码所在的包
+const AutoCodeGaiaPackage = "gaia"
+
+// autoCodeGaiaNamePattern 简称与文件名用于拼接生成文件的路径与代码, 只允许字母开头的字母、数字与下划线
+var autoCodeGaiaNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// AutoCodeGaia 按 gaia(Dify) 数据库中已存在的表生成代码
+type AutoCodeGaia struct {
+	TableName          string               `json:"tableName" example:"表名"`             // 表名, public schema
+	StructName         string               `json:"structName" example:"Struct名称"`      // Struct名称, 为空时按表名转驼峰
+	Description        string               `json:"description" example:"Struct中文名称"`   // Struct中文名称, 为空时取表注释
+	Abbreviation       string               `json:"abbreviation" example:"Struct简称"`    // Struct简称, 作为路由分组与变量前缀
+	HumpPackageName    string               `json:"humpPackageName" example:"go文件名称"`   // go文件名称, 为空时为表名
+	ReadOnly           bool                 `json:"readOnly" example:"false"`           // 只生成列表与详情接口
+	AutoCreateApiToSql bool                 `json:"autoCreateApiToSql" example:"false"` // 是否登记api并授权给当前角色
+	Module             string               `json:"-"`
+	TableComment       string               `json:"-"`
+	Fields             []*AutoCodeGaiaField `json:"-"`
+	PrimaryField       *AutoCodeGaiaField   `json:"-"`
+	HasTenant          bool                 `json:"-"` // 存在 tenant_id 字段, 按角色数据范围过滤
+	HasUUID            bool                 `json:"-"`
+	HasTimer           bool                 `json:"-"`
+	NeedJSON           bool                 `json:"-"`
+	OrderColumn        string               `json:"-"` // 列表排序字段, 优先 created_at
+}
+
+// AutoCodeGaiaField 由表字段推导的结构体字段
+type AutoCodeGaiaField struct {
+	FieldName  string `json:"fieldName"`  // Field名
+	FieldType  string `json:"fieldType"`  // Go 类型
+	ColumnName string `json:"columnName"` // 数据库字段
+	ColumnType string `json:"columnType"` // 数据库类型, 如 uuid、numeric(16,7)、timestamp(6)
+	NotNull    bool   `json:"notNull"`    // 非空
+	Default    string `json:"default"`    // 默认值
+	Comment    string `json:"comment"`    // 字段注释
+	PrimaryKey bool   `json:"primaryKey"` // 是否主键
+	AutoInc    bool   `json:"autoInc"`    // 自增主键
+}
+
+// GormTag 与手写 gaia 模型一致的 gorm 标签
+func (f *AutoCodeGaiaField) GormTag() string {
+	tag := "column:" + f.ColumnName + ";type:" + f.ColumnType
+	if f.PrimaryKey {
+		tag += ";primary_key"
+	}
+	if f.AutoInc {
+		tag += ";autoIncrement"
+	}
+	if f.NotNull && !f.PrimaryKey {
+		tag += ";not null"
+	}
+	if f.Default != "" {
+		tag += ";default:" + f.Default
+	}
+	return tag
+}
+
+// Pretreatment 预处理, 字段由表结构填充后调用
+func (r *AutoCodeGaia) Pretreatment() error {
+	r.Module = global.GVA_CONFIG.AutoCode.Module
+	r.TableName = strings.TrimSpace(r.TableName)
+	if r.TableName == "" {
+		return errors.New("表名不能为空")
+	}
+	if r.StructName == "" {
+		r.StructName = GaiaCamelName(r.TableName)
+	}
+	if !token.IsIdentifier(r.StructName) {
+		return errors.Errorf("Struct名称不合法: %s", r.StructName)
+	}
+	if r.Abbreviation == "" {
+		r.Abbreviation = strings.ToLower(r.StructName[:1]) + r.StructName[1:]
+	}
+	if !autoCodeGaiaNamePattern.MatchString(r.Abbreviation) {
+		return errors.Errorf("Struct简称不合法: %s", r.Abbreviation)
+	}
+	if token.IsKeyword(r.Abbreviation) {
+		r.Abbreviation = r.Abbreviation + "_"
+	} // go 关键字处理
+	if r.HumpPackageName == "" {
+		r.HumpPackageName = r.TableName
+	}
+	if !autoCodeGaiaNamePattern.MatchString(r.HumpPackageName) {
+		return errors.Errorf("go文件名称不合法: %s", r.HumpPackageName)
+	}
+	if strings.HasSuffix(r.HumpPackageName, "test") {
+		r.HumpPackageName = r.HumpPackageName + "_"
+	} // test
+	if r.Description == "" {
+		r.Description = r.TableComment
+	}
+	if r.Description == "" {
+		r.Description = r.TableName
+	}
+	r.PrimaryField = nil
+	r.HasTenant, r.HasUUID, r.HasTimer, r.NeedJSON, r.OrderColumn = false, false, false, false, ""
+	for _, field := range r.Fields {
+		if field.PrimaryKey {
+			if r.PrimaryField != nil {
+				return errors.Errorf("表 %s 为联合主键, 暂不支持生成", r.TableName)
+			}
+			r.PrimaryField = field
+		}
+		if field.ColumnName == "tenant_id" {
+			r.HasTenant = field.FieldType == "uuid.UUID" || field.FieldType == "string"
+		}
+		if field.ColumnName == "created_at" {
+			r.OrderColumn = field.ColumnName
+		}
+		switch strings.TrimPrefix(field.FieldType, "*") {
+		case "uuid.UUID":
+			r.HasUUID = true
+		case "time.Time":
+			r.HasTimer = true
+		case "json.RawMessage":
+			r.NeedJSON = true
+		}
+	}
+	if len(r.Fields) == 0 {
+		return errors.Errorf("表 %s 不存在或没有字段", r.TableName)
+	}
+	if r.PrimaryField == nil {
+		return errors.Errorf("表 %s 没有主键, 无法生成详情与修改接口", r.TableName)
+	}
+	if r.OrderColumn == "" {
+		r.OrderColumn = r.PrimaryField.ColumnName
+	}
+	return nil
+}
+
+// PrimaryQueryType 详情与删除时主键参数的类型, uuid 按字符串接收
+func (r *AutoCodeGaia) PrimaryQueryType() string {
+	switch r.PrimaryField.FieldType {
+	case "int", "int16", "int64":
+		return r.PrimaryField.FieldType
+	}
+	return "string"
+}
+
+// TenantExpr 生成代码中取工作空间ID的表达式, 仅 HasTenant 时使用
+func (r *AutoCodeGaia) TenantExpr() string {
+	for _, field := range r.Fields {
+		if field.ColumnName == "tenant_id" && field.FieldType == "uuid.UUID" {
+			return "entity.TenantID.String()"
+		}
+	}
+	return "entity.TenantID"
+}
+
+func (r *AutoCodeGaia) Apis() []model.SysApi {
+	apis := []model.SysApi{
+		{
+			Path:        "/" + r.Abbreviation + "/" + "find" + r.StructName,
+			Description: "根据ID获取" + r.Description,
+			ApiGroup:    r.Description,
+			Method:      "GET",
+		},
+		{
+			Path:        "/" + r.Abbreviation + "/" + "get" + r.StructName + "List",
+			Description: "获取" + r.Description + "列表",
+			ApiGroup:    r.Description,
+			Method:      "GET",
+		},
+	}
+	if r.ReadOnly {
+		return apis
+	}
+	return append(apis,
+		model.SysApi{
+			Path:        "/" + r.Abbreviation + "/" + "create" + r.StructName,
+			Description: "新增" + r.Description,
+			ApiGroup:    r.Description,
+			Method:      "POST",
+		},
+		model.SysApi{
+			Path:        "/" + r.Abbreviation + "/" + "update" + r.StructName,
+			Description: "更新" + r.Description,
+			ApiGroup:    r.Description,
+			Method:      "PUT",
+		},
+		model.SysApi{
+			Path:        "/" + r.Abbreviation + "/" + "delete" + r.StructName,
+			Description: "删除" + r.Description,
+			ApiGroup:    r.Description,
+			Method:      "DELETE",
+		},
+	)
+}
+
+func (r *AutoCodeGaia) History() SysAutoHistoryCreate {
+	bytes, _ := json.Marshal(r)
+	return SysAutoHistoryCreate{
+		Table:       r.TableName,
+		Package:     AutoCodeGaiaPackage,
+		Request:     string(bytes),
+		StructName:  r.StructName,
+		Description: r.Description,
+	}
+}
+
+// GaiaCamelName 将下划线命名转为驼峰, 常见缩写全大写, 如 app_token_id => AppTokenID
+func GaiaCamelName(name string) string {
+	var builder strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		switch lower := strings.ToLower(word); lower {
+		case "id", "ip", "url", "uri", "uuid", "sql", "json":
+			builder.WriteString(strings.ToUpper(lower))
+		default:
+			builder.WriteString(strings.ToUpper(lower[:1]) + lower[1:])
+		}
+	}
+	name = builder.String()
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = fmt.Sprintf("F%s", name)
+	}
+	return name
+}
@@ -0,0 +1,28 @@
+package request
+
+import (
+	"encoding/json"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// SysExportJobSearch 导出任务历史查询
+type SysExportJobSearch struct {
+	request.PageInfo
+	Kind   string `json:"kind" form:"kind"`
+	Status string `json:"status" form:"status"`
+}
+
+// ExportJobCreate 创建导出任务
+type ExportJobCreate struct {
+	Kind   string          `json:"kind"`   // 任务类型
+	Format string          `json:"format"` // 导出格式, 默认 xlsx
+	Params json.RawMessage `json:"params"` // 任务参数, 由对应类型的处理器解析
+}
+
+// ExportJobDownload 签名下载链接参数
+type ExportJobDownload struct {
+	ID      uint   `form:"id"`
+	Expires int64  `form:"expires"`
+	Sign    string `form:"sign"`
+}
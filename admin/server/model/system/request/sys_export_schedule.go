@@ -0,0 +1,19 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// SysExportScheduleSearch 定时报表查询
+type SysExportScheduleSearch struct {
+	request.PageInfo
+	Name       string `json:"name" form:"name"`
+	TemplateID string `json:"templateID" form:"templateID"`
+}
+
+// SysExportScheduleRunSearch 定时报表执行记录查询
+type SysExportScheduleRunSearch struct {
+	request.PageInfo
+	ScheduleID uint   `json:"scheduleID" form:"scheduleID"`
+	Status     string `json:"status" form:"status"`
+}
@@ -12,3 +12,14 @@ type SysExportTemplateSearch struct {
 	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
 	request.PageInfo
 }
+
+// Extend Start: import validation
+// ImportExcelReq 导入参数
+type ImportExcelReq struct {
+	TemplateID string `form:"templateID"`
+	Mode       string `form:"mode"`   // insert(默认)/upsert/update
+	DryRun     bool   `form:"dryRun"` // 只校验不写入
+	Sheet      string `form:"sheet"`  // 工作表, 默认 Sheet1, 不存在时取第一个工作表
+}
+
+// Extend Stop: import validation
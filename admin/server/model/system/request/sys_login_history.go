@@ -0,0 +1,18 @@
+package request
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+
+type SysLoginHistorySearch struct {
+	request.PageInfo
+	UserID      uint   `json:"userId" form:"userId"`
+	Account     string `json:"account" form:"account"`
+	IP          string `json:"ip" form:"ip"`
+	Success     *bool  `json:"success" form:"success"`
+	AnomalyOnly bool   `json:"anomalyOnly" form:"anomalyOnly"` // 只看新IP/新设备
+}
+
+// LoginUnlockReq 解除锁定, 账号与IP至少填写一个
+type LoginUnlockReq struct {
+	Account string `json:"account"`
+	IP      string `json:"ip"`
+}
@@ -0,0 +1,10 @@
+package request
+
+// PermissionSimulateReq 权限模拟, 用户与角色二选一
+type PermissionSimulateReq struct {
+	UserID      uint   `json:"userId"`      // 用户ID, 按其全部角色模拟
+	AuthorityId uint   `json:"authorityId"` // 角色ID, 未指定用户时使用
+	Path        string `json:"path"`        // 接口路径, 可带路由前缀
+	Method      string `json:"method"`      // 请求方法
+	Menu        string `json:"menu"`        // 菜单 name 或 path
+}
@@ -0,0 +1,6 @@
+package request
+
+// RbacImportReq 导入权限配置包
+type RbacImportReq struct {
+	Prune bool `json:"prune" form:"prune"` // 删除配置包中没有的角色、菜单与接口, 默认只新增与更新
+}
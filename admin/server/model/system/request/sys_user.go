@@ -84,3 +84,14 @@ type UpdateUserPasswd struct {
 }
 
 // Extend Stop GetUserInfoByUserName
+
+// Extend Start: oauth2 email trust
+
+// UserIntegrateReq 管理员关联或解除用户的第三方身份
+type UserIntegrateReq struct {
+	ID       uint   `json:"ID" form:"ID"`             // 用户ID
+	Provider string `json:"provider" form:"provider"` // 提供方标识, 与 account_integrates.provider 一致
+	OpenID   string `json:"open_id" form:"open_id"`   // 提供方返回的用户唯一标识, 解除关联时不需要
+}
+
+// Extend Stop: oauth2 email trust
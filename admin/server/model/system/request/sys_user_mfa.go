@@ -0,0 +1,17 @@
+package request
+
+// MfaCodeReq 已登录用户提交的TOTP验证码或恢复码
+type MfaCodeReq struct {
+	Code string `json:"code"` // 6位验证码或恢复码
+}
+
+// MfaChallengeReq 登录时的二次验证
+type MfaChallengeReq struct {
+	MfaToken string `json:"mfaToken"` // 密码校验通过后下发的临时凭证
+	Code     string `json:"code"`     // 6位验证码或恢复码
+}
+
+// MfaResetReq 管理员重置用户的二次验证
+type MfaResetReq struct {
+	ID uint `json:"ID"` // 用户ID
+}
@@ -0,0 +1,24 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// OffboardReq 离职交接, 预览与执行使用同一请求
+type OffboardReq struct {
+	UserID      uint   `json:"userId"`
+	SuccessorID uint   `json:"successorId"`
+	TokenAction string `json:"tokenAction"` // keep/rotate/revoke, 默认 keep
+	Reason      string `json:"reason"`
+}
+
+// UserStatusReq 停用或恢复用户
+type UserStatusReq struct {
+	UserID uint `json:"userId"`
+}
+
+// SysUserOffboardSearch 交接报告查询
+type SysUserOffboardSearch struct {
+	request.PageInfo
+	UserID uint `json:"userId" form:"userId"`
+}
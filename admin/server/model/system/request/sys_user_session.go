@@ -0,0 +1,18 @@
+package request
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+
+type SysUserSessionSearch struct {
+	request.PageInfo
+	UserID     uint   `json:"userId" form:"userId"`
+	Username   string `json:"username" form:"username"`
+	ActiveOnly bool   `json:"activeOnly" form:"activeOnly"` // 只看在线会话
+}
+
+type SessionRevokeReq struct {
+	SessionID string `json:"sessionId"`
+}
+
+type SessionRevokeUserReq struct {
+	UserID uint `json:"userId"`
+}
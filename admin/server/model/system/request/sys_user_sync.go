@@ -0,0 +1,19 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// SysUserSyncConflictSearch 同步冲突查询
+type SysUserSyncConflictSearch struct {
+	request.PageInfo
+	Type   string `json:"type" form:"type"`
+	Status string `json:"status" form:"status"`
+}
+
+// SyncConflictResolveReq 处理同步冲突
+type SyncConflictResolveReq struct {
+	ID       uint   `json:"id"`
+	Action   string `json:"action"`   // ignore/use_gaia/use_admin/rename/disable
+	Username string `json:"username"` // rename 时的新用户名
+}
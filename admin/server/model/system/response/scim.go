@@ -0,0 +1,43 @@
+package response
+
+import "strconv"
+
+const (
+	ScimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ScimSchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// ScimListResponse SCIM列表返回
+type ScimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// ScimError SCIM错误返回, 同时作为service层的错误类型
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+	Code     int      `json:"-"`
+}
+
+func (e *ScimError) Error() string {
+	return e.Detail
+}
+
+// NewScimError
+// @param: code int HTTP状态码, scimType string, detail string
+func NewScimError(code int, scimType, detail string) *ScimError {
+	return &ScimError{
+		Schemas:  []string{ScimSchemaError},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   detail,
+		Code:     code,
+	}
+}
@@ -0,0 +1,9 @@
+package response
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+
+// AccessTokenCreateResponse 令牌明文仅在创建时返回一次
+type AccessTokenCreateResponse struct {
+	Token       string                `json:"token"`
+	AccessToken system.SysAccessToken `json:"accessToken"`
+}
@@ -0,0 +1,9 @@
+package response
+
+import "time"
+
+// ExportJobLink 导出文件的签名下载链接
+type ExportJobLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
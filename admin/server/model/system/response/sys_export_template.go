@@ -0,0 +1,21 @@
+package response
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+
+// ImportRowError 导入失败的行
+type ImportRowError struct {
+	Row    int    `json:"row"`    // Excel 行号
+	Reason string `json:"reason"` // 失败原因
+}
+
+// ImportExcelResult 导入结果
+type ImportExcelResult struct {
+	Mode     string               `json:"mode"`
+	DryRun   bool                 `json:"dryRun"`
+	Total    int                  `json:"total"`    // 数据行数, 不含空行
+	Inserted int                  `json:"inserted"` // 新增行数, 试运行时为可新增的行数
+	Updated  int                  `json:"updated"`  // 更新行数
+	Failed   int                  `json:"failed"`   // 失败行数
+	Errors   []ImportRowError     `json:"errors"`   // 失败明细, 最多返回前100条
+	ErrorJob *system.SysExportJob `json:"errorJob"` // 错误报告, 通过 /exportJob/getDownloadLink 下载
+}
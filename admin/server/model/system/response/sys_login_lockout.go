@@ -0,0 +1,9 @@
+package response
+
+// LoginLock 当前生效的登录锁定
+type LoginLock struct {
+	Type     string `json:"type"`     // account 或 ip
+	Target   string `json:"target"`   // 邮箱或IP
+	Failures int    `json:"failures"` // 失败次数
+	TTL      int64  `json:"ttl"`      // 剩余锁定秒数
+}
@@ -0,0 +1,85 @@
+package response
+
+import "time"
+
+// PermissionSimulation 权限模拟结果
+type PermissionSimulation struct {
+	UseStrictAuth bool                 `json:"useStrictAuth"`  // 是否开启树形角色
+	User          *SimulatedUser       `json:"user,omitempty"` // 模拟的用户
+	Api           *SimulatedApi        `json:"api,omitempty"`  // 接口判定, 以当前角色为准
+	Menu          *SimulatedMenuCheck  `json:"menu,omitempty"` // 菜单判定, 以当前角色为准
+	Authorities   []SimulatedAuthority `json:"authorities"`    // 逐个角色的明细, 当前角色在前
+}
+
+// SimulatedUser 被模拟的用户
+type SimulatedUser struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"userName"`
+	NickName    string `json:"nickName"`
+	AuthorityId uint   `json:"authorityId"` // 当前角色
+}
+
+// SimulatedApi 接口判定汇总
+type SimulatedApi struct {
+	Path        string `json:"path"`        // 去掉路由前缀后的路径
+	Method      string `json:"method"`      // 请求方法
+	Registered  bool   `json:"registered"`  // 是否在接口管理中登记
+	Description string `json:"description"` // 登记的接口说明
+	Allowed     bool   `json:"allowed"`     // 当前角色是否放行
+	AllowedBy   []uint `json:"allowedBy"`   // 放行的角色, 切换到这些角色后可访问
+	Reason      string `json:"reason"`      // 判定说明
+}
+
+// SimulatedMenuCheck 菜单判定汇总
+type SimulatedMenuCheck struct {
+	Query     string `json:"query"`     // 查询的菜单 name 或 path
+	Visible   bool   `json:"visible"`   // 当前角色是否可见
+	VisibleIn []uint `json:"visibleIn"` // 可见的角色
+	Reason    string `json:"reason"`    // 判定说明
+}
+
+// SimulatedAuthority 单个角色的有效权限
+type SimulatedAuthority struct {
+	AuthorityId   uint               `json:"authorityId"`
+	AuthorityName string             `json:"authorityName"`
+	ParentId      uint               `json:"parentId"`
+	Ancestors     []uint             `json:"ancestors"`            // 自根角色到父角色
+	Current       bool               `json:"current"`              // 是否为当前角色, 只有当前角色参与接口鉴权
+	GrantEndAt    *time.Time         `json:"grantEndAt,omitempty"` // 临时角色的到期时间
+	Manages       []uint             `json:"manages,omitempty"`    // 树形角色下可管理的角色
+	Api           *SimulatedApiRule  `json:"api,omitempty"`        // 接口判定
+	Menu          *SimulatedMenuRule `json:"menu,omitempty"`       // 菜单判定
+	Menus         []SimulatedMenu    `json:"menus"`                // 可见菜单及按钮
+	DataScope     SimulatedDataScope `json:"dataScope"`            // 数据范围
+	Warnings      []string           `json:"warnings,omitempty"`   // 配置问题, 如超出父角色的接口与菜单
+}
+
+// SimulatedApiRule 单个角色的接口判定
+type SimulatedApiRule struct {
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule"` // 命中的 casbin 规则, 形如 "888 /user/:id GET"
+}
+
+// SimulatedMenuRule 单个角色的菜单判定
+type SimulatedMenuRule struct {
+	Visible bool   `json:"visible"`
+	Reason  string `json:"reason"`
+}
+
+// SimulatedMenu 可见菜单
+type SimulatedMenu struct {
+	ID       uint     `json:"id"`
+	ParentId uint     `json:"parentId"`
+	Name     string   `json:"name"`
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	Hidden   bool     `json:"hidden"`
+	Buttons  []string `json:"buttons"` // 可用按钮
+}
+
+// SimulatedDataScope 数据范围
+type SimulatedDataScope struct {
+	Scope     string   `json:"scope"`     // all / own / custom
+	All       bool     `json:"all"`       // 是否不限工作空间
+	TenantIDs []string `json:"tenantIds"` // 可见的工作空间, own 仅在模拟用户时解析
+}
@@ -0,0 +1,33 @@
+package response
+
+// RbacDiff 权限配置包与当前环境的差异
+type RbacDiff struct {
+	DryRun      bool            `json:"dryRun"`      // 仅预览, 未写入
+	Prune       bool            `json:"prune"`       // 是否删除配置包中没有的项
+	Authorities RbacSectionDiff `json:"authorities"` // 角色, 键为角色ID
+	Menus       RbacSectionDiff `json:"menus"`       // 菜单, 键为路由name
+	Apis        RbacSectionDiff `json:"apis"`        // 接口, 键为"方法 路径"
+}
+
+// RbacSectionDiff 单类配置的差异
+type RbacSectionDiff struct {
+	Added   []string     `json:"added"`   // 新增
+	Updated []RbacChange `json:"updated"` // 修改
+	Removed []string     `json:"removed"` // 删除, 仅 prune 时出现
+}
+
+// RbacChange 修改项及变化的字段
+type RbacChange struct {
+	Key    string   `json:"key"`    // 稳定键
+	Fields []string `json:"fields"` // 变化的字段
+}
+
+// Empty 是否没有任何差异
+func (d RbacDiff) Empty() bool {
+	for _, s := range []RbacSectionDiff{d.Authorities, d.Menus, d.Apis} {
+		if len(s.Added)+len(s.Updated)+len(s.Removed) > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package response
+
+import "time"
+
+// MfaChallengeResponse 密码校验通过但需要二次验证
+type MfaChallengeResponse struct {
+	NeedMfa    bool   `json:"needMfa"`
+	NeedEnroll bool   `json:"needEnroll"` // 强制开启但尚未绑定, 需先绑定
+	MfaToken   string `json:"mfaToken"`
+	ExpiresAt  int64  `json:"expiresAt"`
+}
+
+// MfaEnrollResponse 绑定信息, 恢复码仅返回一次
+type MfaEnrollResponse struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"` // otpauth:// 地址, 前端渲染为二维码
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MfaStatusResponse 二次验证状态
+type MfaStatusResponse struct {
+	Enabled           bool       `json:"enabled"`
+	Required          bool       `json:"required"`
+	RecoveryCodesLeft int        `json:"recoveryCodesLeft"`
+	EnabledAt         *time.Time `json:"enabledAt"`
+}
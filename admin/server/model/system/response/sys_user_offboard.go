@@ -0,0 +1,21 @@
+package response
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+
+// OffboardPreview 离职交接预览
+type OffboardPreview struct {
+	UserID        uint                    `json:"userId"`
+	Username      string                  `json:"username"`
+	SuccessorID   uint                    `json:"successorId"`
+	SuccessorName string                  `json:"successorName"`
+	Tenants       []system.OffboardTenant `json:"tenants"`
+	Apps          []system.OffboardApp    `json:"apps"`
+	TokenCount    int64                   `json:"tokenCount"`
+	Warnings      []string                `json:"warnings"`
+}
+
+// OffboardResult 离职交接结果, 轮换后的新密钥只在此返回一次
+type OffboardResult struct {
+	Report    system.SysUserOffboard `json:"report"`
+	NewTokens []system.OffboardToken `json:"newTokens"`
+}
@@ -0,0 +1,10 @@
+package response
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+
+// UserSyncStatus 用户同步状态
+type UserSyncStatus struct {
+	State            system.SysUserSyncState `json:"state"`
+	Running          bool                    `json:"running"`
+	PendingConflicts int64                   `json:"pendingConflicts"`
+}
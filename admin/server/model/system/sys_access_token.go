@@ -0,0 +1,54 @@
+package system
+
+import (
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// AccessTokenPrefix 个人访问令牌前缀, 中间件据此与jwt区分
+const AccessTokenPrefix = "gva_pat_"
+
+// AccessTokenScopesKey 通过个人访问令牌鉴权时, 令牌的接口范围存放在上下文中的键
+const AccessTokenScopesKey = "accessTokenScopes"
+
+// AccessTokenScope 令牌可访问的接口, 与casbin策略的 path/method 一致
+type AccessTokenScope struct {
+	Path   string `json:"path"`
+	Method string `json:"method"`
+}
+
+// SysAccessToken 个人访问令牌, 只保存SHA256哈希
+type SysAccessToken struct {
+	global.GVA_MODEL
+	UserID     uint               `json:"userId" gorm:"index;comment:用户ID"`
+	Name       string             `json:"name" gorm:"comment:令牌名称"`
+	TokenHash  string             `json:"-" gorm:"uniqueIndex;size:64;comment:令牌SHA256哈希"`
+	Hint       string             `json:"hint" gorm:"comment:令牌末四位, 便于辨认"`
+	Scopes     []AccessTokenScope `json:"scopes" gorm:"serializer:json;type:text;comment:允许访问的接口, 须为用户权限的子集"`
+	AllowedIPs string             `json:"allowedIps" gorm:"type:text;comment:IP白名单, 逗号分隔, 支持CIDR, 为空不限制"`
+	ExpiresAt  time.Time          `json:"expiresAt" gorm:"index;comment:过期时间"`
+	LastUsedAt *time.Time         `json:"lastUsedAt" gorm:"comment:最后使用时间"`
+	LastUsedIP string             `json:"lastUsedIp" gorm:"comment:最后使用IP"`
+	RevokedAt  *time.Time         `json:"revokedAt" gorm:"comment:吊销时间"`
+}
+
+func (SysAccessToken) TableName() string {
+	return "sys_access_tokens"
+}
+
+// Active 未吊销且未过期
+func (t SysAccessToken) Active() bool {
+	return t.RevokedAt == nil && t.ExpiresAt.After(time.Now())
+}
+
+// IPList IP白名单
+func (t SysAccessToken) IPList() (list []string) {
+	for _, ip := range strings.Split(t.AllowedIPs, ",") {
+		if ip = strings.TrimSpace(ip); len(ip) > 0 {
+			list = append(list, ip)
+		}
+	}
+	return list
+}
@@ -0,0 +1,27 @@
+package system
+
+import (
+	"time"
+)
+
+// 角色数据范围, 控制 gaia 看板与列表可见的工作空间
+const (
+	DataScopeAll    = "all"    // 全部工作空间
+	DataScopeOwn    = "own"    // 用户自己加入的工作空间
+	DataScopeCustom = "custom" // 指定的工作空间
+)
+
+// DataScopes 支持的数据范围
+var DataScopes = []string{DataScopeAll, DataScopeOwn, DataScopeCustom}
+
+// SysAuthorityDataScope 角色数据范围, 没有记录的角色视为全部工作空间
+type SysAuthorityDataScope struct {
+	AuthorityId uint      `json:"authorityId" gorm:"primarykey;autoIncrement:false;comment:角色ID"`               // 角色ID
+	Scope       string    `json:"scope" gorm:"size:16;not null;default:all;comment:数据范围 all/own/custom"`        // 数据范围
+	TenantIDs   []string  `json:"tenantIds" gorm:"serializer:json;type:text;column:tenant_ids;comment:指定的工作空间"` // 指定的工作空间, 仅 custom 使用
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func (SysAuthorityDataScope) TableName() string {
+	return "sys_authority_data_scopes"
+}
@@ -0,0 +1,67 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 临时角色授权状态
+const (
+	GrantStatusPending  = "pending"  // 待审批或未到开始时间
+	GrantStatusActive   = "active"   // 生效中
+	GrantStatusExpired  = "expired"  // 已到期
+	GrantStatusRevoked  = "revoked"  // 已提前收回
+	GrantStatusRejected = "rejected" // 审批人已拒绝
+)
+
+// 临时角色授权记录动作
+const (
+	GrantActionGrant    = "grant"    // 创建授权
+	GrantActionActivate = "activate" // 到达开始时间, 加入用户角色
+	GrantActionExpire   = "expire"   // 到期, 移除用户角色
+	GrantActionRevoke   = "revoke"   // 提前收回
+	GrantActionApprove  = "approve"  // 审批通过
+	GrantActionReject   = "reject"   // 审批拒绝
+)
+
+// SysAuthorityGrant 临时角色授权, 在开始与结束时间之间为用户加上角色, 到期由定时任务收回
+type SysAuthorityGrant struct {
+	global.GVA_MODEL
+	UserID       uint       `json:"userId" gorm:"index;comment:用户ID"`
+	Username     string     `json:"username" gorm:"comment:用户名"`
+	AuthorityId  uint       `json:"authorityId" gorm:"index;comment:角色ID"`
+	StartAt      time.Time  `json:"startAt" gorm:"comment:开始时间"`
+	EndAt        time.Time  `json:"endAt" gorm:"index;comment:结束时间"`
+	Reason       string     `json:"reason" gorm:"comment:申请原因"`
+	ApproverID   uint       `json:"approverId" gorm:"comment:审批人ID, 0 为无需审批"`
+	Approver     string     `json:"approver" gorm:"comment:审批人"`
+	ApprovedAt   *time.Time `json:"approvedAt" gorm:"comment:审批通过时间, 需审批的授权通过前不会生效"`
+	Status       string     `json:"status" gorm:"index;size:16;comment:状态 pending/active/expired/revoked/rejected"`
+	OperatorID   uint       `json:"operatorId" gorm:"comment:授权人ID"`
+	Operator     string     `json:"operator" gorm:"comment:授权人"`
+	RevokedAt    *time.Time `json:"revokedAt" gorm:"comment:收回时间"`
+	RevokedBy    string     `json:"revokedBy" gorm:"comment:收回人"`
+	RevokeReason string     `json:"revokeReason" gorm:"comment:收回原因"`
+}
+
+func (SysAuthorityGrant) TableName() string {
+	return "sys_authority_grants"
+}
+
+// SysAuthorityGrantLog 临时角色授权记录, 授权、生效、到期与收回各记一条
+type SysAuthorityGrantLog struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time `json:"createdAt"`
+	GrantID     uint      `json:"grantId" gorm:"index;comment:授权ID"`
+	UserID      uint      `json:"userId" gorm:"index;comment:用户ID"`
+	AuthorityId uint      `json:"authorityId" gorm:"comment:角色ID"`
+	Action      string    `json:"action" gorm:"size:16;comment:动作 grant/approve/reject/activate/expire/revoke"`
+	Reason      string    `json:"reason" gorm:"comment:原因"`
+	OperatorID  uint      `json:"operatorId" gorm:"comment:操作人ID, 0 为定时任务"`
+	Operator    string    `json:"operator" gorm:"comment:操作人"`
+}
+
+func (SysAuthorityGrantLog) TableName() string {
+	return "sys_authority_grant_logs"
+}
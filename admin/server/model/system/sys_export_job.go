@@ -0,0 +1,58 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 导出格式
+const (
+	ExportFormatXlsx  = "xlsx"
+	ExportFormatCsv   = "csv"
+	ExportFormatJsonl = "jsonl"
+)
+
+// 导出任务状态
+const (
+	ExportJobPending = "pending"
+	ExportJobRunning = "running"
+	ExportJobSuccess = "success"
+	ExportJobFailed  = "failed"
+	ExportJobExpired = "expired" // 文件已过期清理
+)
+
+// 导出任务类型, 每种类型在 service 中注册对应的处理器
+const (
+	ExportKindTemplate       = "template"        // 导出模板数据
+	ExportKindTemplateFile   = "template_file"   // 导出模板的空白表格
+	ExportKindAppRanking     = "app_ranking"     // 看板应用花费排名
+	ExportKindAccountRanking = "account_ranking" // 看板账号额度排名
+	ExportKindTestReport     = "test_report"     // 应用请求测试报告
+	ExportKindImportErrors   = "import_errors"   // 导入失败的行, 由导入直接生成
+)
+
+// SysExportJob 导出任务, 在后台队列中生成文件并上传至OSS, 按用户保留历史
+type SysExportJob struct {
+	global.GVA_MODEL
+	Kind       string     `json:"kind" gorm:"index;comment:任务类型"`
+	Name       string     `json:"name" gorm:"comment:文件名"`
+	Format     string     `json:"format" gorm:"comment:导出格式"`
+	Params     string     `json:"params" gorm:"type:text;comment:导出参数"`
+	Status     string     `json:"status" gorm:"index;comment:任务状态"`
+	Progress   int        `json:"progress" gorm:"comment:进度百分比"`
+	Total      int64      `json:"total" gorm:"comment:预计行数"`
+	Rows       int64      `json:"rows" gorm:"comment:已导出行数"`
+	Size       int64      `json:"size" gorm:"comment:文件大小"`
+	FileURL    string     `json:"-" gorm:"comment:文件地址"`
+	FileKey    string     `json:"-" gorm:"comment:OSS文件标识"`
+	Error      string     `json:"error" gorm:"type:text;comment:失败原因"`
+	UserID     uint       `json:"userId" gorm:"index;comment:创建人"`
+	StartedAt  *time.Time `json:"startedAt" gorm:"comment:开始时间"`
+	FinishedAt *time.Time `json:"finishedAt" gorm:"comment:完成时间"`
+	ExpiresAt  *time.Time `json:"expiresAt" gorm:"index;comment:文件过期时间"`
+}
+
+func (SysExportJob) TableName() string {
+	return "sys_export_jobs"
+}
@@ -0,0 +1,59 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 定时报表的发送方式
+const (
+	ExportDeliveryAttachment = "attachment" // 作为邮件附件, 超过大小限制时改为下载链接
+	ExportDeliveryLink       = "link"       // 邮件中只包含下载链接
+)
+
+// 定时报表的触发方式
+const (
+	ExportTriggerCron   = "cron"
+	ExportTriggerManual = "manual"
+)
+
+// SysExportSchedule 定时报表, 按 cron 表达式导出模板数据并通过邮件发送
+type SysExportSchedule struct {
+	global.GVA_MODEL
+	Name       string     `json:"name" form:"name" gorm:"comment:报表名称"`
+	TemplateID string     `json:"templateID" form:"templateID" gorm:"index;comment:导出模板标识"`
+	Query      string     `json:"query" form:"query" gorm:"type:text;comment:查询参数, url编码, 可使用相对日期"`
+	Cron       string     `json:"cron" form:"cron" gorm:"comment:cron表达式, 分 时 日 月 周"`
+	Format     string     `json:"format" form:"format" gorm:"comment:导出格式"`
+	Recipients string     `json:"recipients" form:"recipients" gorm:"type:text;comment:收件人, 英文逗号分隔"`
+	Delivery   string     `json:"delivery" form:"delivery" gorm:"comment:发送方式"`
+	Enabled    bool       `json:"enabled" form:"enabled" gorm:"comment:是否启用"`
+	CreatedBy  uint       `json:"createdBy" form:"createdBy" gorm:"comment:创建人"`
+	LastRunAt  *time.Time `json:"lastRunAt" gorm:"comment:最近执行时间"`
+	LastStatus string     `json:"lastStatus" gorm:"comment:最近执行状态"`
+	NextRunAt  *time.Time `json:"nextRunAt" gorm:"-"` // 由定时任务计算, 不入库
+}
+
+func (SysExportSchedule) TableName() string {
+	return "sys_export_schedules"
+}
+
+// SysExportScheduleRun 定时报表的执行记录
+type SysExportScheduleRun struct {
+	global.GVA_MODEL
+	ScheduleID uint       `json:"scheduleID" gorm:"index;comment:定时报表ID"`
+	Trigger    string     `json:"trigger" gorm:"comment:触发方式"`
+	Status     string     `json:"status" gorm:"index;comment:执行状态"` // 复用导出任务状态 running/success/failed
+	Query      string     `json:"query" gorm:"type:text;comment:替换相对日期后的查询参数"`
+	Rows       int64      `json:"rows" gorm:"comment:导出行数"`
+	JobID      uint       `json:"jobID" gorm:"comment:导出文件对应的导出任务"`
+	Delivery   string     `json:"delivery" gorm:"comment:实际发送方式"`
+	Error      string     `json:"error" gorm:"type:text;comment:失败原因"`
+	StartedAt  *time.Time `json:"startedAt" gorm:"comment:开始时间"`
+	FinishedAt *time.Time `json:"finishedAt" gorm:"comment:完成时间"`
+}
+
+func (SysExportScheduleRun) TableName() string {
+	return "sys_export_schedule_runs"
+}
@@ -5,6 +5,16 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 )
 
+// Extend Start: export template sql safety
+// 导出模板可访问的数据库由 casbin 控制, 策略为 (角色ID, ExportDBPathPrefix+数据库别名, ExportDBMethod)
+const (
+	ExportDBPathPrefix = "/exportTemplate/db/"
+	ExportDBMethod     = "USE"
+	ExportDBDefault    = "default" // 主库, 模板未指定数据库时使用
+)
+
+// Extend Stop: export template sql safety
+
 // 导出模板 结构体  SysExportTemplate
 type SysExportTemplate struct {
 	global.GVA_MODEL
@@ -17,14 +27,53 @@ type SysExportTemplate struct {
 	Order        string         `json:"order" form:"order" gorm:"column:order;comment:排序"`
 	Conditions   []Condition    `json:"conditions" form:"conditions" gorm:"foreignKey:TemplateID;references:TemplateID;comment:条件"`
 	JoinTemplate []JoinTemplate `json:"joinTemplate" form:"joinTemplate" gorm:"foreignKey:TemplateID;references:TemplateID;comment:关联"`
+	// Extend Start: import validation
+	ImportKey   string                `json:"importKey" form:"importKey" gorm:"column:import_key;comment:导入时匹配已有记录的字段"`
+	ImportRules map[string]ImportRule `json:"importRules" form:"-" gorm:"serializer:json;type:text;column:import_rules;comment:导入校验规则, 以模板字段为键"`
+	// Extend Stop: import validation
+}
+
+// Extend Start: import validation
+// 导入模式
+const (
+	ImportModeInsert = "insert" // 仅新增
+	ImportModeUpsert = "upsert" // 按 ImportKey 匹配, 已存在则更新, 否则新增
+	ImportModeUpdate = "update" // 按 ImportKey 匹配, 仅更新已存在的记录
+)
+
+// 导入字段类型
+const (
+	ImportTypeString   = "string"
+	ImportTypeInt      = "int"
+	ImportTypeFloat    = "float"
+	ImportTypeBool     = "bool"
+	ImportTypeDate     = "date"
+	ImportTypeDatetime = "datetime"
+)
+
+// ImportRule 导入时单个字段的校验与类型转换规则
+type ImportRule struct {
+	Type     string   `json:"type"`     // 字段类型, 为空时保留原始文本
+	Required bool     `json:"required"` // 是否必填
+	MaxLen   int      `json:"maxLen"`   // 文本最大长度
+	Min      *float64 `json:"min"`      // 数值最小值
+	Max      *float64 `json:"max"`      // 数值最大值
+	Enum     []string `json:"enum"`     // 可选值
+	Pattern  string   `json:"pattern"`  // 正则校验
 }
 
+// Extend Stop: import validation
+
 type JoinTemplate struct {
 	global.GVA_MODEL
 	TemplateID string `json:"templateID" form:"templateID" gorm:"column:template_id;comment:模板标识"`
 	JOINS      string `json:"joins" form:"joins" gorm:"column:joins;comment:关联"`
 	Table      string `json:"table" form:"table" gorm:"column:table;comment:关联表"`
 	ON         string `json:"on" form:"on" gorm:"column:on;comment:关联条件"`
+	// Extend Start: export template sql safety
+	OnLeft  string `json:"onLeft" form:"onLeft" gorm:"column:on_left;comment:关联条件左侧字段 table.column"`
+	OnRight string `json:"onRight" form:"onRight" gorm:"column:on_right;comment:关联条件右侧字段 table.column"`
+	// Extend Stop: export template sql safety
 }
 
 func (JoinTemplate) TableName() string {
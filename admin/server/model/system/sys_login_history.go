@@ -0,0 +1,33 @@
+package system
+
+import (
+	"time"
+)
+
+// 登录方式
+const (
+	LoginMethodPassword = "password"
+	LoginMethodOAuth2   = "oauth2"
+	LoginMethodDingTalk = "dingtalk"
+)
+
+// SysLoginHistory 登录历史, 用于审计与异常登录检测
+type SysLoginHistory struct {
+	ID         uint      `json:"ID" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"CreatedAt" gorm:"index"`
+	UserID     uint      `json:"userId" gorm:"index;comment:用户ID, 账号不存在时为0"`
+	Account    string    `json:"account" gorm:"index;comment:登录账号(邮箱或用户名)"`
+	Method     string    `json:"method" gorm:"comment:登录方式"`
+	IP         string    `json:"ip" gorm:"index;comment:登录IP"`
+	UserAgent  string    `json:"userAgent" gorm:"type:text;comment:User-Agent"`
+	DeviceHash string    `json:"-" gorm:"size:64;comment:设备指纹"`
+	Success    bool      `json:"success" gorm:"comment:是否成功"`
+	Reason     string    `json:"reason" gorm:"comment:失败原因"`
+	NewIP      bool      `json:"newIp" gorm:"comment:首次出现的IP"`
+	NewDevice  bool      `json:"newDevice" gorm:"comment:首次出现的设备"`
+	Locked     bool      `json:"locked" gorm:"comment:本次失败触发了锁定"`
+}
+
+func (SysLoginHistory) TableName() string {
+	return "sys_login_histories"
+}
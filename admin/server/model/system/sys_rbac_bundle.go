@@ -0,0 +1,85 @@
+package system
+
+import "time"
+
+// RbacBundleVersion 权限配置包格式版本, 结构不兼容变化时递增
+const RbacBundleVersion = 1
+
+// RbacBundle 权限配置包, 包含角色、菜单、按钮、接口、casbin 规则与角色数据范围
+// 各项之间以稳定键引用: 角色用角色ID, 菜单用路由name, 按钮用菜单name+按钮key, 接口用方法+路径, 不依赖各环境的自增ID
+type RbacBundle struct {
+	Version     int             `json:"version" yaml:"version"`         // 格式版本
+	ExportedAt  time.Time       `json:"exportedAt" yaml:"exportedAt"`   // 导出时间
+	Authorities []RbacAuthority `json:"authorities" yaml:"authorities"` // 角色
+	Menus       []RbacMenu      `json:"menus" yaml:"menus"`             // 菜单
+	Apis        []RbacApi       `json:"apis" yaml:"apis"`               // 接口
+}
+
+// RbacAuthority 角色及其菜单、按钮、资源权限与 casbin 规则
+type RbacAuthority struct {
+	AuthorityId     uint                `json:"authorityId" yaml:"authorityId"`                             // 角色ID
+	AuthorityName   string              `json:"authorityName" yaml:"authorityName"`                         // 角色名
+	ParentId        uint                `json:"parentId" yaml:"parentId"`                                   // 父角色ID, 0 为顶级
+	DefaultRouter   string              `json:"defaultRouter" yaml:"defaultRouter"`                         // 默认菜单
+	DataAuthorities []uint              `json:"dataAuthorities,omitempty" yaml:"dataAuthorities,omitempty"` // 可查看数据的角色ID
+	Menus           []string            `json:"menus,omitempty" yaml:"menus,omitempty"`                     // 菜单name
+	Buttons         map[string][]string `json:"buttons,omitempty" yaml:"buttons,omitempty"`                 // 菜单name -> 按钮key
+	Policies        []RbacPolicy        `json:"policies,omitempty" yaml:"policies,omitempty"`               // casbin 规则
+	DataScope       *RbacDataScope      `json:"dataScope,omitempty" yaml:"dataScope,omitempty"`             // 数据范围, 为空时导入不修改
+}
+
+// RbacDataScope 角色数据范围, 工作空间以ID引用, 各环境的工作空间不同时需在导入后调整
+type RbacDataScope struct {
+	Scope     string   `json:"scope" yaml:"scope"`                             // 数据范围 all/own/custom
+	TenantIDs []string `json:"tenantIds,omitempty" yaml:"tenantIds,omitempty"` // 指定的工作空间, 仅 custom 使用
+}
+
+// RbacPolicy casbin 规则, 角色ID由所属角色决定
+type RbacPolicy struct {
+	Path   string `json:"path" yaml:"path"`     // 路径
+	Method string `json:"method" yaml:"method"` // 方法
+}
+
+// RbacMenu 菜单, 父菜单以 name 引用
+type RbacMenu struct {
+	Name       string              `json:"name" yaml:"name"`                                 // 路由name
+	Parent     string              `json:"parent,omitempty" yaml:"parent,omitempty"`         // 父菜单name, 空为顶级
+	Path       string              `json:"path" yaml:"path"`                                 // 路由path
+	Component  string              `json:"component" yaml:"component"`                       // 前端文件路径
+	Hidden     bool                `json:"hidden,omitempty" yaml:"hidden,omitempty"`         // 是否在列表隐藏
+	Sort       int                 `json:"sort" yaml:"sort"`                                 // 排序标记
+	Meta       RbacMenuMeta        `json:"meta" yaml:"meta"`                                 // 附加属性
+	Parameters []RbacMenuParameter `json:"parameters,omitempty" yaml:"parameters,omitempty"` // 地址栏参数
+	Buttons    []RbacMenuButton    `json:"buttons,omitempty" yaml:"buttons,omitempty"`       // 按钮
+}
+
+// RbacMenuMeta 菜单附加属性, 与 Meta 相同但带 yaml 标签
+type RbacMenuMeta struct {
+	ActiveName  string `json:"activeName,omitempty" yaml:"activeName,omitempty"`   // 高亮菜单
+	KeepAlive   bool   `json:"keepAlive,omitempty" yaml:"keepAlive,omitempty"`     // 是否缓存
+	DefaultMenu bool   `json:"defaultMenu,omitempty" yaml:"defaultMenu,omitempty"` // 是否是基础路由
+	Title       string `json:"title" yaml:"title"`                                 // 菜单名
+	Icon        string `json:"icon,omitempty" yaml:"icon,omitempty"`               // 菜单图标
+	CloseTab    bool   `json:"closeTab,omitempty" yaml:"closeTab,omitempty"`       // 自动关闭tab
+}
+
+// RbacMenuParameter 菜单地址栏参数
+type RbacMenuParameter struct {
+	Type  string `json:"type" yaml:"type"`   // params 或 query
+	Key   string `json:"key" yaml:"key"`     // 参数key
+	Value string `json:"value" yaml:"value"` // 参数值
+}
+
+// RbacMenuButton 菜单按钮
+type RbacMenuButton struct {
+	Name string `json:"name" yaml:"name"`                     // 按钮key
+	Desc string `json:"desc,omitempty" yaml:"desc,omitempty"` // 按钮备注
+}
+
+// RbacApi 接口
+type RbacApi struct {
+	Path        string `json:"path" yaml:"path"`               // 路径
+	Method      string `json:"method" yaml:"method"`           // 方法
+	ApiGroup    string `json:"apiGroup" yaml:"apiGroup"`       // 分组
+	Description string `json:"description" yaml:"description"` // 描述
+}
@@ -0,0 +1,22 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysUserMfa 用户TOTP二次验证绑定信息
+type SysUserMfa struct {
+	global.GVA_MODEL
+	UserID        uint       `json:"userId" gorm:"uniqueIndex;comment:用户ID"`
+	Secret        string     `json:"-" gorm:"comment:TOTP密钥(Blowfish加密)"`
+	Enabled       bool       `json:"enabled" gorm:"comment:是否已完成绑定"`
+	RecoveryCodes string     `json:"-" gorm:"type:text;comment:恢复码SHA256哈希(JSON数组)"`
+	LastStep      int64      `json:"-" gorm:"comment:最后一次使用的时间窗口, 防重放"`
+	EnabledAt     *time.Time `json:"enabledAt" gorm:"comment:绑定时间"`
+}
+
+func (SysUserMfa) TableName() string {
+	return "sys_user_mfas"
+}
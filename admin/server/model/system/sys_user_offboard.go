@@ -0,0 +1,58 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 离职交接时对离职用户应用密钥的处理方式
+const (
+	OffboardTokenKeep   = "keep"   // 保留
+	OffboardTokenRotate = "rotate" // 轮换为新密钥
+	OffboardTokenRevoke = "revoke" // 删除
+)
+
+// OffboardTenant 需要转移所有权的工作空间
+type OffboardTenant struct {
+	TenantID      string `json:"tenantId"`
+	Name          string `json:"name"`
+	SuccessorRole string `json:"successorRole"` // 接任人当前角色, 为空表示尚未加入
+}
+
+// OffboardApp 需要转移创建人的应用
+type OffboardApp struct {
+	AppID         string `json:"appId"`
+	Name          string `json:"name"`
+	TenantID      string `json:"tenantId"`
+	TenantName    string `json:"tenantName"`
+	TokenCount    int64  `json:"tokenCount"`    // 应用密钥数量
+	SuccessorRole string `json:"successorRole"` // 接任人在应用所属工作空间的角色, 为空时交接会以 editor 加入
+}
+
+// OffboardToken 应用密钥的处理结果, 只保存脱敏后的密钥
+type OffboardToken struct {
+	AppID   string `json:"appId"`
+	TokenID string `json:"tokenId"`
+	Token   string `json:"token"`
+	Action  string `json:"action"`
+}
+
+// SysUserOffboard 离职交接报告
+type SysUserOffboard struct {
+	global.GVA_MODEL
+	UserID        uint             `json:"userId" gorm:"index;comment:离职用户ID"`
+	Username      string           `json:"username" gorm:"comment:离职用户名"`
+	Email         string           `json:"email" gorm:"comment:离职用户邮箱"`
+	SuccessorID   uint             `json:"successorId" gorm:"index;comment:接任人ID"`
+	SuccessorName string           `json:"successorName" gorm:"comment:接任人用户名"`
+	TokenAction   string           `json:"tokenAction" gorm:"comment:应用密钥处理方式 keep/rotate/revoke"`
+	Reason        string           `json:"reason" gorm:"comment:离职原因"`
+	Tenants       []OffboardTenant `json:"tenants" gorm:"serializer:json;type:text;comment:转移所有权的工作空间"`
+	Apps          []OffboardApp    `json:"apps" gorm:"serializer:json;type:text;comment:转移创建人的应用"`
+	Tokens        []OffboardToken  `json:"tokens" gorm:"serializer:json;type:text;comment:应用密钥处理结果"`
+	OperatorID    uint             `json:"operatorId" gorm:"comment:操作人ID"`
+	Operator      string           `json:"operator" gorm:"comment:操作人"`
+}
+
+func (SysUserOffboard) TableName() string {
+	return "sys_user_offboards"
+}
@@ -0,0 +1,42 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 会话注销原因
+const (
+	SessionRevokeLogout   = "logout"   // 用户退出登录
+	SessionRevokeReplaced = "replaced" // 单点登录被新会话顶替
+	SessionRevokeManual   = "revoked"  // 用户或管理员手动注销
+	SessionRevokeDisabled = "disabled" // 用户被禁用
+	SessionRevokeDeleted  = "deleted"  // 用户被删除
+	SessionRevokeGrant    = "grant"    // Extend: 临时角色到期或被收回
+	SessionRevokeRole     = "role"     // Extend: 角色成员被SCIM变更
+)
+
+// SysUserSession 登录会话, 以jwt的jti作为会话ID, 刷新令牌时沿用
+type SysUserSession struct {
+	global.GVA_MODEL
+	SessionID    string     `json:"sessionId" gorm:"uniqueIndex;size:64;comment:会话ID(jwt jti)"`
+	UserID       uint       `json:"userId" gorm:"index;comment:用户ID"`
+	Username     string     `json:"username" gorm:"comment:用户名"`
+	IP           string     `json:"ip" gorm:"comment:登录IP"`
+	UserAgent    string     `json:"userAgent" gorm:"type:text;comment:User-Agent"`
+	LastSeenAt   time.Time  `json:"lastSeenAt" gorm:"comment:最后活跃时间"`
+	ExpiresAt    time.Time  `json:"expiresAt" gorm:"index;comment:过期时间"`
+	RevokedAt    *time.Time `json:"revokedAt" gorm:"index;comment:注销时间"`
+	RevokeReason string     `json:"revokeReason" gorm:"comment:注销原因"`
+	Current      bool       `json:"current" gorm:"-"` // 是否为当前请求的会话
+}
+
+func (SysUserSession) TableName() string {
+	return "sys_user_sessions"
+}
+
+// Active 会话未注销且未过期
+func (s SysUserSession) Active() bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+}
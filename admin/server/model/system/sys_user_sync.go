@@ -0,0 +1,75 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 用户同步冲突类型
+const (
+	SyncConflictDuplicateUsername = "duplicate_username" // 用户名已被其他用户占用
+	SyncConflictEmailChanged      = "email_changed"      // 后台用户与gaia账户邮箱不一致
+	SyncConflictEmailTaken        = "email_taken"        // 邮箱已被关联其他账户的后台用户占用
+	SyncConflictAccountDeleted    = "account_deleted"    // 后台用户关联的gaia账户已不存在
+)
+
+// 冲突处理状态
+const (
+	SyncConflictPending  = "pending"
+	SyncConflictResolved = "resolved"
+	SyncConflictIgnored  = "ignored"
+)
+
+// 冲突处理方式
+const (
+	SyncResolveIgnore   = "ignore"    // 忽略
+	SyncResolveUseGaia  = "use_gaia"  // 以gaia账户为准
+	SyncResolveUseAdmin = "use_admin" // 以后台用户为准
+	SyncResolveRename   = "rename"    // 修改后台用户名
+	SyncResolveDisable  = "disable"   // 停用后台用户
+)
+
+// SysUserSyncConflict 用户同步冲突, 等待管理员处理
+type SysUserSyncConflict struct {
+	global.GVA_MODEL
+	Type       string     `json:"type" gorm:"index;comment:冲突类型"`
+	AccountID  string     `json:"accountId" gorm:"index;comment:gaia账户ID"`
+	UserID     uint       `json:"userId" gorm:"index;comment:后台用户ID"`
+	AdminValue string     `json:"adminValue" gorm:"comment:后台用户的值"`
+	GaiaValue  string     `json:"gaiaValue" gorm:"comment:gaia账户的值"`
+	Detail     string     `json:"detail" gorm:"type:text;comment:冲突说明"`
+	Status     string     `json:"status" gorm:"index;default:pending;comment:处理状态"`
+	LastSeenAt time.Time  `json:"lastSeenAt" gorm:"comment:最近一次检测到的时间"`
+	Resolution string     `json:"resolution" gorm:"comment:处理方式"`
+	ResolvedBy string     `json:"resolvedBy" gorm:"comment:处理人"`
+	ResolvedAt *time.Time `json:"resolvedAt" gorm:"comment:处理时间"`
+}
+
+func (SysUserSyncConflict) TableName() string {
+	return "sys_user_sync_conflicts"
+}
+
+// SysUserSyncState 用户同步进度与最近一次运行的统计
+type SysUserSyncState struct {
+	global.GVA_MODEL
+	Name          string    `json:"name" gorm:"uniqueIndex;size:64;comment:同步任务名称"`
+	AccountCursor time.Time `json:"accountCursor" gorm:"comment:已同步的gaia账户updated_at"`
+	UserCursor    time.Time `json:"userCursor" gorm:"comment:已同步的后台用户updated_at"`
+	DeletedCursor uint      `json:"deletedCursor" gorm:"comment:已检查关联账户是否存在的后台用户ID"`
+	LegacyLinked  bool      `json:"legacyLinked" gorm:"comment:历史用户已按邮箱完成一次性关联"`
+	SuperAdmin    string    `json:"superAdmin" gorm:"comment:超级管理员gaia账户ID, 配置文件未设置时使用"`
+	Runs          int64     `json:"runs" gorm:"comment:累计运行次数"`
+	LastRunAt     time.Time `json:"lastRunAt" gorm:"comment:最近一次开始时间"`
+	LastDuration  int64     `json:"lastDuration" gorm:"comment:最近一次耗时(毫秒)"`
+	LastScanned   int       `json:"lastScanned" gorm:"comment:最近一次检查的记录数"`
+	LastCreated   int       `json:"lastCreated" gorm:"comment:最近一次新建的后台用户数"`
+	LastPulled    int       `json:"lastPulled" gorm:"comment:最近一次从gaia更新的后台用户数"`
+	LastPushed    int       `json:"lastPushed" gorm:"comment:最近一次推送到gaia的账户数"`
+	LastConflicts int       `json:"lastConflicts" gorm:"comment:最近一次新发现的冲突数"`
+	LastError     string    `json:"lastError" gorm:"type:text;comment:最近一次错误"`
+}
+
+func (SysUserSyncState) TableName() string {
+	return "sys_user_sync_states"
+}
@@ -3,7 +3,10 @@ package utils
 import (
 	"crypto/tls"
 	"fmt"
+	"mime"
 	"net/smtp"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/plugin/email/global"
@@ -54,6 +57,11 @@ func EmailTest(subject string, body string) error {
 //@return: error
 
 func send(to []string, subject string, body string) error {
+	return sendWithAttachments(to, subject, body, nil) // Extend: scheduled report
+}
+
+// Extend Start: scheduled report
+func sendWithAttachments(to []string, subject string, body string, files map[string]string) error {
 	from := global.GlobalConfig.From
 	nickname := global.GlobalConfig.Nickname
 	secret := global.GlobalConfig.Secret
@@ -71,6 +79,11 @@ func send(to []string, subject string, body string) error {
 	e.To = to
 	e.Subject = subject
 	e.HTML = []byte(body)
+	for name, path := range files {
+		if err := attach(e, name, path); err != nil {
+			return err
+		}
+	}
 	var err error
 	hostAddr := fmt.Sprintf("%s:%d", host, port)
 	if isSSL {
@@ -80,3 +93,31 @@ func send(to []string, subject string, body string) error {
 	}
 	return err
 }
+
+// attach 以 name 作为附件名添加本地文件
+func attach(e *email.Email, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = e.Attach(f, name, mime.TypeByExtension(filepath.Ext(name)))
+	return err
+}
+
+//@function: EmailWithAttachments
+//@description: 带附件的Email发送方法
+//@param: To string, subject string, body string, files map[string]string 附件名与本地文件路径
+//@return: error
+
+func EmailWithAttachments(To, subject string, body string, files map[string]string) error {
+	var to []string
+	for _, addr := range strings.Split(To, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	return sendWithAttachments(to, subject, body, files)
+}
+
+// Extend Stop: scheduled report
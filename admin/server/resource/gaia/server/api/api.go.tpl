@@ -0,0 +1,175 @@
+package gaia
+
+import (
+	"{{.Module}}/global"
+	{{- if not .ReadOnly }}
+	"{{.Module}}/model/gaia"
+	{{- end }}
+	"{{.Module}}/model/common/response"
+	gaiaReq "{{.Module}}/model/gaia/request"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+{{- $scopeArg := "" }}
+{{- if .HasTenant }}{{ $scopeArg = ", scope" }}{{ end }}
+
+type {{.StructName}}Api struct{}
+
+// Get{{.StructName}}List 分页获取{{.Description}}
+// @Tags {{.StructName}}
+// @Summary 分页获取{{.Description}}{{ if .HasTenant }}, 按角色数据范围过滤{{ end }}
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query gaiaReq.{{.StructName}}Search true "分页获取{{.Description}}"
+// @Success 200 {object} response.Response{data=response.PageResult{list=[]gaia.{{.StructName}}},msg=string} "获取成功"
+// @Router /{{.Abbreviation}}/get{{.StructName}}List [get]
+func ({{.Abbreviation}}Api *{{.StructName}}Api) Get{{.StructName}}List(c *gin.Context) {
+	var pageInfo gaiaReq.{{.StructName}}Search
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	{{- if .HasTenant }}
+	scope, ok := tenantScope(c)
+	if !ok {
+		return
+	}
+	{{- end }}
+	list, total, err := {{.Abbreviation}}Service.Get{{.StructName}}List(pageInfo{{$scopeArg}})
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// Find{{.StructName}} 根据ID获取{{.Description}}
+// @Tags {{.StructName}}
+// @Summary 根据ID获取{{.Description}}
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id query {{.PrimaryQueryType}} true "{{.PrimaryField.ColumnName}}"
+// @Success 200 {object} response.Response{data=gaia.{{.StructName}},msg=string} "查询成功"
+// @Router /{{.Abbreviation}}/find{{.StructName}} [get]
+func ({{.Abbreviation}}Api *{{.StructName}}Api) Find{{.StructName}}(c *gin.Context) {
+	var req struct {
+		ID {{.PrimaryQueryType}} `form:"id" binding:"required"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	{{- if .HasTenant }}
+	scope, ok := tenantScope(c)
+	if !ok {
+		return
+	}
+	{{- end }}
+	entity, err := {{.Abbreviation}}Service.Get{{.StructName}}(req.ID{{$scopeArg}})
+	if err != nil {
+		global.GVA_LOG.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithData(entity, c)
+}
+{{- if not .ReadOnly }}
+
+// Create{{.StructName}} 新增{{.Description}}
+// @Tags {{.StructName}}
+// @Summary 新增{{.Description}}
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaia.{{.StructName}} true "{{.Description}}"
+// @Success 200 {object} response.Response{data=gaia.{{.StructName}},msg=string} "创建成功"
+// @Router /{{.Abbreviation}}/create{{.StructName}} [post]
+func ({{.Abbreviation}}Api *{{.StructName}}Api) Create{{.StructName}}(c *gin.Context) {
+	var entity gaia.{{.StructName}}
+	if err := c.ShouldBindJSON(&entity); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	{{- if .HasTenant }}
+	scope, ok := tenantScope(c)
+	if !ok {
+		return
+	}
+	{{- end }}
+	if err := {{.Abbreviation}}Service.Create{{.StructName}}(&entity{{$scopeArg}}); err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(entity, "创建成功", c)
+}
+
+// Update{{.StructName}} 更新{{.Description}}
+// @Tags {{.StructName}}
+// @Summary 更新{{.Description}}, 按主键覆盖全部字段
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body gaia.{{.StructName}} true "{{.Description}}"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /{{.Abbreviation}}/update{{.StructName}} [put]
+func ({{.Abbreviation}}Api *{{.StructName}}Api) Update{{.StructName}}(c *gin.Context) {
+	var entity gaia.{{.StructName}}
+	if err := c.ShouldBindJSON(&entity); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	{{- if .HasTenant }}
+	scope, ok := tenantScope(c)
+	if !ok {
+		return
+	}
+	{{- end }}
+	if err := {{.Abbreviation}}Service.Update{{.StructName}}(entity{{$scopeArg}}); err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// Delete{{.StructName}} 删除{{.Description}}
+// @Tags {{.StructName}}
+// @Summary 删除{{.Description}}
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param id query {{.PrimaryQueryType}} true "{{.PrimaryField.ColumnName}}"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /{{.Abbreviation}}/delete{{.StructName}} [delete]
+func ({{.Abbreviation}}Api *{{.StructName}}Api) Delete{{.StructName}}(c *gin.Context) {
+	var req struct {
+		ID {{.PrimaryQueryType}} `form:"id" binding:"required"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	{{- if .HasTenant }}
+	scope, ok := tenantScope(c)
+	if !ok {
+		return
+	}
+	{{- end }}
+	if err := {{.Abbreviation}}Service.Delete{{.StructName}}(req.ID{{$scopeArg}}); err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+{{- end }}
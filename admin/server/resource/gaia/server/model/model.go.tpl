@@ -0,0 +1,26 @@
+package gaia
+{{- if or .NeedJSON .HasUUID .HasTimer }}
+
+import (
+	{{- if .NeedJSON }}
+	"encoding/json"
+	{{- end }}
+	{{- if .HasUUID }}
+	"github.com/gofrs/uuid/v5"
+	{{- end }}
+	{{- if .HasTimer }}
+	"time"
+	{{- end }}
+)
+{{- end }}
+
+// {{.StructName}} {{.Description}}, 由 gaia 表 {{.TableName}} 生成
+type {{.StructName}} struct {
+{{- range .Fields }}
+	{{.FieldName}} {{.FieldType}} `gorm:"{{.GormTag}}" json:"{{.ColumnName}}"`{{ if .Comment }} // {{.Comment}}{{ end }}
+{{- end }}
+}
+
+func (*{{.StructName}}) TableName() string {
+	return "{{.TableName}}"
+}
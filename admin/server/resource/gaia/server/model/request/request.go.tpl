@@ -0,0 +1,13 @@
+package request
+
+import (
+	"{{.Module}}/model/common/request"
+)
+
+// {{.StructName}}Search {{.Description}}查询
+type {{.StructName}}Search struct {
+	request.PageInfo
+	{{- if .HasTenant }}
+	TenantID string `json:"tenant_id" form:"tenant_id"` // 工作空间ID
+	{{- end }}
+}
@@ -0,0 +1,29 @@
+package gaia
+
+import (
+	{{- if not .ReadOnly }}
+	"{{.Module}}/middleware"
+	{{- end }}
+	"github.com/gin-gonic/gin"
+)
+
+type {{.StructName}}Router struct{}
+
+// Init{{.StructName}}Router 初始化 {{.Description}} 路由信息
+func (s *{{.StructName}}Router) Init{{.StructName}}Router(Router *gin.RouterGroup, PublicRouter *gin.RouterGroup) {
+	{{- if not .ReadOnly }}
+	{{.Abbreviation}}Router := Router.Group("{{.Abbreviation}}").Use(middleware.OperationRecord())
+	{{- end }}
+	{{.Abbreviation}}RouterWithoutRecord := Router.Group("{{.Abbreviation}}")
+	{{- if not .ReadOnly }}
+	{
+		{{.Abbreviation}}Router.POST("create{{.StructName}}", {{.Abbreviation}}Api.Create{{.StructName}})   // 新增{{.Description}}
+		{{.Abbreviation}}Router.PUT("update{{.StructName}}", {{.Abbreviation}}Api.Update{{.StructName}})    // 更新{{.Description}}
+		{{.Abbreviation}}Router.DELETE("delete{{.StructName}}", {{.Abbreviation}}Api.Delete{{.StructName}}) // 删除{{.Description}}
+	}
+	{{- end }}
+	{
+		{{.Abbreviation}}RouterWithoutRecord.GET("get{{.StructName}}List", {{.Abbreviation}}Api.Get{{.StructName}}List) // 分页获取{{.Description}}
+		{{.Abbreviation}}RouterWithoutRecord.GET("find{{.StructName}}", {{.Abbreviation}}Api.Find{{.StructName}})       // 根据ID获取{{.Description}}
+	}
+}
@@ -0,0 +1,108 @@
+package gaia
+
+import (
+	{{- if not .ReadOnly }}
+	"errors"
+
+	{{- end }}
+	"{{.Module}}/global"
+	"{{.Module}}/model/gaia"
+	gaiaReq "{{.Module}}/model/gaia/request"
+	"gorm.io/gorm"
+)
+
+{{- $scopeParam := "" }}{{ $scopeArg := "" }}
+{{- if .HasTenant }}{{ $scopeParam = ", scope gaia.TenantScope" }}{{ $scopeArg = "scope" }}{{ end }}
+
+type {{.StructName}}Service struct{}
+
+// Get{{.StructName}}List
+// @function: Get{{.StructName}}List
+// @description: 分页获取{{.Description}}{{ if .HasTenant }}, 按数据范围过滤{{ end }}
+// @param: info gaiaReq.{{.StructName}}Search{{$scopeParam}}
+// @return: list []gaia.{{.StructName}}, total int64, err error
+func (s *{{.StructName}}Service) Get{{.StructName}}List(info gaiaReq.{{.StructName}}Search{{$scopeParam}}) (list []gaia.{{.StructName}}, total int64, err error) {
+	db := s.query({{$scopeArg}})
+	{{- if .HasTenant }}
+	if info.TenantID != "" {
+		db = db.Where("tenant_id = ?", info.TenantID)
+	}
+	{{- end }}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("{{.OrderColumn}} DESC").Find(&list).Error
+	return list, total, err
+}
+
+// Get{{.StructName}}
+// @function: Get{{.StructName}}
+// @description: 根据ID获取{{.Description}}
+// @param: id {{.PrimaryQueryType}}{{$scopeParam}}
+// @return: entity gaia.{{.StructName}}, err error
+func (s *{{.StructName}}Service) Get{{.StructName}}(id {{.PrimaryQueryType}}{{$scopeParam}}) (entity gaia.{{.StructName}}, err error) {
+	err = s.query({{$scopeArg}}).Where("{{.PrimaryField.ColumnName}} = ?", id).First(&entity).Error
+	return
+}
+{{- if not .ReadOnly }}
+
+// Create{{.StructName}}
+// @function: Create{{.StructName}}
+// @description: 新增{{.Description}}
+// @param: entity *gaia.{{.StructName}}{{$scopeParam}}
+// @return: err error
+func (s *{{.StructName}}Service) Create{{.StructName}}(entity *gaia.{{.StructName}}{{$scopeParam}}) (err error) {
+	{{- if .HasTenant }}
+	if err = scope.Check({{.TenantExpr}}); err != nil {
+		return
+	}
+	{{- end }}
+	return global.GVA_DB.Create(entity).Error
+}
+
+// Update{{.StructName}}
+// @function: Update{{.StructName}}
+// @description: 更新{{.Description}}, 全部字段覆盖
+// @param: entity gaia.{{.StructName}}{{$scopeParam}}
+// @return: err error
+func (s *{{.StructName}}Service) Update{{.StructName}}(entity gaia.{{.StructName}}{{$scopeParam}}) (err error) {
+	{{- if .HasTenant }}
+	if err = scope.Check({{.TenantExpr}}); err != nil {
+		return
+	}
+	{{- end }}
+	res := s.query({{$scopeArg}}).Where("{{.PrimaryField.ColumnName}} = ?", entity.{{.PrimaryField.FieldName}}).
+		Select("*").Omit("{{.PrimaryField.ColumnName}}").Updates(&entity)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("记录不存在或没有数据权限")
+	}
+	return nil
+}
+
+// Delete{{.StructName}}
+// @function: Delete{{.StructName}}
+// @description: 删除{{.Description}}
+// @param: id {{.PrimaryQueryType}}{{$scopeParam}}
+// @return: err error
+func (s *{{.StructName}}Service) Delete{{.StructName}}(id {{.PrimaryQueryType}}{{$scopeParam}}) (err error) {
+	res := s.query({{$scopeArg}}).Where("{{.PrimaryField.ColumnName}} = ?", id).Delete(&gaia.{{.StructName}}{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("记录不存在或没有数据权限")
+	}
+	return nil
+}
+{{- end }}
+
+func (s *{{.StructName}}Service) query({{ if .HasTenant }}scope gaia.TenantScope{{ end }}) *gorm.DB {
+	{{- if .HasTenant }}
+	return scope.Where(global.GVA_DB.Model(&gaia.{{.StructName}}{}), "tenant_id")
+	{{- else }}
+	return global.GVA_DB.Model(&gaia.{{.StructName}}{})
+	{{- end }}
+}
@@ -0,0 +1,22 @@
+package gaia
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type EndUserRouter struct{}
+
+// InitEndUserRouter 初始化 终端用户 路由信息
+func (s *EndUserRouter) InitEndUserRouter(Router *gin.RouterGroup) {
+	endUserRouter := Router.Group("endUser").Use(middleware.OperationRecord())
+	endUserRouterWithoutRecord := Router.Group("endUser")
+	{
+		endUserRouter.POST("blockEndUser", endUserApi.BlockEndUser)     // 封禁终端用户
+		endUserRouter.POST("unblockEndUser", endUserApi.UnblockEndUser) // 解封终端用户
+	}
+	{
+		endUserRouterWithoutRecord.GET("getEndUserList", endUserApi.GetEndUserList) // 分页获取终端用户
+		endUserRouterWithoutRecord.GET("findEndUser", endUserApi.FindEndUser)       // 获取终端用户详情
+	}
+}
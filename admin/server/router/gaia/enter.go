@@ -8,11 +8,13 @@ type RouterGroup struct {
 	TenantsRouter
 	SystemRouter
 	TestRouter
+	EndUserRouter // Extend: end user directory
 }
 
 var (
 	dashboardApi = api.ApiGroupApp.GaiaApiGroup.DashboardApi
 	tenantsApi   = api.ApiGroupApp.GaiaApiGroup.TenantsApi
+	endUserApi   = api.ApiGroupApp.GaiaApiGroup.EndUserApi // Extend: end user directory
 )
 var systemOAuth2Api = api.ApiGroupApp.GaiaApiGroup.SystemOAuth2Api
 var systemApi = api.ApiGroupApp.GaiaApiGroup.SystemApi
@@ -10,9 +10,15 @@ type SystemRouter struct{}
 func (s *SystemRouter) InitSystemRouter(Router *gin.RouterGroup) {
 	systemRouter := Router.Group("gaia/system")
 	{
-		systemRouter.GET("dingtalk", systemApi.GetDingTalk)          // 获取钉钉系统配置
-		systemRouter.POST("dingtalk", systemApi.SetDingTalk)         // 设置钉钉系统配置
-		systemRouter.GET("oauth2", systemOAuth2Api.GetOAuth2Config)  // 获取OAuth2配置
-		systemRouter.POST("oauth2", systemOAuth2Api.SetOAuth2Config) // 设置OAuth2配置
+		systemRouter.GET("dingtalk", systemApi.GetDingTalk)                          // 获取钉钉系统配置
+		systemRouter.POST("dingtalk", systemApi.SetDingTalk)                         // 设置钉钉系统配置
+		systemRouter.GET("scim", systemApi.GetScim)                                  // 获取SCIM用户预配配置
+		systemRouter.POST("scim", systemApi.SetScim)                                 // 设置SCIM用户预配配置
+		systemRouter.POST("dingtalk/sync", systemApi.SyncDingTalk)                   // 同步钉钉组织架构
+		systemRouter.GET("oauth2", systemOAuth2Api.GetOAuth2Config)                  // 获取OAuth2配置
+		systemRouter.POST("oauth2", systemOAuth2Api.SetOAuth2Config)                 // 设置OAuth2配置
+		systemRouter.GET("oauth2/providers", systemOAuth2Api.GetOAuth2Providers)     // 获取OAuth2提供方列表
+		systemRouter.POST("oauth2/provider", systemOAuth2Api.SetOAuth2Provider)      // 新增或修改OAuth2提供方
+		systemRouter.DELETE("oauth2/provider", systemOAuth2Api.DeleteOAuth2Provider) // 删除OAuth2提供方
 	}
 }
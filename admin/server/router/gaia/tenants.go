@@ -1,6 +1,7 @@
 package gaia
 
 import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -8,10 +9,23 @@ type TenantsRouter struct{}
 
 // InitTenantsRouter 初始化 tenants表 路由信息
 func (s *TenantsRouter) InitTenantsRouter(Router *gin.RouterGroup, PublicRouter *gin.RouterGroup) {
+	tenantsRouter := Router.Group("tenants").Use(middleware.OperationRecord()) // Extend: workspace management
 	tenantsRouterWithoutRecord := Router.Group("tenants")
+	// Extend Start: workspace management
 	{
-		tenantsRouterWithoutRecord.GET("findTenants", tenantsApi.FindTenants)       // 根据ID获取tenants表
-		tenantsRouterWithoutRecord.GET("getTenantsList", tenantsApi.GetTenantsList) // 获取tenants表列表
-		tenantsRouterWithoutRecord.GET("getAllTenants", tenantsApi.GetAllTenants)   // 获取所有工作区
+		tenantsRouter.PUT("updateTenant", tenantsApi.UpdateTenant)                // 修改工作空间
+		tenantsRouter.POST("addTenantMember", tenantsApi.AddTenantMember)         // 添加工作空间成员
+		tenantsRouter.DELETE("removeTenantMember", tenantsApi.RemoveTenantMember) // 移除工作空间成员
+		tenantsRouter.PUT("transferTenantOwner", tenantsApi.TransferTenantOwner)  // 转移工作空间所有权
+		tenantsRouter.PUT("updateMemberRoles", tenantsApi.UpdateMemberRoles)      // 批量修改成员角色
+	}
+	// Extend Stop: workspace management
+	{
+		tenantsRouterWithoutRecord.GET("findTenants", tenantsApi.FindTenants)               // 根据ID获取tenants表
+		tenantsRouterWithoutRecord.GET("getTenantsList", tenantsApi.GetTenantsList)         // 获取tenants表列表
+		tenantsRouterWithoutRecord.GET("getAllTenants", tenantsApi.GetAllTenants)           // 获取所有工作区
+		tenantsRouterWithoutRecord.GET("getTenantMembers", tenantsApi.GetTenantMembers)     // Extend: 获取工作空间成员
+		tenantsRouterWithoutRecord.GET("getAccountTenants", tenantsApi.GetAccountTenants)   // Extend: 获取账户加入的工作空间
+		tenantsRouterWithoutRecord.GET("getMemberAuditList", tenantsApi.GetMemberAuditList) // Extend: 成员变更审计
 	}
 }
@@ -19,6 +19,17 @@ type RouterGroup struct {
 	AuthorityBtnRouter
 	SysExportTemplateRouter
 	SysParamsRouter
+	ScimRouter           // Extend: SCIM provisioning
+	SessionRouter        // Extend: session registry
+	LoginSecurityRouter  // Extend: login lockout
+	AccessTokenRouter    // Extend: personal access token
+	OffboardRouter       // Extend: offboarding
+	UserSyncRouter       // Extend: two-way user sync
+	ExportJobRouter      // Extend: export job queue
+	ExportScheduleRouter // Extend: scheduled report
+	RbacRouter           // Extend: rbac bundle
+	AuthorityGrantRouter // Extend: temporary authority grant
+	PermissionRouter     // Extend: permission simulator
 }
 
 var (
@@ -41,4 +52,15 @@ var (
 	dictionaryDetailApi = api.ApiGroupApp.SystemApiGroup.DictionaryDetailApi
 	autoCodeTemplateApi = api.ApiGroupApp.SystemApiGroup.AutoCodeTemplateApi
 	exportTemplateApi   = api.ApiGroupApp.SystemApiGroup.SysExportTemplateApi
+	scimApi             = api.ApiGroupApp.SystemApiGroup.ScimApi           // Extend: SCIM provisioning
+	sessionApi          = api.ApiGroupApp.SystemApiGroup.SessionApi        // Extend: session registry
+	loginSecurityApi    = api.ApiGroupApp.SystemApiGroup.LoginSecurityApi  // Extend: login lockout
+	accessTokenApi      = api.ApiGroupApp.SystemApiGroup.AccessTokenApi    // Extend: personal access token
+	offboardApi         = api.ApiGroupApp.SystemApiGroup.OffboardApi       // Extend: offboarding
+	userSyncApi         = api.ApiGroupApp.SystemApiGroup.UserSyncApi       // Extend: two-way user sync
+	exportJobApi        = api.ApiGroupApp.SystemApiGroup.ExportJobApi      // Extend: export job queue
+	exportScheduleApi   = api.ApiGroupApp.SystemApiGroup.ExportScheduleApi // Extend: scheduled report
+	rbacApi             = api.ApiGroupApp.SystemApiGroup.RbacApi           // Extend: rbac bundle
+	authorityGrantApi   = api.ApiGroupApp.SystemApiGroup.AuthorityGrantApi // Extend: temporary authority grant
+	permissionApi       = api.ApiGroupApp.SystemApiGroup.PermissionApi     // Extend: permission simulator
 )
@@ -0,0 +1,28 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ScimRouter struct{}
+
+// InitScimRouter SCIM 2.0 用户预配接口, 使用SCIM令牌鉴权, 不走JWT与casbin
+func (s *ScimRouter) InitScimRouter(Router *gin.RouterGroup) {
+	scimRouter := Router.Group("scim/v2").Use(middleware.ScimAuth())
+	{
+		scimRouter.GET("ServiceProviderConfig", scimApi.ServiceProviderConfig) // 服务能力说明
+		scimRouter.GET("Users", scimApi.ListUsers)                             // 查询用户
+		scimRouter.POST("Users", scimApi.CreateUser)                           // 创建用户
+		scimRouter.GET("Users/:id", scimApi.GetUser)                           // 获取用户
+		scimRouter.PUT("Users/:id", scimApi.ReplaceUser)                       // 全量更新用户
+		scimRouter.PATCH("Users/:id", scimApi.PatchUser)                       // 部分更新用户
+		scimRouter.DELETE("Users/:id", scimApi.DeleteUser)                     // 删除用户
+		scimRouter.GET("Groups", scimApi.ListGroups)                           // 查询分组
+		scimRouter.POST("Groups", scimApi.CreateGroup)                         // 关联分组
+		scimRouter.GET("Groups/:id", scimApi.GetGroup)                         // 获取分组
+		scimRouter.PUT("Groups/:id", scimApi.ReplaceGroup)                     // 覆盖分组成员
+		scimRouter.PATCH("Groups/:id", scimApi.PatchGroup)                     // 增删分组成员
+		scimRouter.DELETE("Groups/:id", scimApi.DeleteGroup)                   // 删除分组(不支持)
+	}
+}
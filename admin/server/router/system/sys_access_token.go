@@ -0,0 +1,21 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type AccessTokenRouter struct{}
+
+func (s *AccessTokenRouter) InitAccessTokenRouter(Router *gin.RouterGroup) {
+	accessTokenRouter := Router.Group("accessToken").Use(middleware.OperationRecord())
+	accessTokenRouterWithoutRecord := Router.Group("accessToken")
+	{
+		accessTokenRouter.DELETE("revoke", accessTokenApi.RevokeAccessToken) // 吊销个人访问令牌
+	}
+	{
+		accessTokenRouterWithoutRecord.POST("create", accessTokenApi.CreateAccessToken)   // 创建个人访问令牌, 响应含令牌明文不记录
+		accessTokenRouterWithoutRecord.GET("list", accessTokenApi.GetAccessTokenList)     // 获取自己的个人访问令牌
+		accessTokenRouterWithoutRecord.GET("scopes", accessTokenApi.GetAccessTokenScopes) // 获取可授予令牌的接口
+	}
+}
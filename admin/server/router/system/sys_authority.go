@@ -16,8 +16,10 @@ func (s *AuthorityRouter) InitAuthorityRouter(Router *gin.RouterGroup) {
 		authorityRouter.PUT("updateAuthority", authorityApi.UpdateAuthority)    // 更新角色
 		authorityRouter.POST("copyAuthority", authorityApi.CopyAuthority)       // 拷贝角色
 		authorityRouter.POST("setDataAuthority", authorityApi.SetDataAuthority) // 设置角色资源权限
+		authorityRouter.POST("setDataScope", authorityApi.SetDataScope)         // Extend: 设置角色数据范围
 	}
 	{
 		authorityRouterWithoutRecord.POST("getAuthorityList", authorityApi.GetAuthorityList) // 获取角色列表
+		authorityRouterWithoutRecord.GET("getDataScope", authorityApi.GetDataScope)          // Extend: 获取角色数据范围
 	}
 }
@@ -0,0 +1,23 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthorityGrantRouter struct{}
+
+// InitAuthorityGrantRouter 临时角色授权
+func (s *AuthorityGrantRouter) InitAuthorityGrantRouter(Router *gin.RouterGroup) {
+	grantRouter := Router.Group("authorityGrant").Use(middleware.OperationRecord())
+	grantRouterWithoutRecord := Router.Group("authorityGrant")
+	{
+		grantRouter.POST("createAuthorityGrant", authorityGrantApi.CreateAuthorityGrant)   // 临时授予角色
+		grantRouter.POST("revokeAuthorityGrant", authorityGrantApi.RevokeAuthorityGrant)   // 提前收回
+		grantRouter.POST("approveAuthorityGrant", authorityGrantApi.ApproveAuthorityGrant) // 审批
+	}
+	{
+		grantRouterWithoutRecord.GET("getAuthorityGrantList", authorityGrantApi.GetAuthorityGrantList)       // 授权列表
+		grantRouterWithoutRecord.GET("getAuthorityGrantLogList", authorityGrantApi.GetAuthorityGrantLogList) // 授权记录
+	}
+}
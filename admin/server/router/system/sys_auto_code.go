@@ -19,6 +19,12 @@ func (s *AutoCodeRouter) InitAutoCodeRouter(Router *gin.RouterGroup, RouterPubli
 		autoCodeRouter.POST("createTemp", autoCodeTemplateApi.Create) // 创建自动化代码
 		autoCodeRouter.POST("addFunc", autoCodeTemplateApi.AddFunc)   // 为代码插入方法
 	}
+	// Extend Start: gaia auto code
+	{
+		autoCodeRouter.POST("previewGaia", autoCodeTemplateApi.PreviewGaia) // 预览 gaia 表生成的代码
+		autoCodeRouter.POST("createGaia", autoCodeTemplateApi.CreateGaia)   // 按 gaia 表生成代码
+	}
+	// Extend Stop: gaia auto code
 	{
 		autoCodeRouter.POST("getPackage", autoCodePackageApi.All)       // 获取package包
 		autoCodeRouter.POST("delPackage", autoCodePackageApi.Delete)    // 删除package包
@@ -11,8 +11,16 @@ func (s *BaseRouter) InitBaseRouter(Router *gin.RouterGroup) (R gin.IRoutes) {
 	{
 		baseRouter.POST("login", baseApi.Login)
 		baseRouter.POST("captcha", baseApi.Captcha)
-		baseRouter.POST("oaLogin", baseApi.OaLogin)              // 新增OA登录
-		baseRouter.GET("auth2/callback", baseApi.OAuth2Callback) // 新增oAuth2回调校验
+		baseRouter.POST("oaLogin", baseApi.OaLogin)                    // 新增OA登录
+		baseRouter.GET("auth2/callback", baseApi.OAuth2Callback)       // 新增oAuth2回调校验
+		baseRouter.GET("dingtalk/config", baseApi.DingTalkLoginConfig) // 钉钉扫码登录配置
+		baseRouter.POST("dingtalkLogin", baseApi.DingTalkLogin)        // 钉钉扫码登录
+		baseRouter.GET("oauth2/providers", baseApi.OAuth2Providers)    // 已启用的OAuth2/OIDC提供方
+		baseRouter.GET("oauth2/authorize", baseApi.OAuth2Authorize)    // OAuth2/OIDC授权地址
+		baseRouter.POST("oauth2Login", baseApi.OAuth2Login)            // OAuth2/OIDC登录
+		baseRouter.GET("oauth2/logout", baseApi.OAuth2Logout)          // OIDC单点退出地址
+		baseRouter.POST("mfa/enroll", baseApi.MfaLoginEnroll)          // 登录时强制绑定TOTP
+		baseRouter.POST("mfa/login", baseApi.MfaLogin)                 // 登录二次验证
 	}
 	return baseRouter
 }
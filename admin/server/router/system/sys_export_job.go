@@ -0,0 +1,27 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ExportJobRouter struct{}
+
+// InitExportJobRouter 导出任务队列, 下载接口使用签名链接鉴权
+func (s *ExportJobRouter) InitExportJobRouter(Router *gin.RouterGroup, PublicRouter *gin.RouterGroup) {
+	exportJobRouter := Router.Group("exportJob").Use(middleware.OperationRecord())
+	exportJobRouterWithoutRecord := Router.Group("exportJob")
+	exportJobPublicRouter := PublicRouter.Group("exportJob")
+	{
+		exportJobRouter.POST("createExportJob", exportJobApi.CreateExportJob)   // 创建导出任务
+		exportJobRouter.DELETE("deleteExportJob", exportJobApi.DeleteExportJob) // 删除导出任务
+	}
+	{
+		exportJobRouterWithoutRecord.GET("getExportJobList", exportJobApi.GetExportJobList) // 导出历史
+		exportJobRouterWithoutRecord.GET("findExportJob", exportJobApi.FindExportJob)       // 查询导出任务
+		exportJobRouterWithoutRecord.GET("getDownloadLink", exportJobApi.GetDownloadLink)   // 获取下载链接
+	}
+	{
+		exportJobPublicRouter.GET("download", exportJobApi.DownloadExportJob) // 签名链接下载
+	}
+}
@@ -0,0 +1,25 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ExportScheduleRouter struct{}
+
+// InitExportScheduleRouter 定时报表
+func (s *ExportScheduleRouter) InitExportScheduleRouter(Router *gin.RouterGroup) {
+	exportScheduleRouter := Router.Group("exportSchedule").Use(middleware.OperationRecord())
+	exportScheduleRouterWithoutRecord := Router.Group("exportSchedule")
+	{
+		exportScheduleRouter.POST("createExportSchedule", exportScheduleApi.CreateExportSchedule)   // 创建定时报表
+		exportScheduleRouter.PUT("updateExportSchedule", exportScheduleApi.UpdateExportSchedule)    // 更新定时报表
+		exportScheduleRouter.DELETE("deleteExportSchedule", exportScheduleApi.DeleteExportSchedule) // 删除定时报表
+		exportScheduleRouter.POST("runExportSchedule", exportScheduleApi.RunExportSchedule)         // 立即执行
+	}
+	{
+		exportScheduleRouterWithoutRecord.GET("findExportSchedule", exportScheduleApi.FindExportSchedule)             // 获取定时报表
+		exportScheduleRouterWithoutRecord.GET("getExportScheduleList", exportScheduleApi.GetExportScheduleList)       // 定时报表列表
+		exportScheduleRouterWithoutRecord.GET("getExportScheduleRunList", exportScheduleApi.GetExportScheduleRunList) // 执行记录
+	}
+}
@@ -0,0 +1,21 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type LoginSecurityRouter struct{}
+
+func (s *LoginSecurityRouter) InitLoginSecurityRouter(Router *gin.RouterGroup) {
+	loginSecurityRouter := Router.Group("loginSecurity").Use(middleware.OperationRecord())
+	loginSecurityRouterWithoutRecord := Router.Group("loginSecurity")
+	{
+		loginSecurityRouter.POST("unlock", loginSecurityApi.Unlock) // 解除账号或IP锁定
+	}
+	{
+		loginSecurityRouterWithoutRecord.GET("lockList", loginSecurityApi.GetLockList)                     // 当前生效的锁定
+		loginSecurityRouterWithoutRecord.POST("getLoginHistoryList", loginSecurityApi.GetLoginHistoryList) // 分页获取登录历史
+		loginSecurityRouterWithoutRecord.GET("mine", loginSecurityApi.GetMyLoginHistory)                   // 自己的登录历史
+	}
+}
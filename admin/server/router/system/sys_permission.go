@@ -0,0 +1,15 @@
+package system
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionRouter struct{}
+
+// InitPermissionRouter 权限模拟, 只读不记录操作
+func (s *PermissionRouter) InitPermissionRouter(Router *gin.RouterGroup) {
+	permissionRouterWithoutRecord := Router.Group("permission")
+	{
+		permissionRouterWithoutRecord.POST("simulate", permissionApi.SimulatePermission) // 模拟有效权限
+	}
+}
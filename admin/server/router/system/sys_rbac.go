@@ -0,0 +1,21 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type RbacRouter struct{}
+
+// InitRbacRouter 权限配置包导入导出
+func (s *RbacRouter) InitRbacRouter(Router *gin.RouterGroup) {
+	rbacRouter := Router.Group("rbac").Use(middleware.OperationRecord())
+	rbacRouterWithoutRecord := Router.Group("rbac")
+	{
+		rbacRouter.POST("importRbac", rbacApi.ImportRbac) // 导入配置包
+	}
+	{
+		rbacRouterWithoutRecord.GET("exportRbac", rbacApi.ExportRbac)    // 导出配置包
+		rbacRouterWithoutRecord.POST("previewRbac", rbacApi.PreviewRbac) // 预览导入差异
+	}
+}
@@ -20,10 +20,23 @@ func (s *UserRouter) InitUserRouter(Router *gin.RouterGroup) {
 		userRouter.POST("setUserAuthorities", baseApi.SetUserAuthorities) // 设置用户权限组
 		userRouter.POST("resetPassword", baseApi.ResetPassword)           // 设置用户权限组
 		userRouter.PUT("setSelfSetting", baseApi.SetSelfSetting)          // 用户界面配置
+		// Extend Start: TOTP mfa
+		userRouter.POST("mfa/activate", baseApi.MfaActivate) // 完成TOTP绑定
+		userRouter.POST("mfa/disable", baseApi.MfaDisable)   // 关闭二次验证
+		userRouter.POST("mfa/reset", baseApi.ResetMfa)       // 管理员重置二次验证
+		// Extend Stop: TOTP mfa
+		userRouter.POST("integrate/link", baseApi.LinkUserIntegrate)     // Extend: 管理员关联第三方身份
+		userRouter.POST("integrate/unlink", baseApi.UnlinkUserIntegrate) // Extend: 管理员解除第三方身份关联
 	}
 	{
 		userRouterWithoutRecord.POST("getUserList", baseApi.GetUserList) // 分页获取用户列表
 		userRouterWithoutRecord.GET("getUserInfo", baseApi.GetUserInfo)  // 获取自身信息
 		userRouterWithoutRecord.POST("sync", baseApi.SyncUser)           // Extend: 执行同步用户
+		// Extend Start: TOTP mfa 返回密钥与恢复码, 不记录操作日志
+		userRouterWithoutRecord.GET("mfa", baseApi.GetMfaStatus)
+		userRouterWithoutRecord.POST("mfa/enroll", baseApi.MfaEnroll)
+		userRouterWithoutRecord.POST("mfa/recoveryCodes", baseApi.MfaRecoveryCodes)
+		// Extend Stop: TOTP mfa
+		userRouterWithoutRecord.GET("integrates", baseApi.GetUserIntegrates) // Extend: 用户关联的第三方身份
 	}
 }
@@ -0,0 +1,22 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type OffboardRouter struct{}
+
+func (s *OffboardRouter) InitOffboardRouter(Router *gin.RouterGroup) {
+	offboardRouter := Router.Group("offboard").Use(middleware.OperationRecord())
+	offboardRouterWithoutRecord := Router.Group("offboard")
+	{
+		offboardRouter.POST("deactivate", offboardApi.DeactivateUser) // 停用用户
+		offboardRouter.POST("reactivate", offboardApi.ReactivateUser) // 恢复用户
+	}
+	{
+		offboardRouterWithoutRecord.POST("preview", offboardApi.PreviewOffboard)         // 预览离职交接
+		offboardRouterWithoutRecord.POST("execute", offboardApi.ExecuteOffboard)         // 执行离职交接, 响应含新密钥不记录, 交接报告单独留存
+		offboardRouterWithoutRecord.POST("getOffboardList", offboardApi.GetOffboardList) // 分页获取交接报告
+	}
+}
@@ -0,0 +1,22 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionRouter struct{}
+
+func (s *SessionRouter) InitSessionRouter(Router *gin.RouterGroup) {
+	sessionRouter := Router.Group("session").Use(middleware.OperationRecord())
+	sessionRouterWithoutRecord := Router.Group("session")
+	{
+		sessionRouter.DELETE("mine", sessionApi.RevokeMySession)          // 注销自己的会话
+		sessionRouter.DELETE("revoke", sessionApi.RevokeSession)          // 注销任意会话
+		sessionRouter.DELETE("revokeUser", sessionApi.RevokeUserSessions) // 注销用户的全部会话
+	}
+	{
+		sessionRouterWithoutRecord.GET("mine", sessionApi.GetMySessions)             // 获取自己的在线会话
+		sessionRouterWithoutRecord.POST("getSessionList", sessionApi.GetSessionList) // 分页获取全部会话
+	}
+}
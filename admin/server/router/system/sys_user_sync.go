@@ -0,0 +1,20 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type UserSyncRouter struct{}
+
+func (s *UserSyncRouter) InitUserSyncRouter(Router *gin.RouterGroup) {
+	userSyncRouter := Router.Group("userSync").Use(middleware.OperationRecord())
+	userSyncRouterWithoutRecord := Router.Group("userSync")
+	{
+		userSyncRouter.POST("resolveConflict", userSyncApi.ResolveSyncConflict) // 处理同步冲突
+	}
+	{
+		userSyncRouterWithoutRecord.GET("status", userSyncApi.GetUserSyncStatus)             // 获取同步状态
+		userSyncRouterWithoutRecord.POST("getConflictList", userSyncApi.GetSyncConflictList) // 分页获取同步冲突
+	}
+}
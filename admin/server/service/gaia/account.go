@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
@@ -18,32 +17,6 @@ import (
 	"go.uber.org/zap"
 )
 
-// IsUserPasswordValid
-// @author: [piexlmax](https://github.com/piexlmax)
-// @author: [SliverHorn](https://github.com/SliverHorn)
-// @function: IsUserPasswordValid
-// @description: User Password Valid
-// @param: passwd
-// @return info request.GetUserInfoByUserName, err error
-func IsUserPasswordValid(passwd string) (err error) {
-	// Check if the string is at least 8 characters long
-	if len(passwd) < 8 {
-		return errors.New("请使用最少8位且最少有一个字母数字组合的密码")
-	}
-
-	// Check if the string contains at least one letter
-	if containsLetter := regexp.MustCompile(`[a-zA-Z]`).MatchString(passwd); !containsLetter {
-		return errors.New("请最少最少有一个字母组合的密码")
-	}
-
-	// Check if the string contains at least one digit
-	if containsLetter := regexp.MustCompile(`\d`).MatchString(passwd); !containsLetter {
-		return errors.New("请最少最少有一个数字组合的密码")
-	}
-
-	return nil
-}
-
 // GetSysUser
 // @author: [piexlmax](https://github.com/piexlmax)
 // @author: [SliverHorn](https://github.com/SliverHorn)
@@ -72,42 +45,8 @@ func GetSysUser(account gaia.Account) (system.SysUser, error) {
 	return user, nil
 }
 
-// SyncUserStatus
-// @author: [piexlmax](https://github.com/piexlmax)
-// @author: [SliverHorn](https://github.com/SliverHorn)
-// @function: SyncUserStatus
-// @description: 同步用户状态
-func SyncUserStatus() {
-	// init
-	var err error
-	var account []gaia.Account
-	var userDick = make(map[string]int)
-	if err = global.GVA_DB.Find(&account).Error; err != nil {
-		return
-	}
-	for _, v := range account {
-		var userStatus = system.UserActive
-		if v.Status != gaia.UserActive {
-			userStatus = system.UserDeactivate
-		}
-		userDick[v.Email] = userStatus
-	}
-	// 获取gva用户列表
-	var userList []system.SysUser
-	if err = global.GVA_DB.Find(&userList).Error; err != nil {
-		return
-	}
-	// 循环用户列表
-	for _, v := range userList {
-		if info, ok := userDick[v.Email]; ok {
-			if v.Enable != info {
-				global.GVA_DB.Model(&v).Updates(&map[string]interface{}{
-					"enable": info,
-				})
-			}
-		}
-	}
-}
+// ErrAccountExists 邮箱对应的gaia账户已存在
+var ErrAccountExists = errors.New("该邮箱的gaia账户已存在")
 
 // RegisterUser
 // @author: [piexlmax](https://github.com/piexlmax)
@@ -130,7 +69,7 @@ func RegisterUser(u system.SysUser, token string) (err error) {
 	if err = global.GVA_DB.Where("email=?", u.Email).First(&acc).Error; err == nil {
 		// 用户已存在
 		global.GVA_LOG.Info(fmt.Sprintf("account %s", acc.Name))
-		return nil
+		return ErrAccountExists // Extend: 由调用方决定冲突或关联
 	}
 	// 默认以root执行
 	var adminUser system.SysUser
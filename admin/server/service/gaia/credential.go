@@ -0,0 +1,130 @@
+package gaia
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPasswordIncorrect = errors.New("密码错误")
+	ErrAccountNotFound   = errors.New("无法在Gaia中找到相关用户, 请联系管理员到用户列表执行刷新操作")
+
+	passwordLetterRegexp = regexp.MustCompile(`[a-zA-Z]`)
+	passwordDigitRegexp  = regexp.MustCompile(`\d`)
+)
+
+// CredentialService 统一的密码服务
+// gaia accounts 表中的 PBKDF2 哈希与盐是唯一有效的密码, sys_users.password 的 bcrypt 仅用于迁移旧数据
+type CredentialService struct{}
+
+var CredentialServiceApp = new(CredentialService)
+
+// ValidatePolicy
+// @function: ValidatePolicy
+// @description: 密码策略: 至少8位, 且同时包含字母与数字
+// @param: passwd string
+// @return: err error
+func (c *CredentialService) ValidatePolicy(passwd string) (err error) {
+	if len(passwd) < 8 {
+		return errors.New("请使用最少8位且最少有一个字母数字组合的密码")
+	}
+	if !passwordLetterRegexp.MatchString(passwd) {
+		return errors.New("请最少最少有一个字母组合的密码")
+	}
+	if !passwordDigitRegexp.MatchString(passwd) {
+		return errors.New("请最少最少有一个数字组合的密码")
+	}
+	return nil
+}
+
+// Verify
+// @function: Verify
+// @description: 校验用户密码; gaia账户尚未设置密码时回退校验旧的bcrypt密码, 通过后迁移为PBKDF2
+// @param: user system.SysUser, passwd string
+// @return: err error
+func (c *CredentialService) Verify(user system.SysUser, passwd string) (err error) {
+	var account gaia.Account
+	if account, err = user.GetAccount(); err != nil {
+		return ErrAccountNotFound
+	}
+	if len(account.Password) == 0 {
+		if len(user.Password) == 0 || !utils.BcryptCheck(passwd, user.Password) {
+			return ErrPasswordIncorrect
+		}
+		if err = c.setAccountPassword(user, account, passwd); err != nil {
+			global.GVA_LOG.Error("迁移旧密码失败!", zap.String("email", user.Email), zap.Error(err))
+		}
+		return nil
+	}
+	var ok bool
+	var pwd PasswdEncode
+	if ok, err = pwd.ComparePassword(passwd, account.Password, account.PasswordSalt); err != nil || !ok {
+		return ErrPasswordIncorrect
+	}
+	return nil
+}
+
+// SetPassword
+// @function: SetPassword
+// @description: 校验密码策略后写入gaia账户密码, 用于管理员重置
+// @param: user system.SysUser, passwd string
+// @return: err error
+func (c *CredentialService) SetPassword(user system.SysUser, passwd string) (err error) {
+	if err = c.ValidatePolicy(passwd); err != nil {
+		return err
+	}
+	var account gaia.Account
+	if account, err = user.GetAccount(); err != nil {
+		return ErrAccountNotFound
+	}
+	return c.setAccountPassword(user, account, passwd)
+}
+
+// ChangePassword
+// @function: ChangePassword
+// @description: 校验原密码与密码策略后修改密码
+// @param: user system.SysUser, oldPasswd string, newPasswd string
+// @return: err error
+func (c *CredentialService) ChangePassword(user system.SysUser, oldPasswd, newPasswd string) (err error) {
+	if err = c.Verify(user, oldPasswd); err != nil {
+		return errors.New("原密码错误")
+	}
+	return c.SetPassword(user, newPasswd)
+}
+
+// RetireBcrypt
+// @function: RetireBcrypt
+// @description: 清空gaia账户已有密码的用户的bcrypt密码, 未设置的保留到首次登录时迁移
+// @return: err error
+func (c *CredentialService) RetireBcrypt() error {
+	return global.GVA_DB.Model(&system.SysUser{}).Where("password <> ''").Where(
+		"email IN (?)", global.GVA_DB.Model(&gaia.Account{}).Select("email").Where("password <> ''"),
+	).Update("password", "").Error
+}
+
+// setAccountPassword 写入PBKDF2哈希与盐, 并清空旧的bcrypt密码
+func (c *CredentialService) setAccountPassword(user system.SysUser, account gaia.Account, passwd string) error {
+	var pwd PasswdEncode
+	passwordHashed, salt, err := pwd.EncodePassword(passwd)
+	if err != nil {
+		return err
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Model(&gaia.Account{}).Where("id = ?", account.ID).Updates(map[string]interface{}{
+			"password":      passwordHashed,
+			"password_salt": salt,
+			"updated_at":    time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&system.SysUser{}).Where("id = ?", user.ID).Update("password", "").Error
+	})
+}
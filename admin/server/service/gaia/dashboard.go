@@ -19,11 +19,12 @@ import (
 type DashboardService struct{}
 
 // GetAccountQuotaRankingData 分页获取【账号】额度排名列表
-func (dashboardService *DashboardService) GetAccountQuotaRankingData(info gaiaReq.GetAccountQuotaRankingDataReq) (list []response.GetAccountQuotaRankingDataRes, total int64, err error) {
+func (dashboardService *DashboardService) GetAccountQuotaRankingData(info gaiaReq.GetAccountQuotaRankingDataReq, scope gaia.TenantScope) (list []response.GetAccountQuotaRankingDataRes, total int64, err error) {
 	limit := info.PageSize
 	offset := info.PageSize * (info.Page - 1)
 
 	db := global.GVA_DB.Model(&gaia.AccountMoneyExtend{}).Order("used_quota desc")
+	db = scopeAccounts(db, scope, "account_id") // Extend: 数据范围
 	var accountMoneys []gaia.AccountMoneyExtend
 	err = db.Count(&total).Error
 	if err != nil {
@@ -79,9 +80,10 @@ func (dashboardService *DashboardService) GetAccountQuotaRankingData(info gaiaRe
 }
 
 // GetAppQuotaRankingData 分页获取【应用】配额排名数据
-func (dashboardService *DashboardService) GetAppQuotaRankingData(info gaiaReq.GetAppQuotaRankingDataReq) (list []response.GetAppQuotaRankingDataRes, total int64, err error) {
+func (dashboardService *DashboardService) GetAppQuotaRankingData(info gaiaReq.GetAppQuotaRankingDataReq, scope gaia.TenantScope) (list []response.GetAppQuotaRankingDataRes, total int64, err error) {
 
-	cacheKey := fmt.Sprintf("app_token_quota_ranking:%d:%d", info.Page, info.PageSize)
+	// Extend: 不同数据范围分开缓存
+	cacheKey := fmt.Sprintf("app_token_quota_ranking:%s:%d:%d", scopeCacheKey(scope), info.Page, info.PageSize)
 	var cachedResult struct {
 		List  []response.GetAppQuotaRankingDataRes
 		Total int64
@@ -102,20 +104,20 @@ func (dashboardService *DashboardService) GetAppQuotaRankingData(info gaiaReq.Ge
 		Select("" +
 			"app_id, " +
 			"COUNT(id) as message_num, " +
-			"SUM(CASE WHEN currency = 'RMB' THEN total_price / 7.26 ELSE total_price END) as message_cost").
+			"SUM(" + usdCost("currency", "total_price") + ") as message_cost"). // Extend: usd cost
 		Group("app_id")
+	messageCosts = scopeApps(messageCosts, scope, "app_id") // Extend: 数据范围
 
 	workflowCosts := global.GVA_DB.Table("public.workflow_node_executions").
 		Select("" +
 			"app_id, " +
 			"COUNT(id) as workflow_num, " +
-			"SUM(CASE " +
-			"  WHEN execution_metadata::json->>'currency' = 'RMB' " +
-			"  THEN CAST((execution_metadata::json->>'total_price') AS NUMERIC) / 7.26 " +
-			"  ELSE CAST((execution_metadata::json->>'total_price') AS NUMERIC) " +
-			"END) AS workflow_cost").
+			// Extend: usd cost
+			"SUM(" + usdCost("execution_metadata::json->>'currency'", "CAST((execution_metadata::json->>'total_price') AS NUMERIC)") +
+			") AS workflow_cost").
 		Where("execution_metadata IS NOT NULL AND execution_metadata != '' AND (execution_metadata::json->>'total_price') IS NOT NULL").
 		Group("app_id")
+	workflowCosts = scopeApps(workflowCosts, scope, "app_id") // Extend: 数据范围
 
 	// 主查询
 	query := global.GVA_DB.Table("(?) AS m", messageCosts).
@@ -282,11 +284,12 @@ func (dashboardService *DashboardService) GetAppQuotaRankingData(info gaiaReq.Ge
 }
 
 // GetAppTokenQuotaRankingData 分页获取【应用密钥】配额排名数据列表
-func (dashboardService *DashboardService) GetAppTokenQuotaRankingData(info gaiaReq.GetAppTokenQuotaRankingDataReq) (list []response.GetAppTokenQuotaRankingDataRes, total int64, err error) {
+func (dashboardService *DashboardService) GetAppTokenQuotaRankingData(info gaiaReq.GetAppTokenQuotaRankingDataReq, scope gaia.TenantScope) (list []response.GetAppTokenQuotaRankingDataRes, total int64, err error) {
 	limit := info.PageSize
 	offset := info.PageSize * (info.Page - 1)
 
 	db := global.GVA_DB.Model(&gaia.ApiTokenMoneyExtend{}).Order("accumulated_quota desc")
+	db = scopeApiTokens(db, scope, "app_token_id") // Extend: 数据范围
 	var apiTokenMoneys []gaia.ApiTokenMoneyExtend
 	err = db.Count(&total).Error
 	if err != nil {
@@ -365,10 +368,11 @@ func (dashboardService *DashboardService) GetAppTokenQuotaRankingData(info gaiaR
 }
 
 // GetAppTokenDailyQuotaData 获取每天密钥花费数据列表
-func (dashboardService *DashboardService) GetAppTokenDailyQuotaData(info gaiaReq.GetAppTokenDailyQuotaDataReq) (list []response.GetAppTokenDailyQuotaDataRes, err error) {
+func (dashboardService *DashboardService) GetAppTokenDailyQuotaData(info gaiaReq.GetAppTokenDailyQuotaDataReq, scope gaia.TenantScope) (list []response.GetAppTokenDailyQuotaDataRes, err error) {
 
 	db := global.GVA_DB.Select("DATE(stat_at) as stat_at, SUM(day_used_quota) as day_used_quota").Model(&gaia.ApiTokenMoneyDailyStatExtend{}).Order("stat_at desc").Group("DATE(stat_at)")
 	var apiTokenMoneyDailyStatExtends []gaia.ApiTokenMoneyDailyStatExtend
+	db = scopeApiTokens(db, scope, "app_token_id") // Extend: 数据范围
 
 	if info.AppId != "" {
 		db = db.Where("app_token_id = ?", info.AppId)
@@ -397,7 +401,7 @@ func (dashboardService *DashboardService) GetAppTokenDailyQuotaData(info gaiaReq
 }
 
 // GetAiImageQuotaRankingData 获取【AI图片】使用量排名数据列表
-func (dashboardService *DashboardService) GetAiImageQuotaRankingData(info gaiaReq.GetAiImageQuotaRankingDataReq) (list []response.GetAiImageQuotaRankingRes, err error) {
+func (dashboardService *DashboardService) GetAiImageQuotaRankingData(info gaiaReq.GetAiImageQuotaRankingDataReq, scope gaia.TenantScope) (list []response.GetAiImageQuotaRankingRes, err error) {
 	limit := info.PageSize
 	offset := info.PageSize * (info.Page - 1)
 
@@ -411,6 +415,7 @@ func (dashboardService *DashboardService) GetAiImageQuotaRankingData(info gaiaRe
     `)
 	db = db.Joins("RIGHT JOIN accounts ON account_layover_record_extend.account_id = accounts.id")
 	db = db.Joins("RIGHT JOIN forwarding_extend ON account_layover_record_extend.forwarding_id = forwarding_extend.id")
+	db = scopeAccounts(db, scope, "account_layover_record_extend.account_id") // Extend: 数据范围
 
 	// 添加时间范围筛选
 	if !info.StatAt.IsZero() {
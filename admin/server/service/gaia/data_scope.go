@@ -0,0 +1,65 @@
+package gaia
+
+import (
+	"crypto/md5"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"gorm.io/gorm"
+)
+
+// scopeAccounts 只保留范围内工作空间的成员账户, column 为账户ID字段
+func scopeAccounts(db *gorm.DB, scope gaia.TenantScope, column string) *gorm.DB {
+	if scope.All {
+		return db
+	}
+	accounts := scope.Where(global.GVA_DB.Model(&gaia.TenantAccountJoins{}).Select("account_id"), "tenant_id")
+	return db.Where(column+" IN (?)", accounts)
+}
+
+// scopeApps 只保留范围内工作空间的应用, column 为应用ID字段
+func scopeApps(db *gorm.DB, scope gaia.TenantScope, column string) *gorm.DB {
+	if scope.All {
+		return db
+	}
+	apps := scope.Where(global.GVA_DB.Model(&gaia.Apps{}).Select("id"), "tenant_id")
+	return db.Where(column+" IN (?)", apps)
+}
+
+// scopeApiTokens 只保留范围内工作空间的应用密钥, column 为密钥ID字段
+func scopeApiTokens(db *gorm.DB, scope gaia.TenantScope, column string) *gorm.DB {
+	if scope.All {
+		return db
+	}
+	tokens := scope.Where(global.GVA_DB.Model(&gaia.ApiTokens{}).Select("id"), "tenant_id")
+	return db.Where(column+" IN (?)", tokens)
+}
+
+// checkAccountScope 账户需至少属于一个范围内的工作空间
+func checkAccountScope(scope gaia.TenantScope, accountID string) error {
+	if scope.All {
+		return nil
+	}
+	var count int64
+	db := global.GVA_DB.Model(&gaia.TenantAccountJoins{}).Where("account_id = ?", accountID)
+	if err := scope.Where(db, "tenant_id").Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gaia.ErrTenantOutOfScope
+	}
+	return nil
+}
+
+// scopeCacheKey 缓存按数据范围区分
+func scopeCacheKey(scope gaia.TenantScope) string {
+	if scope.All {
+		return "all"
+	}
+	tenantIDs := slices.Clone(scope.TenantIDs)
+	slices.Sort(tenantIDs)
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(tenantIDs, ","))))
+}
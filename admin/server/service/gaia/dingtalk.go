@@ -0,0 +1,560 @@
+package gaia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faabiosr/cachego"
+	cachesync "github.com/faabiosr/cachego/sync"
+	"github.com/fastwego/dingding"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/gofrs/uuid/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const dingTalkOApiUrl = "https://oapi.dingtalk.com"
+const dingTalkApiUrl = "https://api.dingtalk.com"
+const dingTalkRootDeptID = int64(1) // 钉钉根部门id
+const dingTalkPageSize = 100        // 钉钉用户列表分页大小
+
+type DingTalkService struct{}
+
+// RevokeUserSessions 注销后台用户全部会话, 由 service/system 在初始化时注入, 避免循环引用
+var RevokeUserSessions = func(userID uint, reason string) error { return nil }
+
+// DingTalkUser 钉钉通讯录用户
+type DingTalkUser struct {
+	UserID     string  `json:"userid"`
+	UnionID    string  `json:"unionid"`
+	Name       string  `json:"name"`
+	Mobile     string  `json:"mobile"`
+	Email      string  `json:"email"`
+	OrgEmail   string  `json:"org_email"`
+	Avatar     string  `json:"avatar"`
+	Active     bool    `json:"active"`
+	DeptIDList []int64 `json:"dept_id_list"`
+}
+
+// DingTalkSyncResult 钉钉组织同步结果
+type DingTalkSyncResult struct {
+	Departments int `json:"departments"` // 同步部门数
+	Users       int `json:"users"`       // 钉钉用户数
+	Linked      int `json:"linked"`      // 关联到gaia账户的用户数
+	Deactivated int `json:"deactivated"` // 离职停用数
+	Reactivated int `json:"reactivated"` // 重新入职启用数
+}
+
+// GetMail 优先使用企业邮箱
+func (u DingTalkUser) GetMail() string {
+	if len(u.OrgEmail) > 0 {
+		return u.OrgEmail
+	}
+	return u.Email
+}
+
+// TrustMail 企业邮箱由组织分配且属于钉钉集成配置的邮箱域名, 只有此时才能按邮箱关联已有账户;
+// 个人邮箱由用户自行填写, 始终不可信
+func (u DingTalkUser) TrustMail(integrate gaia.SystemIntegration) bool {
+	return len(u.OrgEmail) > 0 && integrate.TrustEmail(u.OrgEmail)
+}
+
+// client
+// @description: 获取使用企业内部应用凭证的钉钉客户端
+// @return: *dingding.Client, error
+func (d *DingTalkService) client() (*dingding.Client, error) {
+	var integrateService SystemIntegratedService
+	integrate, err := integrateService.GetEnabledIntegrate(gaia.SystemIntegrationDingTalk)
+	if err != nil {
+		return nil, err
+	}
+	return dingding.NewClient(&dingding.DefaultAccessTokenManager{
+		Id:    "dingtalk_access_token:" + integrate.AppKey,
+		Cache: dingTalkTokenCache(),
+		Name:  "access_token",
+		GetRefreshRequestFunc: func() *http.Request {
+			params := url.Values{}
+			params.Add("appkey", integrate.AppKey)
+			params.Add("appsecret", integrate.AppSecret)
+			req, _ := http.NewRequest(http.MethodGet, dingTalkOApiUrl+"/gettoken?"+params.Encode(), nil)
+			return req
+		},
+	}), nil
+}
+
+// dingTalkMemoryCache 未配置redis时在进程内缓存 access_token
+var dingTalkMemoryCache = cachesync.New()
+
+// dingTalkTokenCache 钉钉 access_token 缓存, 优先保存在redis中供多实例共用
+func dingTalkTokenCache() cachego.Cache {
+	if global.GVA_REDIS == nil {
+		return dingTalkMemoryCache
+	}
+	return dingTalkRedisCache{}
+}
+
+// dingTalkRedisCache 以 global.GVA_REDIS 实现 cachego.Cache
+type dingTalkRedisCache struct{}
+
+func (dingTalkRedisCache) Contains(key string) bool {
+	count, err := global.GVA_REDIS.Exists(context.Background(), key).Result()
+	return err == nil && count > 0
+}
+
+func (dingTalkRedisCache) Delete(key string) error {
+	return global.GVA_REDIS.Del(context.Background(), key).Err()
+}
+
+func (dingTalkRedisCache) Fetch(key string) (string, error) {
+	return global.GVA_REDIS.Get(context.Background(), key).Result()
+}
+
+func (c dingTalkRedisCache) FetchMulti(keys []string) map[string]string {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, err := c.Fetch(key); err == nil {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// Flush 缓存与其他数据共用redis, 不清空
+func (dingTalkRedisCache) Flush() error {
+	return nil
+}
+
+func (dingTalkRedisCache) Save(key string, value string, lifeTime time.Duration) error {
+	return global.GVA_REDIS.Set(context.Background(), key, value, lifeTime).Err()
+}
+
+// post
+// @description: 调用钉钉 oapi 接口并解析 result 字段
+// @param: client *dingding.Client, path string, body interface{}, result interface{}
+// @return: error
+func (d *DingTalkService) post(client *dingding.Client, path string, body interface{}, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, dingTalkOApiUrl+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	var resp []byte
+	if resp, err = client.Do(req); err != nil {
+		return fmt.Errorf("钉钉接口 %s 请求失败: %s", path, err.Error())
+	}
+	var res struct {
+		ErrCode int64           `json:"errcode"`
+		ErrMsg  string          `json:"errmsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err = json.Unmarshal(resp, &res); err != nil {
+		return err
+	}
+	if res.ErrCode != 0 {
+		return fmt.Errorf("钉钉接口 %s 返回错误: %s", path, res.ErrMsg)
+	}
+	return json.Unmarshal(res.Result, result)
+}
+
+// GetUserByAuthCode
+// @description: 通过钉钉扫码登录返回的授权码获取钉钉通讯录用户
+// @param: authCode string
+// @return: user DingTalkUser, err error
+func (d *DingTalkService) GetUserByAuthCode(authCode string) (user DingTalkUser, err error) {
+	var integrateService SystemIntegratedService
+	var integrate gaia.SystemIntegration
+	if integrate, err = integrateService.GetEnabledIntegrate(gaia.SystemIntegrationDingTalk); err != nil {
+		return user, err
+	}
+	// 1. 授权码换取个人 accessToken
+	var body []byte
+	if body, err = json.Marshal(&map[string]string{
+		"clientId":     integrate.AppKey,
+		"clientSecret": integrate.AppSecret,
+		"code":         authCode,
+		"grantType":    "authorization_code",
+	}); err != nil {
+		return user, err
+	}
+	var token struct {
+		AccessToken string `json:"accessToken"`
+	}
+	if err = d.doJson(http.MethodPost, dingTalkApiUrl+"/v1.0/oauth2/userAccessToken",
+		"", bytes.NewReader(body), &token); err != nil {
+		return user, err
+	}
+	// 2. 获取个人信息(unionId)
+	var me struct {
+		UnionID string `json:"unionId"`
+		Nick    string `json:"nick"`
+		Mobile  string `json:"mobile"`
+		Email   string `json:"email"`
+	}
+	if err = d.doJson(http.MethodGet, dingTalkApiUrl+"/v1.0/contact/users/me",
+		token.AccessToken, nil, &me); err != nil {
+		return user, err
+	}
+	if len(me.UnionID) == 0 {
+		return user, errors.New("未获取到钉钉用户unionId")
+	}
+	// 3. unionId 换取企业内 userid, 不在组织内的用户无法登录
+	var client *dingding.Client
+	if client, err = d.client(); err != nil {
+		return user, err
+	}
+	var byUnion struct {
+		UserID string `json:"userid"`
+	}
+	if err = d.post(client, "/topapi/user/getbyunionid", map[string]string{
+		"unionid": me.UnionID}, &byUnion); err != nil {
+		return user, errors.New("该钉钉账号不属于当前组织")
+	}
+	if err = d.post(client, "/topapi/v2/user/get", map[string]string{
+		"userid": byUnion.UserID}, &user); err != nil {
+		return user, err
+	}
+	// 登录成功后顺带刷新关联信息
+	_ = d.LinkAccount(user)
+	return user, nil
+}
+
+// TrustMail
+// @description: 钉钉用户的企业邮箱是否可用于关联已有账户
+// @param: user DingTalkUser
+// @return: bool
+func (d *DingTalkService) TrustMail(user DingTalkUser) bool {
+	var integrateService SystemIntegratedService
+	integrate, err := integrateService.GetEnabledIntegrate(gaia.SystemIntegrationDingTalk)
+	return err == nil && user.TrustMail(integrate)
+}
+
+// LinkAccount
+// @description: 将钉钉用户与gaia账户关联
+// @param: user DingTalkUser
+// @return: error
+func (d *DingTalkService) LinkAccount(user DingTalkUser) error {
+	account, err := d.findAccount(user, d.TrustMail(user))
+	if err != nil {
+		return err
+	}
+	return d.saveExtend(account, user)
+}
+
+// LinkCreated
+// @description: 注册后将钉钉用户与新建的gaia账户关联, 不依赖邮箱匹配
+// @param: accountID uuid.UUID, user DingTalkUser
+// @return: error
+func (d *DingTalkService) LinkCreated(accountID uuid.UUID, user DingTalkUser) error {
+	var account gaia.Account
+	if err := global.GVA_DB.Where("id = ?", accountID).First(&account).Error; err != nil {
+		return err
+	}
+	return d.saveExtend(account, user)
+}
+
+// doJson
+// @description: 调用钉钉新版接口
+// @param: method string, link string, accessToken string, body io.Reader, result interface{}
+// @return: error
+func (d *DingTalkService) doJson(method, link, accessToken string, body io.Reader, result interface{}) error {
+	req, err := http.NewRequest(method, link, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(accessToken) > 0 {
+		req.Header.Set("x-acs-dingtalk-access-token", accessToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var bodyByte []byte
+	if bodyByte, err = io.ReadAll(resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("钉钉接口返回错误状态码 %d: %s", resp.StatusCode, string(bodyByte))
+	}
+	return json.Unmarshal(bodyByte, result)
+}
+
+// FindSysUser
+// @description: 通过钉钉用户查找后台用户
+// @param: user DingTalkUser
+// @return: sysUser system.SysUser, err error
+func (d *DingTalkService) FindSysUser(user DingTalkUser) (sysUser system.SysUser, err error) {
+	var account gaia.Account
+	if account, err = d.findAccount(user, d.TrustMail(user)); err != nil {
+		return sysUser, err
+	}
+	// 后台用户以 sys_users.uuid 与gaia账户关联
+	if err = global.GVA_DB.Where("uuid = ?", account.ID).First(&sysUser).Error; err != nil {
+		return sysUser, err
+	}
+	return sysUser, nil
+}
+
+// findAccount
+// @description: 通过钉钉关联表中的 userid/unionid 查找gaia账户, 企业邮箱可信时再按企业邮箱查找
+// @param: user DingTalkUser, trustMail bool 企业邮箱是否属于集成配置的邮箱域名
+// @return: account gaia.Account, err error
+func (d *DingTalkService) findAccount(user DingTalkUser, trustMail bool) (account gaia.Account, err error) {
+	var extend gaia.AccountDingTalkExtend
+	if global.GVA_DB.Where("(ding_talk = ? AND ding_talk <> '') OR (union_id = ? AND union_id <> '')",
+		user.UserID, user.UnionID).First(&extend).Error == nil {
+		if err = global.GVA_DB.Where("id = ?", extend.ID).First(&account).Error; err == nil {
+			return account, nil
+		}
+	}
+	if trustMail {
+		if err = global.GVA_DB.Where("email = ?", user.OrgEmail).First(&account).Error; err == nil {
+			return account, nil
+		}
+	}
+	return account, gorm.ErrRecordNotFound
+}
+
+// saveExtend
+// @description: 写入钉钉关联信息并同步手机号、昵称
+// @param: account gaia.Account, user DingTalkUser
+// @return: error
+func (d *DingTalkService) saveExtend(account gaia.Account, user DingTalkUser) error {
+	var deptIDs []string
+	for _, v := range user.DeptIDList {
+		deptIDs = append(deptIDs, strconv.FormatInt(v, 10))
+	}
+	extend := gaia.AccountDingTalkExtend{
+		ID:        account.ID,
+		DingTalk:  user.UserID,
+		UnionID:   user.UnionID,
+		Name:      user.Name,
+		Mobile:    user.Mobile,
+		DeptIDs:   strings.Join(deptIDs, ","),
+		UpdatedAt: time.Now(),
+	}
+	if err := global.GVA_DB.Save(&extend).Error; err != nil {
+		return err
+	}
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if len(user.Name) > 0 {
+		updates["nick_name"] = user.Name
+	}
+	if len(user.Mobile) > 0 {
+		updates["phone"] = user.Mobile
+	}
+	return global.GVA_DB.Model(&system.SysUser{}).Where("uuid = ?", account.ID).Updates(updates).Error
+}
+
+// listDepartments
+// @description: 递归获取全部部门
+// @param: client *dingding.Client, parentID int64
+// @return: list []gaia.DingTalkDepartment, err error
+func (d *DingTalkService) listDepartments(client *dingding.Client, parentID int64) (list []gaia.DingTalkDepartment, err error) {
+	var children []struct {
+		DeptID   int64  `json:"dept_id"`
+		Name     string `json:"name"`
+		ParentID int64  `json:"parent_id"`
+	}
+	if err = d.post(client, "/topapi/v2/department/listsub", map[string]int64{
+		"dept_id": parentID}, &children); err != nil {
+		return nil, err
+	}
+	for _, v := range children {
+		list = append(list, gaia.DingTalkDepartment{
+			DeptID: v.DeptID, ParentID: v.ParentID, Name: v.Name, UpdatedAt: time.Now()})
+		var sub []gaia.DingTalkDepartment
+		if sub, err = d.listDepartments(client, v.DeptID); err != nil {
+			return nil, err
+		}
+		list = append(list, sub...)
+	}
+	return list, nil
+}
+
+// listUsers
+// @description: 分页获取部门下的直属用户
+// @param: client *dingding.Client, deptID int64
+// @return: users []DingTalkUser, err error
+func (d *DingTalkService) listUsers(client *dingding.Client, deptID int64) (users []DingTalkUser, err error) {
+	var cursor int64
+	for {
+		var page struct {
+			HasMore    bool           `json:"has_more"`
+			NextCursor int64          `json:"next_cursor"`
+			List       []DingTalkUser `json:"list"`
+		}
+		if err = d.post(client, "/topapi/v2/user/list", map[string]int64{
+			"dept_id": deptID, "cursor": cursor, "size": dingTalkPageSize}, &page); err != nil {
+			return nil, err
+		}
+		users = append(users, page.List...)
+		if !page.HasMore {
+			return users, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// SyncOrganization
+// @description: 同步钉钉部门与用户, 填充钉钉关联信息, 停用已离职用户
+// @return: result DingTalkSyncResult, err error
+func (d *DingTalkService) SyncOrganization() (result DingTalkSyncResult, err error) {
+	var client *dingding.Client
+	if client, err = d.client(); err != nil {
+		return result, err
+	}
+	// 部门
+	var departments []gaia.DingTalkDepartment
+	if departments, err = d.listDepartments(client, dingTalkRootDeptID); err != nil {
+		return result, err
+	}
+	departments = append(departments, gaia.DingTalkDepartment{
+		DeptID: dingTalkRootDeptID, Name: "root", UpdatedAt: time.Now()})
+	if err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if tErr := tx.Where("1 = 1").Delete(&gaia.DingTalkDepartment{}).Error; tErr != nil {
+			return tErr
+		}
+		return tx.CreateInBatches(&departments, 100).Error
+	}); err != nil {
+		return result, err
+	}
+	result.Departments = len(departments)
+	var integrateService SystemIntegratedService
+	var integrate gaia.SystemIntegration
+	if integrate, err = integrateService.GetEnabledIntegrate(gaia.SystemIntegrationDingTalk); err != nil {
+		return result, err
+	}
+	// 用户, 同一用户可能属于多个部门
+	var users = make(map[string]DingTalkUser)
+	for _, dept := range departments {
+		var list []DingTalkUser
+		if list, err = d.listUsers(client, dept.DeptID); err != nil {
+			return result, err
+		}
+		for _, v := range list {
+			users[v.UserID] = v
+		}
+	}
+	result.Users = len(users)
+	if result.Users == 0 {
+		// 防止配置错误导致全员被停用
+		return result, errors.New("未从钉钉获取到任何用户, 已跳过离职处理")
+	}
+	for _, v := range users {
+		var account gaia.Account
+		if account, err = d.findAccount(v, v.TrustMail(integrate)); err != nil {
+			continue
+		}
+		var extend gaia.AccountDingTalkExtend
+		if global.GVA_DB.Where("id = ?", account.ID).First(&extend).Error == nil && extend.Departed {
+			// 由同步停用的用户重新出现在组织中, 恢复启用
+			if err = d.setUserEnable(account, system.UserActive); err != nil {
+				global.GVA_LOG.Error("SyncOrganization setUserEnable: ", zap.Error(err))
+			} else {
+				result.Reactivated++
+			}
+		}
+		if err = d.saveExtend(account, v); err != nil {
+			global.GVA_LOG.Error("SyncOrganization saveExtend: ", zap.Error(err))
+			continue
+		}
+		result.Linked++
+	}
+	// 离职处理
+	var extends []gaia.AccountDingTalkExtend
+	if err = global.GVA_DB.Where("departed = ?", false).Find(&extends).Error; err != nil {
+		return result, err
+	}
+	for _, v := range extends {
+		if _, ok := users[v.DingTalk]; ok || len(v.DingTalk) == 0 {
+			continue
+		}
+		var account gaia.Account
+		if global.GVA_DB.Where("id = ?", v.ID).First(&account).Error != nil {
+			continue
+		}
+		if err = d.setUserEnable(account, system.UserDeactivate); err != nil {
+			global.GVA_LOG.Error("SyncOrganization setUserEnable: ", zap.Error(err))
+			continue
+		}
+		if err = global.GVA_DB.Model(&gaia.AccountDingTalkExtend{}).Where("id = ?", v.ID).Updates(map[string]interface{}{
+			"departed":   true,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			global.GVA_LOG.Error("SyncOrganization departed: ", zap.Error(err))
+			continue
+		}
+		result.Deactivated++
+	}
+	return result, nil
+}
+
+// setUserEnable
+// @description: 修改后台用户与gaia账户的启用状态, 停用时注销该用户的全部会话
+// @param: account gaia.Account, enable int
+// @return: err error
+func (d *DingTalkService) setUserEnable(account gaia.Account, enable int) (err error) {
+	status := gaia.UserBanned
+	if enable == system.UserActive {
+		status = gaia.UserActive
+	}
+	if err = global.GVA_DB.Model(&gaia.Account{}).Where("id = ?", account.ID).Updates(map[string]interface{}{
+		"status":     status,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+	var user system.SysUser
+	err = global.GVA_DB.Where("uuid = ?", account.ID).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		if err = global.GVA_DB.Model(&user).Update("enable", enable).Error; err != nil {
+			return err
+		}
+		user.SyncGaiaStatus(enable)
+		if enable != system.UserActive {
+			if err = RevokeUserSessions(user.ID, system.SessionRevokeDisabled); err != nil {
+				return err
+			}
+		}
+	}
+	if enable == system.UserActive {
+		return global.GVA_DB.Model(&gaia.AccountDingTalkExtend{}).Where("id = ?", account.ID).Update("departed", false).Error
+	}
+	return nil
+}
+
+// SyncDingTalk
+// @description: 定时任务入口, 未启用钉钉集成时直接跳过
+func SyncDingTalk() {
+	var integrate gaia.SystemIntegration
+	if err := global.GVA_DB.Where("classify = ? AND status = ?",
+		gaia.SystemIntegrationDingTalk, true).First(&integrate).Error; err != nil {
+		return
+	}
+	var d DingTalkService
+	result, err := d.SyncOrganization()
+	if err != nil {
+		global.GVA_LOG.Error("钉钉组织同步失败", zap.Error(err))
+		return
+	}
+	global.GVA_LOG.Info("钉钉组织同步完成", zap.Any("result", result))
+}
@@ -0,0 +1,80 @@
+package gaia
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/gofrs/uuid/v5"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 钉钉用户只能通过已关联的 userid/unionid, 或属于配置域名的企业邮箱匹配已有账户
+func TestDingTalkFindAccount(t *testing.T) {
+	db := newEndUserTestDB(t)
+	if err := db.AutoMigrate(&gaia.Account{}, &gaia.AccountDingTalkExtend{}); err != nil {
+		t.Fatal(err)
+	}
+	linked := gaia.Account{ID: uuid.Must(uuid.NewV4()), Name: "linked", Email: "linked@corp.com"}
+	victim := gaia.Account{ID: uuid.Must(uuid.NewV4()), Name: "admin", Email: "admin@corp.com"}
+	for _, v := range []*gaia.Account{&linked, &victim} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&gaia.AccountDingTalkExtend{ID: linked.ID, DingTalk: "u1", UnionID: "union-1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&gaia.AccountDingTalkExtend{ID: uuid.Must(uuid.NewV4())}) // 未填写钉钉id的关联不应被空值匹配
+	trusted := gaia.SystemIntegration{Classify: gaia.SystemIntegrationDingTalk, EmailDomains: "corp.com"}
+	untrusted := gaia.SystemIntegration{Classify: gaia.SystemIntegrationDingTalk}
+	tests := []struct {
+		name      string
+		user      DingTalkUser
+		integrate gaia.SystemIntegration
+		want      uuid.UUID
+	}{
+		{name: "按userid匹配", user: DingTalkUser{UserID: "u1"}, integrate: untrusted, want: linked.ID},
+		{name: "按unionid匹配", user: DingTalkUser{UserID: "u9", UnionID: "union-1"}, integrate: untrusted, want: linked.ID},
+		{name: "个人邮箱不可信", user: DingTalkUser{UserID: "u2", Email: victim.Email}, integrate: trusted},
+		{name: "未配置域名时企业邮箱不可信", user: DingTalkUser{UserID: "u2", OrgEmail: victim.Email}, integrate: untrusted},
+		{name: "企业邮箱属于配置域名", user: DingTalkUser{UserID: "u2", OrgEmail: victim.Email}, integrate: trusted, want: victim.ID},
+		{name: "空的钉钉id", user: DingTalkUser{}, integrate: untrusted},
+	}
+	d := &DingTalkService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.findAccount(tt.user, tt.user.TrustMail(tt.integrate))
+			if tt.want == uuid.Nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Fatalf("findAccount() = %s, %v, want not found", got.ID, err)
+				}
+				return
+			}
+			if err != nil || got.ID != tt.want {
+				t.Fatalf("findAccount() = %s, %v, want %s", got.ID, err, tt.want)
+			}
+		})
+	}
+}
+
+// 未配置redis时 access_token 缓存在进程内, 不再写入临时目录
+func TestDingTalkTokenCache(t *testing.T) {
+	oldRedis := global.GVA_REDIS
+	global.GVA_REDIS = nil
+	t.Cleanup(func() { global.GVA_REDIS = oldRedis })
+	cache := dingTalkTokenCache()
+	if err := cache.Save("dingtalk_access_token:test", "token", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := dingTalkTokenCache().Fetch("dingtalk_access_token:test"); err != nil || got != "token" {
+		t.Errorf("Fetch() = %s, %v", got, err)
+	}
+	global.GVA_REDIS = redis.NewClient(&redis.Options{Addr: "127.0.0.1:0", MaxRetries: -1})
+	if _, ok := dingTalkTokenCache().(dingTalkRedisCache); !ok {
+		t.Errorf("配置redis后应使用redis缓存")
+	}
+}
@@ -5,23 +5,12 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
-	"errors"
-	"regexp"
 
 	"golang.org/x/crypto/pbkdf2"
 )
 
 type PasswdEncode struct{}
 
-// validPassword checks if the password matches the required pattern.
-func (PasswdEncode) validPassword(password string) (string, error) {
-	re := regexp.MustCompile(`^(?=.*[a-zA-Z])(?=.*\d).{8,}$`)
-	if re.MatchString(password) {
-		return password, nil
-	}
-	return "", errors.New("密码必须包含字母和数字，且长度必须大于8位")
-}
-
 // hashPassword hashes the password with the given salt using PBKDF2 and SHA-256.
 func hashPassword(passwordStr string, salt []byte) string {
 	dk := pbkdf2.Key([]byte(passwordStr), salt, 10000, sha256.Size, sha256.New)
@@ -0,0 +1,238 @@
+package gaia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaRes "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"gorm.io/gorm"
+)
+
+// 详情中展示的最近会话数
+const endUserRecentConversations = 20
+
+type EndUserService struct{}
+
+// GetEndUserList
+// @function: GetEndUserList
+// @description: 分页获取终端用户及其调用统计, 只返回数据范围内工作空间的终端用户
+// @param: info gaiaReq.EndUserSearch, scope gaia.TenantScope
+// @return: list []gaiaRes.EndUserInfo, total int64, err error
+func (e *EndUserService) GetEndUserList(info gaiaReq.EndUserSearch, scope gaia.TenantScope) (list []gaiaRes.EndUserInfo, total int64, err error) {
+	db := scope.Where(e.query(), "eu.tenant_id")
+	if info.TenantID != "" {
+		db = db.Where("eu.tenant_id = ?", info.TenantID)
+	}
+	if info.AppID != "" {
+		db = db.Where("eu.app_id = ?", info.AppID)
+	}
+	if info.SessionID != "" {
+		db = db.Where("eu.session_id LIKE ?", "%"+info.SessionID+"%")
+	}
+	if info.Type != "" {
+		db = db.Where("eu.type = ?", info.Type)
+	}
+	if info.Blocked != nil {
+		if *info.Blocked {
+			db = db.Where("b.id IS NOT NULL")
+		} else {
+			db = db.Where("b.id IS NULL")
+		}
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err = db.Select(e.columns()).Scopes(info.Paginate()).Order("eu.created_at DESC").Scan(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, e.fillStats(list)
+}
+
+// GetEndUser
+// @function: GetEndUser
+// @description: 获取终端用户详情与最近的会话, 数据范围外的终端用户视为不存在
+// @param: id string, scope gaia.TenantScope
+// @return: detail gaiaRes.EndUserDetail, err error
+func (e *EndUserService) GetEndUser(id string, scope gaia.TenantScope) (detail gaiaRes.EndUserDetail, err error) {
+	var list []gaiaRes.EndUserInfo
+	if err = scope.Where(e.query(), "eu.tenant_id").Select(e.columns()).Where("eu.id = ?", id).Scan(&list).Error; err != nil {
+		return detail, err
+	}
+	if len(list) == 0 {
+		return detail, errors.New("终端用户不存在")
+	}
+	if err = e.fillStats(list); err != nil {
+		return detail, err
+	}
+	detail.EndUserInfo = list[0]
+	if detail.Blocked {
+		var block gaia.EndUserBlock
+		if err = global.GVA_DB.Where("end_user_id = ?", id).First(&block).Error; err == nil {
+			detail.Block = &block
+		}
+	}
+	err = global.GVA_DB.Table("conversations AS c").
+		Select("c.id, c.name, c.from_source, c.created_at, c.updated_at, COUNT(m.id) AS message_count, "+
+			"COALESCE(SUM("+usdCost("m.currency", "m.total_price")+"), 0) AS total_cost, "+
+			"COALESCE(MAX(m.created_at), c.created_at) AS last_message_at").
+		Joins("LEFT JOIN messages AS m ON m.conversation_id = c.id").
+		Where("c.from_end_user_id = ? AND c.is_deleted = ?", id, false).
+		Group("c.id").Order("c.updated_at DESC").Limit(endUserRecentConversations).
+		Scan(&detail.Conversations).Error
+	return detail, err
+}
+
+// BlockEndUser
+// @function: BlockEndUser
+// @description: 封禁终端用户, 记录封禁并在共享Redis中写入标记, gaia侧鉴权时拒绝该用户的请求
+// @param: req gaiaReq.EndUserBlockReq, operatorID uint, operator string, scope gaia.TenantScope
+// @return: err error
+func (e *EndUserService) BlockEndUser(req gaiaReq.EndUserBlockReq, operatorID uint, operator string, scope gaia.TenantScope) (err error) {
+	if global.GVA_REDIS == nil {
+		return errors.New("未配置Redis, 无法封禁终端用户")
+	}
+	var endUser gaia.EndUser
+	if err = global.GVA_DB.Where("id = ?", req.ID).First(&endUser).Error; err != nil {
+		return errors.New("终端用户不存在")
+	}
+	if err = scope.Check(endUser.TenantID); err != nil {
+		return err
+	}
+	block := gaia.EndUserBlock{
+		EndUserID:  endUser.ID,
+		TenantID:   endUser.TenantID,
+		AppID:      endUser.AppID,
+		Reason:     req.Reason,
+		OperatorID: operatorID,
+		Operator:   operator,
+		CreatedAt:  time.Now(),
+	}
+	if err = global.GVA_DB.Where("end_user_id = ?", endUser.ID).Attrs(block).FirstOrCreate(&block).Error; err != nil {
+		return err
+	}
+	return global.GVA_REDIS.Set(context.Background(), fmt.Sprintf(gaia.EndUserBlockedKey, endUser.ID), block.Reason, 0).Err()
+}
+
+// UnblockEndUser
+// @function: UnblockEndUser
+// @description: 解封终端用户
+// @param: id string, scope gaia.TenantScope
+// @return: err error
+func (e *EndUserService) UnblockEndUser(id string, scope gaia.TenantScope) (err error) {
+	if global.GVA_REDIS == nil {
+		return errors.New("未配置Redis, 无法解封终端用户")
+	}
+	var block gaia.EndUserBlock
+	if err = global.GVA_DB.Select("tenant_id").Where("end_user_id = ?", id).First(&block).Error; err != nil {
+		return errors.New("终端用户未被封禁")
+	}
+	if err = scope.Check(block.TenantID); err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Where("end_user_id = ?", id).Delete(&gaia.EndUserBlock{}).Error; err != nil {
+		return err
+	}
+	return global.GVA_REDIS.Del(context.Background(), fmt.Sprintf(gaia.EndUserBlockedKey, id)).Err()
+}
+
+// SyncEndUserBlocks
+// @function: SyncEndUserBlocks
+// @description: 以 end_user_block_extend 为准重建共享Redis中的封禁标记, 补齐丢失的标记并清理已解封的残留标记
+// @return: err error
+func (e *EndUserService) SyncEndUserBlocks() (err error) {
+	if global.GVA_REDIS == nil {
+		return nil
+	}
+	var blocks []gaia.EndUserBlock
+	if err = global.GVA_DB.Select("end_user_id", "reason").Find(&blocks).Error; err != nil {
+		return err
+	}
+	ctx := context.Background()
+	keys := make(map[string]bool, len(blocks))
+	pipe := global.GVA_REDIS.Pipeline()
+	for _, block := range blocks {
+		key := fmt.Sprintf(gaia.EndUserBlockedKey, block.EndUserID)
+		keys[key] = true
+		pipe.Set(ctx, key, block.Reason, 0)
+	}
+	iter := global.GVA_REDIS.Scan(ctx, 0, fmt.Sprintf(gaia.EndUserBlockedKey, "*"), 500).Iterator()
+	for iter.Next(ctx) {
+		if !keys[iter.Val()] {
+			pipe.Del(ctx, iter.Val())
+		}
+	}
+	if err = iter.Err(); err != nil {
+		return err
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (e *EndUserService) query() *gorm.DB {
+	return global.GVA_DB.Table("end_users AS eu").
+		Joins("LEFT JOIN apps AS a ON a.id = eu.app_id").
+		Joins("LEFT JOIN tenants AS t ON t.id = eu.tenant_id").
+		Joins("LEFT JOIN end_user_block_extend AS b ON b.end_user_id = CAST(eu.id AS TEXT)")
+}
+
+func (e *EndUserService) columns() string {
+	return "eu.*, a.name AS app_name, t.name AS tenant_name, b.id IS NOT NULL AS blocked"
+}
+
+// fillStats 统计终端用户的消息数、花费、工作流运行次数以及首次与最近出现时间
+func (e *EndUserService) fillStats(list []gaiaRes.EndUserInfo) error {
+	if len(list) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(list))
+	for _, v := range list {
+		ids = append(ids, v.ID)
+	}
+	type row struct {
+		UserID   string    `gorm:"column:user_id"`
+		Num      int64     `gorm:"column:num"`
+		Cost     float64   `gorm:"column:cost"`
+		LastSeen time.Time `gorm:"column:last_seen"`
+	}
+	var messages, runs []row
+	if err := global.GVA_DB.Model(&gaia.Messages{}).
+		Select("from_end_user_id AS user_id, COUNT(id) AS num, MAX(created_at) AS last_seen, "+
+			"COALESCE(SUM("+usdCost("currency", "total_price")+"), 0) AS cost").
+		Where("from_end_user_id IN ?", ids).Group("from_end_user_id").Scan(&messages).Error; err != nil {
+		return fmt.Errorf("统计终端用户消息失败：%w", err)
+	}
+	if err := global.GVA_DB.Model(&gaia.WorkflowRun{}).
+		Select("created_by AS user_id, COUNT(id) AS num, MAX(created_at) AS last_seen").
+		Where("created_by_role = ? AND created_by IN ?", gaia.IndirectAccessUser, ids).
+		Group("created_by").Scan(&runs).Error; err != nil {
+		return fmt.Errorf("统计终端用户工作流运行失败：%w", err)
+	}
+	stats := make(map[string]*gaiaRes.EndUserInfo, len(list))
+	for i := range list {
+		list[i].FirstSeen = list[i].CreatedAt
+		list[i].LastSeen = list[i].UpdatedAt
+		stats[list[i].ID] = &list[i]
+	}
+	for _, r := range messages {
+		if v, ok := stats[r.UserID]; ok {
+			v.MessageCount, v.TotalCost = r.Num, r.Cost
+			if r.LastSeen.After(v.LastSeen) {
+				v.LastSeen = r.LastSeen
+			}
+		}
+	}
+	for _, r := range runs {
+		if v, ok := stats[r.UserID]; ok {
+			v.WorkflowRuns = r.Num
+			if r.LastSeen.After(v.LastSeen) {
+				v.LastSeen = r.LastSeen
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package gaia
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newEndUserTestDB 使用内存sqlite替换全局数据库, gaia 的表依赖postgres扩展, 只建查询用到的字段
+func newEndUserTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&gaia.EndUser{}, &gaia.EndUserBlock{}, &gaia.AppRequestTest{}, &gaia.AppRequestTestBatch{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, ddl := range []string{
+		`CREATE TABLE apps (id text primary key, tenant_id text, name text)`,
+		`CREATE TABLE tenants (id text primary key, name text)`,
+		`CREATE TABLE messages (id text primary key, conversation_id text, from_end_user_id text, currency text, total_price real, created_at datetime)`,
+		`CREATE TABLE workflow_runs (id text primary key, created_by text, created_by_role text, created_at datetime)`,
+		`CREATE TABLE conversations (id text primary key, name text, from_source text, from_end_user_id text,
+			is_deleted bool, created_at datetime, updated_at datetime)`,
+	} {
+		if err = db.Exec(ddl).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldDB, oldLog := global.GVA_DB, global.GVA_LOG
+	global.GVA_DB, global.GVA_LOG = db, zap.NewNop()
+	t.Cleanup(func() {
+		global.GVA_DB, global.GVA_LOG = oldDB, oldLog
+		if sqlDB, e := db.DB(); e == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestEndUserTenantScope(t *testing.T) {
+	db := newEndUserTestDB(t)
+	now := time.Now()
+	for _, v := range []struct{ tenant, app, user string }{{"t1", "a1", "u1"}, {"t2", "a2", "u2"}} {
+		db.Exec(`INSERT INTO tenants (id, name) VALUES (?, ?)`, v.tenant, v.tenant)
+		db.Exec(`INSERT INTO apps (id, tenant_id, name) VALUES (?, ?, ?)`, v.app, v.tenant, v.app)
+		if err := db.Create(&gaia.EndUser{ID: v.user, TenantID: v.tenant, AppID: v.app, SessionID: v.user,
+			CreatedAt: now, UpdatedAt: now}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&gaia.EndUserBlock{EndUserID: "u2", TenantID: "t2", AppID: "a2", CreatedAt: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&gaia.AppRequestTestBatch{ID: 1})
+	db.Create(&gaia.AppRequestTestBatch{ID: 2})
+	db.Create(&gaia.AppRequestTest{AppID: "a1", BatchId: 1})
+	db.Create(&gaia.AppRequestTest{AppID: "a2", BatchId: 2})
+
+	service := &EndUserService{}
+	scoped := gaia.TenantScope{TenantIDs: []string{"t1"}}
+	tests := []struct {
+		name      string
+		scope     gaia.TenantScope
+		wantUsers []string
+		wantBatch []uint
+	}{
+		{name: "不限制数据范围", scope: gaia.AllTenants, wantUsers: []string{"u1", "u2"}, wantBatch: []uint{1, 2}},
+		{name: "只能看到范围内工作空间", scope: scoped, wantUsers: []string{"u1"}, wantBatch: []uint{1}},
+		{name: "没有任何工作空间", scope: gaia.TenantScope{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info gaiaReq.EndUserSearch
+			info.Page, info.PageSize = 1, 10
+			list, total, err := service.GetEndUserList(info, tt.scope)
+			if err != nil {
+				t.Fatalf("GetEndUserList() error = %v", err)
+			}
+			var got []string
+			for _, v := range list {
+				got = append(got, v.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantUsers) || total != int64(len(tt.wantUsers)) {
+				t.Errorf("GetEndUserList() = %v (%d), want %v", got, total, tt.wantUsers)
+			}
+			_, batches, _, err := (&TestService{}).AppRequestTestBatch(gaiaReq.GetAppRequestTestRequest{}, tt.scope)
+			if err != nil {
+				t.Fatalf("AppRequestTestBatch() error = %v", err)
+			}
+			var gotBatch []uint
+			for _, v := range batches {
+				gotBatch = append([]uint{v.ID}, gotBatch...)
+			}
+			if fmt.Sprint(gotBatch) != fmt.Sprint(tt.wantBatch) {
+				t.Errorf("AppRequestTestBatch() = %v, want %v", gotBatch, tt.wantBatch)
+			}
+		})
+	}
+
+	if _, err := service.GetEndUser("u2", scoped); err == nil {
+		t.Errorf("GetEndUser() 不应返回其他工作空间的终端用户")
+	}
+	if detail, err := service.GetEndUser("u2", gaia.AllTenants); err != nil || !detail.Blocked {
+		t.Errorf("GetEndUser() = %+v, %v, want blocked", detail.EndUserInfo, err)
+	}
+	// 无可用redis, 数据范围在写入redis前校验
+	oldRedis := global.GVA_REDIS
+	global.GVA_REDIS = redis.NewClient(&redis.Options{Addr: "127.0.0.1:0", MaxRetries: -1})
+	t.Cleanup(func() { global.GVA_REDIS = oldRedis })
+	if err := service.UnblockEndUser("u2", scoped); !errors.Is(err, gaia.ErrTenantOutOfScope) {
+		t.Errorf("UnblockEndUser() error = %v, want %v", err, gaia.ErrTenantOutOfScope)
+	}
+	if err := service.BlockEndUser(gaiaReq.EndUserBlockReq{ID: "u2"}, 1, "admin", scoped); !errors.Is(err, gaia.ErrTenantOutOfScope) {
+		t.Errorf("BlockEndUser() error = %v, want %v", err, gaia.ErrTenantOutOfScope)
+	}
+}
+
+// 花费统计按配置的汇率将 RMB 折算为美元
+func TestUsdCost(t *testing.T) {
+	db := newEndUserTestDB(t)
+	db.Exec(`INSERT INTO messages (id, currency, total_price) VALUES ('m1', 'RMB', 8), ('m2', 'USD', 1)`)
+	oldGaia := global.GVA_CONFIG.Gaia
+	t.Cleanup(func() { global.GVA_CONFIG.Gaia = oldGaia })
+	for _, tt := range []struct {
+		rate float64
+		want float64
+	}{{rate: 0, want: 8/defaultRmbExchangeRate + 1}, {rate: 4, want: 3}} {
+		global.GVA_CONFIG.Gaia.RmbExchangeRate = tt.rate
+		var cost float64
+		if err := db.Table("messages").Select("SUM(" + usdCost("currency", "total_price") + ")").Scan(&cost).Error; err != nil {
+			t.Fatal(err)
+		}
+		if diff := cost - tt.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("rate %v: cost = %v, want %v", tt.rate, cost, tt.want)
+		}
+	}
+}
@@ -6,4 +6,8 @@ type ServiceGroup struct {
 	QuotaService
 	TenantsService
 	TestService
+	DingTalkService
+	OAuth2Service
+	CredentialService
+	EndUserService // Extend: end user directory
 }
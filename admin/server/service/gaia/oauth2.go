@@ -0,0 +1,426 @@
+package gaia
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/oidc"
+)
+
+const oauth2StateTTL = 10 * time.Minute // 授权请求有效期
+
+const oaProviderName = "oa" // 由 oa-login 配置迁移的提供方
+
+var providerNameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+type OAuth2Service struct{}
+
+// OAuth2State 授权请求期间保存的校验信息
+type OAuth2State struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// oauth2Binding 写入浏览器 HttpOnly cookie 的校验信息, 回调时与 state 及服务端记录比对, 防止授权请求被其他浏览器使用
+type oauth2Binding struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// encode 编码为cookie值
+func (b oauth2Binding) encode() string {
+	data, _ := json.Marshal(&b)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeOAuth2Binding 解析cookie值, 格式错误时返回空值
+func decodeOAuth2Binding(value string) (binding oauth2Binding) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		_ = json.Unmarshal(data, &binding)
+	}
+	return binding
+}
+
+// oauth2Session 登录成功后保存的提供方与id_token
+type oauth2Session struct {
+	Provider string `json:"provider"`
+	IDToken  string `json:"id_token"`
+}
+
+// OIDCConfig
+// @description: 将集成配置转换为OIDC客户端配置
+// @param: integrate gaia.SystemIntegration
+// @return: config oidc.Config, err error
+func OIDCConfig(integrate gaia.SystemIntegration) (config oidc.Config, err error) {
+	var configMap request.SystemOAuth2Request
+	if err = json.Unmarshal([]byte(integrate.Config), &configMap); err != nil {
+		return config, errors.New("解析OAuth2配置失败")
+	}
+	return oidc.Config{
+		ServerURL:        configMap.ServerURL,
+		DiscoveryURL:     configMap.DiscoveryURL,
+		AuthorizeURL:     configMap.AuthorizeURL,
+		TokenURL:         configMap.TokenURL,
+		UserinfoURL:      configMap.UserinfoURL,
+		LogoutURL:        configMap.LogoutURL,
+		ClientID:         integrate.AppID,
+		ClientSecret:     integrate.AppSecret,
+		Scope:            configMap.Scope,
+		TokenAuthMethod:  configMap.TokenAuthMethod,
+		RedirectURI:      configMap.RedirectUri,
+		UserNameField:    configMap.UserNameField,
+		UserEmailField:   configMap.UserEmailField,
+		UserIDField:      configMap.UserIDField,
+		UserAvatarField:  configMap.UserAvatarField,
+		UserinfoMethod:   configMap.UserinfoMethod,
+		UserinfoRawToken: configMap.UserinfoRawToken,
+
+		UserEmailVerifiedField: configMap.UserEmailVerifiedField, // Extend: oauth2 email trust
+	}, nil
+}
+
+// GetProviderList
+// @description: 获取全部OAuth2/OIDC提供方, 密钥已脱敏
+// @return: list []gaia.SystemIntegration, err error
+func (o *OAuth2Service) GetProviderList() (list []gaia.SystemIntegration, err error) {
+	if err = global.GVA_DB.Where("classify = ?", gaia.SystemIntegrationOAuth2).Order("id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i := range list {
+		var secret string
+		if secret, err = utils.DecryptBlowfish(list[i].AppSecret, global.GVA_CONFIG.JWT.SigningKey); err == nil {
+			list[i].AppSecret = utils.AddAsteriskToString(secret)
+		}
+		list[i].Name = list[i].ProviderName()
+	}
+	return list, nil
+}
+
+// SetProvider
+// @description: 新增或修改OAuth2/OIDC提供方
+// @param: integrate gaia.SystemIntegration, code string, test bool
+// @return: err error
+func (o *OAuth2Service) SetProvider(integrate gaia.SystemIntegration, code string, test bool) (err error) {
+	integrate.Classify = gaia.SystemIntegrationOAuth2
+	integrate.EmailDomains = strings.Join(integrate.Domains(), ",")
+	if !providerNameRegexp.MatchString(integrate.Name) {
+		return errors.New("提供方标识只能包含小写字母、数字、下划线与中划线")
+	}
+	var list []gaia.SystemIntegration
+	if err = global.GVA_DB.Where("classify = ? AND id <> ?", gaia.SystemIntegrationOAuth2, integrate.Id).Find(&list).Error; err != nil {
+		return err
+	}
+	for _, v := range list {
+		if v.ProviderName() == integrate.Name {
+			return errors.New("提供方标识已存在")
+		}
+	}
+	var integrateService SystemIntegratedService
+	if integrate.Id == 0 {
+		// 新增时仅测试不保存
+		if test {
+			return integrateService.TestConnection(integrate, code)
+		}
+		record := gaia.SystemIntegration{Classify: gaia.SystemIntegrationOAuth2, Name: integrate.Name}
+		if err = global.GVA_DB.Create(&record).Error; err != nil {
+			return err
+		}
+		integrate.Id = record.Id
+	}
+	return integrateService.SetIntegratedConfig(integrate, code, test)
+}
+
+// DeleteProvider
+// @description: 删除OAuth2/OIDC提供方, 已有的账户关联保留
+// @param: id uint
+// @return: err error
+func (o *OAuth2Service) DeleteProvider(id uint) error {
+	return global.GVA_DB.Where("id = ? AND classify = ?", id, gaia.SystemIntegrationOAuth2).Delete(&gaia.SystemIntegration{}).Error
+}
+
+// GetEnabledProviders
+// @description: 登录页展示的已启用提供方; 传入邮箱时按域名路由, 优先返回域名匹配的提供方, 否则返回不限域名的提供方
+// @param: email string
+// @return: list []response.OAuth2Provider, err error
+func (o *OAuth2Service) GetEnabledProviders(email string) (list []response.OAuth2Provider, err error) {
+	var providers []gaia.SystemIntegration
+	if err = global.GVA_DB.Where("classify = ? AND status = ?", gaia.SystemIntegrationOAuth2, true).Order("id").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	var matched, open []response.OAuth2Provider
+	for _, v := range providers {
+		item := response.OAuth2Provider{Name: v.ProviderName(), Title: v.Title}
+		if len(item.Title) == 0 {
+			item.Title = item.Name
+		}
+		switch {
+		case len(email) == 0:
+			list = append(list, item)
+		case len(v.Domains()) == 0:
+			open = append(open, item)
+		case v.MatchEmail(email):
+			matched = append(matched, item)
+		}
+	}
+	if len(email) == 0 {
+		return list, nil
+	}
+	if len(matched) > 0 {
+		return matched, nil
+	}
+	return open, nil
+}
+
+// GetEnabledProvider
+// @description: 按标识获取已启用的提供方(密钥已解密), 标识为空时使用第一个已启用的提供方
+// @param: name string
+// @return: integrate gaia.SystemIntegration, err error
+func (o *OAuth2Service) GetEnabledProvider(name string) (integrate gaia.SystemIntegration, err error) {
+	var providers []gaia.SystemIntegration
+	if err = global.GVA_DB.Where("classify = ? AND status = ?", gaia.SystemIntegrationOAuth2, true).Order("id").Find(&providers).Error; err != nil {
+		return integrate, err
+	}
+	for _, v := range providers {
+		if len(name) == 0 || v.ProviderName() == name {
+			if v.AppSecret, err = utils.DecryptBlowfish(v.AppSecret, global.GVA_CONFIG.JWT.SigningKey); err != nil {
+				return integrate, errors.New("AppSecret解析失败")
+			}
+			return v, nil
+		}
+	}
+	return integrate, errors.New("未找到已启用的OAuth2提供方")
+}
+
+// client
+// @description: 使用提供方配置创建客户端, 回调地址固定使用集成配置中的地址
+// @param: provider gaia.SystemIntegration
+// @return: *oidc.Client, error
+func (o *OAuth2Service) client(provider gaia.SystemIntegration) (*oidc.Client, error) {
+	config, err := OIDCConfig(provider)
+	if err != nil {
+		return nil, err
+	}
+	return oidc.NewClient(context.Background(), config)
+}
+
+// Authorize
+// @description: 生成授权地址, state、nonce与PKCE verifier保存在redis中, 同时返回需写入浏览器HttpOnly cookie的绑定值
+// @param: name string 提供方标识
+// @return: link string, state string, binding string, err error
+func (o *OAuth2Service) Authorize(name string) (link, state, binding string, err error) {
+	var provider gaia.SystemIntegration
+	if provider, err = o.GetEnabledProvider(name); err != nil {
+		return "", "", "", err
+	}
+	var client *oidc.Client
+	if client, err = o.client(provider); err != nil {
+		return "", "", "", err
+	}
+	bind := oauth2Binding{
+		State:        oidc.RandomString(24),
+		Nonce:        oidc.RandomString(24),
+		CodeVerifier: oidc.RandomString(48),
+	}
+	info := OAuth2State{
+		Provider:     provider.ProviderName(),
+		Nonce:        bind.Nonce,
+		CodeVerifier: bind.CodeVerifier,
+	}
+	var infoByte []byte
+	if infoByte, err = json.Marshal(&info); err != nil {
+		return "", "", "", err
+	}
+	if err = global.GVA_REDIS.Set(context.Background(), oauth2StateKey(bind.State),
+		string(infoByte), oauth2StateTTL).Err(); err != nil {
+		return "", "", "", err
+	}
+	return client.AuthCodeURL(bind.State, bind.Nonce, bind.CodeVerifier), bind.State, bind.encode(), nil
+}
+
+// Callback
+// @description: 校验state与浏览器绑定值并完成授权码换取、id_token校验、用户字段映射与邮箱域名校验
+// @param: code string, state string, binding string 发起授权的浏览器cookie中的绑定值
+// @return: provider gaia.SystemIntegration, info oidc.UserInfo, token oidc.Token, err error
+func (o *OAuth2Service) Callback(code, state, binding string) (
+	provider gaia.SystemIntegration, info oidc.UserInfo, token oidc.Token, err error) {
+	if len(state) == 0 {
+		return provider, info, token, errors.New("缺少state参数")
+	}
+	bind := decodeOAuth2Binding(binding)
+	if len(bind.State) == 0 || subtle.ConstantTimeCompare([]byte(bind.State), []byte(state)) != 1 {
+		return provider, info, token, errors.New("state与当前浏览器不匹配, 请重新登录")
+	}
+	// state 只能使用一次
+	var infoStr string
+	if infoStr, err = global.GVA_REDIS.GetDel(context.Background(), oauth2StateKey(state)).Result(); err != nil {
+		return provider, info, token, errors.New("state无效或已过期, 请重新登录")
+	}
+	var saved OAuth2State
+	if err = json.Unmarshal([]byte(infoStr), &saved); err != nil {
+		return provider, info, token, err
+	}
+	if subtle.ConstantTimeCompare([]byte(bind.Nonce), []byte(saved.Nonce)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(bind.CodeVerifier), []byte(saved.CodeVerifier)) != 1 {
+		return provider, info, token, errors.New("state与当前浏览器不匹配, 请重新登录")
+	}
+	if provider, err = o.GetEnabledProvider(saved.Provider); err != nil {
+		return provider, info, token, err
+	}
+	var client *oidc.Client
+	if client, err = o.client(provider); err != nil {
+		return provider, info, token, err
+	}
+	if info, token, err = client.Authenticate(context.Background(), code, saved.CodeVerifier, saved.Nonce); err != nil {
+		return provider, info, token, err
+	}
+	if len(info.Email) > 0 && !provider.MatchEmail(info.Email) {
+		return provider, info, token, fmt.Errorf("邮箱 %s 不属于身份提供方 %s", info.Email, provider.ProviderName())
+	}
+	return provider, info, token, nil
+}
+
+// SaveIDToken
+// @description: 登录成功后保存提供方与id_token, 用于退出登录时作为id_token_hint
+// @param: userUUID string, provider string, idToken string, ttl time.Duration
+func (o *OAuth2Service) SaveIDToken(userUUID, provider, idToken string, ttl time.Duration) {
+	if len(idToken) == 0 {
+		return
+	}
+	session, _ := json.Marshal(&oauth2Session{Provider: provider, IDToken: idToken})
+	global.GVA_REDIS.Set(context.Background(), oauth2IDTokenKey(userUUID), string(session), ttl)
+}
+
+// LogoutURL
+// @description: 生成RP发起的退出登录地址, 非OAuth2登录或未配置end_session_endpoint时返回空
+// @param: userUUID string, postLogoutRedirectURI string
+// @return: string, error
+func (o *OAuth2Service) LogoutURL(userUUID, postLogoutRedirectURI string) (string, error) {
+	sessionStr, err := global.GVA_REDIS.GetDel(context.Background(), oauth2IDTokenKey(userUUID)).Result()
+	if err != nil {
+		return "", nil
+	}
+	var session oauth2Session
+	if err = json.Unmarshal([]byte(sessionStr), &session); err != nil {
+		return "", err
+	}
+	provider, err := o.GetEnabledProvider(session.Provider)
+	if err != nil {
+		return "", err
+	}
+	client, err := o.client(provider)
+	if err != nil {
+		return "", err
+	}
+	return client.LogoutURL(session.IDToken, postLogoutRedirectURI), nil
+}
+
+// TestDiscovery
+// @description: 测试发现文档与JWKS地址是否可用
+// @param: integrate gaia.SystemIntegration
+// @return: error
+func (o *OAuth2Service) TestDiscovery(integrate gaia.SystemIntegration) error {
+	config, err := OIDCConfig(integrate)
+	if err != nil {
+		return err
+	}
+	if len(config.DiscoveryURL) == 0 {
+		return nil
+	}
+	client, err := oidc.NewClient(context.Background(), config)
+	if err != nil {
+		return err
+	}
+	if client.IsOIDC() && len(client.Endpoints().JwksURI) == 0 {
+		return errors.New("发现文档中缺少jwks_uri")
+	}
+	return nil
+}
+
+// MigrateOaLogin
+// @description: 将配置文件中旧的 oa-login 配置迁移为名为 oa 的OAuth2提供方;
+// 旧配置中没有授权地址, 迁移后保持停用, 需在后台补充授权地址后启用.
+// 旧OA登录按OA返回的邮箱直接匹配用户, 迁移后声明信任OA邮箱, 已有用户首次登录时按邮箱关联;
+// 提供方已存在且尚未配置该项时补充, 兼容之前已迁移的配置
+// @return: err error
+func (o *OAuth2Service) MigrateOaLogin() (err error) {
+	oa := global.GVA_CONFIG.OaLogin
+	if len(oa.Url) == 0 || len(oa.Oauth2ClientId) == 0 {
+		return nil
+	}
+	var list []gaia.SystemIntegration
+	if err = global.GVA_DB.Where("classify = ?", gaia.SystemIntegrationOAuth2).Find(&list).Error; err != nil {
+		return err
+	}
+	for _, v := range list {
+		if v.ProviderName() != oaProviderName {
+			continue
+		}
+		configMap := map[string]interface{}{}
+		if err = json.Unmarshal([]byte(v.Config), &configMap); err != nil {
+			return err
+		}
+		if _, ok := configMap["trust_issuer_email"]; ok {
+			return nil
+		}
+		configMap["trust_issuer_email"] = true
+		var configBytes []byte
+		if configBytes, err = json.Marshal(&configMap); err != nil {
+			return err
+		}
+		return global.GVA_DB.Model(&gaia.SystemIntegration{}).Where("id = ?", v.Id).Update("config", string(configBytes)).Error
+	}
+	var configBytes []byte
+	if configBytes, err = json.Marshal(&map[string]interface{}{
+		"server_url":         oa.Url,
+		"token_url":          oa.GetTokenByCodeApiPath,
+		"userinfo_url":       oa.GetUserApiPath,
+		"user_id_field":      "data.username",
+		"user_name_field":    "data.username",
+		"user_email_field":   "data.email",
+		"userinfo_method":    "POST",
+		"userinfo_raw_token": true,
+		"trust_issuer_email": true,
+	}); err != nil {
+		return err
+	}
+	var secret string
+	if secret, err = utils.EncryptBlowfish([]byte(oa.Oauth2ClientSecret), global.GVA_CONFIG.JWT.SigningKey); err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Create(&gaia.SystemIntegration{
+		Classify:  gaia.SystemIntegrationOAuth2,
+		Name:      oaProviderName,
+		Title:     "OA",
+		Status:    false,
+		AppID:     oa.Oauth2ClientId,
+		AppSecret: secret,
+		Config:    string(configBytes),
+	}).Error; err != nil {
+		return err
+	}
+	global.GVA_LOG.Warn("已将 oa-login 配置迁移为OAuth2提供方 oa, 请在后台补充授权地址与回调地址后启用")
+	return nil
+}
+
+func oauth2StateKey(state string) string {
+	return fmt.Sprintf("oauth2_state:%s", state)
+}
+
+func oauth2IDTokenKey(userUUID string) string {
+	return fmt.Sprintf("oauth2_id_token:%s", userUUID)
+}
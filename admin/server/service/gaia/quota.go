@@ -21,7 +21,7 @@ type QuotaService struct{}
 // @Produce application/json
 // @Param info gaiaReq.GetAccountQuotaRankingDataReq
 // @Return list []response.GetQuotaManagementDataResponse, total int64, err error
-func (dashboardService *QuotaService) GetQuotaManagementData(info gaiaReq.GetAccountQuotaRankingDataReq) (
+func (dashboardService *QuotaService) GetQuotaManagementData(info gaiaReq.GetAccountQuotaRankingDataReq, scope gaia.TenantScope) (
 	list []response.GetQuotaManagementDataResponse, total int64, err error) {
 	if info.PageSize == 0 {
 		info.PageSize = 10
@@ -32,6 +32,7 @@ func (dashboardService *QuotaService) GetQuotaManagementData(info gaiaReq.GetAcc
 	s := strings.TrimSpace(info.Keyword)
 	offset := info.PageSize * (info.Page - 1)
 	db := global.GVA_DB.Model(&gaia.AccountMoneyExtend{}).Order("used_quota desc")
+	db = scopeAccounts(db, scope, "account_id") // Extend: 数据范围
 	if len(s) > 0 {
 		s = fmt.Sprintf("%%%s%%", s)
 		if err = global.GVA_DB.Debug().Select("id").Where(
@@ -110,7 +111,11 @@ func (dashboardService *QuotaService) GetQuotaManagementData(info gaiaReq.GetAcc
 // @Produce application/json
 // @Param info gaiaReq.SetUserQuotaRequest
 // @Return err error
-func (dashboardService *QuotaService) SetUserQuota(uid uuid.UUID, quota float64) error {
+func (dashboardService *QuotaService) SetUserQuota(uid uuid.UUID, quota float64, scope gaia.TenantScope) error {
+	// Extend: 只能设置数据范围内的账户
+	if err := checkAccountScope(scope, uid.String()); err != nil {
+		return err
+	}
 
 	return global.GVA_DB.Model(&gaia.AccountMoneyExtend{}).Where(
 		"account_id = ?", uid).Updates(&map[string]interface{}{
@@ -1,10 +1,10 @@
 package gaia
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,6 +16,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/oidc"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -48,6 +49,25 @@ func (e *SystemIntegratedService) GetIntegratedConfig(classID uint) (integrate g
 	return integrate
 }
 
+// GetEnabledIntegrate
+// @Tags System Integrated
+// @Summary 获取已启用的系统集成配置(密钥已解密, 仅供服务端内部使用)
+// @param: classID uint
+// @return: integrate gaia.SystemIntegration, err error
+func (e *SystemIntegratedService) GetEnabledIntegrate(classID uint) (integrate gaia.SystemIntegration, err error) {
+	if err = global.GVA_DB.Where("classify = ?", classID).First(&integrate).Error; err != nil {
+		return integrate, errors.New("未找到相关集成配置")
+	}
+	if !integrate.Status {
+		return integrate, errors.New("集成配置未启用")
+	}
+	if integrate.AppSecret, err = utils.DecryptBlowfish(
+		integrate.AppSecret, global.GVA_CONFIG.JWT.SigningKey); err != nil {
+		return integrate, errors.New("AppSecret解析失败")
+	}
+	return integrate, nil
+}
+
 // SetIntegratedConfig
 // @Tags System Integrated
 // @Summary 设置系统集成配置
@@ -60,7 +80,12 @@ func (e *SystemIntegratedService) SetIntegratedConfig(
 	integrate gaia.SystemIntegration, code string, test bool) (err error) {
 	// classID是否在
 	var log gaia.SystemIntegration
-	if err = global.GVA_DB.Where("classify = ?", integrate.Classify).First(&log).Error; err != nil {
+	// Extend: multiple oauth2 providers 指定id时按id更新
+	db := global.GVA_DB.Where("classify = ?", integrate.Classify)
+	if integrate.Id > 0 {
+		db = db.Where("id = ?", integrate.Id)
+	}
+	if err = db.First(&log).Error; err != nil {
 		return err
 	}
 	// AppSecret
@@ -100,8 +125,7 @@ func (e *SystemIntegratedService) SetIntegratedConfig(
 		return err
 	}
 	// save
-	if err = global.GVA_DB.Model(&gaia.SystemIntegration{}).Where(
-		"id=?", log.Id).Updates(&map[string]interface{}{
+	values := map[string]interface{}{
 		"config":     integrate.Config,
 		"status":     integrate.Status,
 		"agent_id":   integrate.AgentID,
@@ -109,7 +133,21 @@ func (e *SystemIntegratedService) SetIntegratedConfig(
 		"app_secret": log.AppSecret,
 		"corp_id":    log.CorpID,
 		"app_id":     log.AppID,
-	}).Error; err != nil {
+	}
+	// Extend Start: multiple oauth2 providers
+	if integrate.Id > 0 {
+		values["name"] = integrate.Name
+		values["title"] = integrate.Title
+		values["authority_id"] = integrate.AuthorityId
+		values["email_domains"] = integrate.EmailDomains
+	}
+	// Extend Stop: multiple oauth2 providers
+	// Extend: DingTalk login 企业邮箱属于这些域名时才按邮箱关联已有账户
+	if integrate.Classify == gaia.SystemIntegrationDingTalk {
+		values["email_domains"] = strings.Join(integrate.Domains(), ",")
+	}
+	if err = global.GVA_DB.Model(&gaia.SystemIntegration{}).Where(
+		"id=?", log.Id).Updates(&values).Error; err != nil {
 		return err
 	}
 	return nil
@@ -161,6 +199,12 @@ func (e *SystemIntegratedService) TestConnection(integrate gaia.SystemIntegratio
 	case gaia.SystemIntegrationOAuth2:
 		// 测试OAuth2连接
 		return e.TestOAuth2Connection(integrate, code)
+	case gaia.SystemIntegrationSCIM:
+		// SCIM 由IdP主动推送, 只需要令牌
+		if len(integrate.AppSecret) == 0 {
+			return errors.New("SCIM令牌不能为空")
+		}
+		return nil
 	default:
 		return errors.New("不支持的集成类型")
 	}
@@ -176,74 +220,63 @@ func (e *SystemIntegratedService) TestConnection(integrate gaia.SystemIntegratio
 // @return: error
 func (e *SystemIntegratedService) TestOAuth2Connection(integrate gaia.SystemIntegration, code string) (err error) {
 	// 解析Config字段
-	var configMap request.SystemOAuth2Request
-	if err = json.Unmarshal([]byte(integrate.Config), &configMap); err != nil {
+	var config oidc.Config
+	if config, err = OIDCConfig(integrate); err != nil {
 		global.GVA_LOG.Error("解析OAuth2配置失败!", zap.Error(err))
 		return err
 	}
+	// 配置了发现地址的, 保存时即校验发现文档可用
+	var oauth2Service OAuth2Service
+	if err = oauth2Service.TestDiscovery(integrate); err != nil {
+		return err
+	}
 	// 没有code的（保存操作）
 	if len(code) == 0 {
 		return nil
 	}
 	// 检查必要字段
-	if configMap.ServerURL == "" || configMap.TokenURL == "" || integrate.AppID == "" || integrate.AppSecret == "" {
+	if (config.ServerURL == "" && config.DiscoveryURL == "") || integrate.AppID == "" || integrate.AppSecret == "" {
 		return errors.New("请填写完整的 OAuth2 配置信息")
 	}
-
-	// 合成请求byte
-	formData := url.Values{}
-	formData.Set("grant_type", "authorization_code")
-	formData.Set("code", code)
 	// redirect_uri 必须与授权时一致
-	formData.Set("redirect_uri", strings.TrimSpace(configMap.RedirectUri))
-	// 支持basic与post两种认证方式
-	// 默认使用client_secret_post，除非配置为basic
-	tokenAuthMethod := strings.ToLower(strings.TrimSpace(configMap.TokenAuthMethod))
-	useBasic := tokenAuthMethod == "client_secret_basic"
-	if !useBasic {
-		formData.Set("client_secret", integrate.AppSecret)
-		formData.Set("client_id", integrate.AppID)
-	}
-
-	// 发送请求
-	var req *http.Request
-	client := &http.Client{}
-	req, err = http.NewRequest("POST", fmt.Sprintf(
-		"%s%s", configMap.ServerURL, configMap.TokenURL), strings.NewReader(formData.Encode()))
-	if err != nil {
-		global.GVA_LOG.Error("创建测试请求失败", zap.Error(err))
-		return errors.New(fmt.Sprintf("创建测试请求失败: %s", err.Error()))
-	}
-
-	// 设置认证与Content-Type
-	if useBasic {
-		req.SetBasicAuth(integrate.AppID, integrate.AppSecret)
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	} else {
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	config.RedirectURI = strings.TrimSpace(config.RedirectURI)
+	var client *oidc.Client
+	if client, err = oidc.NewClient(context.Background(), config); err != nil {
+		return err
 	}
-
-	// 发送请求
-	resp, err := client.Do(req)
-	if err != nil {
+	var token oidc.Token
+	if token, err = client.Exchange(context.Background(), code, ""); err != nil {
 		global.GVA_LOG.Error("测试 OAuth2 连接失败", zap.Error(err))
-		return errors.New(fmt.Sprintf("连接 OAuth2 服务器失败: %s", err.Error()))
+		return fmt.Errorf("连接 OAuth2 服务器失败: %s", err.Error())
 	}
-	defer resp.Body.Close()
+	// 返回了id_token的同时校验签名
+	if len(token.IDToken) > 0 && len(client.Endpoints().JwksURI) > 0 {
+		if _, err = client.VerifyIDToken(context.Background(), token.IDToken, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		global.GVA_LOG.Error("测试 OAuth2 连接失败", zap.Int("status", resp.StatusCode))
-		return errors.New(fmt.Sprintf("OAuth2 服务器返回错误状态码: %d", resp.StatusCode))
+// SetScimConfig
+// @Tags System Integrated
+// @Summary 设置SCIM用户预配, 首次启用或轮换时生成新令牌
+// @param: req request.SystemScimRequest
+// @return: token string 新生成的令牌, 仅返回一次, err error
+func (e *SystemIntegratedService) SetScimConfig(req request.SystemScimRequest) (token string, err error) {
+	integrate := e.GetIntegratedConfig(gaia.SystemIntegrationSCIM)
+	if req.Rotate || len(integrate.AppSecret) == 0 {
+		token = oidc.RandomString(32)
+		integrate.AppSecret = token
 	}
-	var bodyByte []byte
-	if bodyByte, err = io.ReadAll(resp.Body); err != nil {
-		return fmt.Errorf("OAuth2 request io.ReadAll: %s", resp.Status)
+	var config []byte
+	if config, err = json.Marshal(&gaia.ScimConfig{Authorities: req.Authorities}); err != nil {
+		return "", err
 	}
-
-	var tokenMap request.SystemOAuth2Error
-	if err = json.Unmarshal(bodyByte, &tokenMap); err == nil && tokenMap.Code != 0 {
-		return fmt.Errorf("OAuth2 Eroor: %s", tokenMap.Info)
+	integrate.Config = string(config)
+	integrate.Status = req.Status
+	if err = e.SetIntegratedConfig(integrate, "", false); err != nil {
+		return "", err
 	}
-
-	return nil
+	return token, nil
 }
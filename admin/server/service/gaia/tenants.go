@@ -1,27 +1,65 @@
 package gaia
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
 	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	gaiaRes "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/response"
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
 )
 
 type TenantsService struct{}
 
+// defaultRmbExchangeRate 未配置 gaia.rmb_exchange_rate 时 RMB 折算为美元的汇率
+const defaultRmbExchangeRate = 7.26
+
+// usdCost 将 RMB 计价的花费折算为美元的 SQL 表达式, 花费统计统一使用
+func usdCost(currency, price string) string {
+	rate := global.GVA_CONFIG.Gaia.RmbExchangeRate
+	if rate <= 0 {
+		rate = defaultRmbExchangeRate
+	}
+	return fmt.Sprintf("CASE WHEN %s = 'RMB' THEN %s / %s ELSE %s END", currency, price, strconv.FormatFloat(rate, 'f', -1, 64), price)
+}
+
 // GetTenants 根据id获取tenants表记录
-func (tenantsService *TenantsService) GetTenants(id string) (tenants gaia.Tenants, err error) {
+func (tenantsService *TenantsService) GetTenants(id string, scope gaia.TenantScope) (tenants gaia.Tenants, err error) {
+	// Extend: 数据范围
+	if err = scope.Check(id); err != nil {
+		return
+	}
 	err = global.GVA_DB.Where("id = ?", id).First(&tenants).Error
 	return
 }
 
 // GetTenantsInfoList 分页获取tenants表记录
-func (tenantsService *TenantsService) GetTenantsInfoList(info gaiaReq.TenantsSearch) (list []gaia.Tenants, total int64, err error) {
+func (tenantsService *TenantsService) GetTenantsInfoList(info gaiaReq.TenantsSearch, scope gaia.TenantScope) (list []gaiaRes.TenantInfo, total int64, err error) {
 	limit := info.PageSize
 	offset := info.PageSize * (info.Page - 1)
 	// 创建db
 	db := global.GVA_DB.Model(&gaia.Tenants{})
+	db = scope.Where(db, "id") // Extend: 数据范围
 	var tenantss []gaia.Tenants
 	// 如果有条件搜索 下方会自动创建搜索语句
+	// Extend Start: workspace management
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.Plan != "" {
+		db = db.Where("plan = ?", info.Plan)
+	}
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	// Extend Stop: workspace management
 	err = db.Count(&total).Error
 	if err != nil {
 		return
@@ -32,11 +70,422 @@ func (tenantsService *TenantsService) GetTenantsInfoList(info gaiaReq.TenantsSea
 	}
 
 	err = db.Find(&tenantss).Error
-	return tenantss, total, err
+	if err != nil {
+		return
+	}
+	// Extend Start: workspace management
+	ids := make([]string, 0, len(tenantss))
+	for _, tenant := range tenantss {
+		ids = append(ids, tenant.Id)
+	}
+	usages, err := tenantsService.getTenantUsages(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	owners, err := tenantsService.getTenantOwners(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, tenant := range tenantss {
+		owner := owners[tenant.Id]
+		list = append(list, gaiaRes.TenantInfo{
+			Tenants:     tenant,
+			TenantUsage: usages[tenant.Id],
+			OwnerName:   owner.Name,
+			OwnerEmail:  owner.Email,
+		})
+	}
+	// Extend Stop: workspace management
+	return list, total, err
 }
 
 // GetAllTenants 获取所有工作区
-func (tenantsService *TenantsService) GetAllTenants() (tenants []gaia.Tenants, err error) {
-	err = global.GVA_DB.Find(&tenants).Error
+func (tenantsService *TenantsService) GetAllTenants(scope gaia.TenantScope) (tenants []gaia.Tenants, err error) {
+	err = scope.Where(global.GVA_DB, "id").Find(&tenants).Error // Extend: 数据范围
 	return
 }
+
+// Extend Start: workspace management
+
+// UpdateTenant 修改工作空间名称、套餐与状态
+func (tenantsService *TenantsService) UpdateTenant(req gaiaReq.TenantUpdateReq, scope gaia.TenantScope) (err error) {
+	if _, err = tenantsService.GetTenants(req.ID, scope); errors.Is(err, gaia.ErrTenantOutOfScope) {
+		return err
+	} else if err != nil {
+		return errors.New("工作空间不存在")
+	}
+	values := map[string]interface{}{}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		values["name"] = name
+	}
+	if req.Plan != "" {
+		if !slices.Contains(gaia.TenantPlans, req.Plan) {
+			return fmt.Errorf("不支持的套餐: %s", req.Plan)
+		}
+		values["plan"] = req.Plan
+	}
+	if req.Status != "" {
+		if req.Status != gaia.TenantStatusNormal && req.Status != gaia.TenantStatusArchive {
+			return fmt.Errorf("不支持的状态: %s", req.Status)
+		}
+		if req.Status == gaia.TenantStatusArchive && req.ID == new(gaia.Tenants).GetSuperAdminTenantId() {
+			return errors.New("不能归档系统默认工作空间")
+		}
+		values["status"] = req.Status
+	}
+	if len(values) == 0 {
+		return errors.New("没有需要修改的内容")
+	}
+	values["updated_at"] = time.Now()
+	return global.GVA_DB.Model(&gaia.Tenants{}).Where("id = ?", req.ID).Updates(values).Error
+}
+
+// GetTenantMembers 获取工作空间成员及其角色
+func (tenantsService *TenantsService) GetTenantMembers(tenantID string, scope gaia.TenantScope) (list []gaiaRes.TenantMember, err error) {
+	if err = scope.Check(tenantID); err != nil {
+		return
+	}
+	err = global.GVA_DB.Table("tenant_account_joins AS j").
+		Select("j.account_id, a.name, a.email, a.status, j.role, j.current, j.created_at").
+		Joins("JOIN accounts AS a ON a.id = j.account_id").
+		Where("j.tenant_id = ?", tenantID).
+		Order("CASE j.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, j.created_at").
+		Scan(&list).Error
+	return list, err
+}
+
+// AddTenantMember 通过邮箱添加工作空间成员
+func (tenantsService *TenantsService) AddTenantMember(req gaiaReq.TenantMemberReq, operatorID uint, operator string, scope gaia.TenantScope) (err error) {
+	tenant, err := tenantsService.GetTenants(req.TenantID, scope)
+	if errors.Is(err, gaia.ErrTenantOutOfScope) {
+		return err
+	} else if err != nil {
+		return errors.New("工作空间不存在")
+	}
+	if req.Role == "" {
+		req.Role = gaia.TenantRoleNormal
+	}
+	if !slices.Contains(gaia.TenantMemberRoles, req.Role) {
+		return fmt.Errorf("不支持的角色: %s", req.Role)
+	}
+	var account gaia.Account
+	if err = global.GVA_DB.Where("email = ?", strings.TrimSpace(req.Email)).First(&account).Error; err != nil {
+		return errors.New("账户不存在")
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		added, err := tenantsService.addMember(tx, tenant.Id, account.ID, req.Role, gaia.TenantMemberAudit{
+			Source: gaia.TenantAuditSourceAdmin, OperatorID: operatorID, Operator: operator,
+		})
+		if err == nil && !added {
+			err = errors.New("该账户已是工作空间成员")
+		}
+		return err
+	})
+}
+
+// RemoveTenantMember 移除工作空间成员, 所有者需先转移所有权
+func (tenantsService *TenantsService) RemoveTenantMember(req gaiaReq.TenantAccountReq, operatorID uint, operator string, scope gaia.TenantScope) (err error) {
+	if err = scope.Check(req.TenantID); err != nil {
+		return
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var join gaia.TenantAccountJoins
+		if err := tx.Where("tenant_id = ? AND account_id = ?", req.TenantID, req.AccountID).First(&join).Error; err != nil {
+			return errors.New("该账户不是工作空间成员")
+		}
+		if join.Role == gaia.TenantRoleOwner {
+			return errors.New("不能移除工作空间所有者, 请先转移所有权")
+		}
+		if err := tx.Delete(&gaia.TenantAccountJoins{}, "id = ?", join.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&gaia.TenantMemberAudit{
+			TenantID: req.TenantID, AccountID: req.AccountID, Action: gaia.TenantAuditRemove, OldRole: join.Role,
+			Source: gaia.TenantAuditSourceAdmin, OperatorID: operatorID, Operator: operator,
+		}).Error; err != nil {
+			return err
+		}
+		if !join.Current {
+			return nil
+		}
+		// 被移除的是当前工作空间时, 切换到该账户最早加入的工作空间
+		var next gaia.TenantAccountJoins
+		if err := tx.Where("account_id = ?", join.AccountID).Order("created_at").First(&next).Error; err != nil {
+			return nil
+		}
+		return tx.Model(&gaia.TenantAccountJoins{}).Where("id = ?", next.ID).Update("current", true).Error
+	})
+}
+
+// TransferTenantOwner 转移工作空间所有权, 原所有者降为管理员
+func (tenantsService *TenantsService) TransferTenantOwner(req gaiaReq.TenantAccountReq, operatorID uint, operator string, scope gaia.TenantScope) (err error) {
+	if err = scope.Check(req.TenantID); err != nil {
+		return
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var join gaia.TenantAccountJoins
+		if err := tx.Where("tenant_id = ? AND account_id = ?", req.TenantID, req.AccountID).First(&join).Error; err != nil {
+			return errors.New("新所有者必须是工作空间成员")
+		}
+		if join.Role == gaia.TenantRoleOwner {
+			return errors.New("该账户已是工作空间所有者")
+		}
+		var owners []gaia.TenantAccountJoins
+		if err := tx.Where("tenant_id = ? AND role = ?", req.TenantID, gaia.TenantRoleOwner).Find(&owners).Error; err != nil {
+			return err
+		}
+		template := gaia.TenantMemberAudit{
+			TenantID: req.TenantID, Action: gaia.TenantAuditTransfer,
+			Source: gaia.TenantAuditSourceAdmin, OperatorID: operatorID, Operator: operator,
+		}
+		for _, owner := range owners {
+			if err := tenantsService.setRole(tx, owner, gaia.TenantRoleAdmin, template); err != nil {
+				return err
+			}
+		}
+		return tenantsService.setRole(tx, join, gaia.TenantRoleOwner, template)
+	})
+}
+
+// GetAccountTenants 获取账户加入的工作空间及角色
+func (tenantsService *TenantsService) GetAccountTenants(accountID string, scope gaia.TenantScope) (list []gaiaRes.AccountTenant, err error) {
+	err = scope.Where(global.GVA_DB, "j.tenant_id").Table("tenant_account_joins AS j").
+		Select("j.tenant_id, t.name AS tenant_name, t.plan, t.status, j.role, j.current, j.created_at").
+		Joins("JOIN tenants AS t ON t.id = j.tenant_id").
+		Where("j.account_id = ?", accountID).
+		Order("j.created_at").
+		Scan(&list).Error
+	return list, err
+}
+
+// UpdateMemberRoles 批量修改成员角色, 变更后每个涉及的工作空间必须有且只有一个所有者
+func (tenantsService *TenantsService) UpdateMemberRoles(req gaiaReq.TenantRoleUpdateReq, operatorID uint, operator string, scope gaia.TenantScope) (err error) {
+	if len(req.Items) == 0 {
+		return errors.New("没有需要修改的成员")
+	}
+	for _, item := range req.Items {
+		if err = scope.Check(item.TenantID); err != nil {
+			return
+		}
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		tenantIDs := make([]string, 0, len(req.Items))
+		for _, item := range req.Items {
+			if item.Role != gaia.TenantRoleOwner && !slices.Contains(gaia.TenantMemberRoles, item.Role) {
+				return fmt.Errorf("不支持的角色: %s", item.Role)
+			}
+			var join gaia.TenantAccountJoins
+			if err := tx.Where("tenant_id = ? AND account_id = ?", item.TenantID, item.AccountID).First(&join).Error; err != nil {
+				return fmt.Errorf("账户 %s 不是工作空间 %s 的成员", item.AccountID, item.TenantID)
+			}
+			if err := tenantsService.setRole(tx, join, item.Role, gaia.TenantMemberAudit{
+				TenantID: item.TenantID, Action: gaia.TenantAuditRole, Reason: req.Reason,
+				Source: gaia.TenantAuditSourceAdmin, OperatorID: operatorID, Operator: operator,
+			}); err != nil {
+				return err
+			}
+			if !slices.Contains(tenantIDs, item.TenantID) {
+				tenantIDs = append(tenantIDs, item.TenantID)
+			}
+		}
+		for _, tenantID := range tenantIDs {
+			var owners int64
+			if err := tx.Model(&gaia.TenantAccountJoins{}).Where("tenant_id = ? AND role = ?", tenantID, gaia.TenantRoleOwner).
+				Count(&owners).Error; err != nil {
+				return err
+			}
+			if owners != 1 {
+				return fmt.Errorf("工作空间 %s 修改后必须有且只有一个所有者", tenantID)
+			}
+		}
+		return nil
+	})
+}
+
+// GetMemberAuditList 分页获取成员变更审计
+func (tenantsService *TenantsService) GetMemberAuditList(info gaiaReq.TenantMemberAuditSearch, scope gaia.TenantScope) (list []gaia.TenantMemberAudit, total int64, err error) {
+	db := global.GVA_DB.Model(&gaia.TenantMemberAudit{})
+	db = scope.Where(db, "tenant_id")
+	if info.TenantID != "" {
+		db = db.Where("tenant_id = ?", info.TenantID)
+	}
+	if info.AccountID != "" {
+		db = db.Where("account_id = ?", info.AccountID)
+	}
+	if info.Action != "" {
+		db = db.Where("action = ?", info.Action)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// ApplyDefaultTenants 按默认工作空间策略将账户加入配置的工作空间, 已加入的保持原角色
+func (tenantsService *TenantsService) ApplyDefaultTenants(accountID uuid.UUID) (err error) {
+	policy := global.GVA_CONFIG.Gaia
+	if len(policy.DefaultTenantIds) == 0 {
+		return nil
+	}
+	role := policy.DefaultTenantRole
+	if role == "" {
+		role = gaia.TenantRoleNormal
+	}
+	if !slices.Contains(gaia.TenantMemberRoles, role) {
+		return fmt.Errorf("默认工作空间角色配置错误: %s", role)
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		for _, tenantID := range policy.DefaultTenantIds {
+			if _, err := uuid.FromString(tenantID); err != nil {
+				return fmt.Errorf("默认工作空间ID配置错误: %s", tenantID)
+			}
+			var count int64
+			tx.Model(&gaia.Tenants{}).Where("id = ? AND status = ?", tenantID, gaia.TenantStatusNormal).Count(&count)
+			if count == 0 {
+				continue
+			}
+			if _, err := tenantsService.addMember(tx, tenantID, accountID, role, gaia.TenantMemberAudit{
+				Source: gaia.TenantAuditSourceSync, Operator: "SyncUser",
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// EnsureMember 账户不是工作空间成员时以指定角色加入, 用于离职交接等需要在同一事务内完成的场景
+func (tenantsService *TenantsService) EnsureMember(tx *gorm.DB, tenantID string, accountID uuid.UUID, role string, audit gaia.TenantMemberAudit) error {
+	_, err := tenantsService.addMember(tx, tenantID, accountID, role, audit)
+	return err
+}
+
+// HandoverTenant 将工作空间所有权从 from 转移给 to, to 未加入时先以管理员加入, 原所有者降为管理员
+func (tenantsService *TenantsService) HandoverTenant(tx *gorm.DB, tenantID string, from, to uuid.UUID, audit gaia.TenantMemberAudit) error {
+	if err := tenantsService.EnsureMember(tx, tenantID, to, gaia.TenantRoleAdmin, audit); err != nil {
+		return err
+	}
+	var joins []gaia.TenantAccountJoins
+	if err := tx.Where("tenant_id = ? AND account_id IN ?", tenantID, []uuid.UUID{from, to}).Find(&joins).Error; err != nil {
+		return err
+	}
+	audit.Action = gaia.TenantAuditTransfer
+	for _, join := range joins {
+		role := gaia.TenantRoleOwner
+		if join.AccountID == from {
+			role = gaia.TenantRoleAdmin
+		}
+		if err := tenantsService.setRole(tx, join, role, audit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMember 加入工作空间并记录审计, 已是成员时返回 false
+func (tenantsService *TenantsService) addMember(tx *gorm.DB, tenantID string, accountID uuid.UUID, role string, audit gaia.TenantMemberAudit) (added bool, err error) {
+	var count int64
+	tx.Model(&gaia.TenantAccountJoins{}).Where("tenant_id = ? AND account_id = ?", tenantID, accountID).Count(&count)
+	if count > 0 {
+		return false, nil
+	}
+	// 账户还没有任何工作空间时, 将其设为当前工作空间
+	tx.Model(&gaia.TenantAccountJoins{}).Where("account_id = ?", accountID).Count(&count)
+	if err = tx.Create(&gaia.TenantAccountJoins{
+		ID:        uuid.Must(uuid.NewV4()),
+		TenantID:  uuid.FromStringOrNil(tenantID),
+		AccountID: accountID,
+		Role:      role,
+		Current:   count == 0,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}).Error; err != nil {
+		return false, err
+	}
+	audit.TenantID, audit.AccountID, audit.Action, audit.NewRole = tenantID, accountID.String(), gaia.TenantAuditAdd, role
+	return true, tx.Create(&audit).Error
+}
+
+// setRole 修改成员角色并记录审计, 角色未变化时不记录
+func (tenantsService *TenantsService) setRole(tx *gorm.DB, join gaia.TenantAccountJoins, role string, audit gaia.TenantMemberAudit) error {
+	if join.Role == role {
+		return nil
+	}
+	if err := tx.Model(&gaia.TenantAccountJoins{}).Where("id = ?", join.ID).
+		Updates(map[string]interface{}{"role": role, "updated_at": time.Now()}).Error; err != nil {
+		return err
+	}
+	audit.TenantID, audit.AccountID, audit.OldRole, audit.NewRole = join.TenantID.String(), join.AccountID.String(), join.Role, role
+	return tx.Create(&audit).Error
+}
+
+// getTenantUsages 统计工作空间的成员数、应用数、消息数与花费
+func (tenantsService *TenantsService) getTenantUsages(ids []string) (usages map[string]gaiaRes.TenantUsage, err error) {
+	usages = make(map[string]gaiaRes.TenantUsage, len(ids))
+	if len(ids) == 0 {
+		return usages, nil
+	}
+	type row struct {
+		TenantID string  `gorm:"column:tenant_id"`
+		Num      int64   `gorm:"column:num"`
+		Cost     float64 `gorm:"column:cost"`
+	}
+	var members, apps, messages []row
+	if err = global.GVA_DB.Model(&gaia.TenantAccountJoins{}).Select("tenant_id, COUNT(id) AS num").
+		Where("tenant_id IN ?", ids).Group("tenant_id").Scan(&members).Error; err != nil {
+		return nil, fmt.Errorf("统计工作空间成员失败：%w", err)
+	}
+	if err = global.GVA_DB.Model(&gaia.Apps{}).Select("tenant_id, COUNT(id) AS num").
+		Where("tenant_id IN ?", ids).Group("tenant_id").Scan(&apps).Error; err != nil {
+		return nil, fmt.Errorf("统计工作空间应用失败：%w", err)
+	}
+	if err = global.GVA_DB.Table("messages AS m").
+		Select("a.tenant_id, COUNT(m.id) AS num, "+
+			"COALESCE(SUM("+usdCost("m.currency", "m.total_price")+"), 0) AS cost").
+		Joins("JOIN apps AS a ON a.id = m.app_id").
+		Where("a.tenant_id IN ?", ids).Group("a.tenant_id").Scan(&messages).Error; err != nil {
+		return nil, fmt.Errorf("统计工作空间消息失败：%w", err)
+	}
+	for _, r := range members {
+		usage := usages[r.TenantID]
+		usage.MemberCount = r.Num
+		usages[r.TenantID] = usage
+	}
+	for _, r := range apps {
+		usage := usages[r.TenantID]
+		usage.AppCount = r.Num
+		usages[r.TenantID] = usage
+	}
+	for _, r := range messages {
+		usage := usages[r.TenantID]
+		usage.MessageCount = r.Num
+		usage.TotalCost = r.Cost
+		usages[r.TenantID] = usage
+	}
+	return usages, nil
+}
+
+// getTenantOwners 获取工作空间所有者
+func (tenantsService *TenantsService) getTenantOwners(ids []string) (owners map[string]gaia.Account, err error) {
+	owners = make(map[string]gaia.Account, len(ids))
+	if len(ids) == 0 {
+		return owners, nil
+	}
+	var rows []struct {
+		TenantID string `gorm:"column:tenant_id"`
+		Name     string `gorm:"column:name"`
+		Email    string `gorm:"column:email"`
+	}
+	if err = global.GVA_DB.Table("tenant_account_joins AS j").Select("j.tenant_id, a.name, a.email").
+		Joins("JOIN accounts AS a ON a.id = j.account_id").
+		Where("j.tenant_id IN ? AND j.role = ?", ids, gaia.TenantRoleOwner).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询工作空间所有者失败：%w", err)
+	}
+	for _, r := range rows {
+		owners[r.TenantID] = gaia.Account{Name: r.Name, Email: r.Email}
+	}
+	return owners, nil
+}
+
+// Extend Stop: workspace management
@@ -447,7 +447,7 @@ func (e *TestService) AppRequestTest() (err error) {
 // @Produce application/json
 // @Param info request.PageInfo
 // @Return list []response.GetQuotaManagementDataResponse, total int64, err error
-func (e *TestService) AppRequestTestList(info request.GetAppRequestTestRequest) (
+func (e *TestService) AppRequestTestList(info request.GetAppRequestTestRequest, scope gaia.TenantScope) (
 	_ bool, list []response.GetAppRequestTestDataResponse, total int64, err error) {
 	if info.PageSize == 0 {
 		info.PageSize = 10
@@ -459,6 +459,7 @@ func (e *TestService) AppRequestTestList(info request.GetAppRequestTestRequest)
 	if len(info.Apps) > 0 {
 		db.Where("app_id IN (?)", info.Apps)
 	}
+	db = scopeApps(db, scope, "app_id") // Extend: 数据范围
 	// 是否筛选状态
 	switch info.Status {
 	case request.GetAppRequestFilterSuccess:
@@ -537,9 +538,9 @@ func (e *TestService) AppRequestTestList(info request.GetAppRequestTestRequest)
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
-// @Param info request.PageInfo
+// @Param info request.PageInfo, scope gaia.TenantScope
 // @Return list []response.GetQuotaManagementDataResponse, total int64, err error
-func (e *TestService) AppRequestTestBatch(info request.GetAppRequestTestRequest) (
+func (e *TestService) AppRequestTestBatch(info request.GetAppRequestTestRequest, scope gaia.TenantScope) (
 	_ bool, list []gaia.AppRequestTestBatch, total int64, err error) {
 	// init
 	if info.PageSize == 0 {
@@ -548,6 +549,13 @@ func (e *TestService) AppRequestTestBatch(info request.GetAppRequestTestRequest)
 	limit := info.PageSize
 	offset := info.PageSize * (info.Page - 1)
 	db := global.GVA_DB.Model(&gaia.AppRequestTestBatch{})
+	// Extend Start: 数据范围
+	// 只返回包含范围内应用测试结果的批次
+	if !scope.All {
+		tests := scopeApps(global.GVA_DB.Model(&gaia.AppRequestTest{}).Select("batch_id"), scope, "app_id")
+		db = db.Where("id IN (?)", tests)
+	}
+	// Extend Stop: 数据范围
 
 	err = db.Count(&total).Error
 	if err != nil {
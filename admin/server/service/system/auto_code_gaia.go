@@ -0,0 +1,447 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	model "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	utilsAst "github.com/flipped-aurora/gin-vue-admin/server/utils/ast"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var AutoCodeGaia = new(autoCodeGaia)
+
+// autoCodeGaia 按 gaia(Dify) 库中已存在的表生成代码, 表结构由 Dify 迁移维护, 这里只读取不建表
+type autoCodeGaia struct{}
+
+// gaiaColumn information_schema 中的字段信息
+type gaiaColumn struct {
+	ColumnName        string  `gorm:"column:column_name"`
+	UdtName           string  `gorm:"column:udt_name"`
+	IsNullable        string  `gorm:"column:is_nullable"`
+	ColumnDefault     *string `gorm:"column:column_default"`
+	CharacterLength   *int    `gorm:"column:character_maximum_length"`
+	NumericPrecision  *int    `gorm:"column:numeric_precision"`
+	NumericScale      *int    `gorm:"column:numeric_scale"`
+	DatetimePrecision *int    `gorm:"column:datetime_precision"`
+	Comment           *string `gorm:"column:comment"`
+	PrimaryKey        bool    `gorm:"column:primary_key"`
+}
+
+// gaiaTemplates 模板与生成文件的对应关系, 生成文件相对 server 目录
+var gaiaTemplates = map[string][]string{
+	"model/model.go.tpl":           {"model", "gaia"},
+	"model/request/request.go.tpl": {"model", "gaia", "request"},
+	"service/service.go.tpl":       {"service", "gaia"},
+	"api/api.go.tpl":               {"api", "v1", "gaia"},
+	"router/router.go.tpl":         {"router", "gaia"},
+}
+
+// Columns
+// @function: Columns
+// @description: 读取 gaia 表结构并推导字段, 仅支持 pgsql
+// @param: ctx context.Context, info *request.AutoCodeGaia
+// @return: err error
+func (s *autoCodeGaia) Columns(ctx context.Context, info *request.AutoCodeGaia) (err error) {
+	if global.GVA_CONFIG.System.DbType != "pgsql" {
+		return errors.New("gaia 代码生成仅支持 pgsql")
+	}
+	db := global.GVA_DB.WithContext(ctx)
+	var columns []gaiaColumn
+	err = db.Raw(`
+SELECT c.column_name, c.udt_name, c.is_nullable, c.column_default,
+       c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.datetime_precision,
+       col_description(format('%I.%I', c.table_schema, c.table_name)::regclass, c.ordinal_position) AS comment,
+       EXISTS (
+           SELECT 1
+           FROM information_schema.table_constraints tc
+                    JOIN information_schema.key_column_usage kcu
+                         ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema
+           WHERE tc.constraint_type = 'PRIMARY KEY'
+             AND tc.table_schema = c.table_schema
+             AND tc.table_name = c.table_name
+             AND kcu.column_name = c.column_name
+       ) AS primary_key
+FROM information_schema.columns c
+WHERE c.table_schema = 'public' AND c.table_name = ?
+ORDER BY c.ordinal_position`, info.TableName).Scan(&columns).Error
+	if err != nil {
+		return errors.Wrap(err, "读取表结构失败!")
+	}
+	var comment sql.NullString
+	err = db.Raw(`SELECT obj_description(format('public.%I', ?::text)::regclass, 'pg_class')`, info.TableName).Row().Scan(&comment)
+	if err == nil {
+		info.TableComment = gaiaComment(comment.String)
+	}
+	info.Fields = make([]*request.AutoCodeGaiaField, 0, len(columns))
+	for _, column := range columns {
+		info.Fields = append(info.Fields, gaiaField(column))
+	}
+	return nil
+}
+
+// gaiaField 按 pg 类型推导 Go 类型, 与手写的 gaia 模型保持一致
+func gaiaField(column gaiaColumn) *request.AutoCodeGaiaField {
+	field := &request.AutoCodeGaiaField{
+		FieldName:  request.GaiaCamelName(column.ColumnName),
+		ColumnName: column.ColumnName,
+		ColumnType: column.UdtName,
+		NotNull:    column.IsNullable == "NO",
+		PrimaryKey: column.PrimaryKey,
+	}
+	if column.Comment != nil {
+		field.Comment = gaiaComment(*column.Comment)
+	}
+	nullable := !field.NotNull && !field.PrimaryKey
+	switch column.UdtName {
+	case "uuid":
+		field.FieldType = "uuid.UUID"
+		if nullable {
+			field.FieldType = "*uuid.UUID"
+		}
+	case "int2":
+		field.FieldType = "int16"
+	case "int4":
+		field.FieldType = "int"
+	case "int8":
+		field.FieldType = "int64"
+	case "float4":
+		field.FieldType = "float32"
+	case "float8":
+		field.FieldType = "float64"
+	case "numeric":
+		field.FieldType = "float64"
+		if column.NumericPrecision != nil && column.NumericScale != nil {
+			field.ColumnType = fmt.Sprintf("numeric(%d,%d)", *column.NumericPrecision, *column.NumericScale)
+		}
+	case "bool":
+		field.FieldType = "bool"
+	case "varchar", "bpchar":
+		field.FieldType = "string"
+		if column.CharacterLength != nil {
+			field.ColumnType = fmt.Sprintf("%s(%d)", column.UdtName, *column.CharacterLength)
+		}
+		if column.UdtName == "bpchar" {
+			field.ColumnType = strings.Replace(field.ColumnType, "bpchar", "char", 1)
+		}
+	case "timestamp", "timestamptz", "date":
+		field.FieldType = "time.Time"
+		if nullable {
+			field.FieldType = "*time.Time"
+		}
+		if column.UdtName != "date" && column.DatetimePrecision != nil {
+			field.ColumnType = fmt.Sprintf("%s(%d)", column.UdtName, *column.DatetimePrecision)
+		}
+	case "json", "jsonb":
+		field.FieldType = "json.RawMessage"
+	case "bytea":
+		field.FieldType = "[]byte"
+	default:
+		field.FieldType = "string" // text 与枚举等按字符串处理
+	}
+	if column.ColumnDefault != nil {
+		def := *column.ColumnDefault
+		switch {
+		case strings.HasPrefix(def, "nextval("):
+			field.AutoInc = true
+		case strings.ContainsAny(def, ";\"`\n"):
+			// 无法写入 gorm 标签的默认值, 由数据库处理
+		default:
+			field.Default = def
+		}
+	}
+	return field
+}
+
+// gaiaComment 注释写入单行注释, 去掉换行
+func gaiaComment(comment string) string {
+	return strings.Join(strings.Fields(comment), " ")
+}
+
+// Preview
+// @function: Preview
+// @description: 预览 gaia 表生成的代码
+// @param: ctx context.Context, info request.AutoCodeGaia
+// @return: map[string]string, error
+func (s *autoCodeGaia) Preview(ctx context.Context, info request.AutoCodeGaia) (map[string]string, error) {
+	err := s.Columns(ctx, &info)
+	if err != nil {
+		return nil, err
+	}
+	codes, _, _, err := s.generate(&info)
+	if err != nil {
+		return nil, err
+	}
+	preview := make(map[string]string, len(codes))
+	for key, code := range codes {
+		if len(key) > len(global.GVA_CONFIG.AutoCode.Root) {
+			key, _ = filepath.Rel(global.GVA_CONFIG.AutoCode.Root, key)
+		}
+		preview[key] = "```go\n\n" + code + "\n\n```"
+	}
+	return preview, nil
+}
+
+// Create
+// @function: Create
+// @description: 生成 gaia 表的代码并注入, 可选登记api并授权给当前角色, 任一步骤失败时回滚已写入的文件、注入与api
+// @param: ctx context.Context, info request.AutoCodeGaia, authorityID uint
+// @return: err error
+func (s *autoCodeGaia) Create(ctx context.Context, info request.AutoCodeGaia, authorityID uint) (err error) {
+	err = s.Columns(ctx, &info)
+	if err != nil {
+		return err
+	}
+	codes, templates, injections, err := s.generate(&info)
+	if err != nil {
+		return err
+	}
+	if AutocodeHistory.Repeat("", info.StructName, request.AutoCodeGaiaPackage) {
+		return errors.New("已经创建过此数据结构,请勿重复创建!")
+	}
+	for _, create := range templates {
+		if _, err = os.Stat(create); err == nil {
+			return errors.Errorf("[filepath:%s]文件已存在!", create)
+		}
+	}
+	// 注入的文件已存在, 先保存原内容, 失败时恢复; 新生成的文件失败时删除, 避免留下无法编译的代码
+	originals := make(map[string][]byte, len(injections))
+	for key := range codes {
+		if data, e := os.ReadFile(key); e == nil {
+			originals[key] = data
+		}
+	}
+	defer func() {
+		if err != nil {
+			s.rollback(codes, originals)
+		}
+	}()
+	for key, code := range codes {
+		err = os.MkdirAll(filepath.Dir(key), os.ModePerm)
+		if err != nil {
+			return errors.Wrapf(err, "[filepath:%s]创建文件夹失败!", key)
+		}
+		err = os.WriteFile(key, []byte(code), 0666)
+		if err != nil {
+			return errors.Wrapf(err, "[filepath:%s]写入文件失败!", key)
+		}
+	}
+
+	history := info.History()
+	if info.AutoCreateApiToSql {
+		var created []uint
+		var rules [][]string
+		history.ApiIDs, created, rules, err = s.createApis(ctx, info.Apis(), authorityID)
+		defer func() {
+			if err != nil {
+				s.removeApis(ctx, created, rules)
+			}
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	history.Templates = templates
+	history.Injections = make(map[string]string, len(injections))
+	for key, value := range injections {
+		bytes, _ := json.Marshal(value)
+		history.Injections[key] = string(bytes)
+	}
+	return AutocodeHistory.Create(ctx, history)
+}
+
+// createApis 登记api并授权给当前角色, 已存在的api直接复用, 返回全部api、本次新建的api与新增的策略
+func (s *autoCodeGaia) createApis(ctx context.Context, apis []model.SysApi, authorityID uint) (ids, created []uint, rules [][]string, err error) {
+	sub := fmt.Sprint(authorityID)
+	err = global.GVA_DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, v := range apis {
+			var api model.SysApi
+			err := tx.Where("path = ? AND method = ?", v.Path, v.Method).First(&api).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err = tx.Create(&v).Error; err != nil {
+					return err
+				}
+				api = v
+				created = append(created, v.ID)
+			} else if err != nil {
+				return err
+			}
+			ids = append(ids, api.ID)
+			var count int64
+			err = tx.Table("casbin_rule").Where("ptype = 'p' AND v0 = ? AND v1 = ? AND v2 = ?", sub, api.Path, api.Method).Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				rules = append(rules, []string{sub, api.Path, api.Method})
+			}
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		return CasbinServiceApp.AddPolicies(tx, rules)
+	})
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "登记api失败!")
+	}
+	return ids, created, rules, CasbinServiceApp.FreshCasbin()
+}
+
+// removeApis 删除本次新建的api与新增的策略
+func (s *autoCodeGaia) removeApis(ctx context.Context, created []uint, rules [][]string) {
+	if len(created) == 0 && len(rules) == 0 {
+		return
+	}
+	err := global.GVA_DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(created) > 0 {
+			if err := tx.Unscoped().Delete(&model.SysApi{}, "id IN ?", created).Error; err != nil {
+				return err
+			}
+		}
+		for _, rule := range rules {
+			if err := tx.Where("ptype = 'p' AND v0 = ? AND v1 = ? AND v2 = ?", rule[0], rule[1], rule[2]).
+				Delete(&gormadapter.CasbinRule{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = CasbinServiceApp.FreshCasbin()
+	}
+	if err != nil {
+		global.GVA_LOG.Error("回滚登记的api失败!", zap.Error(err))
+	}
+}
+
+// rollback 恢复注入前的文件并删除新生成的文件
+func (s *autoCodeGaia) rollback(codes map[string]string, originals map[string][]byte) {
+	for key := range codes {
+		var err error
+		if data, ok := originals[key]; ok {
+			err = os.WriteFile(key, data, 0666)
+		} else {
+			err = os.Remove(key)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			global.GVA_LOG.Error("回滚生成的代码失败!", zap.String("path", key), zap.Error(err))
+		}
+	}
+}
+
+// generate 渲染模板并注入 enter.go 与 router_biz.go, 字段需已由 Columns 填充, 返回文件内容、模板对应的生成文件与注入记录
+func (s *autoCodeGaia) generate(info *request.AutoCodeGaia) (map[string]string, map[string]string, map[string]utilsAst.Ast, error) {
+	err := info.Pretreatment()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	server := filepath.Join(global.GVA_CONFIG.AutoCode.Root, global.GVA_CONFIG.AutoCode.Server)
+	codes := make(map[string]string, len(gaiaTemplates)+4)
+	templates := make(map[string]string, len(gaiaTemplates))
+	for tpl, dirs := range gaiaTemplates {
+		key := filepath.Join(server, "resource", request.AutoCodeGaiaPackage, "server", tpl)
+		dir := filepath.Join(append([]string{server}, dirs...)...)
+		create := filepath.Join(dir, info.HumpPackageName+".go")
+		// 文件名已在 Pretreatment 中校验, 这里再确认生成的文件不会离开模板对应的目录
+		if filepath.Dir(create) != dir {
+			return nil, nil, nil, errors.Errorf("go文件名称不合法: %s", info.HumpPackageName)
+		}
+		var files *template.Template
+		files, err = template.ParseFiles(key)
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "[filpath:%s]读取模版文件失败!", key)
+		}
+		var builder strings.Builder
+		err = files.Execute(&builder, info)
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "[filpath:%s]生成文件失败!", create)
+		}
+		var source []byte
+		source, err = format.Source([]byte(builder.String()))
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "[filpath:%s]格式化失败!", create)
+		}
+		codes[create] = string(source)
+		templates[key] = create
+	} // 生成文件
+
+	asts := []utilsAst.Ast{
+		&utilsAst.PackageModuleEnter{
+			Type:        utilsAst.TypePackageApiModuleEnter,
+			Path:        filepath.Join(server, "api", "v1", request.AutoCodeGaiaPackage, "enter.go"),
+			ImportPath:  fmt.Sprintf(`"%s/service"`, info.Module),
+			StructName:  info.StructName + "Api",
+			AppName:     "ServiceGroupApp",
+			GroupName:   "GaiaServiceGroup",
+			ModuleName:  info.Abbreviation + "Service",
+			PackageName: "service",
+			ServiceName: info.StructName + "Service",
+		},
+		&utilsAst.PackageModuleEnter{
+			Type:        utilsAst.TypePackageRouterModuleEnter,
+			Path:        filepath.Join(server, "router", request.AutoCodeGaiaPackage, "enter.go"),
+			ImportPath:  fmt.Sprintf(`api "%s/api/v1"`, info.Module),
+			StructName:  info.StructName + "Router",
+			AppName:     "ApiGroupApp",
+			GroupName:   "GaiaApiGroup",
+			ModuleName:  info.Abbreviation + "Api",
+			PackageName: "api",
+			ServiceName: info.StructName + "Api",
+		},
+		&utilsAst.PackageModuleEnter{
+			Type:       utilsAst.TypePackageServiceModuleEnter,
+			Path:       filepath.Join(server, "service", request.AutoCodeGaiaPackage, "enter.go"),
+			StructName: info.StructName + "Service",
+		},
+		&utilsAst.PackageInitializeRouter{
+			Type:                 utilsAst.TypePackageInitializeRouter,
+			Path:                 filepath.Join(server, "initialize", "router_biz.go"),
+			ImportPath:           fmt.Sprintf(`"%s/router"`, info.Module),
+			AppName:              "RouterGroupApp",
+			GroupName:            "Gaia",
+			ModuleName:           "gaiaRouter",
+			PackageName:          "router",
+			FunctionName:         "Init" + info.StructName + "Router",
+			LeftRouterGroupName:  "privateGroup",
+			RightRouterGroupName: "publicGroup",
+		},
+	}
+	injections := make(map[string]utilsAst.Ast, len(asts))
+	for _, value := range asts {
+		var builder strings.Builder
+		parse, err := value.Parse("", &builder)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err = value.Injection(parse); err != nil {
+			return nil, nil, nil, err
+		}
+		err = value.Format("", &builder, parse)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		var path string
+		switch entity := value.(type) {
+		case *utilsAst.PackageModuleEnter:
+			path = entity.Path
+			injections[entity.Type.String()] = entity
+		case *utilsAst.PackageInitializeRouter:
+			path = entity.Path
+			injections[entity.Type.String()] = entity
+		}
+		codes[path] = builder.String()
+	} // 注入代码
+	return codes, templates, injections, nil
+}
@@ -0,0 +1,170 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"go.uber.org/zap"
+)
+
+func gaiaTestColumns(columns ...gaiaColumn) []*request.AutoCodeGaiaField {
+	fields := make([]*request.AutoCodeGaiaField, 0, len(columns))
+	for _, column := range columns {
+		fields = append(fields, gaiaField(column))
+	}
+	return fields
+}
+
+func gaiaTestPtr[T any](v T) *T { return &v }
+
+// 渲染 gaia 模板并注入, 生成的代码需能通过解析, 本地有 go 工具链时通过 -overlay 编译, 不修改源码目录
+func TestAutoCodeGaiaGenerate(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldAutoCode := global.GVA_CONFIG.AutoCode
+	global.GVA_CONFIG.AutoCode = config.Autocode{Root: root, Server: "server", Module: "github.com/flipped-aurora/gin-vue-admin/server"}
+	t.Cleanup(func() { global.GVA_CONFIG.AutoCode = oldAutoCode })
+
+	tests := []struct {
+		name string
+		info request.AutoCodeGaia
+	}{
+		{
+			name: "按工作空间过滤的uuid主键表",
+			info: request.AutoCodeGaia{TableName: "codegen_documents", Fields: gaiaTestColumns(
+				gaiaColumn{ColumnName: "id", UdtName: "uuid", IsNullable: "NO", PrimaryKey: true, ColumnDefault: gaiaTestPtr("uuid_generate_v4()")},
+				gaiaColumn{ColumnName: "tenant_id", UdtName: "uuid", IsNullable: "NO"},
+				gaiaColumn{ColumnName: "name", UdtName: "varchar", IsNullable: "NO", CharacterLength: gaiaTestPtr(255), Comment: gaiaTestPtr("名称\n说明")},
+				gaiaColumn{ColumnName: "config", UdtName: "jsonb", IsNullable: "YES"},
+				gaiaColumn{ColumnName: "amount", UdtName: "numeric", IsNullable: "NO", NumericPrecision: gaiaTestPtr(16), NumericScale: gaiaTestPtr(7)},
+				gaiaColumn{ColumnName: "enabled", UdtName: "bool", IsNullable: "NO", ColumnDefault: gaiaTestPtr("true")},
+				gaiaColumn{ColumnName: "app_id", UdtName: "uuid", IsNullable: "YES"},
+				gaiaColumn{ColumnName: "created_at", UdtName: "timestamp", IsNullable: "NO", DatetimePrecision: gaiaTestPtr(6)},
+				gaiaColumn{ColumnName: "archived_at", UdtName: "timestamptz", IsNullable: "YES"},
+			)},
+		},
+		{
+			name: "只读的自增主键表",
+			info: request.AutoCodeGaia{TableName: "codegen_logs", ReadOnly: true, Fields: gaiaTestColumns(
+				gaiaColumn{ColumnName: "id", UdtName: "int4", IsNullable: "NO", PrimaryKey: true, ColumnDefault: gaiaTestPtr("nextval('codegen_logs_id_seq'::regclass)")},
+				gaiaColumn{ColumnName: "tenant_id", UdtName: "varchar", IsNullable: "YES", CharacterLength: gaiaTestPtr(36)},
+				gaiaColumn{ColumnName: "content", UdtName: "text", IsNullable: "YES"},
+				gaiaColumn{ColumnName: "raw", UdtName: "bytea", IsNullable: "YES"},
+			)},
+		},
+		{
+			name: "不含工作空间的表",
+			info: request.AutoCodeGaia{TableName: "codegen_settings", StructName: "CodegenSetting", Fields: gaiaTestColumns(
+				gaiaColumn{ColumnName: "key", UdtName: "varchar", IsNullable: "NO", PrimaryKey: true, CharacterLength: gaiaTestPtr(64)},
+				gaiaColumn{ColumnName: "value", UdtName: "text", IsNullable: "NO"},
+				gaiaColumn{ColumnName: "updated_at", UdtName: "date", IsNullable: "YES"},
+			)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			codes, templates, _, err := AutoCodeGaia.generate(&info)
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			if len(templates) != len(gaiaTemplates) {
+				t.Fatalf("generate() 生成 %d 个文件, want %d", len(templates), len(gaiaTemplates))
+			}
+			fset := token.NewFileSet()
+			for path, code := range codes {
+				if _, err = parser.ParseFile(fset, path, code, parser.AllErrors); err != nil {
+					t.Fatalf("%s 解析失败: %v\n%s", path, err, code)
+				}
+			}
+			gaiaTestBuild(t, filepath.Join(root, "server"), codes)
+		})
+	}
+}
+
+// 简称与文件名用于拼接路径, 不合法时在渲染前拒绝
+func TestAutoCodeGaiaName(t *testing.T) {
+	fields := gaiaTestColumns(gaiaColumn{ColumnName: "id", UdtName: "int4", IsNullable: "NO", PrimaryKey: true})
+	for _, info := range []request.AutoCodeGaia{
+		{TableName: "codegen_logs", HumpPackageName: "../../../main", Fields: fields},
+		{TableName: "codegen_logs", HumpPackageName: "1codegen", Fields: fields},
+		{TableName: "codegen-logs", StructName: "CodegenLog", Fields: fields},
+		{TableName: "codegen_logs", Abbreviation: "codegen/../log", Fields: fields},
+	} {
+		if _, _, _, err := AutoCodeGaia.generate(&info); err == nil || !strings.Contains(err.Error(), "不合法") {
+			t.Errorf("generate(%q, %q) error = %v", info.HumpPackageName, info.Abbreviation, err)
+		}
+	}
+}
+
+// gaiaTestBuild 通过 go build -overlay 编译生成与注入后的代码
+func gaiaTestBuild(t *testing.T, server string, codes map[string]string) {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if testing.Short() || err != nil {
+		t.Log("跳过编译检查")
+		return
+	}
+	dir := t.TempDir()
+	overlay := struct{ Replace map[string]string }{Replace: make(map[string]string, len(codes))}
+	i := 0
+	for path, code := range codes {
+		i++
+		file := filepath.Join(dir, fmt.Sprintf("%d_%s", i, filepath.Base(path)))
+		if err = os.WriteFile(file, []byte(code), 0666); err != nil {
+			t.Fatal(err)
+		}
+		overlay.Replace[path] = file
+	}
+	data, _ := json.Marshal(overlay)
+	overlayFile := filepath.Join(dir, "overlay.json")
+	if err = os.WriteFile(overlayFile, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(goBin, "build", "-overlay="+overlayFile, "./model/gaia/...", "./service/gaia", "./api/v1/gaia",
+		"./router/gaia", "./initialize")
+	cmd.Dir = server
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("生成的代码编译失败: %v\n%s", err, out)
+	}
+}
+
+func TestAutoCodeGaiaRollback(t *testing.T) {
+	oldLog := global.GVA_LOG
+	global.GVA_LOG = zap.NewNop()
+	t.Cleanup(func() { global.GVA_LOG = oldLog })
+	dir := t.TempDir()
+	injected, created := filepath.Join(dir, "enter.go"), filepath.Join(dir, "model", "codegen.go")
+	if err := os.WriteFile(injected, []byte("package gaia\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	originals := map[string][]byte{injected: []byte("package gaia\n")}
+	codes := map[string]string{injected: "package gaia\n\n// 注入\n", created: "package gaia\n"}
+	for path, code := range codes {
+		_ = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err := os.WriteFile(path, []byte(code), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	AutoCodeGaia.rollback(codes, originals)
+	if data, err := os.ReadFile(injected); err != nil || string(data) != "package gaia\n" {
+		t.Errorf("注入的文件未恢复: %q, %v", data, err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("新生成的文件未删除: %v", err)
+	}
+	// 部分文件尚未写入时回滚不报错
+	AutoCodeGaia.rollback(map[string]string{filepath.Join(dir, "missing.go"): ""}, nil)
+}
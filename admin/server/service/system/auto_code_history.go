@@ -67,6 +67,11 @@ func (s *autoCodeHistory) RollBack(ctx context.Context, info request.SysAutoHist
 	if err != nil {
 		return err
 	}
+	// Extend Start: gaia auto code
+	if info.DeleteTable && history.Package == request.AutoCodeGaiaPackage {
+		return errors.New("gaia 表由 Dify 维护, 不能删除")
+	}
+	// Extend Stop: gaia auto code
 	if history.ExportTemplateID != 0 {
 		err = global.GVA_DB.Delete(&model.SysExportTemplate{}, "id = ?", history.ExportTemplateID).Error
 		if err != nil {
@@ -232,6 +232,9 @@ func (s *autoCodePackage) Templates(ctx context.Context) ([]string, error) {
 			if entries[i].Name() == "preview" {
 				continue
 			} // preview 为预览代码生成器的代码
+			if entries[i].Name() == "gaia" {
+				continue
+			} // Extend: gaia 为 gaia 表代码生成器
 			templates = append(templates, entries[i].Name())
 		}
 	}
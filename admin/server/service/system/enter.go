@@ -18,8 +18,22 @@ type ServiceGroup struct {
 	AuthorityBtnService
 	SysExportTemplateService
 	SysParamsService
-	AutoCodePlugin   autoCodePlugin
-	AutoCodePackage  autoCodePackage
-	AutoCodeHistory  autoCodeHistory
-	AutoCodeTemplate autoCodeTemplate
+	ScimService           // Extend: SCIM provisioning
+	MfaService            // Extend: TOTP mfa
+	SessionService        // Extend: session registry
+	LockoutService        // Extend: login lockout
+	AccessTokenService    // Extend: personal access token
+	OffboardService       // Extend: offboarding
+	UserSyncService       // Extend: two-way user sync
+	ExportJobService      // Extend: export job queue
+	ExportScheduleService // Extend: scheduled report
+	DataScopeService      // Extend: data scope
+	RbacService           // Extend: rbac bundle
+	AuthorityGrantService // Extend: temporary authority grant
+	PermissionService     // Extend: permission simulator
+	AutoCodePlugin        autoCodePlugin
+	AutoCodePackage       autoCodePackage
+	AutoCodeHistory       autoCodeHistory
+	AutoCodeTemplate      autoCodeTemplate
+	AutoCodeGaia          autoCodeGaia // Extend: gaia auto code
 }
@@ -0,0 +1,850 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	ScimProvider         = "scim"       // account_integrates.provider, open_id 保存 externalId
+	scimGroupAuthority   = "authority-" // 角色分组ID前缀
+	scimGroupTenant      = "tenant-"    // 工作空间分组ID前缀
+	scimDefaultPageCount = 100
+)
+
+// scimFilterRegexp 仅支持 attr eq "value"
+var scimFilterRegexp = regexp.MustCompile(`(?i)^\s*([\w.]+)\s+eq\s+"([^"]*)"\s*$`)
+
+// scimMemberPathRegexp members[value eq "id"]
+var scimMemberPathRegexp = regexp.MustCompile(`(?i)^members\[value\s+eq\s+"([^"]*)"\]$`)
+
+type ScimService struct{}
+
+var (
+	errScimNotFound   = systemRes.NewScimError(http.StatusNotFound, "", "资源不存在")
+	errScimNoEmail    = systemRes.NewScimError(http.StatusBadRequest, "invalidValue", "用户缺少邮箱")
+	errScimUniqueness = systemRes.NewScimError(http.StatusConflict, "uniqueness", "邮箱已存在")
+	errScimUsername   = systemRes.NewScimError(http.StatusConflict, "uniqueness", "用户名已存在")
+	errScimUnmanaged  = systemRes.NewScimError(http.StatusForbidden, "mutability", "该角色未开放SCIM管理")
+	errScimProtected  = systemRes.NewScimError(http.StatusForbidden, "mutability", "该用户持有超级管理员或未开放SCIM管理的角色, 不允许通过SCIM修改")
+)
+
+// ListUsers
+// @description: 查询用户, 支持 userName/emails.value/externalId/id eq 过滤
+// @param: filter string, startIndex int, count int
+// @return: systemRes.ScimListResponse, error
+func (s *ScimService) ListUsers(filter string, startIndex, count int) (res systemRes.ScimListResponse, err error) {
+	startIndex, count = scimPage(startIndex, count)
+	db := global.GVA_DB.Model(&system.SysUser{})
+	if len(filter) > 0 {
+		match := scimFilterRegexp.FindStringSubmatch(filter)
+		if match == nil {
+			return res, systemRes.NewScimError(http.StatusBadRequest, "invalidFilter", "不支持的过滤条件: "+filter)
+		}
+		switch strings.ToLower(match[1]) {
+		case "username":
+			db = db.Where("username = ? OR email = ?", match[2], match[2])
+		case "emails.value", "emails":
+			db = db.Where("email = ?", match[2])
+		case "id":
+			db = db.Where("uuid = ?", match[2])
+		case "externalid":
+			db = db.Where("email IN (?)", global.GVA_DB.Model(&gaia.Account{}).Select("email").Where(
+				"id IN (?)", global.GVA_DB.Model(&gaia.AccountIntegrate{}).Select("account_id").Where(
+					"provider = ? AND open_id = ?", ScimProvider, match[2])))
+		default:
+			return res, systemRes.NewScimError(http.StatusBadRequest, "invalidFilter", "不支持的过滤属性: "+match[1])
+		}
+	}
+	var total int64
+	if err = db.Count(&total).Error; err != nil {
+		return res, err
+	}
+	var users []system.SysUser
+	if err = db.Preload("Authorities").Order("id").Offset(startIndex - 1).Limit(count).Find(&users).Error; err != nil {
+		return res, err
+	}
+	resources := make([]systemReq.ScimUser, 0, len(users))
+	for _, v := range users {
+		resources = append(resources, s.toScimUser(v))
+	}
+	return scimList(int(total), startIndex, resources), nil
+}
+
+// GetUser
+// @param: id string SysUser.UUID
+// @return: systemReq.ScimUser, error
+func (s *ScimService) GetUser(id string) (systemReq.ScimUser, error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return systemReq.ScimUser{}, err
+	}
+	return s.toScimUser(user), nil
+}
+
+// CreateUser
+// @description: 注册后台用户与gaia账户, 记录externalId并按active设置状态
+// @param: req systemReq.ScimUser
+// @return: systemReq.ScimUser, error
+func (s *ScimService) CreateUser(req systemReq.ScimUser) (res systemReq.ScimUser, err error) {
+	email := strings.TrimSpace(req.PrimaryEmail())
+	if !strings.Contains(email, "@") {
+		return res, errScimNoEmail
+	}
+	if !errors.Is(global.GVA_DB.Where("email = ?", email).First(&system.SysUser{}).Error, gorm.ErrRecordNotFound) {
+		return res, errScimUniqueness
+	}
+	// 未指定用户名时由邮箱生成, 与同步、OAuth2注册使用相同的防冲突规则
+	username := strings.TrimSpace(req.UserName)
+	if len(username) > 0 {
+		if usernameTaken(username) {
+			return res, errScimUsername
+		}
+	} else {
+		var ok bool
+		if username, _, ok = usernameForEmail(email); !ok {
+			return res, errScimUsername
+		}
+	}
+	var phone string
+	if len(req.PhoneNumbers) > 0 {
+		phone = req.PhoneNumbers[0].Value
+	}
+	var user system.SysUser
+	// IdP 为用户来源, 邮箱对应的gaia账户已存在时直接关联
+	if user, _, err = UserServiceApp.RegisterLinked(system.SysUser{
+		Username:    username,
+		NickName:    req.Nickname(),
+		AuthorityId: system.NormalAuthorityId,
+		Authorities: []system.SysAuthority{{AuthorityId: system.NormalAuthorityId}},
+		Enable:      system.UserActive,
+		Phone:       phone,
+		Email:       email,
+		Password:    utils.RandomString(16),
+	}, ""); err != nil {
+		return res, err
+	}
+	if err = s.linkExternalID(user, req.ExternalID); err != nil {
+		return res, err
+	}
+	if req.Active != nil && !bool(*req.Active) {
+		if err = s.setActive(&user, false); err != nil {
+			return res, err
+		}
+	}
+	return s.GetUser(user.UUID.String())
+}
+
+// ReplaceUser
+// @description: PUT 全量更新用户属性
+// @param: id string, req systemReq.ScimUser
+// @return: systemReq.ScimUser, error
+func (s *ScimService) ReplaceUser(id string, req systemReq.ScimUser) (res systemReq.ScimUser, err error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return res, err
+	}
+	if err = s.checkWritable(user); err != nil {
+		return res, err
+	}
+	values := map[string]interface{}{"nick_name": req.Nickname()}
+	if len(req.UserName) > 0 {
+		values["username"] = req.UserName
+	}
+	if email := strings.TrimSpace(req.PrimaryEmail()); strings.Contains(email, "@") {
+		values["email"] = email
+	}
+	if len(req.PhoneNumbers) > 0 {
+		values["phone"] = req.PhoneNumbers[0].Value
+	}
+	if err = s.updateUser(&user, values); err != nil {
+		return res, err
+	}
+	if err = s.linkExternalID(user, req.ExternalID); err != nil {
+		return res, err
+	}
+	if req.Active != nil {
+		if err = s.setActive(&user, bool(*req.Active)); err != nil {
+			return res, err
+		}
+	}
+	return s.GetUser(user.UUID.String())
+}
+
+// PatchUser
+// @description: PATCH 更新用户属性, 兼容带path与不带path(值为对象)两种写法
+// @param: id string, req systemReq.ScimPatchOp
+// @return: systemReq.ScimUser, error
+func (s *ScimService) PatchUser(id string, req systemReq.ScimPatchOp) (res systemReq.ScimUser, err error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return res, err
+	}
+	if err = s.checkWritable(user); err != nil {
+		return res, err
+	}
+	for _, op := range req.Operations {
+		// 不带path时value为属性对象
+		attrs := map[string]json.RawMessage{}
+		if len(op.Path) == 0 {
+			if err = json.Unmarshal(op.Value, &attrs); err != nil {
+				return res, systemRes.NewScimError(http.StatusBadRequest, "invalidSyntax", "value 格式错误")
+			}
+		} else {
+			attrs[op.Path] = op.Value
+		}
+		if strings.EqualFold(op.Op, "remove") {
+			continue // 不支持删除必填属性, 忽略
+		}
+		values := map[string]interface{}{}
+		for path, raw := range attrs {
+			switch strings.ToLower(path) {
+			case "active":
+				var active systemReq.ScimBool
+				if err = json.Unmarshal(raw, &active); err != nil {
+					return res, systemRes.NewScimError(http.StatusBadRequest, "invalidValue", "active 格式错误")
+				}
+				if err = s.setActive(&user, bool(active)); err != nil {
+					return res, err
+				}
+			case "username":
+				values["username"] = scimString(raw)
+			case "displayname", "name.formatted":
+				values["nick_name"] = scimString(raw)
+			case "externalid":
+				if err = s.linkExternalID(user, scimString(raw)); err != nil {
+					return res, err
+				}
+			case "emails", `emails[type eq "work"].value`:
+				if email := scimMultiValue(raw); strings.Contains(email, "@") {
+					values["email"] = email
+				}
+			case "phonenumbers", `phonenumbers[type eq "work"].value`, `phonenumbers[type eq "mobile"].value`:
+				values["phone"] = scimMultiValue(raw)
+			}
+		}
+		if len(values) > 0 {
+			if err = s.updateUser(&user, values); err != nil {
+				return res, err
+			}
+		}
+	}
+	return s.GetUser(user.UUID.String())
+}
+
+// DeleteUser
+// @description: 删除后台用户并关闭gaia账户
+// @param: id string
+// @return: error
+func (s *ScimService) DeleteUser(id string) error {
+	user, err := s.findUser(id)
+	if err != nil {
+		return err
+	}
+	if err = s.checkWritable(user); err != nil {
+		return err
+	}
+	user.SyncGaiaStatus(system.UserDeactivate)
+	return UserServiceApp.DeleteUser(int(user.ID))
+}
+
+// ListGroups
+// @description: 角色与工作空间均作为分组返回, 支持 displayName/id eq 过滤
+// @param: filter string, startIndex int, count int, withMembers bool
+// @return: systemRes.ScimListResponse, error
+func (s *ScimService) ListGroups(filter string, startIndex, count int, withMembers bool) (res systemRes.ScimListResponse, err error) {
+	startIndex, count = scimPage(startIndex, count)
+	var attr, value string
+	if len(filter) > 0 {
+		match := scimFilterRegexp.FindStringSubmatch(filter)
+		if match == nil {
+			return res, systemRes.NewScimError(http.StatusBadRequest, "invalidFilter", "不支持的过滤条件: "+filter)
+		}
+		attr, value = strings.ToLower(match[1]), match[2]
+		if attr != "displayname" && attr != "id" {
+			return res, systemRes.NewScimError(http.StatusBadRequest, "invalidFilter", "不支持的过滤属性: "+match[1])
+		}
+	}
+	var filtered []systemReq.ScimGroup
+	if filtered, err = s.findGroups(attr, value); err != nil {
+		return res, err
+	}
+	total := len(filtered)
+	if startIndex-1 < total {
+		filtered = filtered[startIndex-1 : min(total, startIndex-1+count)]
+	} else {
+		filtered = nil
+	}
+	resources := make([]systemReq.ScimGroup, 0, len(filtered))
+	for _, v := range filtered {
+		if withMembers {
+			if v, err = s.GetGroup(v.ID); err != nil {
+				return res, err
+			}
+		} else {
+			v.Schemas = []string{systemReq.ScimSchemaGroup}
+			v.Members = []systemReq.ScimMultiValue{}
+			v.Meta = &systemReq.ScimMeta{ResourceType: "Group"}
+		}
+		resources = append(resources, v)
+	}
+	return scimList(total, startIndex, resources), nil
+}
+
+// findGroups 按属性查询角色与工作空间分组, attr 为空时返回全部
+func (s *ScimService) findGroups(attr, value string) (groups []systemReq.ScimGroup, err error) {
+	authorityDB := global.GVA_DB.Order("authority_id")
+	tenantDB := global.GVA_DB.Order("created_at")
+	switch attr {
+	case "displayname":
+		authorityDB = authorityDB.Where("authority_name = ?", value)
+		tenantDB = tenantDB.Where("name = ?", value)
+	case "id":
+		switch {
+		case strings.HasPrefix(value, scimGroupAuthority):
+			id, err := strconv.ParseUint(strings.TrimPrefix(value, scimGroupAuthority), 10, 32)
+			if err != nil {
+				return nil, nil
+			}
+			authorityDB = authorityDB.Where("authority_id = ?", id)
+			tenantDB = nil
+		case strings.HasPrefix(value, scimGroupTenant):
+			id, err := uuid.FromString(strings.TrimPrefix(value, scimGroupTenant))
+			if err != nil {
+				return nil, nil
+			}
+			authorityDB = nil
+			tenantDB = tenantDB.Where("id = ?", id)
+		default:
+			return nil, nil
+		}
+	}
+	if authorityDB != nil {
+		var authorities []system.SysAuthority
+		if err = authorityDB.Find(&authorities).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range authorities {
+			groups = append(groups, systemReq.ScimGroup{
+				ID:          scimGroupAuthority + strconv.Itoa(int(v.AuthorityId)),
+				DisplayName: v.AuthorityName,
+			})
+		}
+	}
+	if tenantDB != nil {
+		var tenants []gaia.Tenants
+		if err = tenantDB.Find(&tenants).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range tenants {
+			groups = append(groups, systemReq.ScimGroup{ID: scimGroupTenant + v.Id, DisplayName: v.Name})
+		}
+	}
+	return groups, nil
+}
+
+// GetGroup
+// @param: id string authority-<角色ID> 或 tenant-<工作空间ID>
+// @return: systemReq.ScimGroup, error
+func (s *ScimService) GetGroup(id string) (group systemReq.ScimGroup, err error) {
+	group = systemReq.ScimGroup{
+		Schemas: []string{systemReq.ScimSchemaGroup},
+		ID:      id,
+		Members: []systemReq.ScimMultiValue{},
+		Meta:    &systemReq.ScimMeta{ResourceType: "Group"},
+	}
+	var users []system.SysUser
+	switch {
+	case strings.HasPrefix(id, scimGroupAuthority):
+		var authority system.SysAuthority
+		if err = global.GVA_DB.Where("authority_id = ?", strings.TrimPrefix(id, scimGroupAuthority)).First(&authority).Error; err != nil {
+			return group, errScimNotFound
+		}
+		group.DisplayName = authority.AuthorityName
+		if err = global.GVA_DB.Where("id IN (?)", global.GVA_DB.Model(&system.SysUserAuthority{}).Select("sys_user_id").Where(
+			"sys_authority_authority_id = ?", authority.AuthorityId)).Find(&users).Error; err != nil {
+			return group, err
+		}
+	case strings.HasPrefix(id, scimGroupTenant):
+		var tenant gaia.Tenants
+		if err = global.GVA_DB.Where("id = ?", strings.TrimPrefix(id, scimGroupTenant)).First(&tenant).Error; err != nil {
+			return group, errScimNotFound
+		}
+		group.DisplayName = tenant.Name
+		if err = global.GVA_DB.Where("email IN (?)", global.GVA_DB.Model(&gaia.Account{}).Select("email").Where(
+			"id IN (?)", global.GVA_DB.Model(&gaia.TenantAccountJoins{}).Select("account_id").Where(
+				"tenant_id = ?", tenant.Id))).Find(&users).Error; err != nil {
+			return group, err
+		}
+	default:
+		return group, errScimNotFound
+	}
+	for _, v := range users {
+		group.Members = append(group.Members, systemReq.ScimMultiValue{Value: v.UUID.String(), Display: v.NickName})
+	}
+	return group, nil
+}
+
+// CreateGroup
+// @description: 分组只能关联已存在的同名角色或工作空间, 并设置成员
+// @param: req systemReq.ScimGroup
+// @return: systemReq.ScimGroup, error
+func (s *ScimService) CreateGroup(req systemReq.ScimGroup) (group systemReq.ScimGroup, err error) {
+	var groups []systemReq.ScimGroup
+	if groups, err = s.findGroups("displayname", req.DisplayName); err != nil {
+		return group, err
+	}
+	if len(groups) == 0 {
+		return group, systemRes.NewScimError(http.StatusBadRequest, "invalidValue",
+			"只能关联已存在的角色或工作空间: "+req.DisplayName)
+	}
+	return s.ReplaceGroup(groups[0].ID, req)
+}
+
+// ReplaceGroup
+// @description: PUT 以请求中的成员覆盖分组成员
+// @param: id string, req systemReq.ScimGroup
+// @return: systemReq.ScimGroup, error
+func (s *ScimService) ReplaceGroup(id string, req systemReq.ScimGroup) (group systemReq.ScimGroup, err error) {
+	if group, err = s.GetGroup(id); err != nil {
+		return group, err
+	}
+	if err = s.setMembers(id, memberIDs(group.Members), memberIDs(req.Members)); err != nil {
+		return group, err
+	}
+	return s.GetGroup(id)
+}
+
+// PatchGroup
+// @description: PATCH 增删分组成员, 修改分组名称的操作会被忽略
+// @param: id string, req systemReq.ScimPatchOp
+// @return: systemReq.ScimGroup, error
+func (s *ScimService) PatchGroup(id string, req systemReq.ScimPatchOp) (group systemReq.ScimGroup, err error) {
+	if group, err = s.GetGroup(id); err != nil {
+		return group, err
+	}
+	current := memberIDs(group.Members)
+	target := memberIDs(group.Members)
+	for _, op := range req.Operations {
+		var members []systemReq.ScimMultiValue
+		if match := scimMemberPathRegexp.FindStringSubmatch(op.Path); match != nil {
+			members = []systemReq.ScimMultiValue{{Value: match[1]}}
+		} else if strings.EqualFold(op.Path, "members") {
+			_ = json.Unmarshal(op.Value, &members)
+		} else if len(op.Path) == 0 {
+			var value systemReq.ScimGroup
+			_ = json.Unmarshal(op.Value, &value)
+			members = value.Members
+		} else {
+			continue
+		}
+		switch strings.ToLower(op.Op) {
+		case "add":
+			for _, v := range members {
+				target[v.Value] = true
+			}
+		case "remove":
+			if len(members) == 0 && strings.EqualFold(op.Path, "members") {
+				target = map[string]bool{}
+			}
+			for _, v := range members {
+				delete(target, v.Value)
+			}
+		case "replace":
+			target = memberIDs(members)
+		}
+	}
+	if err = s.setMembers(id, current, target); err != nil {
+		return group, err
+	}
+	return s.GetGroup(id)
+}
+
+// setMembers 按差异增删分组成员, 角色分组须为SCIM可管理的角色
+func (s *ScimService) setMembers(id string, current, target map[string]bool) error {
+	if strings.HasPrefix(id, scimGroupAuthority) {
+		if _, err := s.managedAuthority(id); err != nil {
+			return err
+		}
+	}
+	var ids []string
+	for v := range current {
+		if !target[v] {
+			ids = append(ids, v)
+		}
+	}
+	sort.Strings(ids)
+	for _, v := range ids {
+		if err := s.removeMember(id, v); err != nil {
+			return err
+		}
+	}
+	ids = ids[:0]
+	for v := range target {
+		if !current[v] {
+			ids = append(ids, v)
+		}
+	}
+	sort.Strings(ids)
+	for _, v := range ids {
+		if err := s.addMember(id, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMember 角色分组写入 sys_user_authority, 工作空间分组写入 tenant_account_joins
+func (s *ScimService) addMember(groupID, userID string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return systemRes.NewScimError(http.StatusBadRequest, "invalidValue", "成员不存在: "+userID)
+	}
+	if strings.HasPrefix(groupID, scimGroupAuthority) {
+		var authorityID uint
+		if authorityID, err = s.managedAuthority(groupID); err != nil {
+			return err
+		}
+		if err = global.GVA_DB.Where(system.SysUserAuthority{
+			SysUserId: user.ID, SysAuthorityAuthorityId: authorityID,
+		}).FirstOrCreate(&system.SysUserAuthority{}).Error; err != nil {
+			return err
+		}
+		// 角色变更后需重新登录以刷新令牌中的角色
+		return SessionServiceApp.RevokeUser(user.ID, system.SessionRevokeRole)
+	}
+	account, err := s.account(user)
+	if err != nil {
+		return err
+	}
+	tenantID, err := uuid.FromString(strings.TrimPrefix(groupID, scimGroupTenant))
+	if err != nil {
+		return errScimNotFound
+	}
+	var count int64
+	global.GVA_DB.Model(&gaia.TenantAccountJoins{}).Where("tenant_id = ? AND account_id = ?", tenantID, account.ID).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	return global.GVA_DB.Create(&gaia.TenantAccountJoins{
+		ID:        uuid.Must(uuid.NewV4()),
+		TenantID:  tenantID,
+		AccountID: account.ID,
+		Role:      "normal",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}).Error
+}
+
+// removeMember 移除主角色时切换到剩余角色; 工作空间所有者不会被移除
+func (s *ScimService) removeMember(groupID, userID string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil
+	}
+	if strings.HasPrefix(groupID, scimGroupAuthority) {
+		var authorityID uint
+		if authorityID, err = s.managedAuthority(groupID); err != nil {
+			return err
+		}
+		if err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&system.SysUserAuthority{}, "sys_user_id = ? AND sys_authority_authority_id = ?",
+				user.ID, authorityID).Error; err != nil {
+				return err
+			}
+			if user.AuthorityId != authorityID {
+				return nil
+			}
+			var rest system.SysUserAuthority
+			if err := tx.Where("sys_user_id = ?", user.ID).First(&rest).Error; err != nil {
+				rest = system.SysUserAuthority{SysUserId: user.ID, SysAuthorityAuthorityId: system.NormalAuthorityId}
+				if err = tx.Create(&rest).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&system.SysUser{}).Where("id = ?", user.ID).Update("authority_id", rest.SysAuthorityAuthorityId).Error
+		}); err != nil {
+			return err
+		}
+		return SessionServiceApp.RevokeUser(user.ID, system.SessionRevokeRole)
+	}
+	account, err := s.account(user)
+	if err != nil {
+		return nil
+	}
+	return global.GVA_DB.Where("tenant_id = ? AND account_id = ? AND role <> ?",
+		strings.TrimPrefix(groupID, scimGroupTenant), account.ID, "owner").Delete(&gaia.TenantAccountJoins{}).Error
+}
+
+// managedAuthority 解析角色分组ID, 角色须存在且在SCIM配置允许管理的角色中, 超级管理员角色始终不受SCIM管理
+func (s *ScimService) managedAuthority(groupID string) (uint, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(groupID, scimGroupAuthority), 10, 32)
+	if err != nil {
+		return 0, errScimNotFound
+	}
+	authorityID := uint(id)
+	var count int64
+	if err = global.GVA_DB.Model(&system.SysAuthority{}).Where("authority_id = ?", authorityID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, errScimNotFound
+	}
+	managed, err := s.managedAuthorities()
+	if err != nil {
+		return 0, err
+	}
+	if !managed[authorityID] {
+		return 0, errScimUnmanaged
+	}
+	return authorityID, nil
+}
+
+// managedAuthorities SCIM配置允许管理的角色, 超级管理员角色始终不受SCIM管理
+func (s *ScimService) managedAuthorities() (map[uint]bool, error) {
+	managed := map[uint]bool{}
+	var integrate gaia.SystemIntegration
+	err := global.GVA_DB.Where("classify = ?", gaia.SystemIntegrationSCIM).First(&integrate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return managed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config gaia.ScimConfig
+	_ = json.Unmarshal([]byte(integrate.Config), &config)
+	for _, v := range config.Authorities {
+		if v != system.AdminAuthorityId {
+			managed[v] = true
+		}
+	}
+	return managed, nil
+}
+
+// checkWritable 用户持有超级管理员或SCIM未管理的角色时拒绝通过SCIM修改或删除;
+// 普通用户角色是SCIM新建用户的默认角色, 视为可管理
+func (s *ScimService) checkWritable(user system.SysUser) error {
+	managed, err := s.managedAuthorities()
+	if err != nil {
+		return err
+	}
+	managed[system.NormalAuthorityId] = true
+	ids := []uint{user.AuthorityId}
+	for _, v := range user.Authorities {
+		ids = append(ids, v.AuthorityId)
+	}
+	for _, id := range ids {
+		if id == system.AdminAuthorityId || !managed[id] {
+			return errScimProtected
+		}
+	}
+	return nil
+}
+
+// findUser 通过 SysUser.UUID 查找用户
+func (s *ScimService) findUser(id string) (user system.SysUser, err error) {
+	if _, err = uuid.FromString(id); err != nil {
+		return user, errScimNotFound
+	}
+	if err = global.GVA_DB.Preload("Authorities").Where("uuid = ?", id).First(&user).Error; err != nil {
+		return user, errScimNotFound
+	}
+	return user, nil
+}
+
+// account 用户对应的gaia账户, 以 accounts.id = sys_users.uuid 关联, 不随邮箱变化
+func (s *ScimService) account(user system.SysUser) (account gaia.Account, err error) {
+	err = global.GVA_DB.Where("id = ?", user.UUID).First(&account).Error
+	return account, err
+}
+
+// updateUser 同步更新后台用户与gaia账户, gaia账户以 accounts.id = sys_users.uuid 关联
+func (s *ScimService) updateUser(user *system.SysUser, values map[string]interface{}) error {
+	if email, ok := values["email"]; ok && email != user.Email {
+		if !errors.Is(global.GVA_DB.Where("email = ? AND id <> ?", email, user.ID).First(&system.SysUser{}).Error, gorm.ErrRecordNotFound) {
+			return errScimUniqueness
+		}
+		if !errors.Is(global.GVA_DB.Where("email = ? AND id <> ?", email, user.UUID).First(&gaia.Account{}).Error, gorm.ErrRecordNotFound) {
+			return errScimUniqueness
+		}
+	}
+	if v, ok := values["username"]; ok && len(v.(string)) == 0 {
+		delete(values, "username")
+	} else if ok && v != user.Username && usernameTaken(v.(string)) {
+		return errScimUsername
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		accountValues := map[string]interface{}{"updated_at": time.Now()}
+		if v, ok := values["nick_name"]; ok && len(v.(string)) > 0 {
+			accountValues["name"] = v
+		} else {
+			delete(values, "nick_name")
+		}
+		if v, ok := values["email"]; ok {
+			accountValues["email"] = v
+		}
+		if err := tx.Model(&gaia.Account{}).Where("id = ?", user.UUID).Updates(accountValues).Error; err != nil {
+			return err
+		}
+		values["updated_at"] = time.Now()
+		if err := tx.Model(&system.SysUser{}).Where("id = ?", user.ID).Updates(values).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", user.ID).First(user).Error
+	})
+}
+
+// setActive 启用/停用: 后台用户、gaia账户状态与登录限制同时生效
+func (s *ScimService) setActive(user *system.SysUser, active bool) error {
+	enable, status := system.UserDeactivate, gaia.UserBanned
+	if active {
+		enable, status = system.UserActive, gaia.UserActive
+	}
+	if err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&gaia.Account{}).Where("id = ? AND status <> ?", user.UUID, gaia.UserClosed).Updates(
+			map[string]interface{}{"status": status, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&system.SysUser{}).Where("id = ?", user.ID).Update("enable", enable).Error
+	}); err != nil {
+		return err
+	}
+	user.Enable = enable
+	user.SyncGaiaStatus(enable)
+	if !active {
+		return SessionServiceApp.RevokeUser(user.ID, system.SessionRevokeDisabled)
+	}
+	return nil
+}
+
+// linkExternalID 在 account_integrates 中记录IdP的externalId
+func (s *ScimService) linkExternalID(user system.SysUser, externalID string) error {
+	if len(externalID) == 0 {
+		return nil
+	}
+	account, err := s.account(user)
+	if err != nil {
+		return err
+	}
+	return global.GVA_DB.Where("account_id = ? AND provider = ?", account.ID, ScimProvider).Assign(map[string]interface{}{
+		"open_id":    externalID,
+		"updated_at": time.Now(),
+	}).FirstOrCreate(&gaia.AccountIntegrate{
+		ID:        uuid.Must(uuid.NewV4()),
+		AccountID: account.ID,
+		Provider:  ScimProvider,
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+// toScimUser 转换为SCIM用户
+func (s *ScimService) toScimUser(user system.SysUser) systemReq.ScimUser {
+	active := systemReq.ScimBool(user.Enable == system.UserActive)
+	res := systemReq.ScimUser{
+		Schemas:     []string{systemReq.ScimSchemaUser},
+		ID:          user.UUID.String(),
+		UserName:    user.Username,
+		Name:        systemReq.ScimName{Formatted: user.NickName},
+		DisplayName: user.NickName,
+		Active:      &active,
+		Emails:      []systemReq.ScimMultiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Meta: &systemReq.ScimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt.Format(time.RFC3339),
+			LastModified: user.UpdatedAt.Format(time.RFC3339),
+		},
+	}
+	if len(user.Phone) > 0 {
+		res.PhoneNumbers = []systemReq.ScimMultiValue{{Value: user.Phone, Type: "work"}}
+	}
+	for _, v := range user.Authorities {
+		res.Groups = append(res.Groups, systemReq.ScimMultiValue{
+			Value:   scimGroupAuthority + strconv.Itoa(int(v.AuthorityId)),
+			Display: v.AuthorityName,
+		})
+	}
+	if account, err := s.account(user); err == nil {
+		var integrate gaia.AccountIntegrate
+		if global.GVA_DB.Where("account_id = ? AND provider = ?", account.ID, ScimProvider).First(&integrate).Error == nil {
+			res.ExternalID = integrate.OpenID
+		}
+		var tenants []gaia.Tenants
+		global.GVA_DB.Where("id IN (?)", global.GVA_DB.Model(&gaia.TenantAccountJoins{}).Select("tenant_id").Where(
+			"account_id = ?", account.ID)).Find(&tenants)
+		for _, v := range tenants {
+			res.Groups = append(res.Groups, systemReq.ScimMultiValue{Value: scimGroupTenant + v.Id, Display: v.Name})
+		}
+	}
+	return res
+}
+
+func scimPage(startIndex, count int) (int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count <= 0 || count > scimDefaultPageCount {
+		count = scimDefaultPageCount
+	}
+	return startIndex, count
+}
+
+func scimList(total, startIndex int, resources interface{}) systemRes.ScimListResponse {
+	itemsPerPage := 0
+	switch v := resources.(type) {
+	case []systemReq.ScimUser:
+		itemsPerPage = len(v)
+	case []systemReq.ScimGroup:
+		itemsPerPage = len(v)
+	}
+	return systemRes.ScimListResponse{
+		Schemas:      []string{systemRes.ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: itemsPerPage,
+		Resources:    resources,
+	}
+}
+
+// scimString 解析字符串值
+func scimString(raw json.RawMessage) string {
+	var v string
+	_ = json.Unmarshal(raw, &v)
+	return strings.TrimSpace(v)
+}
+
+// scimMultiValue 多值属性取主值, 同时兼容直接传字符串
+func scimMultiValue(raw json.RawMessage) string {
+	var list []systemReq.ScimMultiValue
+	if json.Unmarshal(raw, &list) == nil && len(list) > 0 {
+		for _, v := range list {
+			if v.Primary {
+				return strings.TrimSpace(v.Value)
+			}
+		}
+		return strings.TrimSpace(list[0].Value)
+	}
+	return scimString(raw)
+}
+
+func memberIDs(members []systemReq.ScimMultiValue) map[string]bool {
+	ids := make(map[string]bool, len(members))
+	for _, v := range members {
+		ids[v.Value] = true
+	}
+	return ids
+}
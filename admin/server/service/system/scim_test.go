@@ -0,0 +1,182 @@
+package system
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/gofrs/uuid/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestScimManagedAuthority(t *testing.T) {
+	db := newTestDB(t, &system.SysAuthority{}, &gaia.SystemIntegration{})
+	for _, v := range []system.SysAuthority{
+		{AuthorityId: system.AdminAuthorityId, AuthorityName: "超级管理员"},
+		{AuthorityId: 100, AuthorityName: "开发"},
+		{AuthorityId: 200, AuthorityName: "运营"},
+	} {
+		if err := db.Create(&v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&gaia.SystemIntegration{
+		Classify: gaia.SystemIntegrationSCIM,
+		Config:   `{"authorities":[100,888]}`,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		groupID string
+		want    uint
+		wantErr error
+	}{
+		{groupID: "authority-100", want: 100},
+		{groupID: "authority-abc", wantErr: errScimNotFound},
+		{groupID: "authority-300", wantErr: errScimNotFound},
+		{groupID: "authority-200", wantErr: errScimUnmanaged},
+		{groupID: "authority-888", wantErr: errScimUnmanaged},
+	}
+	s := &ScimService{}
+	for _, tt := range tests {
+		t.Run(tt.groupID, func(t *testing.T) {
+			got, err := s.managedAuthority(tt.groupID)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("managedAuthority() = %d, %v, want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScimCheckWritable(t *testing.T) {
+	db := newTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{}, &gaia.SystemIntegration{})
+	if err := db.Create(&gaia.SystemIntegration{Classify: gaia.SystemIntegrationSCIM, Config: `{"authorities":[100,888]}`}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []uint{system.NormalAuthorityId, system.AdminAuthorityId, 100, 200} {
+		db.Create(&system.SysAuthority{AuthorityId: v, AuthorityName: strconv.Itoa(int(v))})
+	}
+	tests := []struct {
+		name        string
+		authorities []uint
+		wantErr     error
+	}{
+		{name: "默认角色", authorities: []uint{system.NormalAuthorityId}},
+		{name: "SCIM管理的角色", authorities: []uint{100, system.NormalAuthorityId}},
+		{name: "超级管理员", authorities: []uint{system.AdminAuthorityId}, wantErr: errScimProtected},
+		{name: "附加了超级管理员角色", authorities: []uint{100, system.AdminAuthorityId}, wantErr: errScimProtected},
+		{name: "未开放SCIM管理的角色", authorities: []uint{200}, wantErr: errScimProtected},
+	}
+	s := &ScimService{}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := system.SysUser{UUID: uuid.Must(uuid.NewV4()), Username: "user" + strconv.Itoa(i), AuthorityId: tt.authorities[0]}
+			if err := db.Create(&user).Error; err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range tt.authorities {
+				db.Create(&system.SysUserAuthority{SysUserId: user.ID, SysAuthorityAuthorityId: v})
+			}
+			found, err := s.findUser(user.UUID.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = s.checkWritable(found); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkWritable() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				return
+			}
+			if err = s.DeleteUser(user.UUID.String()); !errors.Is(err, errScimProtected) {
+				t.Errorf("DeleteUser() error = %v, want %v", err, errScimProtected)
+			}
+			if _, err = s.ReplaceUser(user.UUID.String(), systemReq.ScimUser{UserName: "renamed"}); !errors.Is(err, errScimProtected) {
+				t.Errorf("ReplaceUser() error = %v, want %v", err, errScimProtected)
+			}
+			if _, err = s.PatchUser(user.UUID.String(), systemReq.ScimPatchOp{}); !errors.Is(err, errScimProtected) {
+				t.Errorf("PatchUser() error = %v, want %v", err, errScimProtected)
+			}
+			var count int64
+			db.Model(&system.SysUser{}).Where("id = ? AND username = ?", user.ID, user.Username).Count(&count)
+			if count != 1 {
+				t.Errorf("受保护的用户被修改")
+			}
+		})
+	}
+}
+
+func TestScimUsernameUniqueness(t *testing.T) {
+	db := newTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{}, &gaia.SystemIntegration{})
+	users := []system.SysUser{
+		{UUID: uuid.Must(uuid.NewV4()), Username: "alice", Email: "alice@corp.com", AuthorityId: system.NormalAuthorityId},
+		{UUID: uuid.Must(uuid.NewV4()), Username: "bob", Email: "bob@corp.com", AuthorityId: system.NormalAuthorityId},
+	}
+	for i := range users {
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &ScimService{}
+	emails := []systemReq.ScimMultiValue{{Value: "carol@corp.com", Primary: true}}
+	if _, err := s.CreateUser(systemReq.ScimUser{UserName: "alice", Emails: emails}); !errors.Is(err, errScimUsername) {
+		t.Errorf("CreateUser() error = %v, want %v", err, errScimUsername)
+	}
+	if _, err := s.ReplaceUser(users[1].UUID.String(), systemReq.ScimUser{UserName: "alice"}); !errors.Is(err, errScimUsername) {
+		t.Errorf("ReplaceUser() error = %v, want %v", err, errScimUsername)
+	}
+	patch := systemReq.ScimPatchOp{Operations: []systemReq.ScimPatchOperation{{Op: "replace", Path: "userName", Value: []byte(`"alice"`)}}}
+	if _, err := s.PatchUser(users[1].UUID.String(), patch); !errors.Is(err, errScimUsername) {
+		t.Errorf("PatchUser() error = %v, want %v", err, errScimUsername)
+	}
+	var count int64
+	db.Model(&system.SysUser{}).Where("username = ?", "alice").Count(&count)
+	if count != 1 {
+		t.Errorf("用户名重复: %d", count)
+	}
+}
+
+// gaia账户以 accounts.id = sys_users.uuid 关联, 邮箱不一致时也不会修改同邮箱的其他账户
+func TestScimAccountByUUID(t *testing.T) {
+	db := newTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{}, &gaia.SystemIntegration{},
+		&gaia.Account{}, &gaia.AccountIntegrate{})
+	oldRedis := global.GVA_REDIS
+	global.GVA_REDIS = redis.NewClient(&redis.Options{Addr: "127.0.0.1:0", MaxRetries: -1})
+	t.Cleanup(func() { global.GVA_REDIS = oldRedis })
+	user := system.SysUser{UUID: uuid.Must(uuid.NewV4()), Username: "alice", Email: "alice@corp.com", AuthorityId: system.NormalAuthorityId}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	own := gaia.Account{ID: user.UUID, Name: "alice", Email: "alice-old@corp.com", Status: gaia.UserBanned}
+	other := gaia.Account{ID: uuid.Must(uuid.NewV4()), Name: "other", Email: user.Email, Status: gaia.UserBanned}
+	taken := gaia.Account{ID: uuid.Must(uuid.NewV4()), Name: "taken", Email: "taken@corp.com", Status: gaia.UserActive}
+	for _, v := range []*gaia.Account{&own, &other, &taken} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &ScimService{}
+	patch := systemReq.ScimPatchOp{Operations: []systemReq.ScimPatchOperation{
+		{Op: "replace", Path: "displayName", Value: []byte(`"Alice"`)},
+		{Op: "replace", Path: "active", Value: []byte(`true`)},
+	}}
+	if _, err := s.PatchUser(user.UUID.String(), patch); err != nil {
+		t.Fatalf("PatchUser() error = %v", err)
+	}
+	db.Where("id = ?", own.ID).First(&own)
+	db.Where("id = ?", other.ID).First(&other)
+	if own.Name != "Alice" || own.Status != gaia.UserActive {
+		t.Errorf("关联账户未更新: %s, %s", own.Name, own.Status)
+	}
+	if other.Name != "other" || other.Status != gaia.UserBanned {
+		t.Errorf("同邮箱的其他账户被修改: %s, %s", other.Name, other.Status)
+	}
+	// 邮箱已被其他gaia账户使用
+	patch = systemReq.ScimPatchOp{Operations: []systemReq.ScimPatchOperation{{Op: "replace", Path: "emails", Value: []byte(`"taken@corp.com"`)}}}
+	if _, err := s.PatchUser(user.UUID.String(), patch); !errors.Is(err, errScimUniqueness) {
+		t.Errorf("PatchUser() error = %v, want %v", err, errScimUniqueness)
+	}
+}
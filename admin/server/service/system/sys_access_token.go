@@ -0,0 +1,189 @@
+package system
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/util"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/oidc"
+	"go.uber.org/zap"
+)
+
+const (
+	accessTokenMaxDays = 365
+	// 令牌管理接口不允许通过令牌本身访问
+	accessTokenRouterPrefix = "/accessToken/"
+	accessTokenUsedPrefix   = "access_token_used:"
+)
+
+var ErrAccessTokenInvalid = errors.New("访问令牌无效或已过期")
+
+type AccessTokenService struct{}
+
+var AccessTokenServiceApp = new(AccessTokenService)
+
+// Create
+// @function: Create
+// @description: 创建个人访问令牌, 接口范围须为当前角色权限的子集
+// @param: userID uint, authorityID uint, req systemReq.AccessTokenCreateReq
+// @return: res systemRes.AccessTokenCreateResponse, err error
+func (a *AccessTokenService) Create(userID, authorityID uint, req systemReq.AccessTokenCreateReq) (res systemRes.AccessTokenCreateResponse, err error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if len(req.Name) == 0 {
+		return res, errors.New("令牌名称不能为空")
+	}
+	if req.ExpireDays < 1 || req.ExpireDays > accessTokenMaxDays {
+		return res, fmt.Errorf("有效天数须在1-%d之间", accessTokenMaxDays)
+	}
+	for _, ip := range req.AllowedIPs {
+		if _, _, cErr := net.ParseCIDR(ip); cErr != nil && net.ParseIP(ip) == nil {
+			return res, fmt.Errorf("IP白名单格式错误: %s", ip)
+		}
+	}
+	if len(req.Scopes) == 0 {
+		return res, errors.New("请至少选择一个接口权限")
+	}
+	enforcer := CasbinServiceApp.Casbin()
+	sub := strconv.Itoa(int(authorityID))
+	for i, scope := range req.Scopes {
+		scope.Method = strings.ToUpper(scope.Method)
+		req.Scopes[i] = scope
+		if strings.HasPrefix(scope.Path, accessTokenRouterPrefix) {
+			return res, errors.New("访问令牌不能用于管理访问令牌")
+		}
+		if ok, _ := enforcer.Enforce(sub, scope.Path, scope.Method); !ok {
+			return res, fmt.Errorf("当前角色没有接口权限: %s %s", scope.Method, scope.Path)
+		}
+	}
+	res.Token = system.AccessTokenPrefix + oidc.RandomString(32)
+	res.AccessToken = system.SysAccessToken{
+		UserID:     userID,
+		Name:       req.Name,
+		TokenHash:  a.hash(res.Token),
+		Hint:       res.Token[len(res.Token)-4:],
+		Scopes:     req.Scopes,
+		AllowedIPs: strings.Join(req.AllowedIPs, ","),
+		ExpiresAt:  time.Now().AddDate(0, 0, req.ExpireDays),
+	}
+	err = global.GVA_DB.Create(&res.AccessToken).Error
+	return res, err
+}
+
+// GetList
+// @function: GetList
+// @description: 获取用户的访问令牌
+// @param: userID uint
+// @return: list []system.SysAccessToken, err error
+func (a *AccessTokenService) GetList(userID uint) (list []system.SysAccessToken, err error) {
+	err = global.GVA_DB.Where("user_id = ?", userID).Order("id desc").Find(&list).Error
+	return list, err
+}
+
+// Revoke
+// @function: Revoke
+// @description: 吊销用户自己的访问令牌
+// @param: id uint, userID uint
+// @return: err error
+func (a *AccessTokenService) Revoke(id, userID uint) error {
+	now := time.Now()
+	db := global.GVA_DB.Model(&system.SysAccessToken{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", &now)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return errors.New("令牌不存在或已吊销")
+	}
+	return nil
+}
+
+// Authenticate
+// @function: Authenticate
+// @description: 校验访问令牌, 返回令牌及其所属用户
+// @param: raw string, ip string
+// @return: token system.SysAccessToken, user system.SysUser, err error
+func (a *AccessTokenService) Authenticate(raw, ip string) (token system.SysAccessToken, user system.SysUser, err error) {
+	if err = global.GVA_DB.Where("token_hash = ?", a.hash(raw)).First(&token).Error; err != nil {
+		return token, user, ErrAccessTokenInvalid
+	}
+	if !token.Active() {
+		return token, user, ErrAccessTokenInvalid
+	}
+	if !a.allowIP(token, ip) {
+		return token, user, fmt.Errorf("IP %s 不在访问令牌的白名单内", ip)
+	}
+	if err = global.GVA_DB.Where("id = ?", token.UserID).First(&user).Error; err != nil {
+		return token, user, ErrAccessTokenInvalid
+	}
+	if user.Enable != system.UserActive {
+		return token, user, errors.New("用户被禁止登录")
+	}
+	a.touch(token, ip)
+	return token, user, nil
+}
+
+// Allowed
+// @function: Allowed
+// @description: 请求的接口是否在令牌范围内
+// @param: scopes []system.AccessTokenScope, path string, method string
+// @return: bool
+func (a *AccessTokenService) Allowed(scopes []system.AccessTokenScope, path, method string) bool {
+	if strings.HasPrefix(path, accessTokenRouterPrefix) {
+		return false
+	}
+	for _, scope := range scopes {
+		if scope.Method == method && util.KeyMatch2(path, scope.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AccessTokenService) allowIP(token system.SysAccessToken, ip string) bool {
+	list := token.IPList()
+	if len(list) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	for _, item := range list {
+		if _, network, err := net.ParseCIDR(item); err == nil {
+			if addr != nil && network.Contains(addr) {
+				return true
+			}
+		} else if item == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// touch 记录最后使用时间, 每分钟最多写库一次
+func (a *AccessTokenService) touch(token system.SysAccessToken, ip string) {
+	key := accessTokenUsedPrefix + strconv.Itoa(int(token.ID))
+	if _, ok := global.BlackCache.Get(key); ok {
+		return
+	}
+	global.BlackCache.Set(key, struct{}{}, time.Minute)
+	now := time.Now()
+	if err := global.GVA_DB.Model(&system.SysAccessToken{}).Where("id = ?", token.ID).Updates(map[string]interface{}{
+		"last_used_at": &now,
+		"last_used_ip": ip,
+	}).Error; err != nil {
+		global.GVA_LOG.Error("更新访问令牌使用时间失败!", zap.Error(err))
+	}
+}
+
+func (a *AccessTokenService) hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -166,6 +166,10 @@ func (authorityService *AuthorityService) DeleteAuthority(auth *system.SysAuthor
 		if err = tx.Where("authority_id = ?", auth.AuthorityId).Delete(&[]system.SysAuthorityBtn{}).Error; err != nil {
 			return err
 		}
+		// Extend: data scope
+		if err = tx.Where("authority_id = ?", auth.AuthorityId).Delete(&system.SysAuthorityDataScope{}).Error; err != nil {
+			return err
+		}
 
 		authorityId := strconv.Itoa(int(auth.AuthorityId))
 
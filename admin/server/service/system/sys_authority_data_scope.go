@@ -0,0 +1,109 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DataScopeService struct{}
+
+var DataScopeServiceApp = new(DataScopeService)
+
+// GetDataScope
+// @function: GetDataScope
+// @description: 获取角色数据范围, 未设置时为全部工作空间
+// @param: authorityID uint
+// @return: scope system.SysAuthorityDataScope, err error
+func (d *DataScopeService) GetDataScope(authorityID uint) (scope system.SysAuthorityDataScope, err error) {
+	err = global.GVA_DB.Where("authority_id = ?", authorityID).First(&scope).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return system.SysAuthorityDataScope{AuthorityId: authorityID, Scope: system.DataScopeAll}, nil
+	}
+	return scope, err
+}
+
+// SetDataScope
+// @function: SetDataScope
+// @description: 设置角色数据范围, 指定工作空间时须全部存在
+// @param: req systemReq.SetDataScopeReq
+// @return: err error
+func (d *DataScopeService) SetDataScope(req systemReq.SetDataScopeReq) (err error) {
+	if !slices.Contains(system.DataScopes, req.Scope) {
+		return fmt.Errorf("不支持的数据范围: %s", req.Scope)
+	}
+	if errors.Is(global.GVA_DB.Where("authority_id = ?", req.AuthorityId).First(&system.SysAuthority{}).Error, gorm.ErrRecordNotFound) {
+		return errors.New("该角色不存在")
+	}
+	var tenantIDs []string
+	if req.Scope == system.DataScopeCustom {
+		for _, id := range req.TenantIDs {
+			if id = strings.TrimSpace(id); id != "" && !slices.Contains(tenantIDs, id) {
+				tenantIDs = append(tenantIDs, id)
+			}
+		}
+		if len(tenantIDs) == 0 {
+			return errors.New("请选择工作空间")
+		}
+		var count int64
+		if err = global.GVA_DB.Model(&gaia.Tenants{}).Where("id IN ?", tenantIDs).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(tenantIDs) {
+			return errors.New("存在不存在的工作空间")
+		}
+	}
+	return global.GVA_DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "authority_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "tenant_ids", "updated_at"}),
+	}).Create(&system.SysAuthorityDataScope{
+		AuthorityId: req.AuthorityId,
+		Scope:       req.Scope,
+		TenantIDs:   tenantIDs,
+		UpdatedAt:   time.Now(),
+	}).Error
+}
+
+// TenantScope
+// @function: TenantScope
+// @description: 解析角色在 gaia 中可见的工作空间, own 按用户对应的 gaia 账户所在工作空间
+// @param: authorityID uint, accountID string
+// @return: scope gaia.TenantScope, err error
+func (d *DataScopeService) TenantScope(authorityID uint, accountID string) (scope gaia.TenantScope, err error) {
+	dataScope, err := d.GetDataScope(authorityID)
+	if err != nil {
+		return
+	}
+	switch dataScope.Scope {
+	case system.DataScopeOwn:
+		err = global.GVA_DB.Model(&gaia.TenantAccountJoins{}).Where("account_id = ?", accountID).
+			Pluck("tenant_id", &scope.TenantIDs).Error
+	case system.DataScopeCustom:
+		scope.TenantIDs = dataScope.TenantIDs
+	default:
+		scope = gaia.AllTenants
+	}
+	return scope, err
+}
+
+// UserTenantScope
+// @function: UserTenantScope
+// @description: 按用户当前角色解析数据范围, 用于后台任务
+// @param: userID uint
+// @return: scope gaia.TenantScope, err error
+func (d *DataScopeService) UserTenantScope(userID uint) (scope gaia.TenantScope, err error) {
+	var user system.SysUser
+	if err = global.GVA_DB.Select("uuid", "authority_id").Where("id = ?", userID).First(&user).Error; err != nil {
+		return
+	}
+	return d.TenantScope(user.AuthorityId, user.UUID.String())
+}
@@ -0,0 +1,423 @@
+package system
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// errGrantChanged 授权状态已被其他实例或操作修改
+var errGrantChanged = errors.New("授权状态已变化")
+
+type AuthorityGrantService struct{}
+
+var AuthorityGrantServiceApp = new(AuthorityGrantService)
+
+// CreateGrant
+// @function: CreateGrant
+// @description: 为用户临时授予角色, 无需审批且开始时间已到时立即生效, 指定审批人的需审批通过后才生效
+// @param: adminAuthorityID uint, req systemReq.AuthorityGrantReq, operatorID uint, operator string
+// @return: grant system.SysAuthorityGrant, err error
+func (a *AuthorityGrantService) CreateGrant(adminAuthorityID uint, req systemReq.AuthorityGrantReq, operatorID uint, operator string) (grant system.SysAuthorityGrant, err error) {
+	now := time.Now()
+	start := now
+	if req.StartAt != nil && req.StartAt.After(now) {
+		start = *req.StartAt
+	}
+	if !req.EndAt.After(start) {
+		return grant, errors.New("结束时间必须晚于开始时间")
+	}
+	if req.UserID == operatorID {
+		return grant, errors.New("不能为自己授权")
+	}
+	if err = AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, req.AuthorityId); err != nil {
+		return
+	}
+	var user system.SysUser
+	if err = global.GVA_DB.Where("id = ?", req.UserID).First(&user).Error; err != nil {
+		return grant, errors.New("用户不存在")
+	}
+	if err = global.GVA_DB.Where("authority_id = ?", req.AuthorityId).First(&system.SysAuthority{}).Error; err != nil {
+		return grant, errors.New("角色不存在")
+	}
+	grant = system.SysAuthorityGrant{
+		UserID:      user.ID,
+		Username:    user.Username,
+		AuthorityId: req.AuthorityId,
+		StartAt:     start,
+		EndAt:       req.EndAt,
+		Reason:      req.Reason,
+		Status:      system.GrantStatusPending,
+		OperatorID:  operatorID,
+		Operator:    operator,
+	}
+	if req.ApproverID != 0 {
+		if req.ApproverID == user.ID {
+			return grant, errors.New("审批人不能是被授权人")
+		}
+		if req.ApproverID == operatorID {
+			return grant, errors.New("审批人不能是授权人")
+		}
+		var approver system.SysUser
+		if err = global.GVA_DB.Where("id = ?", req.ApproverID).First(&approver).Error; err != nil {
+			return grant, errors.New("审批人不存在")
+		}
+		if err = a.checkApprover(approver.ID, req.AuthorityId); err != nil {
+			return grant, err
+		}
+		grant.ApproverID, grant.Approver = approver.ID, approver.Username
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&system.SysAuthorityGrant{}).
+			Where("user_id = ? AND authority_id = ? AND status IN ?", grant.UserID, grant.AuthorityId, []string{system.GrantStatusPending, system.GrantStatusActive}).
+			Where("start_at < ? AND end_at > ?", grant.EndAt, grant.StartAt).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("该时间段内已有相同角色的授权")
+		}
+		// 没有生效中的授权却已拥有该角色, 说明是长期角色
+		if err := tx.Model(&system.SysUserAuthority{}).
+			Where("sys_user_id = ? AND sys_authority_authority_id = ?", grant.UserID, grant.AuthorityId).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("用户已长期拥有该角色")
+		}
+		if err := tx.Create(&grant).Error; err != nil {
+			return err
+		}
+		if err := a.log(tx, grant, system.GrantActionGrant, grant.Reason, operatorID, operator); err != nil {
+			return err
+		}
+		if grant.StartAt.After(now) || grant.ApproverID != 0 {
+			return nil
+		}
+		return a.activate(tx, &grant)
+	})
+	return grant, err
+}
+
+// ApproveGrant
+// @function: ApproveGrant
+// @description: 审批人审批临时角色授权, 通过后开始时间已到时立即生效, 拒绝则结束授权
+// @param: req systemReq.AuthorityGrantApproveReq, operatorID uint, operator string
+// @return: err error
+func (a *AuthorityGrantService) ApproveGrant(req systemReq.AuthorityGrantApproveReq, operatorID uint, operator string) (err error) {
+	var grant system.SysAuthorityGrant
+	if err = global.GVA_DB.Where("id = ?", req.ID).First(&grant).Error; err != nil {
+		return errors.New("授权不存在")
+	}
+	if grant.ApproverID == 0 {
+		return errors.New("该授权无需审批")
+	}
+	if grant.ApproverID != operatorID {
+		return errors.New("只有指定的审批人可以审批")
+	}
+	if grant.UserID == operatorID || grant.OperatorID == operatorID {
+		return errors.New("不能审批自己申请或发起的授权")
+	}
+	if grant.Status != system.GrantStatusPending || grant.ApprovedAt != nil {
+		return errors.New("授权已审批或已结束")
+	}
+	if !req.Approved {
+		err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+			return a.deactivate(tx, grant, system.GrantStatusRejected, req.Reason, operatorID, operator)
+		})
+		if errors.Is(err, errGrantChanged) {
+			return errors.New("授权状态已变化, 请刷新后重试")
+		}
+		return err
+	}
+	// 审批人的角色可能在发起后变化, 通过前重新校验
+	if err = a.checkApprover(operatorID, grant.AuthorityId); err != nil {
+		return err
+	}
+	now := time.Now()
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&system.SysAuthorityGrant{}).Where("id = ? AND status = ? AND approved_at IS NULL", grant.ID, system.GrantStatusPending).
+			Update("approved_at", &now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errGrantChanged
+		}
+		grant.ApprovedAt = &now
+		if err := a.log(tx, grant, system.GrantActionApprove, req.Reason, operatorID, operator); err != nil {
+			return err
+		}
+		if grant.StartAt.After(now) || !grant.EndAt.After(now) {
+			return nil
+		}
+		return a.activate(tx, &grant)
+	})
+	if errors.Is(err, errGrantChanged) {
+		return errors.New("授权状态已变化, 请刷新后重试")
+	}
+	if err != nil || grant.Status != system.GrantStatusActive {
+		return err
+	}
+	return a.refresh([]uint{grant.UserID})
+}
+
+// RevokeGrant
+// @function: RevokeGrant
+// @description: 提前收回临时角色, 生效中的会移除用户角色并注销其在线会话
+// @param: req systemReq.AuthorityGrantRevokeReq, operatorID uint, operator string
+// @return: err error
+func (a *AuthorityGrantService) RevokeGrant(req systemReq.AuthorityGrantRevokeReq, operatorID uint, operator string) (err error) {
+	var grant system.SysAuthorityGrant
+	if err = global.GVA_DB.Where("id = ?", req.ID).First(&grant).Error; err != nil {
+		return errors.New("授权不存在")
+	}
+	if grant.Status != system.GrantStatusPending && grant.Status != system.GrantStatusActive {
+		return errors.New("授权已结束")
+	}
+	active := grant.Status == system.GrantStatusActive
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		return a.deactivate(tx, grant, system.GrantStatusRevoked, req.Reason, operatorID, operator)
+	})
+	if errors.Is(err, errGrantChanged) {
+		return errors.New("授权状态已变化, 请刷新后重试")
+	}
+	if err != nil || !active {
+		return err
+	}
+	return a.refresh([]uint{grant.UserID})
+}
+
+// ExpireGrants
+// @function: ExpireGrants
+// @description: 定时任务: 到达开始时间的授权生效, 到达结束时间的授权收回, 并刷新 casbin 与注销受影响用户的会话
+// @return: err error
+func (a *AuthorityGrantService) ExpireGrants() (err error) {
+	now := time.Now()
+	var pending []system.SysAuthorityGrant
+	if err = global.GVA_DB.Where("status = ? AND start_at <= ? AND end_at > ?", system.GrantStatusPending, now, now).
+		Where("(approver_id = 0 OR approved_at IS NOT NULL)"). // 需审批的授权通过后才生效
+		Find(&pending).Error; err != nil {
+		return
+	}
+	for i := range pending {
+		err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+			return a.activate(tx, &pending[i])
+		})
+		if err != nil && !errors.Is(err, errGrantChanged) {
+			global.GVA_LOG.Error("临时角色生效失败!", zap.Uint("grant", pending[i].ID), zap.Error(err))
+		}
+	}
+
+	var ended []system.SysAuthorityGrant
+	if err = global.GVA_DB.Where("status IN ? AND end_at <= ?", []string{system.GrantStatusPending, system.GrantStatusActive}, now).
+		Find(&ended).Error; err != nil {
+		return
+	}
+	var users []uint
+	for _, grant := range ended {
+		err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+			return a.deactivate(tx, grant, system.GrantStatusExpired, "到期自动收回", 0, "")
+		})
+		if err != nil {
+			if !errors.Is(err, errGrantChanged) {
+				global.GVA_LOG.Error("临时角色到期收回失败!", zap.Uint("grant", grant.ID), zap.Error(err))
+			}
+			continue
+		}
+		if grant.Status == system.GrantStatusActive && !slices.Contains(users, grant.UserID) {
+			users = append(users, grant.UserID)
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	return a.refresh(users)
+}
+
+// ActiveGrantAuthorities
+// @function: ActiveGrantAuthorities
+// @description: 用户生效中的临时角色, 重新分配长期角色时保留
+// @param: db *gorm.DB, userID uint
+// @return: authorityIds []uint, err error
+func (a *AuthorityGrantService) ActiveGrantAuthorities(db *gorm.DB, userID uint) (authorityIds []uint, err error) {
+	err = db.Model(&system.SysAuthorityGrant{}).Where("user_id = ? AND status = ?", userID, system.GrantStatusActive).
+		Pluck("authority_id", &authorityIds).Error
+	return
+}
+
+// GetGrantList
+// @function: GetGrantList
+// @description: 分页获取临时角色授权
+// @param: info systemReq.AuthorityGrantSearch
+// @return: list []system.SysAuthorityGrant, total int64, err error
+func (a *AuthorityGrantService) GetGrantList(info systemReq.AuthorityGrantSearch) (list []system.SysAuthorityGrant, total int64, err error) {
+	db := global.GVA_DB.Model(&system.SysAuthorityGrant{})
+	if info.UserID != 0 {
+		db = db.Where("user_id = ?", info.UserID)
+	}
+	if info.AuthorityId != 0 {
+		db = db.Where("authority_id = ?", info.AuthorityId)
+	}
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// GetGrantLogList
+// @function: GetGrantLogList
+// @description: 分页获取临时角色授权记录
+// @param: info systemReq.AuthorityGrantLogSearch
+// @return: list []system.SysAuthorityGrantLog, total int64, err error
+func (a *AuthorityGrantService) GetGrantLogList(info systemReq.AuthorityGrantLogSearch) (list []system.SysAuthorityGrantLog, total int64, err error) {
+	db := global.GVA_DB.Model(&system.SysAuthorityGrantLog{})
+	if info.GrantID != 0 {
+		db = db.Where("grant_id = ?", info.GrantID)
+	}
+	if info.UserID != 0 {
+		db = db.Where("user_id = ?", info.UserID)
+	}
+	if info.Action != "" {
+		db = db.Where("action = ?", info.Action)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// activate 加入用户角色并标记为生效中
+func (a *AuthorityGrantService) activate(tx *gorm.DB, grant *system.SysAuthorityGrant) error {
+	res := tx.Model(&system.SysAuthorityGrant{}).Where("id = ? AND status = ?", grant.ID, system.GrantStatusPending).
+		Update("status", system.GrantStatusActive)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errGrantChanged
+	}
+	grant.Status = system.GrantStatusActive
+	var count int64
+	if err := tx.Model(&system.SysUserAuthority{}).
+		Where("sys_user_id = ? AND sys_authority_authority_id = ?", grant.UserID, grant.AuthorityId).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		if err := tx.Create(&system.SysUserAuthority{SysUserId: grant.UserID, SysAuthorityAuthorityId: grant.AuthorityId}).Error; err != nil {
+			return err
+		}
+	}
+	return a.log(tx, *grant, system.GrantActionActivate, "", 0, "")
+}
+
+// deactivate 结束授权, 生效中的移除用户角色, 当前角色是该角色时切换到剩余的第一个角色, 没有剩余角色时回到默认角色
+func (a *AuthorityGrantService) deactivate(tx *gorm.DB, grant system.SysAuthorityGrant, status, reason string, operatorID uint, operator string) error {
+	values := map[string]interface{}{"status": status}
+	action := system.GrantActionExpire
+	if status == system.GrantStatusRevoked || status == system.GrantStatusRejected {
+		now := time.Now()
+		action = system.GrantActionRevoke
+		if status == system.GrantStatusRejected {
+			action = system.GrantActionReject
+		}
+		values["revoked_at"], values["revoked_by"], values["revoke_reason"] = &now, operator, reason
+	}
+	res := tx.Model(&system.SysAuthorityGrant{}).Where("id = ? AND status = ?", grant.ID, grant.Status).Updates(values)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errGrantChanged
+	}
+	if grant.Status == system.GrantStatusActive {
+		if err := tx.Delete(&system.SysUserAuthority{}, "sys_user_id = ? AND sys_authority_authority_id = ?", grant.UserID, grant.AuthorityId).Error; err != nil {
+			return err
+		}
+		var user system.SysUser
+		if err := tx.Select("id", "authority_id").Where("id = ?", grant.UserID).First(&user).Error; err == nil && user.AuthorityId == grant.AuthorityId {
+			var remain []uint
+			if err = tx.Model(&system.SysUserAuthority{}).Where("sys_user_id = ?", grant.UserID).
+				Pluck("sys_authority_authority_id", &remain).Error; err != nil {
+				return err
+			}
+			// 与 SCIM 移除成员一致, 没有剩余角色时回到默认角色, 避免当前角色仍指向已收回的角色
+			if len(remain) == 0 {
+				if err = tx.Create(&system.SysUserAuthority{SysUserId: grant.UserID, SysAuthorityAuthorityId: system.NormalAuthorityId}).Error; err != nil {
+					return err
+				}
+				remain = []uint{system.NormalAuthorityId}
+			}
+			if err = tx.Model(&system.SysUser{}).Where("id = ?", grant.UserID).Update("authority_id", slices.Min(remain)).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return a.log(tx, grant, action, reason, operatorID, operator)
+}
+
+// checkApprover 审批人须拥有超级管理员角色或目标角色的上级角色, 不受 UseStrictAuth 影响
+func (a *AuthorityGrantService) checkApprover(approverID, authorityID uint) error {
+	var approver system.SysUser
+	if err := global.GVA_DB.Select("id", "authority_id").Where("id = ?", approverID).First(&approver).Error; err != nil {
+		return errors.New("审批人不存在")
+	}
+	var held []uint
+	if err := global.GVA_DB.Model(&system.SysUserAuthority{}).Where("sys_user_id = ?", approverID).
+		Pluck("sys_authority_authority_id", &held).Error; err != nil {
+		return err
+	}
+	held = append(held, approver.AuthorityId)
+	if slices.Contains(held, system.AdminAuthorityId) {
+		return nil
+	}
+	// 自目标角色逐级向上查找, 限制层数避免父角色成环
+	for i, id := 0, authorityID; i < 32; i++ {
+		var authority system.SysAuthority
+		if err := global.GVA_DB.Select("authority_id", "parent_id").Where("authority_id = ?", id).First(&authority).Error; err != nil ||
+			authority.ParentId == nil || *authority.ParentId == 0 {
+			break
+		}
+		id = *authority.ParentId
+		if slices.Contains(held, id) {
+			return nil
+		}
+	}
+	return errors.New("审批人没有管理该角色的权限")
+}
+
+// refresh 刷新 casbin 并注销用户的在线会话, 使携带旧角色的令牌失效
+func (a *AuthorityGrantService) refresh(users []uint) error {
+	for _, userID := range users {
+		if err := SessionServiceApp.RevokeUser(userID, system.SessionRevokeGrant); err != nil {
+			global.GVA_LOG.Error("注销会话失败!", zap.Uint("user", userID), zap.Error(err))
+		}
+	}
+	return CasbinServiceApp.FreshCasbin()
+}
+
+func (a *AuthorityGrantService) log(tx *gorm.DB, grant system.SysAuthorityGrant, action, reason string, operatorID uint, operator string) error {
+	return tx.Create(&system.SysAuthorityGrantLog{
+		GrantID:     grant.ID,
+		UserID:      grant.UserID,
+		AuthorityId: grant.AuthorityId,
+		Action:      action,
+		Reason:      reason,
+		OperatorID:  operatorID,
+		Operator:    operator,
+	}).Error
+}
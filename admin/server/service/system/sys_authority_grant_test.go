@@ -0,0 +1,130 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"gorm.io/gorm"
+)
+
+func TestAuthorityGrantApproval(t *testing.T) {
+	db := newTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{},
+		&system.SysAuthorityGrant{}, &system.SysAuthorityGrantLog{})
+	users := []system.SysUser{
+		{Username: "alice", AuthorityId: system.NormalAuthorityId}, // 被授权人
+		{Username: "admin", AuthorityId: system.AdminAuthorityId},  // 授权人
+		{Username: "boss", AuthorityId: system.AdminAuthorityId},   // 审批人
+	}
+	for i := range users {
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	alice, admin, boss := users[0], users[1], users[2]
+	if err := db.Create(&system.SysAuthority{AuthorityId: 9528, AuthorityName: "运维"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	service := &AuthorityGrantService{}
+	req := systemReq.AuthorityGrantReq{UserID: alice.ID, AuthorityId: 9528, EndAt: time.Now().Add(time.Hour), Reason: "排查故障"}
+
+	req.ApproverID = admin.ID
+	if _, err := service.CreateGrant(system.AdminAuthorityId, req, admin.ID, admin.Username); err == nil {
+		t.Fatalf("CreateGrant() 授权人不能指定自己为审批人")
+	}
+
+	self := req
+	self.UserID, self.ApproverID = admin.ID, boss.ID
+	if _, err := service.CreateGrant(system.AdminAuthorityId, self, admin.ID, admin.Username); err == nil {
+		t.Fatalf("CreateGrant() 不能为自己授权")
+	}
+
+	// 审批人须能管理目标角色, 与是否开启 UseStrictAuth 无关
+	carol := system.SysUser{Username: "carol", AuthorityId: 9600}
+	db.Create(&carol)
+	db.Create(&system.SysAuthority{AuthorityId: 9600, AuthorityName: "普通用户"})
+	req.ApproverID = carol.ID
+	if _, err := service.CreateGrant(system.AdminAuthorityId, req, admin.ID, admin.Username); err == nil {
+		t.Fatalf("CreateGrant() 审批人不能管理目标角色")
+	}
+	parent := uint(9600)
+	db.Model(&system.SysAuthority{}).Where("authority_id = ?", 9528).Update("parent_id", &parent)
+	if err := service.checkApprover(carol.ID, 9528); err != nil {
+		t.Fatalf("checkApprover() 上级角色可以审批: %v", err)
+	}
+	db.Model(&system.SysAuthority{}).Where("authority_id = ?", 9528).Update("parent_id", 0)
+
+	req.ApproverID = boss.ID
+	grant, err := service.CreateGrant(system.AdminAuthorityId, req, admin.ID, admin.Username)
+	if err != nil {
+		t.Fatalf("CreateGrant() error = %v", err)
+	}
+	hasRole := func() bool {
+		var count int64
+		db.Model(&system.SysUserAuthority{}).Where("sys_user_id = ? AND sys_authority_authority_id = ?", alice.ID, 9528).Count(&count)
+		return count > 0
+	}
+	if grant.Status != system.GrantStatusPending || hasRole() {
+		t.Fatalf("需审批的授权不应立即生效: %s", grant.Status)
+	}
+	if err = service.ExpireGrants(); err != nil {
+		t.Fatal(err)
+	}
+	if hasRole() {
+		t.Fatalf("未审批的授权不应由定时任务生效")
+	}
+
+	for _, operator := range []system.SysUser{alice, admin} {
+		if err = service.ApproveGrant(systemReq.AuthorityGrantApproveReq{ID: grant.ID, Approved: true}, operator.ID, operator.Username); err == nil {
+			t.Fatalf("ApproveGrant() %s 不是审批人, 不能审批", operator.Username)
+		}
+	}
+
+	// 审批时尚未到开始时间, 由定时任务生效
+	db.Model(&system.SysAuthorityGrant{}).Where("id = ?", grant.ID).Update("start_at", time.Now().Add(time.Minute))
+	if err = service.ApproveGrant(systemReq.AuthorityGrantApproveReq{ID: grant.ID, Approved: true}, boss.ID, boss.Username); err != nil {
+		t.Fatalf("ApproveGrant() error = %v", err)
+	}
+	if err = service.ApproveGrant(systemReq.AuthorityGrantApproveReq{ID: grant.ID, Approved: true}, boss.ID, boss.Username); err == nil {
+		t.Fatalf("ApproveGrant() 不能重复审批")
+	}
+	db.Model(&system.SysAuthorityGrant{}).Where("id = ?", grant.ID).Update("start_at", time.Now().Add(-time.Minute))
+	if err = service.ExpireGrants(); err != nil {
+		t.Fatal(err)
+	}
+	if !hasRole() {
+		t.Fatalf("审批通过后应生效")
+	}
+
+	// 生效中的授权结束时, 用户没有其他角色则回到默认角色
+	db.Model(&system.SysUser{}).Where("id = ?", alice.ID).Update("authority_id", 9528)
+	db.Where("id = ?", grant.ID).First(&grant)
+	if err = db.Transaction(func(tx *gorm.DB) error {
+		return service.deactivate(tx, grant, system.GrantStatusExpired, "", 0, "")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var user system.SysUser
+	db.Where("id = ?", alice.ID).First(&user)
+	var count int64
+	db.Model(&system.SysUserAuthority{}).Where("sys_user_id = ? AND sys_authority_authority_id = ?", alice.ID, system.NormalAuthorityId).Count(&count)
+	if hasRole() || user.AuthorityId != system.NormalAuthorityId || count != 1 {
+		t.Errorf("收回后 authority_id = %d, 默认角色关联 %d", user.AuthorityId, count)
+	}
+
+	// 审批拒绝
+	req.AuthorityId = system.AdminAuthorityId
+	db.Create(&system.SysAuthority{AuthorityId: system.AdminAuthorityId, AuthorityName: "管理员"})
+	rejected, err := service.CreateGrant(system.AdminAuthorityId, req, admin.ID, admin.Username)
+	if err != nil {
+		t.Fatalf("CreateGrant() error = %v", err)
+	}
+	if err = service.ApproveGrant(systemReq.AuthorityGrantApproveReq{ID: rejected.ID, Reason: "无需管理员"}, boss.ID, boss.Username); err != nil {
+		t.Fatalf("ApproveGrant() error = %v", err)
+	}
+	db.Where("id = ?", rejected.ID).First(&rejected)
+	if rejected.Status != system.GrantStatusRejected {
+		t.Errorf("拒绝后状态 = %s, want %s", rejected.Status, system.GrantStatusRejected)
+	}
+}
@@ -0,0 +1,578 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// 接口返回的失败明细条数, 完整明细见错误报告
+	importMaxErrors = 100
+	// 批量写入失败时逐行重试使用的保存点
+	importSavePoint = "gva_import"
+)
+
+// importTypes 校验规则支持的类型, 为空时按字符串原样写入
+var importTypes = map[string]bool{
+	"": true, system.ImportTypeString: true, system.ImportTypeInt: true, system.ImportTypeFloat: true,
+	system.ImportTypeBool: true, system.ImportTypeDate: true, system.ImportTypeDatetime: true,
+}
+
+// errImportDryRun 试运行结束时回滚事务
+var errImportDryRun = errors.New("dry run")
+
+var importDateLayouts = []string{"2006-01-02", "2006/01/02", "2006/1/2", "2006.01.02", "01-02-06", "1/2/06"}
+
+var importDatetimeLayouts = []string{
+	"2006-01-02 15:04:05", "2006/01/02 15:04:05", "2006/1/2 15:04:05", "2006-01-02 15:04", "2006/1/2 15:04",
+	time.RFC3339, "1/2/06 15:04",
+}
+
+// importColumn 表头中可以导入的列
+type importColumn struct {
+	index   int    // 所在列
+	key     string // 数据库字段
+	title   string
+	rule    system.ImportRule
+	pattern *regexp.Regexp
+}
+
+// importRow 校验通过、等待写入的行
+type importRow struct {
+	line  int      // Excel 行号
+	cells []string // 原始内容, 写入失败时输出到错误报告
+	item  map[string]interface{}
+}
+
+// importer 单次导入的状态
+type importer struct {
+	tx         *gorm.DB
+	table      string
+	mode       string
+	key        string
+	columns    []importColumn
+	header     []string
+	hasCreated bool
+	hasUpdated bool
+	batch      []importRow
+	batchKeys  map[string]bool
+	result     *systemRes.ImportExcelResult
+	report     *importReport
+}
+
+// ImportExcel
+// @function: ImportExcel
+// @description: 按模板导入表格, 支持新增、按键新增或更新、仅更新三种模式, 逐行校验并转换类型, 失败的行写入错误报告
+// @param: req systemReq.ImportExcelReq, file *multipart.FileHeader, userID uint
+// @return: result systemRes.ImportExcelResult, err error
+func (sysExportTemplateService *SysExportTemplateService) ImportExcel(req systemReq.ImportExcelReq, file *multipart.FileHeader, userID uint) (result systemRes.ImportExcelResult, err error) {
+	if req.Mode == "" {
+		req.Mode = system.ImportModeInsert
+	}
+	result = systemRes.ImportExcelResult{Mode: req.Mode, DryRun: req.DryRun, Errors: []systemRes.ImportRowError{}}
+	switch req.Mode {
+	case system.ImportModeInsert, system.ImportModeUpsert, system.ImportModeUpdate:
+	default:
+		return result, fmt.Errorf("不支持的导入模式 %s", req.Mode)
+	}
+	var template system.SysExportTemplate
+	if err = global.GVA_DB.First(&template, "template_id = ?", req.TemplateID).Error; err != nil {
+		return result, errors.New("导出模板不存在")
+	}
+	schema, err := sysExportTemplateService.checkTemplate(&template)
+	if err != nil {
+		return result, err
+	}
+	if req.Mode != system.ImportModeInsert && template.ImportKey == "" {
+		return result, errors.New("模板未配置导入匹配字段, 只能新增")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return result, err
+	}
+	defer src.Close()
+	f, err := excelize.OpenReader(src)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+	sheet, err := importSheet(f, req.Sheet)
+	if err != nil {
+		return result, err
+	}
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return result, errors.New("表格为空")
+	}
+	header, err := rows.Columns()
+	if err != nil {
+		return result, err
+	}
+	columns, err := importColumns(&template, schema, header)
+	if err != nil {
+		return result, err
+	}
+	imp := &importer{
+		table:      template.TableName,
+		mode:       req.Mode,
+		key:        template.ImportKey,
+		columns:    columns,
+		header:     header,
+		hasCreated: schema.fields()["created_at"] != nil,
+		hasUpdated: schema.fields()["updated_at"] != nil,
+		batchKeys:  make(map[string]bool),
+		result:     &result,
+		report:     &importReport{},
+	}
+	defer imp.report.discard()
+	if imp.key != "" && req.Mode != system.ImportModeInsert {
+		found := false
+		for _, column := range columns {
+			found = found || column.key == imp.key
+		}
+		if !found {
+			return result, fmt.Errorf("表格中缺少导入匹配字段 %s", imp.key)
+		}
+	}
+
+	db := schema.db
+	err = db.Transaction(func(tx *gorm.DB) error {
+		imp.tx = tx
+		for line := 2; rows.Next(); line++ {
+			cells, err := rows.Columns()
+			if err != nil {
+				return err
+			}
+			if err = imp.add(line, cells); err != nil {
+				return err
+			}
+		}
+		if err := rows.Error(); err != nil {
+			return err
+		}
+		if err := imp.flush(); err != nil {
+			return err
+		}
+		if req.DryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return result, err
+	}
+	if imp.report.rows > 0 {
+		name := template.Name + "导入错误" + utils.RandomString(6) + ".xlsx"
+		path, err := imp.report.save()
+		if err != nil {
+			return result, err
+		}
+		defer os.Remove(path)
+		job, err := ExportJobServiceApp.StoreFile(system.ExportKindImportErrors, name, path, int64(imp.report.rows), userID)
+		if err != nil {
+			return result, fmt.Errorf("保存错误报告失败: %w", err)
+		}
+		result.ErrorJob = &job
+	}
+	return result, nil
+}
+
+// importSheet 指定的工作表, 未指定时优先 Sheet1, 否则取第一个工作表
+func importSheet(f *excelize.File, sheet string) (string, error) {
+	sheets := f.GetSheetList()
+	if sheet != "" {
+		for _, name := range sheets {
+			if name == sheet {
+				return sheet, nil
+			}
+		}
+		return "", fmt.Errorf("工作表 %s 不存在", sheet)
+	}
+	for _, name := range sheets {
+		if name == "Sheet1" {
+			return name, nil
+		}
+	}
+	if len(sheets) == 0 {
+		return "", errors.New("表格中没有工作表")
+	}
+	return sheets[0], nil
+}
+
+// importColumns 按表头匹配模板字段, 表头可以是模板中的标题或字段名, 关联表的字段不导入
+func importColumns(template *system.SysExportTemplate, schema *exportSchema, header []string) ([]importColumn, error) {
+	keys, err := utils.GetJSONKeys(template.TemplateInfo)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]string)
+	if err = json.Unmarshal([]byte(template.TemplateInfo), &titles); err != nil {
+		return nil, err
+	}
+	byHeader := make(map[string]importColumn)
+	for _, key := range keys {
+		m := exportSelectPattern.FindStringSubmatch(strings.TrimSpace(key))
+		if m[1] != "" && m[1] != schema.main {
+			continue
+		}
+		column := importColumn{key: m[2], title: titles[key], rule: template.ImportRules[key]}
+		if rule, ok := template.ImportRules[m[2]]; ok {
+			column.rule = rule
+		}
+		if column.rule.Pattern != "" {
+			if column.pattern, err = regexp.Compile(column.rule.Pattern); err != nil {
+				return nil, fmt.Errorf("字段 %s 的校验规则不合法: %w", column.title, err)
+			}
+		}
+		byHeader[column.title] = column
+		byHeader[key] = column
+	}
+	var columns []importColumn
+	seen := make(map[string]bool)
+	for i, title := range header {
+		column, ok := byHeader[strings.TrimSpace(title)]
+		if !ok || seen[column.key] {
+			continue
+		}
+		seen[column.key] = true
+		column.index = i
+		columns = append(columns, column)
+	}
+	for _, column := range byHeader {
+		if column.rule.Required && !seen[column.key] {
+			return nil, fmt.Errorf("表格中缺少必填列 %s", column.title)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("表头与模板字段不匹配")
+	}
+	return columns, nil
+}
+
+// add 校验并转换一行, 校验失败的行直接写入错误报告
+func (imp *importer) add(line int, cells []string) error {
+	blank := true
+	for _, cell := range cells {
+		blank = blank && strings.TrimSpace(cell) == ""
+	}
+	if blank {
+		return nil
+	}
+	imp.result.Total++
+	item := make(map[string]interface{}, len(imp.columns)+2)
+	var reasons []string
+	for _, column := range imp.columns {
+		var raw string
+		if column.index < len(cells) {
+			raw = strings.TrimSpace(cells[column.index])
+		}
+		value, err := importValue(raw, column)
+		if err != nil {
+			reasons = append(reasons, column.title+": "+err.Error())
+			continue
+		}
+		item[column.key] = value
+	}
+	var key string
+	if imp.mode != system.ImportModeInsert && len(reasons) == 0 {
+		if item[imp.key] == nil {
+			reasons = append(reasons, "匹配字段为空")
+		} else {
+			key = fmt.Sprint(item[imp.key])
+		}
+	}
+	if len(reasons) > 0 {
+		return imp.fail(line, cells, strings.Join(reasons, "; "))
+	}
+	now := time.Now()
+	if imp.hasCreated && item["created_at"] == nil {
+		item["created_at"] = now
+	}
+	if imp.hasUpdated && item["updated_at"] == nil {
+		item["updated_at"] = now
+	}
+	// 同一批次中出现重复的匹配值时先写入前面的行, 后面的行按更新处理
+	if key != "" && imp.batchKeys[key] {
+		if err := imp.flush(); err != nil {
+			return err
+		}
+	}
+	if key != "" {
+		imp.batchKeys[key] = true
+	}
+	imp.batch = append(imp.batch, importRow{line: line, cells: cells, item: item})
+	batchSize := global.GVA_CONFIG.Excel.BatchSize
+	if batchSize <= 0 {
+		batchSize = exportDefaultBatchSize
+	}
+	if len(imp.batch) >= batchSize {
+		return imp.flush()
+	}
+	return nil
+}
+
+// flush 写入当前批次
+func (imp *importer) flush() error {
+	batch := imp.batch
+	imp.batch, imp.batchKeys = nil, make(map[string]bool)
+	if len(batch) == 0 {
+		return nil
+	}
+	if imp.mode == system.ImportModeInsert {
+		return imp.insert(batch)
+	}
+	keys := make([]interface{}, 0, len(batch))
+	for _, row := range batch {
+		keys = append(keys, row.item[imp.key])
+	}
+	var existing []map[string]interface{}
+	if err := imp.tx.Table(imp.table).Select(imp.key).Where(imp.key+" IN ?", keys).Find(&existing).Error; err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, row := range existing {
+		exists[fmt.Sprint(exportCell(row[imp.key]))] = true
+	}
+	var inserts []importRow
+	for _, row := range batch {
+		key := fmt.Sprint(exportCell(row.item[imp.key]))
+		if !exists[key] {
+			if imp.mode == system.ImportModeUpdate {
+				if err := imp.fail(row.line, row.cells, "记录不存在"); err != nil {
+					return err
+				}
+				continue
+			}
+			inserts = append(inserts, row)
+			continue
+		}
+		updates := make(map[string]interface{}, len(row.item))
+		for k, v := range row.item {
+			if k != imp.key && k != "created_at" {
+				updates[k] = v
+			}
+		}
+		err := imp.savePoint(func(tx *gorm.DB) error {
+			return tx.Table(imp.table).Where(imp.key+" = ?", row.item[imp.key]).Updates(updates).Error
+		})
+		if err != nil {
+			if err = imp.fail(row.line, row.cells, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		imp.result.Updated++
+	}
+	return imp.insert(inserts)
+}
+
+// insert 批量新增, 失败时逐行重试以找出出错的行
+func (imp *importer) insert(rows []importRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	items := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		items[i] = row.item
+	}
+	err := imp.savePoint(func(tx *gorm.DB) error {
+		return tx.Table(imp.table).Create(&items).Error
+	})
+	if err == nil {
+		imp.result.Inserted += len(rows)
+		return nil
+	}
+	for _, row := range rows {
+		err = imp.savePoint(func(tx *gorm.DB) error {
+			return tx.Table(imp.table).Create(row.item).Error
+		})
+		if err != nil {
+			if err = imp.fail(row.line, row.cells, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		imp.result.Inserted++
+	}
+	return nil
+}
+
+// savePoint 在保存点中执行, 失败时回滚到保存点, 事务可以继续使用
+func (imp *importer) savePoint(fn func(tx *gorm.DB) error) error {
+	if err := imp.tx.SavePoint(importSavePoint).Error; err != nil {
+		return err
+	}
+	if err := fn(imp.tx); err != nil {
+		if rErr := imp.tx.RollbackTo(importSavePoint).Error; rErr != nil {
+			return rErr
+		}
+		return err
+	}
+	return nil
+}
+
+// fail 记录失败的行
+func (imp *importer) fail(line int, cells []string, reason string) error {
+	imp.result.Failed++
+	if len(imp.result.Errors) < importMaxErrors {
+		imp.result.Errors = append(imp.result.Errors, systemRes.ImportRowError{Row: line, Reason: reason})
+	}
+	return imp.report.add(imp.header, line, cells, reason)
+}
+
+// importValue 按规则校验并转换单元格内容
+func importValue(raw string, column importColumn) (interface{}, error) {
+	rule := column.rule
+	if raw == "" {
+		if rule.Required {
+			return nil, errors.New("不能为空")
+		}
+		if rule.Type == system.ImportTypeString {
+			return "", nil
+		}
+		return nil, nil
+	}
+	if len(rule.Enum) > 0 && !slices.Contains(rule.Enum, raw) {
+		return nil, fmt.Errorf("只能是 %s 之一", strings.Join(rule.Enum, "/"))
+	}
+	if column.pattern != nil && !column.pattern.MatchString(raw) {
+		return nil, errors.New("格式不正确")
+	}
+	switch rule.Type {
+	case system.ImportTypeInt, system.ImportTypeFloat:
+		n, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", ""), 64)
+		if err != nil {
+			return nil, errors.New("不是数字")
+		}
+		if rule.Min != nil && n < *rule.Min {
+			return nil, fmt.Errorf("不能小于 %v", *rule.Min)
+		}
+		if rule.Max != nil && n > *rule.Max {
+			return nil, fmt.Errorf("不能大于 %v", *rule.Max)
+		}
+		if rule.Type == system.ImportTypeFloat {
+			return n, nil
+		}
+		if n != math.Trunc(n) {
+			return nil, errors.New("不是整数")
+		}
+		return int64(n), nil
+	case system.ImportTypeBool:
+		switch strings.ToLower(raw) {
+		case "1", "true", "yes", "y", "是":
+			return true, nil
+		case "0", "false", "no", "n", "否":
+			return false, nil
+		}
+		return nil, errors.New("不是布尔值")
+	case system.ImportTypeDate:
+		return importTime(raw, importDateLayouts)
+	case system.ImportTypeDatetime:
+		return importTime(raw, append(importDatetimeLayouts, importDateLayouts...))
+	}
+	if rule.MaxLen > 0 && utf8.RuneCountInString(raw) > rule.MaxLen {
+		return nil, fmt.Errorf("长度不能超过 %d", rule.MaxLen)
+	}
+	return raw, nil
+}
+
+// importTime 解析日期, 同时支持 Excel 的日期序列号
+func importTime(raw string, layouts []string) (interface{}, error) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+		if t, err := excelize.ExcelDateToTime(serial, false); err == nil {
+			return t, nil
+		}
+	}
+	return nil, errors.New("不是有效的日期")
+}
+
+// importReport 错误报告, 原始列之后追加行号与失败原因
+type importReport struct {
+	f    *excelize.File
+	sw   *excelize.StreamWriter
+	rows int
+}
+
+func (r *importReport) add(header []string, line int, cells []string, reason string) (err error) {
+	if r.f == nil {
+		r.f = excelize.NewFile()
+		if r.sw, err = r.f.NewStreamWriter("Sheet1"); err != nil {
+			return err
+		}
+		if err = r.write(append(append([]string{}, header...), "行号", "失败原因")); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(header))
+	copy(row, cells)
+	return r.write(append(row, strconv.Itoa(line), reason))
+}
+
+func (r *importReport) write(cells []string) error {
+	r.rows++
+	values := make([]interface{}, len(cells))
+	for i, cell := range cells {
+		values[i] = cell
+	}
+	cell, err := excelize.CoordinatesToCellName(1, r.rows)
+	if err != nil {
+		return err
+	}
+	return r.sw.SetRow(cell, values)
+}
+
+// save 写入临时文件并返回路径
+func (r *importReport) save() (string, error) {
+	// 表头不计入失败行数
+	r.rows--
+	if err := r.sw.Flush(); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp("", "gva-import-*.xlsx")
+	if err != nil {
+		return "", err
+	}
+	err = r.f.Write(tmp)
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func (r *importReport) discard() {
+	if r.f != nil {
+		_ = r.f.Close()
+	}
+}
@@ -0,0 +1,469 @@
+package system
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/upload"
+	"go.uber.org/zap"
+)
+
+const (
+	// 同时运行的导出任务数
+	exportJobConcurrency = 2
+	// 没有新任务通知时轮询数据库的间隔, 多实例部署时由其他实例创建的任务也能被领取
+	exportJobPollInterval = 30 * time.Second
+	// 进度写入间隔, 同时作为运行中任务的心跳
+	exportJobFlushInterval = 2 * time.Second
+	// 运行中的任务超过该时间没有心跳视为已中断
+	exportJobStaleAfter = 5 * time.Minute
+	// 每次清理的过期文件数
+	exportJobClearBatch = 100
+
+	exportDefaultRetention  = 7  // 天
+	exportDefaultLinkExpire = 30 // 分钟
+)
+
+// ExportJobContext 处理器运行时的上下文, 用于上报进度
+type ExportJobContext struct {
+	Job   *system.SysExportJob
+	done  atomic.Int64
+	total atomic.Int64
+}
+
+// SetTotal 设置预计导出的行数
+func (c *ExportJobContext) SetTotal(total int64) {
+	c.total.Store(total)
+}
+
+// Add 增加已导出的行数
+func (c *ExportJobContext) Add(n int64) {
+	c.done.Add(n)
+}
+
+// progress 完成前进度最多为99
+func (c *ExportJobContext) progress() int {
+	total := c.total.Load()
+	if total <= 0 {
+		return 0
+	}
+	return int(min(c.done.Load()*100/total, 99))
+}
+
+// ExportJobHandler 一类导出任务的处理器
+type ExportJobHandler interface {
+	// Prepare 入队前校验参数, 返回不含扩展名的文件名
+	Prepare(params json.RawMessage) (name string, err error)
+	// Run 在后台按 ctx.Job.Format 生成文件写入 w, 返回写出的行数
+	Run(ctx *ExportJobContext, params json.RawMessage, w io.Writer) (rows int64, err error)
+}
+
+var exportJobHandlers = make(map[string]ExportJobHandler)
+
+// RegisterExportJobHandler 注册导出任务处理器, 需在启动工作协程前调用
+func RegisterExportJobHandler(kind string, handler ExportJobHandler) {
+	exportJobHandlers[kind] = handler
+}
+
+var (
+	exportJobOnce sync.Once
+	exportJobWake = make(chan struct{}, 1)
+)
+
+// StartExportJobWorkers 启动导出任务的工作协程, 服务重启后继续处理排队中的任务
+func StartExportJobWorkers() {
+	exportJobOnce.Do(func() {
+		for i := 0; i < exportJobConcurrency; i++ {
+			go exportJobWorker()
+		}
+	})
+}
+
+func exportJobWorker() {
+	ticker := time.NewTicker(exportJobPollInterval)
+	defer ticker.Stop()
+	for {
+		for ExportJobServiceApp.runNext() {
+		}
+		select {
+		case <-exportJobWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+type ExportJobService struct{}
+
+var ExportJobServiceApp = new(ExportJobService)
+
+// Enqueue
+// @function: Enqueue
+// @description: 创建导出任务并通知工作协程, params 由对应类型的处理器解析
+// @param: kind string, format string, params interface{}, userID uint
+// @return: job system.SysExportJob, err error
+func (s *ExportJobService) Enqueue(kind, format string, params interface{}, userID uint) (job system.SysExportJob, err error) {
+	handler, ok := exportJobHandlers[kind]
+	if !ok {
+		return job, fmt.Errorf("不支持的导出类型: %s", kind)
+	}
+	if format == "" {
+		format = system.ExportFormatXlsx
+	}
+	if !ValidExportFormat(format) {
+		return job, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return job, err
+	}
+	name, err := handler.Prepare(raw)
+	if err != nil {
+		return job, err
+	}
+	job = system.SysExportJob{
+		Kind:   kind,
+		Name:   name + utils.RandomString(6) + "." + format,
+		Format: format,
+		Params: string(raw),
+		Status: system.ExportJobPending,
+		UserID: userID,
+	}
+	if err = global.GVA_DB.Create(&job).Error; err != nil {
+		return job, err
+	}
+	select {
+	case exportJobWake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// StoreFile
+// @function: StoreFile
+// @description: 将已生成的本地文件上传至OSS并记为已完成的任务, 与队列中的导出共用历史、下载链接与过期清理
+// @param: kind string, name string, path string, rows int64, userID uint
+// @return: job system.SysExportJob, err error
+func (s *ExportJobService) StoreFile(kind, name, path string, rows int64, userID uint) (job system.SysExportJob, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return job, err
+	}
+	fileURL, key, err := upload.UploadLocalFile(upload.NewOss(), path, name)
+	if err != nil {
+		return job, err
+	}
+	now := time.Now()
+	expiresAt := exportJobExpiresAt(now)
+	job = system.SysExportJob{
+		Kind:       kind,
+		Name:       name,
+		Format:     strings.TrimPrefix(filepath.Ext(name), "."),
+		Status:     system.ExportJobSuccess,
+		Progress:   100,
+		Total:      rows,
+		Rows:       rows,
+		Size:       info.Size(),
+		FileURL:    fileURL,
+		FileKey:    key,
+		UserID:     userID,
+		StartedAt:  &now,
+		FinishedAt: &now,
+		ExpiresAt:  &expiresAt,
+	}
+	err = global.GVA_DB.Create(&job).Error
+	return job, err
+}
+
+// GetExportJobList
+// @function: GetExportJobList
+// @description: 分页获取用户自己的导出历史
+// @param: info systemReq.SysExportJobSearch, userID uint
+// @return: list []system.SysExportJob, total int64, err error
+func (s *ExportJobService) GetExportJobList(info systemReq.SysExportJobSearch, userID uint) (list []system.SysExportJob, total int64, err error) {
+	db := global.GVA_DB.Model(&system.SysExportJob{}).Where("user_id = ?", userID)
+	if info.Kind != "" {
+		db = db.Where("kind = ?", info.Kind)
+	}
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// GetExportJob
+// @function: GetExportJob
+// @description: 获取用户自己的导出任务
+// @param: id uint, userID uint
+// @return: job system.SysExportJob, err error
+func (s *ExportJobService) GetExportJob(id, userID uint) (job system.SysExportJob, err error) {
+	err = global.GVA_DB.Where("id = ? AND user_id = ?", id, userID).First(&job).Error
+	return job, err
+}
+
+// DeleteExportJob
+// @function: DeleteExportJob
+// @description: 删除用户自己的导出任务及其文件, 运行中的任务不能删除
+// @param: id uint, userID uint
+// @return: err error
+func (s *ExportJobService) DeleteExportJob(id, userID uint) (err error) {
+	job, err := s.GetExportJob(id, userID)
+	if err != nil {
+		return errors.New("导出任务不存在")
+	}
+	if job.Status == system.ExportJobRunning {
+		return errors.New("任务运行中, 无法删除")
+	}
+	if job.FileKey != "" {
+		if err = upload.NewOss().DeleteFile(job.FileKey); err != nil {
+			return fmt.Errorf("删除导出文件失败: %w", err)
+		}
+	}
+	return global.GVA_DB.Delete(&job).Error
+}
+
+// GetDownloadLink
+// @function: GetDownloadLink
+// @description: 为已完成的导出任务生成带签名的限时下载链接
+// @param: id uint, userID uint
+// @return: link systemRes.ExportJobLink, err error
+func (s *ExportJobService) GetDownloadLink(id, userID uint) (link systemRes.ExportJobLink, err error) {
+	job, err := s.GetExportJob(id, userID)
+	if err != nil {
+		return link, errors.New("导出任务不存在")
+	}
+	if job.Status != system.ExportJobSuccess {
+		return link, errors.New("导出文件不可下载")
+	}
+	expire := global.GVA_CONFIG.Excel.LinkExpire
+	if expire <= 0 {
+		expire = exportDefaultLinkExpire
+	}
+	return exportJobLink(job, time.Now().Add(time.Duration(expire)*time.Minute)), nil
+}
+
+// exportJobLink 生成签名下载链接, 有效期不超过文件的保留时间
+func exportJobLink(job system.SysExportJob, expiresAt time.Time) (link systemRes.ExportJobLink) {
+	link.ExpiresAt = expiresAt
+	if job.ExpiresAt != nil && job.ExpiresAt.Before(link.ExpiresAt) {
+		link.ExpiresAt = *job.ExpiresAt
+	}
+	query := url.Values{}
+	query.Set("id", fmt.Sprint(job.ID))
+	query.Set("expires", fmt.Sprint(link.ExpiresAt.Unix()))
+	query.Set("sign", exportJobSign(job.ID, link.ExpiresAt.Unix()))
+	link.URL = global.GVA_CONFIG.System.RouterPrefix + "/exportJob/download?" + query.Encode()
+	return link
+}
+
+// OpenDownload
+// @function: OpenDownload
+// @description: 校验签名下载链接并打开导出文件, 本地存储直接读取, 其他OSS由服务端代理下载, 不暴露文件地址
+// @param: req systemReq.ExportJobDownload
+// @return: job system.SysExportJob, file io.ReadCloser, err error
+func (s *ExportJobService) OpenDownload(req systemReq.ExportJobDownload) (job system.SysExportJob, file io.ReadCloser, err error) {
+	if req.Expires < time.Now().Unix() {
+		return job, nil, errors.New("下载链接已过期")
+	}
+	if !hmac.Equal([]byte(req.Sign), []byte(exportJobSign(req.ID, req.Expires))) {
+		return job, nil, errors.New("下载链接无效")
+	}
+	if err = global.GVA_DB.First(&job, req.ID).Error; err != nil {
+		return job, nil, errors.New("导出任务不存在")
+	}
+	if job.Status != system.ExportJobSuccess {
+		return job, nil, errors.New("导出文件已过期")
+	}
+	if global.GVA_CONFIG.System.OssType == "local" {
+		file, err = os.Open(filepath.Join(global.GVA_CONFIG.Local.StorePath, job.FileKey))
+		return job, file, err
+	}
+	resp, err := http.Get(job.FileURL)
+	if err != nil {
+		return job, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return job, nil, fmt.Errorf("获取导出文件失败: %s", resp.Status)
+	}
+	return job, resp.Body, nil
+}
+
+// ClearExpired
+// @function: ClearExpired
+// @description: 定时任务调用, 将失去心跳的任务标记为失败, 并删除过期的导出文件
+// @return: err error
+func (s *ExportJobService) ClearExpired() (err error) {
+	now := time.Now()
+	err = global.GVA_DB.Model(&system.SysExportJob{}).
+		Where("status = ? AND updated_at < ?", system.ExportJobRunning, now.Add(-exportJobStaleAfter)).
+		Updates(map[string]interface{}{"status": system.ExportJobFailed, "error": "任务中断", "finished_at": now}).Error
+	if err != nil {
+		return err
+	}
+	oss := upload.NewOss()
+	for {
+		var jobs []system.SysExportJob
+		err = global.GVA_DB.Where("status = ? AND expires_at < ?", system.ExportJobSuccess, now).
+			Order("id").Limit(exportJobClearBatch).Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+		var cleared []uint
+		for _, job := range jobs {
+			if job.FileKey != "" {
+				if dErr := oss.DeleteFile(job.FileKey); dErr != nil {
+					// 删除失败的文件保留, 下次清理时重试
+					global.GVA_LOG.Error("删除过期导出文件失败!", zap.Uint("job", job.ID), zap.Error(dErr))
+					continue
+				}
+			}
+			cleared = append(cleared, job.ID)
+		}
+		if len(cleared) == 0 {
+			return nil
+		}
+		err = global.GVA_DB.Model(&system.SysExportJob{}).Where("id IN ?", cleared).
+			Updates(map[string]interface{}{"status": system.ExportJobExpired, "file_url": "", "file_key": ""}).Error
+		if err != nil || len(jobs) < exportJobClearBatch {
+			return err
+		}
+	}
+}
+
+// runNext 领取并执行一个排队中的任务, 没有任务时返回 false
+func (s *ExportJobService) runNext() bool {
+	if global.GVA_DB == nil {
+		return false
+	}
+	var jobs []system.SysExportJob
+	if err := global.GVA_DB.Where("status = ?", system.ExportJobPending).Order("id").Limit(1).Find(&jobs).Error; err != nil || len(jobs) == 0 {
+		return false
+	}
+	job := jobs[0]
+	now := time.Now()
+	// 以状态为条件更新, 多个实例同时领取时只有一个成功
+	res := global.GVA_DB.Model(&system.SysExportJob{}).
+		Where("id = ? AND status = ?", job.ID, system.ExportJobPending).
+		Updates(map[string]interface{}{"status": system.ExportJobRunning, "started_at": now})
+	if res.Error != nil {
+		global.GVA_LOG.Error("领取导出任务失败!", zap.Uint("job", job.ID), zap.Error(res.Error))
+		return false
+	}
+	if res.RowsAffected == 1 {
+		s.run(&job)
+	}
+	return true
+}
+
+func (s *ExportJobService) run(job *system.SysExportJob) {
+	ctx := &ExportJobContext{Job: job}
+	stop := make(chan struct{})
+	go s.heartbeat(ctx, stop)
+
+	updates := map[string]interface{}{}
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+		close(stop)
+		now := time.Now()
+		updates["finished_at"] = &now
+		if err != nil {
+			global.GVA_LOG.Error("导出任务失败!", zap.Uint("job", job.ID), zap.String("kind", job.Kind), zap.Error(err))
+			updates["status"], updates["error"] = system.ExportJobFailed, err.Error()
+		} else {
+			expiresAt := exportJobExpiresAt(now)
+			updates["status"], updates["progress"], updates["expires_at"] = system.ExportJobSuccess, 100, &expiresAt
+		}
+		global.GVA_DB.Model(&system.SysExportJob{}).Where("id = ?", job.ID).Updates(updates)
+	}()
+
+	handler, ok := exportJobHandlers[job.Kind]
+	if !ok {
+		err = fmt.Errorf("不支持的导出类型: %s", job.Kind)
+		return
+	}
+	tmp, err := os.CreateTemp("", "gva-export-*."+job.Format)
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	buf := bufio.NewWriter(tmp)
+	rows, err := handler.Run(ctx, json.RawMessage(job.Params), buf)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	updates["rows"] = rows
+	if err != nil {
+		return
+	}
+	if info, sErr := os.Stat(tmp.Name()); sErr == nil {
+		updates["size"] = info.Size()
+	}
+	fileURL, key, err := upload.UploadLocalFile(upload.NewOss(), tmp.Name(), job.Name)
+	updates["file_url"], updates["file_key"] = fileURL, key
+}
+
+// heartbeat 定期写入进度, 直至任务结束
+func (s *ExportJobService) heartbeat(ctx *ExportJobContext, stop <-chan struct{}) {
+	ticker := time.NewTicker(exportJobFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			global.GVA_DB.Model(&system.SysExportJob{}).
+				Where("id = ? AND status = ?", ctx.Job.ID, system.ExportJobRunning).
+				Updates(map[string]interface{}{
+					"rows":       ctx.done.Load(),
+					"total":      ctx.total.Load(),
+					"progress":   ctx.progress(),
+					"updated_at": time.Now(),
+				})
+		}
+	}
+}
+
+// exportJobExpiresAt 文件的过期时间
+func exportJobExpiresAt(now time.Time) time.Time {
+	retention := global.GVA_CONFIG.Excel.Retention
+	if retention <= 0 {
+		retention = exportDefaultRetention
+	}
+	return now.AddDate(0, 0, retention)
+}
+
+// exportJobSign 下载链接签名, 以JWT签名密钥派生
+func exportJobSign(id uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(global.GVA_CONFIG.JWT.SigningKey))
+	_, _ = fmt.Fprintf(mac, "export-job:%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,223 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	gaiaReq "github.com/flipped-aurora/gin-vue-admin/server/model/gaia/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+// 分页导出时每页的行数
+const exportJobPageSize = 500
+
+func init() {
+	RegisterExportJobHandler(system.ExportKindTemplate, templateExportHandler{})
+	RegisterExportJobHandler(system.ExportKindTemplateFile, templateFileExportHandler{})
+	RegisterExportJobHandler(system.ExportKindAppRanking, appRankingExportHandler{})
+	RegisterExportJobHandler(system.ExportKindAccountRanking, accountRankingExportHandler{})
+	RegisterExportJobHandler(system.ExportKindTestReport, testReportExportHandler{})
+}
+
+// TemplateExportParams 导出模板数据的任务参数
+type TemplateExportParams struct {
+	TemplateID string `json:"templateID"`
+	Query      string `json:"query"` // 与 exportExcel 相同的查询参数, url 编码
+}
+
+// templateExportHandler 按导出模板分批查询数据
+type templateExportHandler struct{}
+
+func (templateExportHandler) plan(params json.RawMessage) (*ExportPlan, error) {
+	var p TemplateExportParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.TemplateID == "" {
+		return nil, errors.New("模板ID不能为空")
+	}
+	values, err := url.ParseQuery(p.Query)
+	if err != nil {
+		return nil, err
+	}
+	return SysExportTemplateServiceApp.PrepareExport(p.TemplateID, values)
+}
+
+func (h templateExportHandler) Prepare(params json.RawMessage) (string, error) {
+	plan, err := h.plan(params)
+	if err != nil {
+		return "", err
+	}
+	return plan.Name(), nil
+}
+
+func (h templateExportHandler) Run(ctx *ExportJobContext, params json.RawMessage, w io.Writer) (int64, error) {
+	plan, err := h.plan(params)
+	if err != nil {
+		return 0, err
+	}
+	total, err := plan.Count()
+	if err != nil {
+		return 0, err
+	}
+	ctx.SetTotal(total)
+	return plan.Write(ctx.Job.Format, w, ctx.Add)
+}
+
+// templateFileExportHandler 导出模板的空白表格, 只包含表头
+type templateFileExportHandler struct{}
+
+func (templateFileExportHandler) template(params json.RawMessage) (template system.SysExportTemplate, err error) {
+	var p TemplateExportParams
+	if err = json.Unmarshal(params, &p); err != nil {
+		return template, err
+	}
+	err = global.GVA_DB.First(&template, "template_id = ?", p.TemplateID).Error
+	return template, err
+}
+
+func (h templateFileExportHandler) Prepare(params json.RawMessage) (string, error) {
+	template, err := h.template(params)
+	if err != nil {
+		return "", err
+	}
+	return template.Name + "模板", nil
+}
+
+func (h templateFileExportHandler) Run(ctx *ExportJobContext, params json.RawMessage, w io.Writer) (int64, error) {
+	template, err := h.template(params)
+	if err != nil {
+		return 0, err
+	}
+	keys, err := utils.GetJSONKeys(template.TemplateInfo)
+	if err != nil {
+		return 0, err
+	}
+	var titleMap map[string]string
+	if err = json.Unmarshal([]byte(template.TemplateInfo), &titleMap); err != nil {
+		return 0, err
+	}
+	titles := make([]string, len(keys))
+	for i, key := range keys {
+		titles[i] = titleMap[key]
+	}
+	return writeExportRows(ctx.Job.Format, w, titles, keys, func(func(values ...interface{}) error) error {
+		return nil
+	})
+}
+
+// appRankingExportHandler 看板应用花费排名
+type appRankingExportHandler struct{}
+
+func (appRankingExportHandler) Prepare(json.RawMessage) (string, error) {
+	return "应用花费排名", nil
+}
+
+func (appRankingExportHandler) Run(ctx *ExportJobContext, _ json.RawMessage, w io.Writer) (int64, error) {
+	titles := []string{"排名", "应用名称", "应用类型", "账号名称", "总花费", "对话花费", "工作流花费", "调用次数", "使用次数"}
+	keys := []string{"ranking", "name", "mode", "account_name", "total_cost", "message_cost", "workflow_cost", "record_num", "use_num"}
+	scope, err := DataScopeServiceApp.UserTenantScope(ctx.Job.UserID) // Extend: 按发起人的数据范围导出
+	if err != nil {
+		return 0, err
+	}
+	return exportPages(ctx, w, titles, keys, func(info request.PageInfo) (rows [][]interface{}, total int64, more bool, err error) {
+		list, _, err := new(serviceGaia.DashboardService).GetAppQuotaRankingData(gaiaReq.GetAppQuotaRankingDataReq{PageInfo: info}, scope)
+		for _, v := range list {
+			rows = append(rows, []interface{}{v.Ranking, v.Name, v.Mode, v.AccountName, v.TotalCost, v.MessageCost, v.WorkflowCost, v.RecordNum, v.UseNum})
+		}
+		// 看板中的总数只统计缓存的前几页, 这里不作为进度依据
+		return rows, 0, len(list) == info.PageSize, err
+	})
+}
+
+// accountRankingExportHandler 看板账号额度排名
+type accountRankingExportHandler struct{}
+
+func (accountRankingExportHandler) Prepare(json.RawMessage) (string, error) {
+	return "账号额度排名", nil
+}
+
+func (accountRankingExportHandler) Run(ctx *ExportJobContext, _ json.RawMessage, w io.Writer) (int64, error) {
+	titles := []string{"排名", "姓名", "已使用额度", "总额度"}
+	keys := []string{"ranking", "name", "used_quota", "total_quota"}
+	scope, err := DataScopeServiceApp.UserTenantScope(ctx.Job.UserID) // Extend: 按发起人的数据范围导出
+	if err != nil {
+		return 0, err
+	}
+	return exportPages(ctx, w, titles, keys, func(info request.PageInfo) (rows [][]interface{}, total int64, more bool, err error) {
+		list, total, err := new(serviceGaia.DashboardService).GetAccountQuotaRankingData(gaiaReq.GetAccountQuotaRankingDataReq{PageInfo: info}, scope)
+		for _, v := range list {
+			rows = append(rows, []interface{}{v.Ranking, v.Name, v.UsedQuota, v.TotalQuota})
+		}
+		return rows, total, int64(info.Page*info.PageSize) < total, err
+	})
+}
+
+// testReportExportHandler 应用请求测试报告, 参数与 getAppRequestTestList 相同
+type testReportExportHandler struct{}
+
+func (testReportExportHandler) Prepare(params json.RawMessage) (string, error) {
+	var p gaiaReq.GetAppRequestTestRequest
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", err
+	}
+	if err := global.GVA_DB.First(&gaia.AppRequestTestBatch{}, p.BatchId).Error; err != nil {
+		return "", errors.New("测试批次不存在")
+	}
+	return fmt.Sprintf("测试报告-批次%d", p.BatchId), nil
+}
+
+func (testReportExportHandler) Run(ctx *ExportJobContext, params json.RawMessage, w io.Writer) (int64, error) {
+	var p gaiaReq.GetAppRequestTestRequest
+	if err := json.Unmarshal(params, &p); err != nil {
+		return 0, err
+	}
+	titles := []string{"应用", "状态", "输入", "输出", "错误信息", "历史对照", "旧耗时", "耗时"}
+	keys := []string{"name", "status", "inputs", "outputs", "error", "comparison", "log_time", "elapsed_time"}
+	scope, err := DataScopeServiceApp.UserTenantScope(ctx.Job.UserID) // Extend: 按发起人的数据范围导出
+	if err != nil {
+		return 0, err
+	}
+	return exportPages(ctx, w, titles, keys, func(info request.PageInfo) (rows [][]interface{}, total int64, more bool, err error) {
+		p.PageInfo = info
+		_, list, total, err := new(serviceGaia.TestService).AppRequestTestList(p, scope)
+		for _, v := range list {
+			status := "失败"
+			if v.Status {
+				status = "成功"
+			}
+			rows = append(rows, []interface{}{v.Name, status, v.Inputs, v.Outputs, v.Error, v.Comparison, v.LogTime, v.ElapsedTime})
+		}
+		return rows, total, int64(info.Page*info.PageSize) < total, err
+	})
+}
+
+// exportPages 逐页调用 fetch 并写出, fetch 返回当前页的行、总数(未知时为0)以及是否还有下一页
+func exportPages(ctx *ExportJobContext, w io.Writer, titles, keys []string, fetch func(info request.PageInfo) (rows [][]interface{}, total int64, more bool, err error)) (int64, error) {
+	return writeExportRows(ctx.Job.Format, w, titles, keys, func(emit func(values ...interface{}) error) error {
+		for page := 1; ; page++ {
+			rows, total, more, err := fetch(request.PageInfo{Page: page, PageSize: exportJobPageSize})
+			if err != nil {
+				return err
+			}
+			ctx.SetTotal(total)
+			for _, row := range rows {
+				if err = emit(row...); err != nil {
+					return err
+				}
+			}
+			ctx.Add(int64(len(rows)))
+			if !more {
+				return nil
+			}
+		}
+	})
+}
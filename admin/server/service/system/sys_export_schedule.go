@@ -0,0 +1,401 @@
+package system
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	emailGlobal "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/global"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// 定时报表在 GVA_Timer 中使用的 cron 名称
+	exportScheduleCron = "ExportSchedule"
+	// 附件大小上限, MB
+	exportDefaultAttachLimit = 10
+)
+
+var (
+	// 已注册到 GVA_Timer 的定时报表
+	exportScheduleEntries   = make(map[uint]cron.EntryID)
+	exportScheduleEntriesMu sync.Mutex
+)
+
+type ExportScheduleService struct{}
+
+var ExportScheduleServiceApp = new(ExportScheduleService)
+
+// CreateExportSchedule
+// @function: CreateExportSchedule
+// @description: 创建定时报表, 启用时立即注册到定时任务
+// @param: schedule *system.SysExportSchedule
+// @return: err error
+func (s *ExportScheduleService) CreateExportSchedule(schedule *system.SysExportSchedule) (err error) {
+	if err = s.check(schedule); err != nil {
+		return err
+	}
+	if err = global.GVA_DB.Create(schedule).Error; err != nil {
+		return err
+	}
+	return s.schedule(*schedule)
+}
+
+// UpdateExportSchedule
+// @function: UpdateExportSchedule
+// @description: 更新定时报表并重新注册定时任务
+// @param: schedule system.SysExportSchedule
+// @return: err error
+func (s *ExportScheduleService) UpdateExportSchedule(schedule system.SysExportSchedule) (err error) {
+	if err = s.check(&schedule); err != nil {
+		return err
+	}
+	var old system.SysExportSchedule
+	if err = global.GVA_DB.First(&old, schedule.ID).Error; err != nil {
+		return errors.New("定时报表不存在")
+	}
+	err = global.GVA_DB.Model(&old).Select("name", "template_id", "query", "cron", "format", "recipients", "delivery", "enabled").Updates(&schedule).Error
+	if err != nil {
+		return err
+	}
+	s.unschedule(schedule.ID)
+	return s.schedule(schedule)
+}
+
+// DeleteExportSchedule
+// @function: DeleteExportSchedule
+// @description: 删除定时报表及其执行记录
+// @param: id uint
+// @return: err error
+func (s *ExportScheduleService) DeleteExportSchedule(id uint) (err error) {
+	s.unschedule(id)
+	if err = global.GVA_DB.Delete(&system.SysExportSchedule{}, id).Error; err != nil {
+		return err
+	}
+	return global.GVA_DB.Where("schedule_id = ?", id).Delete(&system.SysExportScheduleRun{}).Error
+}
+
+// GetExportSchedule
+// @function: GetExportSchedule
+// @description: 根据id获取定时报表
+// @param: id uint
+// @return: schedule system.SysExportSchedule, err error
+func (s *ExportScheduleService) GetExportSchedule(id uint) (schedule system.SysExportSchedule, err error) {
+	if err = global.GVA_DB.First(&schedule, id).Error; err != nil {
+		return schedule, err
+	}
+	exportScheduleNext(&schedule)
+	return schedule, nil
+}
+
+// GetExportScheduleList
+// @function: GetExportScheduleList
+// @description: 分页获取定时报表
+// @param: info systemReq.SysExportScheduleSearch
+// @return: list []system.SysExportSchedule, total int64, err error
+func (s *ExportScheduleService) GetExportScheduleList(info systemReq.SysExportScheduleSearch) (list []system.SysExportSchedule, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysExportSchedule{})
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.TemplateID != "" {
+		db = db.Where("template_id = ?", info.TemplateID)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	if limit != 0 {
+		db = db.Limit(limit).Offset(offset)
+	}
+	if err = db.Order("id desc").Find(&list).Error; err != nil {
+		return
+	}
+	for i := range list {
+		exportScheduleNext(&list[i])
+	}
+	return list, total, nil
+}
+
+// GetExportScheduleRunList
+// @function: GetExportScheduleRunList
+// @description: 分页获取定时报表的执行记录
+// @param: info systemReq.SysExportScheduleRunSearch
+// @return: list []system.SysExportScheduleRun, total int64, err error
+func (s *ExportScheduleService) GetExportScheduleRunList(info systemReq.SysExportScheduleRunSearch) (list []system.SysExportScheduleRun, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysExportScheduleRun{})
+	if info.ScheduleID != 0 {
+		db = db.Where("schedule_id = ?", info.ScheduleID)
+	}
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	if limit != 0 {
+		db = db.Limit(limit).Offset(offset)
+	}
+	err = db.Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// RunExportSchedule
+// @function: RunExportSchedule
+// @description: 立即执行一次定时报表, 在后台导出并发送, 返回执行记录
+// @param: id uint
+// @return: run system.SysExportScheduleRun, err error
+func (s *ExportScheduleService) RunExportSchedule(id uint) (run system.SysExportScheduleRun, err error) {
+	var schedule system.SysExportSchedule
+	if err = global.GVA_DB.First(&schedule, id).Error; err != nil {
+		return run, errors.New("定时报表不存在")
+	}
+	if run, err = s.startRun(schedule, system.ExportTriggerManual); err != nil {
+		return run, err
+	}
+	go s.execute(schedule, run)
+	return run, nil
+}
+
+// LoadExportSchedules
+// @function: LoadExportSchedules
+// @description: 启动时将已启用的定时报表注册到 GVA_Timer, 并将上次退出时中断的执行记录置为失败
+// @return: err error
+func (s *ExportScheduleService) LoadExportSchedules() (err error) {
+	now := time.Now()
+	err = global.GVA_DB.Model(&system.SysExportScheduleRun{}).Where("status = ?", system.ExportJobRunning).
+		Updates(map[string]interface{}{"status": system.ExportJobFailed, "error": "服务重启, 执行中断", "finished_at": &now}).Error
+	if err != nil {
+		return err
+	}
+	var schedules []system.SysExportSchedule
+	if err = global.GVA_DB.Where("enabled = ?", true).Find(&schedules).Error; err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		if err := s.schedule(schedule); err != nil {
+			global.GVA_LOG.Error("注册定时报表失败!", zap.Uint("schedule", schedule.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// check 校验并补全默认值
+func (s *ExportScheduleService) check(schedule *system.SysExportSchedule) error {
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	if schedule.Name == "" {
+		return errors.New("报表名称不能为空")
+	}
+	if err := global.GVA_DB.Select("id").First(&system.SysExportTemplate{}, "template_id = ?", schedule.TemplateID).Error; err != nil {
+		return errors.New("导出模板不存在")
+	}
+	schedule.Cron = strings.TrimSpace(schedule.Cron)
+	if _, err := cron.ParseStandard(schedule.Cron); err != nil {
+		return fmt.Errorf("cron表达式不合法: %w", err)
+	}
+	if schedule.Format == "" {
+		schedule.Format = system.ExportFormatXlsx
+	}
+	if !ValidExportFormat(schedule.Format) {
+		return fmt.Errorf("不支持的导出格式 %s", schedule.Format)
+	}
+	if schedule.Delivery == "" {
+		schedule.Delivery = system.ExportDeliveryAttachment
+	}
+	if schedule.Delivery != system.ExportDeliveryAttachment && schedule.Delivery != system.ExportDeliveryLink {
+		return fmt.Errorf("不支持的发送方式 %s", schedule.Delivery)
+	}
+	var recipients []string
+	for _, addr := range strings.Split(schedule.Recipients, ",") {
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("收件人 %s 不是有效的邮箱", addr)
+		}
+		recipients = append(recipients, addr)
+	}
+	if len(recipients) == 0 {
+		return errors.New("收件人不能为空")
+	}
+	schedule.Recipients = strings.Join(recipients, ",")
+	values, err := url.ParseQuery(schedule.Query)
+	if err != nil {
+		return fmt.Errorf("查询参数不合法: %w", err)
+	}
+	_, err = utils.ResolveDateTokens(values, time.Now())
+	return err
+}
+
+// schedule 将启用的定时报表注册到 GVA_Timer
+func (s *ExportScheduleService) schedule(schedule system.SysExportSchedule) error {
+	if !schedule.Enabled {
+		return nil
+	}
+	exportScheduleEntriesMu.Lock()
+	defer exportScheduleEntriesMu.Unlock()
+	id := schedule.ID
+	entryID, err := global.GVA_Timer.AddTaskByFunc(exportScheduleCron, schedule.Cron, func() {
+		s.cronRun(id)
+	}, fmt.Sprintf("定时报表 %d %s", schedule.ID, schedule.Name))
+	if err != nil {
+		return err
+	}
+	exportScheduleEntries[id] = entryID
+	return nil
+}
+
+// unschedule 从 GVA_Timer 移除定时报表
+func (s *ExportScheduleService) unschedule(id uint) {
+	exportScheduleEntriesMu.Lock()
+	defer exportScheduleEntriesMu.Unlock()
+	if entryID, ok := exportScheduleEntries[id]; ok {
+		global.GVA_Timer.RemoveTask(exportScheduleCron, int(entryID))
+		delete(exportScheduleEntries, id)
+	}
+}
+
+// cronRun 定时触发, 多实例部署时通过更新最近执行时间抢占, 同一分钟只有一个实例执行
+func (s *ExportScheduleService) cronRun(id uint) {
+	now := time.Now()
+	res := global.GVA_DB.Model(&system.SysExportSchedule{}).
+		Where("id = ? AND enabled = ? AND (last_run_at IS NULL OR last_run_at < ?)", id, true, now.Truncate(time.Minute)).
+		Update("last_run_at", &now)
+	if res.Error != nil || res.RowsAffected != 1 {
+		return
+	}
+	var schedule system.SysExportSchedule
+	if err := global.GVA_DB.First(&schedule, id).Error; err != nil {
+		return
+	}
+	run, err := s.startRun(schedule, system.ExportTriggerCron)
+	if err != nil {
+		global.GVA_LOG.Error("定时报表执行失败!", zap.Uint("schedule", id), zap.Error(err))
+		return
+	}
+	s.execute(schedule, run)
+}
+
+// startRun 创建执行记录
+func (s *ExportScheduleService) startRun(schedule system.SysExportSchedule, trigger string) (run system.SysExportScheduleRun, err error) {
+	now := time.Now()
+	run = system.SysExportScheduleRun{ScheduleID: schedule.ID, Trigger: trigger, Status: system.ExportJobRunning, StartedAt: &now}
+	err = global.GVA_DB.Create(&run).Error
+	return run, err
+}
+
+// execute 导出并发送, 结果写入执行记录与定时报表的最近状态
+func (s *ExportScheduleService) execute(schedule system.SysExportSchedule, run system.SysExportScheduleRun) {
+	updates := map[string]interface{}{}
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+		now := time.Now()
+		updates["status"], updates["finished_at"] = system.ExportJobSuccess, &now
+		if err != nil {
+			global.GVA_LOG.Error("定时报表执行失败!", zap.Uint("schedule", schedule.ID), zap.Error(err))
+			updates["status"], updates["error"] = system.ExportJobFailed, err.Error()
+		}
+		global.GVA_DB.Model(&system.SysExportScheduleRun{}).Where("id = ?", run.ID).Updates(updates)
+		global.GVA_DB.Model(&system.SysExportSchedule{}).Where("id = ?", schedule.ID).
+			Updates(map[string]interface{}{"last_run_at": run.StartedAt, "last_status": updates["status"]})
+	}()
+
+	values, err := url.ParseQuery(schedule.Query)
+	if err != nil {
+		return
+	}
+	if values, err = utils.ResolveDateTokens(values, *run.StartedAt); err != nil {
+		return
+	}
+	updates["query"] = values.Encode()
+	plan, err := SysExportTemplateServiceApp.PrepareExport(schedule.TemplateID, values)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp("", "gva-schedule-*."+schedule.Format)
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	buf := bufio.NewWriter(tmp)
+	rows, err := plan.Write(schedule.Format, buf, nil)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	updates["rows"] = rows
+	if err != nil {
+		return
+	}
+	name := schedule.Name + run.StartedAt.Format("20060102") + "." + schedule.Format
+	job, err := ExportJobServiceApp.StoreFile(system.ExportKindTemplate, name, tmp.Name(), rows, schedule.CreatedBy)
+	if err != nil {
+		return
+	}
+	updates["job_id"] = job.ID
+	delivery, err := s.deliver(schedule, job, tmp.Name(), rows)
+	updates["delivery"] = delivery
+}
+
+// deliver 通过邮件发送报表, 返回实际的发送方式
+func (s *ExportScheduleService) deliver(schedule system.SysExportSchedule, job system.SysExportJob, path string, rows int64) (string, error) {
+	if emailGlobal.GlobalConfig.Host == "" {
+		return "", errors.New("邮件服务未配置")
+	}
+	limit := global.GVA_CONFIG.Excel.AttachLimit
+	if limit <= 0 {
+		limit = exportDefaultAttachLimit
+	}
+	delivery := schedule.Delivery
+	if delivery == system.ExportDeliveryAttachment && job.Size > int64(limit)<<20 {
+		delivery = system.ExportDeliveryLink
+	}
+	subject := fmt.Sprintf("[定时报表] %s %s", schedule.Name, job.CreatedAt.Format("2006-01-02"))
+	body := fmt.Sprintf("<p>报表: %s</p><p>生成时间: %s</p><p>数据行数: %d</p>",
+		html.EscapeString(schedule.Name), job.CreatedAt.Format("2006-01-02 15:04:05"), rows)
+	files := map[string]string{}
+	if delivery == system.ExportDeliveryAttachment {
+		files[job.Name] = path
+	} else {
+		if global.GVA_CONFIG.Excel.SiteURL == "" {
+			return delivery, errors.New("未配置 excel.site-url, 无法在邮件中生成下载链接")
+		}
+		link := exportJobLink(job, *job.ExpiresAt)
+		href := strings.TrimRight(global.GVA_CONFIG.Excel.SiteURL, "/") + link.URL
+		body += fmt.Sprintf(`<p>下载: <a href="%s">%s</a></p><p>链接有效期至 %s</p>`,
+			html.EscapeString(href), html.EscapeString(job.Name), link.ExpiresAt.Format("2006-01-02 15:04"))
+	}
+	return delivery, emailUtils.EmailWithAttachments(schedule.Recipients, subject, body, files)
+}
+
+// exportScheduleNext 计算下次执行时间
+func exportScheduleNext(schedule *system.SysExportSchedule) {
+	if !schedule.Enabled {
+		return
+	}
+	if sched, err := cron.ParseStandard(schedule.Cron); err == nil {
+		next := sched.Next(time.Now())
+		schedule.NextRunAt = &next
+	}
+}
@@ -0,0 +1,359 @@
+package system
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	exportDefaultBatchSize      = 1000
+	exportDefaultAsyncThreshold = 50000
+	// xlsx 单个工作表的最大行数, 含表头
+	exportXlsxMaxRows = 1048576
+	// 分页键在查询结果中的别名
+	exportKeysetAlias = "gva_keyset_%d"
+)
+
+// ExportPlan 导出计划, 按批查询并逐行写出, 内存占用与数据总量无关
+type ExportPlan struct {
+	template system.SysExportTemplate
+	db       *gorm.DB
+	selects  string
+	titles   []string // 表头
+	keys     []string // 结果中每列对应的字段名
+	limit    int
+	offset   int
+	// 分页方式: keyset 不为空时按 keyset 翻页, 否则按 order + offset 翻页
+	keyset []string
+	desc   bool
+	order  string
+}
+
+// setOrder 确定翻页方式
+// 有主键时按 (排序字段, 主键) 做 keyset 分页; 排序字段可为空时行比较会漏掉 NULL 行, 以及没有主键时, 退回 offset 分页
+func (p *ExportPlan) setOrder(table, orderColumn string, desc bool, primaryKey string, fields map[string]gorm.ColumnType) {
+	p.desc = desc
+	if orderColumn != "" {
+		p.order = orderColumn
+		if desc {
+			p.order += " desc"
+		}
+	}
+	if primaryKey == "" {
+		return
+	}
+	if orderColumn != "" && orderColumn != primaryKey {
+		if nullable, ok := fields[orderColumn].Nullable(); !ok || nullable {
+			return
+		}
+		p.keyset = append(p.keyset, table+"."+orderColumn)
+	}
+	p.keyset = append(p.keyset, table+"."+primaryKey)
+}
+
+// Name 模板名称
+func (p *ExportPlan) Name() string {
+	return p.template.Name
+}
+
+// FileName 下载文件名
+func (p *ExportPlan) FileName(format string) string {
+	return p.template.Name + utils.RandomString(6) + "." + format
+}
+
+// Count 导出的行数, 已计入 limit 与 offset
+func (p *ExportPlan) Count() (total int64, err error) {
+	if err = p.db.Count(&total).Error; err != nil {
+		return 0, err
+	}
+	total = max(total-int64(p.offset), 0)
+	if p.limit > 0 {
+		total = min(total, int64(p.limit))
+	}
+	return total, nil
+}
+
+// Write 按格式将数据写入 w, 返回写出的行数, progress 不为空时每写出一行回调一次
+func (p *ExportPlan) Write(format string, w io.Writer, progress func(rows int64)) (rows int64, err error) {
+	return writeExportRows(format, w, p.titles, p.keys, func(emit func(values ...interface{}) error) error {
+		return p.each(func(row map[string]interface{}) error {
+			values := make([]interface{}, len(p.keys))
+			for i, key := range p.keys {
+				values[i] = exportCell(row[key])
+			}
+			if err := emit(values...); err != nil {
+				return err
+			}
+			if progress != nil {
+				progress(1)
+			}
+			return nil
+		})
+	})
+}
+
+// writeExportRows 写出表头后由 fill 逐行写出数据, 返回写出的行数
+func writeExportRows(format string, w io.Writer, titles, keys []string, fill func(emit func(values ...interface{}) error) error) (rows int64, err error) {
+	ew, err := newExportWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+	err = ew.header(titles, keys)
+	if err == nil {
+		err = fill(func(values ...interface{}) error {
+			rows++
+			return ew.row(values)
+		})
+	}
+	if err != nil {
+		ew.discard()
+		return rows, err
+	}
+	return rows, ew.close()
+}
+
+// each 分批查询, 每批查询后逐行回调
+func (p *ExportPlan) each(fn func(row map[string]interface{}) error) error {
+	batch := global.GVA_CONFIG.Excel.BatchSize
+	if batch <= 0 {
+		batch = exportDefaultBatchSize
+	}
+	selects := p.selects
+	for i, key := range p.keyset {
+		selects += fmt.Sprintf(", %s AS "+exportKeysetAlias, key, i)
+	}
+	remaining, offset := p.limit, p.offset
+	var last []interface{}
+	for {
+		size := batch
+		if p.limit > 0 {
+			if remaining <= 0 {
+				return nil
+			}
+			size = min(size, remaining)
+		}
+		db := p.db.Select(selects)
+		if len(p.keyset) > 0 {
+			if last != nil {
+				db = db.Where(p.keysetWhere(), last...)
+			} else if offset > 0 {
+				db = db.Offset(offset)
+			}
+			db = db.Order(p.keysetOrder())
+		} else {
+			if p.order != "" {
+				db = db.Order(p.order)
+			}
+			db = db.Offset(offset)
+		}
+		var rows []map[string]interface{}
+		if err := db.Limit(size).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		if len(rows) < size {
+			return nil
+		}
+		remaining -= len(rows)
+		offset += len(rows)
+		lastRow := rows[len(rows)-1]
+		last = last[:0]
+		for i := range p.keyset {
+			last = append(last, lastRow[fmt.Sprintf(exportKeysetAlias, i)])
+		}
+	}
+}
+
+func (p *ExportPlan) keysetWhere() string {
+	op := ">"
+	if p.desc {
+		op = "<"
+	}
+	if len(p.keyset) == 1 {
+		return p.keyset[0] + " " + op + " ?"
+	}
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(p.keyset, ", "), op, strings.TrimSuffix(strings.Repeat("?, ", len(p.keyset)), ", "))
+}
+
+func (p *ExportPlan) keysetOrder() string {
+	direction := " asc"
+	if p.desc {
+		direction = " desc"
+	}
+	return strings.Join(p.keyset, direction+", ") + direction
+}
+
+// exportCell 时间按 2006-01-02 15:04:05 输出, 空值输出为空
+func exportCell(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return t.Format("2006-01-02 15:04:05")
+	case []byte:
+		return string(t)
+	}
+	return v
+}
+
+// ValidExportFormat 是否为支持的导出格式
+func ValidExportFormat(format string) bool {
+	switch format {
+	case system.ExportFormatXlsx, system.ExportFormatCsv, system.ExportFormatJsonl:
+		return true
+	}
+	return false
+}
+
+// ExportContentType 导出格式对应的 Content-Type
+func ExportContentType(format string) string {
+	switch format {
+	case system.ExportFormatCsv:
+		return "text/csv; charset=utf-8"
+	case system.ExportFormatJsonl:
+		return "application/x-ndjson; charset=utf-8"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+// ExportAsyncThreshold 超过该行数的导出转为异步任务
+func ExportAsyncThreshold() int64 {
+	if global.GVA_CONFIG.Excel.AsyncThreshold > 0 {
+		return int64(global.GVA_CONFIG.Excel.AsyncThreshold)
+	}
+	return exportDefaultAsyncThreshold
+}
+
+// exportWriter 按格式逐行写出
+type exportWriter interface {
+	header(titles, keys []string) error
+	row(values []interface{}) error
+	close() error
+	discard() // 出错时释放资源
+}
+
+func newExportWriter(format string, w io.Writer) (exportWriter, error) {
+	switch format {
+	case system.ExportFormatXlsx:
+		return &xlsxExportWriter{w: w}, nil
+	case system.ExportFormatCsv:
+		return &csvExportWriter{w: w, cw: csv.NewWriter(w)}, nil
+	case system.ExportFormatJsonl:
+		return &jsonlExportWriter{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+// xlsxExportWriter 使用 excelize StreamWriter, 行数据超出内存阈值时由 excelize 写入临时文件
+type xlsxExportWriter struct {
+	w    io.Writer
+	f    *excelize.File
+	sw   *excelize.StreamWriter
+	rows int
+}
+
+func (x *xlsxExportWriter) header(titles, _ []string) (err error) {
+	x.f = excelize.NewFile()
+	if x.sw, err = x.f.NewStreamWriter("Sheet1"); err != nil {
+		return err
+	}
+	values := make([]interface{}, len(titles))
+	for i, title := range titles {
+		values[i] = title
+	}
+	return x.row(values)
+}
+
+func (x *xlsxExportWriter) row(values []interface{}) error {
+	x.rows++
+	if x.rows > exportXlsxMaxRows {
+		return errors.New("超过xlsx单表最大行数, 请使用csv或jsonl格式导出")
+	}
+	cell, err := excelize.CoordinatesToCellName(1, x.rows)
+	if err != nil {
+		return err
+	}
+	return x.sw.SetRow(cell, values)
+}
+
+func (x *xlsxExportWriter) close() error {
+	defer x.f.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	return x.f.Write(x.w)
+}
+
+func (x *xlsxExportWriter) discard() {
+	if x.f != nil {
+		_ = x.f.Close()
+	}
+}
+
+type csvExportWriter struct {
+	w  io.Writer
+	cw *csv.Writer
+}
+
+func (c *csvExportWriter) header(titles, _ []string) error {
+	// 写入BOM, Excel打开时按UTF-8识别
+	if _, err := c.w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	return c.cw.Write(titles)
+}
+
+func (c *csvExportWriter) row(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = fmt.Sprintf("%v", v)
+	}
+	return c.cw.Write(record)
+}
+
+func (c *csvExportWriter) close() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+func (c *csvExportWriter) discard() {}
+
+// jsonlExportWriter 每行一个JSON对象, 以字段名为键
+type jsonlExportWriter struct {
+	enc  *json.Encoder
+	keys []string
+}
+
+func (j *jsonlExportWriter) header(_, keys []string) error {
+	j.keys = keys
+	return nil
+}
+
+func (j *jsonlExportWriter) row(values []interface{}) error {
+	item := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		item[j.keys[i]] = v
+	}
+	return j.enc.Encode(item)
+}
+
+func (j *jsonlExportWriter) close() error {
+	return nil
+}
+
+func (j *jsonlExportWriter) discard() {}
@@ -11,11 +11,9 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
-	"mime/multipart"
 	"net/url"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type SysExportTemplateService struct {
@@ -123,66 +121,77 @@ func (sysExportTemplateService *SysExportTemplateService) GetSysExportTemplateIn
 	return sysExportTemplates, total, err
 }
 
-// ExportExcel 导出Excel
-// Author [piexlmax](https://github.com/piexlmax)
-func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID string, values url.Values) (file *bytes.Buffer, name string, err error) {
+// PrepareExport 解析导出模板与查询参数, 生成分批查询、流式写出的导出计划
+// Extend: streaming export, 原 ExportExcel 一次性查询全部数据并在内存中生成表格
+// Extend: export template sql safety, 模板中的表、字段与操作符在拼接SQL前逐一校验, 参数值全部通过占位符传入
+func (sysExportTemplateService *SysExportTemplateService) PrepareExport(templateID string, values url.Values) (plan *ExportPlan, err error) {
 	var template system.SysExportTemplate
 	err = global.GVA_DB.Preload("Conditions").Preload("JoinTemplate").First(&template, "template_id = ?", templateID).Error
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-	// Create a new sheet.
-	index, err := f.NewSheet("Sheet1")
+	schema, err := sysExportTemplateService.checkTemplate(&template)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
 	}
 	var templateInfoMap = make(map[string]string)
 	columns, err := utils.GetJSONKeys(template.TemplateInfo)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 	err = json.Unmarshal([]byte(template.TemplateInfo), &templateInfoMap)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	var tableTitle []string
-	var selectKeyFmt []string
+	plan = &ExportPlan{template: template}
+	selects := make([]string, 0, len(columns))
 	for _, key := range columns {
-		selectKeyFmt = append(selectKeyFmt, fmt.Sprintf("%s", key))
-		tableTitle = append(tableTitle, templateInfoMap[key])
-	}
-
-	selects := strings.Join(selectKeyFmt, ", ")
-	var tableMap []map[string]interface{}
-	db := global.GVA_DB
-	if template.DBName != "" {
-		db = global.MustGetGlobalDBByDBName(template.DBName)
+		m := exportSelectPattern.FindStringSubmatch(strings.TrimSpace(key))
+		selectColumn, _ := schema.hasColumn(m[1], m[2])
+		resultKey := m[2]
+		if m[3] != "" {
+			selectColumn += " AS " + m[3]
+			resultKey = m[3]
+		}
+		selects = append(selects, selectColumn)
+		plan.titles = append(plan.titles, templateInfoMap[key])
+		plan.keys = append(plan.keys, resultKey)
 	}
+	plan.selects = strings.Join(selects, ", ")
 
-	if len(template.JoinTemplate) > 0 {
-		for _, join := range template.JoinTemplate {
-			db = db.Joins(join.JOINS + " " + join.Table + " ON " + join.ON)
-		}
+	db := schema.db
+	for _, join := range template.JoinTemplate {
+		db = db.Joins(join.JOINS + " " + join.Table + " ON " + join.OnLeft + " = " + join.OnRight)
 	}
 
-	db = db.Select(selects).Table(template.TableName)
+	db = db.Table(template.TableName)
 
-	if len(template.Conditions) > 0 {
-		for _, condition := range template.Conditions {
-			sql := fmt.Sprintf("%s %s ?", condition.Column, condition.Operator)
+	for _, condition := range template.Conditions {
+		switch condition.Operator {
+		case "BETWEEN", "NOT BETWEEN":
+			// 区间条件从 start+key 与 end+key 取值
+			start, end := values.Get("start"+condition.From), values.Get("end"+condition.From)
+			// Extend Start: scheduled report
+			// 相对日期区间为左闭右开, 不能用 BETWEEN, 否则会包含下一区间的开始
+			if start != "" && end != "" && values.Get(utils.DateRangeOpenEndKey+condition.From) != "" {
+				if condition.Operator == "BETWEEN" {
+					db = db.Where(fmt.Sprintf("%s >= ? AND %s < ?", condition.Column, condition.Column), start, end)
+				} else {
+					db = db.Where(fmt.Sprintf("(%s < ? OR %s >= ?)", condition.Column, condition.Column), start, end)
+				}
+				continue
+			}
+			// Extend Stop: scheduled report
+			if start != "" && end != "" {
+				db = db.Where(fmt.Sprintf("%s %s ? AND ?", condition.Column, condition.Operator), start, end)
+			}
+		default:
 			value := values.Get(condition.From)
 			if value != "" {
-				if condition.Operator == "LIKE" {
+				if condition.Operator == "LIKE" || condition.Operator == "NOT LIKE" {
 					value = "%" + value + "%"
 				}
-				db = db.Where(sql, value)
+				db = db.Where(fmt.Sprintf("%s %s ?", condition.Column, condition.Operator), value)
 			}
 		}
 	}
@@ -191,12 +200,12 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 	if limit != "" {
 		l, e := strconv.Atoi(limit)
 		if e == nil {
-			db = db.Limit(l)
+			plan.limit = l
 		}
 	}
 	// 模板的默认limit
 	if limit == "" && template.Limit != nil && *template.Limit != 0 {
-		db = db.Limit(*template.Limit)
+		plan.limit = *template.Limit
 	}
 
 	// 通过参数传入offset
@@ -204,23 +213,13 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 	if offset != "" {
 		o, e := strconv.Atoi(offset)
 		if e == nil {
-			db = db.Offset(o)
+			plan.offset = o
 		}
 	}
 
-	// 获取当前表的所有字段
+	// 当前表的所有字段
 	table := template.TableName
-	orderColumns, err := db.Migrator().ColumnTypes(table)
-	if err != nil {
-		return nil, "", err
-	}
-
-	// 创建一个 map 来存储字段名
-	fields := make(map[string]bool)
-
-	for _, column := range orderColumns {
-		fields[column.Name()] = true
-	}
+	fields := schema.fields()
 
 	// 通过参数传入order
 	order := values.Get("order")
@@ -230,69 +229,16 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 		order = template.Order
 	}
 
+	var orderColumn string
+	var desc bool
 	if order != "" {
-		checkOrderArr := strings.Split(order, " ")
-		orderStr := ""
-		// 检查请求的排序字段是否在字段列表中
-		if _, ok := fields[checkOrderArr[0]]; !ok {
-			return nil, "", fmt.Errorf("order by %s is not in the fields", order)
+		if orderColumn, desc, err = parseExportOrder(order, fields); err != nil {
+			return nil, err
 		}
-		orderStr = checkOrderArr[0]
-		if len(checkOrderArr) > 1 {
-			if checkOrderArr[1] != "asc" && checkOrderArr[1] != "desc" {
-				return nil, "", fmt.Errorf("order by %s is not secure", order)
-			}
-			orderStr = orderStr + " " + checkOrderArr[1]
-		}
-		db = db.Order(orderStr)
 	}
-
-	err = db.Debug().Find(&tableMap).Error
-	if err != nil {
-		return nil, "", err
-	}
-	var rows [][]string
-	rows = append(rows, tableTitle)
-	for _, exTable := range tableMap {
-		var row []string
-		for _, column := range columns {
-			column = strings.ReplaceAll(column, "\"", "")
-			column = strings.ReplaceAll(column, "`", "")
-			if len(template.JoinTemplate) > 0 {
-				columnAs := strings.Split(column, " as ")
-				if len(columnAs) > 1 {
-					column = strings.TrimSpace(strings.Split(column, " as ")[1])
-				} else {
-					columnArr := strings.Split(column, ".")
-					if len(columnArr) > 1 {
-						column = strings.Split(column, ".")[1]
-					}
-				}
-			}
-			// 需要对时间类型特殊处理
-			if t, ok := exTable[column].(time.Time); ok {
-				row = append(row, t.Format("2006-01-02 15:04:05"))
-			} else {
-				row = append(row, fmt.Sprintf("%v", exTable[column]))
-			}
-		}
-		rows = append(rows, row)
-	}
-	for i, row := range rows {
-		for j, colCell := range row {
-			sErr := f.SetCellValue("Sheet1", fmt.Sprintf("%s%d", getColumnName(j+1), i+1), colCell)
-			if sErr != nil {
-				return nil, "", sErr
-			}
-		}
-	}
-	f.SetActiveSheet(index)
-	file, err = f.WriteToBuffer()
-	if err != nil {
-		return nil, "", err
-	}
-
-	return file, template.Name, nil
+	plan.setOrder(table, orderColumn, desc, schema.primaryKey, fields)
+	plan.db = db.Session(&gorm.Session{})
+	return plan, nil
 }
 
 // ExportTemplate 导出Excel模板
@@ -343,74 +289,7 @@ func (sysExportTemplateService *SysExportTemplateService) ExportTemplate(templat
 	return file, template.Name, nil
 }
 
-// ImportExcel 导入Excel
-// Author [piexlmax](https://github.com/piexlmax)
-func (sysExportTemplateService *SysExportTemplateService) ImportExcel(templateID string, file *multipart.FileHeader) (err error) {
-	var template system.SysExportTemplate
-	err = global.GVA_DB.First(&template, "template_id = ?", templateID).Error
-	if err != nil {
-		return err
-	}
-
-	src, err := file.Open()
-	if err != nil {
-		return err
-	}
-	defer src.Close()
-
-	f, err := excelize.OpenReader(src)
-	if err != nil {
-		return err
-	}
-
-	rows, err := f.GetRows("Sheet1")
-	if err != nil {
-		return err
-	}
-
-	var templateInfoMap = make(map[string]string)
-	err = json.Unmarshal([]byte(template.TemplateInfo), &templateInfoMap)
-	if err != nil {
-		return err
-	}
-
-	var titleKeyMap = make(map[string]string)
-	for key, title := range templateInfoMap {
-		titleKeyMap[title] = key
-	}
-
-	db := global.GVA_DB
-	if template.DBName != "" {
-		db = global.MustGetGlobalDBByDBName(template.DBName)
-	}
-
-	return db.Transaction(func(tx *gorm.DB) error {
-		excelTitle := rows[0]
-		values := rows[1:]
-		items := make([]map[string]interface{}, 0, len(values))
-		for _, row := range values {
-			var item = make(map[string]interface{})
-			for ii, value := range row {
-				key := titleKeyMap[excelTitle[ii]]
-				item[key] = value
-			}
-
-			needCreated := tx.Migrator().HasColumn(template.TableName, "created_at")
-			needUpdated := tx.Migrator().HasColumn(template.TableName, "updated_at")
-
-			if item["created_at"] == nil && needCreated {
-				item["created_at"] = time.Now()
-			}
-			if item["updated_at"] == nil && needUpdated {
-				item["updated_at"] = time.Now()
-			}
-
-			items = append(items, item)
-		}
-		cErr := tx.Table(template.TableName).CreateInBatches(&items, 1000).Error
-		return cErr
-	})
-}
+// Extend: import validation 导入已移至 sys_export_import.go, 支持导入模式、校验规则、试运行与错误报告
 
 func getColumnName(n int) string {
 	columnName := ""
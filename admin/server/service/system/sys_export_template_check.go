@@ -0,0 +1,373 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	// 表名与字段名只允许字母、数字与下划线
+	exportIdentifierPattern = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	// 字段引用: column 或 table.column
+	exportColumnPattern = regexp.MustCompile(`^(?:([A-Za-z_]\w*)\.)?([A-Za-z_]\w*)$`)
+	// 导出字段: column、table.column, 可带 as 别名
+	exportSelectPattern = regexp.MustCompile(`^(?:([A-Za-z_]\w*)\.)?([A-Za-z_]\w*)(?:\s+(?i:as)\s+([A-Za-z_]\w*))?$`)
+	// 旧版关联条件: table1.a = table2.b
+	exportLegacyOnPattern = regexp.MustCompile(`^\s*([A-Za-z_]\w*\.[A-Za-z_]\w*)\s*=\s*([A-Za-z_]\w*\.[A-Za-z_]\w*)\s*$`)
+)
+
+// exportOperators 条件允许使用的操作符
+var exportOperators = map[string]bool{
+	"=": true, "<>": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true,
+	"LIKE": true, "NOT LIKE": true, "BETWEEN": true, "NOT BETWEEN": true,
+}
+
+// exportJoinTypes 允许的关联方式
+var exportJoinTypes = map[string]bool{"LEFT JOIN": true, "INNER JOIN": true, "RIGHT JOIN": true}
+
+// exportDeniedTables 导出模板不允许使用的表, 无论位于哪个数据库: 含密码、令牌、密钥等凭据, 或决定登录与权限,
+// 导出会泄露凭据, 通过模板导入则可直接改写权限
+var exportDeniedTables = map[string]bool{
+	// 后台用户、会话与权限
+	"sys_users": true, "sys_user_authority": true, "sys_authorities": true, "sys_authority_menus": true,
+	"sys_authority_data_scopes": true, "sys_authority_grants": true, "sys_apis": true, "sys_ignore_apis": true,
+	"casbin_rule": true, "jwt_blacklists": true, "sys_user_sessions": true, "sys_user_mfas": true,
+	"sys_access_tokens": true, "sys_export_templates": true, "sys_export_template_condition": true,
+	"sys_export_template_join": true,
+	// gaia 账户、令牌与第三方集成
+	"accounts": true, "account_integrates": true, "api_tokens": true, "system_integration_extend": true,
+	"tenants": true, "invitation_codes": true, "dify_setups": true,
+	// gaia 中加密保存的模型、工具、数据源、外部知识库与追踪凭据, 以 api/models 中的表结构为准
+	"providers": true, "provider_models": true, "load_balancing_model_configs": true,
+	"tool_builtin_providers": true, "tool_api_providers": true, "data_source_oauth_bindings": true,
+	"data_source_api_key_auth_bindings": true, "api_based_extensions": true, "tidb_auth_bindings": true,
+	"external_knowledge_apis": true, "trace_app_config": true,
+}
+
+// exportTenantColumns 含这些字段的表属于 gaia 工作空间的数据, 模板按表导出无法按工作空间过滤
+var exportTenantColumns = []string{"tenant_id", "app_id"}
+
+// exportSchema 模板涉及的表及字段, 来自数据库的 ColumnTypes, 模板中引用的表名与字段名必须在其中
+type exportSchema struct {
+	db         *gorm.DB
+	main       string
+	tables     map[string]map[string]gorm.ColumnType
+	primaryKey string // 主表的第一个主键字段
+}
+
+func (s *exportSchema) addTable(table string) error {
+	if !exportIdentifierPattern.MatchString(table) {
+		return fmt.Errorf("表名 %s 不合法", table)
+	}
+	if exportDeniedTables[strings.ToLower(table)] {
+		return fmt.Errorf("表 %s 包含凭据或权限数据, 不允许在导出模板中使用", table)
+	}
+	if _, ok := s.tables[table]; ok {
+		return nil
+	}
+	columnTypes, err := s.db.Migrator().ColumnTypes(table)
+	if err != nil || len(columnTypes) == 0 {
+		return fmt.Errorf("表 %s 不存在", table)
+	}
+	columns := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, column := range columnTypes {
+		columns[column.Name()] = column
+		if pk, ok := column.PrimaryKey(); ok && pk && table == s.main && s.primaryKey == "" {
+			s.primaryKey = column.Name()
+		}
+	}
+	s.tables[table] = columns
+	return nil
+}
+
+// column 校验字段引用并返回 table.column 形式, 未指定表名时字段需属于主表, 避免关联查询中字段名有歧义
+func (s *exportSchema) column(ref string) (string, error) {
+	m := exportColumnPattern.FindStringSubmatch(strings.TrimSpace(ref))
+	if m == nil {
+		return "", fmt.Errorf("字段 %s 不合法", ref)
+	}
+	return s.hasColumn(m[1], m[2])
+}
+
+func (s *exportSchema) hasColumn(table, column string) (string, error) {
+	if table == "" {
+		table = s.main
+	}
+	columns, ok := s.tables[table]
+	if !ok {
+		return "", fmt.Errorf("表 %s 不在模板的主表或关联表中", table)
+	}
+	if _, ok = columns[column]; !ok {
+		return "", fmt.Errorf("表 %s 中不存在字段 %s", table, column)
+	}
+	return table + "." + column, nil
+}
+
+// tenantTable 模板涉及的第一个工作空间数据表, 没有时返回空
+func (s *exportSchema) tenantTable() string {
+	for table, columns := range s.tables {
+		for _, column := range exportTenantColumns {
+			if _, ok := columns[column]; ok {
+				return table
+			}
+		}
+	}
+	return ""
+}
+
+// fields 主表字段
+func (s *exportSchema) fields() map[string]gorm.ColumnType {
+	return s.tables[s.main]
+}
+
+// exportTemplateDB 模板使用的数据库, 未配置的数据库返回错误而不是 panic
+func exportTemplateDB(dbName string) (*gorm.DB, error) {
+	if dbName == "" {
+		return global.GVA_DB, nil
+	}
+	db := global.GetGlobalDBByDBName(dbName)
+	if db == nil {
+		return nil, fmt.Errorf("数据库 %s 未配置", dbName)
+	}
+	return db, nil
+}
+
+// ValidateSysExportTemplate
+// @function: ValidateSysExportTemplate
+// @description: 保存导出模板前校验, 表、字段、操作符与关联条件均需合法, 关联条件统一为结构化字段, 且在角色的数据范围内
+// @param: template *system.SysExportTemplate, authorityID uint
+// @return: err error
+func (sysExportTemplateService *SysExportTemplateService) ValidateSysExportTemplate(template *system.SysExportTemplate, authorityID uint) (err error) {
+	schema, err := sysExportTemplateService.checkTemplate(template)
+	if err != nil {
+		return err
+	}
+	return sysExportTemplateService.checkDataScope(schema, authorityID)
+}
+
+// checkDataScope 数据范围不是全部工作空间的角色不能使用工作空间数据表, 避免通过导出模板绕过数据范围
+func (sysExportTemplateService *SysExportTemplateService) checkDataScope(schema *exportSchema, authorityID uint) error {
+	dataScope, err := DataScopeServiceApp.GetDataScope(authorityID)
+	if err != nil {
+		return err
+	}
+	if dataScope.Scope == system.DataScopeAll {
+		return nil
+	}
+	if table := schema.tenantTable(); table != "" {
+		return fmt.Errorf("表 %s 属于工作空间数据, 当前角色的数据范围不能在导出模板中使用", table)
+	}
+	return nil
+}
+
+// CheckDBAuthority
+// @function: CheckDBAuthority
+// @description: 通过 casbin 校验角色能否在导出模板中使用该数据库
+// @param: dbName string, authorityID uint
+// @return: err error
+func (sysExportTemplateService *SysExportTemplateService) CheckDBAuthority(dbName string, authorityID uint) (err error) {
+	if dbName == "" {
+		dbName = system.ExportDBDefault
+	}
+	ok, err := CasbinServiceApp.Casbin().Enforce(strconv.Itoa(int(authorityID)), system.ExportDBPathPrefix+dbName, system.ExportDBMethod)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("没有使用数据库 %s 的权限", dbName)
+	}
+	return nil
+}
+
+// CheckTemplateAuthority
+// @function: CheckTemplateAuthority
+// @description: 校验角色能否使用模板所在的数据库与模板中的表, 导出、导入前调用
+// @param: templateID string, authorityID uint
+// @return: err error
+func (sysExportTemplateService *SysExportTemplateService) CheckTemplateAuthority(templateID string, authorityID uint) (err error) {
+	var template system.SysExportTemplate
+	if err = global.GVA_DB.Preload("Conditions").Preload("JoinTemplate").First(&template, "template_id = ?", templateID).Error; err != nil {
+		return errors.New("导出模板不存在")
+	}
+	if err = sysExportTemplateService.CheckDBAuthority(template.DBName, authorityID); err != nil {
+		return err
+	}
+	schema, err := sysExportTemplateService.checkTemplate(&template)
+	if err != nil {
+		return err
+	}
+	return sysExportTemplateService.checkDataScope(schema, authorityID)
+}
+
+// CheckTemplateIDsAuthority
+// @function: CheckTemplateIDsAuthority
+// @description: 校验角色能否使用这些模板所在的数据库, 修改、删除模板前调用
+// @param: ids []int, authorityID uint
+// @return: err error
+func (sysExportTemplateService *SysExportTemplateService) CheckTemplateIDsAuthority(ids []int, authorityID uint) (err error) {
+	var dbNames []string
+	if err = global.GVA_DB.Model(&system.SysExportTemplate{}).Where("id IN ?", ids).Distinct().Pluck("db_name", &dbNames).Error; err != nil {
+		return err
+	}
+	for _, dbName := range dbNames {
+		if err = sysExportTemplateService.CheckDBAuthority(dbName, authorityID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncExportDBApis
+// @function: SyncExportDBApis
+// @description: 为主库与已配置的数据库登记api, 以便在角色的api权限中授予导出模板使用数据库的权限
+// @return: err error
+func (sysExportTemplateService *SysExportTemplateService) SyncExportDBApis() (err error) {
+	names := []string{system.ExportDBDefault, "*"}
+	for name := range global.GVA_DBList {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		description := "导出模板使用数据库 " + name
+		if name == "*" {
+			description = "导出模板使用全部数据库"
+		}
+		api := system.SysApi{ApiGroup: "导出数据库", Path: system.ExportDBPathPrefix + name, Method: system.ExportDBMethod, Description: description}
+		if err = global.GVA_DB.Where("path = ? AND method = ?", api.Path, api.Method).FirstOrCreate(&api).Error; err != nil {
+			return err
+		}
+	}
+	// 首次启用时为管理员授予全部数据库, 保持升级前管理员的导出行为
+	enforcer := CasbinServiceApp.Casbin()
+	policies, err := enforcer.GetFilteredPolicy(2, system.ExportDBMethod)
+	if err != nil || len(policies) > 0 {
+		return err
+	}
+	_, err = enforcer.AddPolicy(strconv.Itoa(system.AdminAuthorityId), system.ExportDBPathPrefix+"*", system.ExportDBMethod)
+	return err
+}
+
+// checkTemplate 校验模板并返回涉及的表结构
+func (sysExportTemplateService *SysExportTemplateService) checkTemplate(template *system.SysExportTemplate) (*exportSchema, error) {
+	db, err := exportTemplateDB(template.DBName)
+	if err != nil {
+		return nil, err
+	}
+	schema := &exportSchema{db: db, main: template.TableName, tables: make(map[string]map[string]gorm.ColumnType)}
+	if err = schema.addTable(template.TableName); err != nil {
+		return nil, err
+	}
+	for i := range template.JoinTemplate {
+		join := &template.JoinTemplate[i]
+		join.JOINS = strings.ToUpper(strings.Join(strings.Fields(join.JOINS), " "))
+		if !exportJoinTypes[join.JOINS] {
+			return nil, fmt.Errorf("不支持的关联方式 %s", join.JOINS)
+		}
+		if err = schema.addTable(join.Table); err != nil {
+			return nil, err
+		}
+	}
+	// 关联表全部登记后再校验关联条件, 条件中可以引用其他关联表
+	for i := range template.JoinTemplate {
+		join := &template.JoinTemplate[i]
+		if join.OnLeft == "" && join.OnRight == "" {
+			m := exportLegacyOnPattern.FindStringSubmatch(join.ON)
+			if m == nil {
+				return nil, fmt.Errorf("关联条件 %s 不合法, 仅支持 table1.a = table2.b", join.ON)
+			}
+			join.OnLeft, join.OnRight = m[1], m[2]
+		}
+		if join.OnLeft, err = schema.column(join.OnLeft); err != nil {
+			return nil, err
+		}
+		if join.OnRight, err = schema.column(join.OnRight); err != nil {
+			return nil, err
+		}
+		join.ON = join.OnLeft + " = " + join.OnRight
+	}
+	columns, err := utils.GetJSONKeys(template.TemplateInfo)
+	if err != nil {
+		return nil, errors.New("模板信息不是合法的JSON")
+	}
+	var templateInfoMap map[string]string
+	if err = json.Unmarshal([]byte(template.TemplateInfo), &templateInfoMap); err != nil {
+		return nil, errors.New("模板信息不是合法的JSON")
+	}
+	for _, key := range columns {
+		m := exportSelectPattern.FindStringSubmatch(strings.TrimSpace(key))
+		if m == nil {
+			return nil, fmt.Errorf("导出字段 %s 不合法, 仅支持 column、table.column 与 as 别名", key)
+		}
+		if _, err = schema.hasColumn(m[1], m[2]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range template.Conditions {
+		condition := &template.Conditions[i]
+		condition.Operator = strings.ToUpper(strings.Join(strings.Fields(condition.Operator), " "))
+		if !exportOperators[condition.Operator] {
+			return nil, fmt.Errorf("不支持的操作符 %s", condition.Operator)
+		}
+		if condition.From == "" {
+			return nil, errors.New("条件取值的key不能为空")
+		}
+		if condition.Column, err = schema.column(condition.Column); err != nil {
+			return nil, err
+		}
+	}
+	if template.Order != "" {
+		if _, _, err = parseExportOrder(template.Order, schema.fields()); err != nil {
+			return nil, err
+		}
+	}
+	// Extend Start: import validation
+	if template.ImportKey != "" {
+		if _, ok := schema.fields()[template.ImportKey]; !ok {
+			return nil, fmt.Errorf("导入匹配字段 %s 不在主表中", template.ImportKey)
+		}
+	}
+	for key, rule := range template.ImportRules {
+		if _, ok := templateInfoMap[key]; !ok {
+			if _, ok = schema.fields()[key]; !ok {
+				return nil, fmt.Errorf("校验规则的字段 %s 不在模板中", key)
+			}
+		}
+		if !importTypes[rule.Type] {
+			return nil, fmt.Errorf("字段 %s 的类型 %s 不支持", key, rule.Type)
+		}
+		if rule.Pattern != "" {
+			if _, err = regexp.Compile(rule.Pattern); err != nil {
+				return nil, fmt.Errorf("字段 %s 的正则 %s 不合法", key, rule.Pattern)
+			}
+		}
+	}
+	// Extend Stop: import validation
+	return schema, nil
+}
+
+// parseExportOrder 解析 "column [asc|desc]", 排序字段需属于主表
+func parseExportOrder(order string, fields map[string]gorm.ColumnType) (column string, desc bool, err error) {
+	parts := strings.Fields(order)
+	if len(parts) == 0 || len(parts) > 2 {
+		return "", false, fmt.Errorf("order by %s is not secure", order)
+	}
+	if _, ok := fields[parts[0]]; !ok {
+		return "", false, fmt.Errorf("order by %s is not in the fields", order)
+	}
+	if len(parts) > 1 {
+		direction := strings.ToLower(parts[1])
+		if direction != "asc" && direction != "desc" {
+			return "", false, fmt.Errorf("order by %s is not secure", order)
+		}
+		desc = direction == "desc"
+	}
+	return parts[0], desc, nil
+}
@@ -0,0 +1,232 @@
+package system
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	adapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+func TestCheckTemplate(t *testing.T) {
+	detailJoin := system.JoinTemplate{JOINS: "left  join", Table: "sys_dictionary_details",
+		ON: "sys_dictionaries.id = sys_dictionary_details.sys_dictionary_id"}
+	tests := []struct {
+		name     string
+		template system.SysExportTemplate
+		wantErr  string
+	}{
+		{
+			name: "合法模板",
+			template: system.SysExportTemplate{TableName: "sys_dictionaries", Order: "id desc",
+				TemplateInfo: `{"name":"名称","sys_dictionary_details.label as label":"展示值"}`,
+				JoinTemplate: []system.JoinTemplate{detailJoin},
+				Conditions:   []system.Condition{{From: "type", Column: "type", Operator: "like"}}},
+		},
+		{
+			name:     "主表为凭据表",
+			template: system.SysExportTemplate{TableName: "sys_users", TemplateInfo: `{"password":"密码"}`},
+			wantErr:  "不允许",
+		},
+		{
+			name:     "大小写不同的凭据表",
+			template: system.SysExportTemplate{TableName: "API_Tokens", TemplateInfo: `{"token":"令牌"}`},
+			wantErr:  "不允许",
+		},
+		{
+			name: "关联权限表",
+			template: system.SysExportTemplate{TableName: "sys_dictionaries", TemplateInfo: `{"name":"名称"}`,
+				JoinTemplate: []system.JoinTemplate{{JOINS: "LEFT JOIN", Table: "casbin_rule", ON: "sys_dictionaries.id = casbin_rule.v0"}}},
+			wantErr: "不允许",
+		},
+		{
+			name:     "表名不合法",
+			template: system.SysExportTemplate{TableName: "sys_dictionaries;drop", TemplateInfo: `{"name":"名称"}`},
+			wantErr:  "表名",
+		},
+		{
+			name:     "字段不存在",
+			template: system.SysExportTemplate{TableName: "sys_dictionaries", TemplateInfo: `{"secret":"密钥"}`},
+			wantErr:  "不存在字段",
+		},
+		{
+			name:     "导出字段包含表达式",
+			template: system.SysExportTemplate{TableName: "sys_dictionaries", TemplateInfo: `{"(select 1)":"注入"}`},
+			wantErr:  "不合法",
+		},
+		{
+			name: "操作符不支持",
+			template: system.SysExportTemplate{TableName: "sys_dictionaries", TemplateInfo: `{"name":"名称"}`,
+				Conditions: []system.Condition{{From: "type", Column: "type", Operator: "= 1 OR 1 ="}}},
+			wantErr: "操作符",
+		},
+		{
+			name:     "排序不安全",
+			template: system.SysExportTemplate{TableName: "sys_dictionaries", TemplateInfo: `{"name":"名称"}`, Order: "id; drop"},
+			wantErr:  "order by",
+		},
+		{
+			name: "导入匹配字段不在主表",
+			template: system.SysExportTemplate{TableName: "sys_dictionaries", TemplateInfo: `{"name":"名称"}`,
+				ImportKey: "label"},
+			wantErr: "导入匹配字段",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &system.SysDictionary{}, &system.SysDictionaryDetail{}, &system.SysUser{})
+			if err := db.Exec(`CREATE TABLE casbin_rule (id integer primary key, ptype text, v0 text, v1 text, v2 text)`).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Exec(`CREATE TABLE api_tokens (id text primary key, token text)`).Error; err != nil {
+				t.Fatal(err)
+			}
+			template := tt.template
+			_, err := (&SysExportTemplateService{}).checkTemplate(&template)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkTemplate() error = %v", err)
+				}
+				join := template.JoinTemplate[0]
+				if join.JOINS != "LEFT JOIN" || join.ON != "sys_dictionaries.id = sys_dictionary_details.sys_dictionary_id" {
+					t.Errorf("关联未规范化: %+v", join)
+				}
+				if template.Conditions[0].Column != "sys_dictionaries.type" || template.Conditions[0].Operator != "LIKE" {
+					t.Errorf("条件未规范化: %+v", template.Conditions[0])
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("checkTemplate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepareExportDateRange(t *testing.T) {
+	db := newTestDB(t, &system.SysExportTemplate{}, &system.Condition{}, &system.JoinTemplate{}, &system.SysDictionary{})
+	template := system.SysExportTemplate{TemplateID: "dict", TableName: "sys_dictionaries", TemplateInfo: `{"name":"名称"}`,
+		Conditions: []system.Condition{{From: "created", Column: "created_at", Operator: "BETWEEN"}}}
+	if err := db.Create(&template).Error; err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2024, 3, 12, 0, 0, 0, 0, time.Local)
+	for name, at := range map[string]time.Time{
+		"start":     day.AddDate(0, 0, -6),
+		"last":      day.Add(24*time.Hour - 500*time.Millisecond),
+		"next":      day.AddDate(0, 0, 1),
+		"before":    day.AddDate(0, 0, -6).Add(-time.Millisecond),
+		"yesterday": day.Add(12 * time.Hour),
+	} {
+		dict := system.SysDictionary{Name: name}
+		dict.CreatedAt = at
+		if err := db.Create(&dict).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	values, err := utils.ResolveDateTokens(url.Values{"created": {"{{last_7_days}}"}}, day.AddDate(0, 0, 1).Add(10*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := (&SysExportTemplateService{}).PrepareExport("dict", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	if err = plan.db.Order("name").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"last", "start", "yesterday"}; strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("区间内的记录 = %v, want %v", names, want)
+	}
+}
+
+var (
+	difyTablePattern  = regexp.MustCompile(`__tablename__\s*=\s*["'](\w+)["']`)
+	difyColumnPattern = regexp.MustCompile(`^\s+(\w+)\s*(?::[^=]*)?=\s*(?:db\.Column|mapped_column)\(`)
+	// 保存密码、令牌、密钥或加密凭据的字段
+	difyCredentialPattern = regexp.MustCompile(`^(password|token|api_key|access_token|app_secret|credentials|credentials_str|tracing_config)$|^encrypted_`)
+)
+
+// difySchema 从 api/models 解析 gaia 的表及字段
+func difySchema(t *testing.T) map[string][]string {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join("..", "..", "..", "..", "api", "models", "*.py"))
+	if len(files) == 0 {
+		t.Skip("未找到 api/models")
+	}
+	tables := make(map[string][]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table := ""
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "class ") {
+				table = ""
+			}
+			if m := difyTablePattern.FindStringSubmatch(line); m != nil {
+				table = m[1]
+				tables[table] = nil
+			} else if m = difyColumnPattern.FindStringSubmatch(line); m != nil && table != "" {
+				tables[table] = append(tables[table], m[1])
+			}
+		}
+	}
+	return tables
+}
+
+// 禁用的表须真实存在, gaia 中保存凭据的表须全部禁用
+func TestExportDeniedTables(t *testing.T) {
+	dify := difySchema(t)
+	db := newTestDB(t, &system.SysUser{}, &system.SysUserAuthority{}, &system.SysAuthority{}, &system.SysAuthorityMenu{},
+		&system.SysAuthorityDataScope{}, &system.SysAuthorityGrant{}, &system.SysApi{}, &system.SysIgnoreApi{},
+		&adapter.CasbinRule{}, &system.JwtBlacklist{}, &system.SysUserSession{}, &system.SysUserMfa{},
+		&system.SysAccessToken{}, &system.SysExportTemplate{}, &system.Condition{}, &system.JoinTemplate{})
+	for table := range exportDeniedTables {
+		if _, ok := dify[table]; !ok && !db.Migrator().HasTable(table) {
+			t.Errorf("禁用的表 %s 不存在", table)
+		}
+	}
+	// 外部知识库的 api_key 保存在 settings 中
+	credentials := map[string]bool{"external_knowledge_apis": true}
+	for table, columns := range dify {
+		for _, column := range columns {
+			if difyCredentialPattern.MatchString(column) {
+				credentials[table] = true
+			}
+		}
+	}
+	for table := range credentials {
+		if !exportDeniedTables[table] {
+			t.Errorf("表 %s 保存凭据, 需要禁止在导出模板中使用", table)
+		}
+	}
+}
+
+func TestExportTemplateDataScope(t *testing.T) {
+	db := newTestDB(t, &system.SysDictionary{}, &system.SysAuthorityDataScope{})
+	if err := db.Exec(`CREATE TABLE conversations (id text primary key, app_id text, name text)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&system.SysAuthorityDataScope{AuthorityId: 9528, Scope: system.DataScopeCustom, TenantIDs: []string{"t1"}})
+	service := &SysExportTemplateService{}
+	gaiaTemplate := system.SysExportTemplate{TableName: "conversations", TemplateInfo: `{"name":"名称"}`}
+	if err := service.ValidateSysExportTemplate(&gaiaTemplate, system.AdminAuthorityId); err != nil {
+		t.Errorf("全部数据范围的角色 ValidateSysExportTemplate() error = %v", err)
+	}
+	if err := service.ValidateSysExportTemplate(&gaiaTemplate, 9528); err == nil || !strings.Contains(err.Error(), "工作空间") {
+		t.Errorf("指定工作空间的角色使用 gaia 表 error = %v", err)
+	}
+	dictTemplate := system.SysExportTemplate{TableName: "sys_dictionaries", TemplateInfo: `{"name":"名称"}`}
+	if err := service.ValidateSysExportTemplate(&dictTemplate, 9528); err != nil {
+		t.Errorf("指定工作空间的角色使用后台表 error = %v", err)
+	}
+}
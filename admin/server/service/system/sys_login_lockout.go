@@ -0,0 +1,381 @@
+package system
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 与 Dify 共用的账号失败计数, 只限制新的登录, 不影响已签发的令牌
+	accountLockPrefix = "login_error_rate_limit:"
+	ipLockPrefix      = "login_error_rate_limit_ip:"
+	// 锁定次数, 用于逐级延长锁定时长
+	lockLevelPrefix = "login_lock_level:"
+	lockLevelTTL    = 24 * time.Hour
+	// 锁定期间同一账号与IP的失败在窗口内只记录一条登录历史, 避免刷爆登录历史表
+	historyThrottlePrefix = "login_history_throttle:"
+	historyThrottleWindow = time.Minute
+
+	LockTypeAccount = "account"
+	LockTypeIP      = "ip"
+)
+
+// 去掉版本号后的UA作为设备指纹, 避免浏览器升级被识别为新设备
+var userAgentVersionRegexp = regexp.MustCompile(`[\d._]+`)
+
+type LockoutService struct{}
+
+var LockoutServiceApp = new(LockoutService)
+
+// AccountKey
+// @function: AccountKey
+// @description: 将登录输入的用户名或邮箱统一为邮箱, 找不到用户时使用原始输入
+// @param: identifier string
+// @return: string
+func (l *LockoutService) AccountKey(identifier string) string {
+	identifier = strings.TrimSpace(identifier)
+	var email string
+	global.GVA_DB.Model(&system.SysUser{}).Select("email").
+		Where("username = ? or email = ?", identifier, identifier).Limit(1).Scan(&email)
+	if len(email) > 0 {
+		return email
+	}
+	return identifier
+}
+
+// Check
+// @function: Check
+// @description: 检查账号与IP是否处于锁定中
+// @param: account string, ip string
+// @return: err error
+func (l *LockoutService) Check(account, ip string) error {
+	if ttl, ok := l.locked(accountLockPrefix+account, global.GVA_CONFIG.Gaia.LoginMaxErrorLimit); len(account) > 0 && ok {
+		return fmt.Errorf("登录失败次数过多, 账号已锁定, 请%s后重试", lockDurationText(ttl))
+	}
+	if ttl, ok := l.locked(ipLockPrefix+ip, global.GVA_CONFIG.Lockout.IpMaxErrorLimit); len(ip) > 0 && ok {
+		return fmt.Errorf("登录失败次数过多, 当前IP已锁定, 请%s后重试", lockDurationText(ttl))
+	}
+	return nil
+}
+
+// Fail
+// @function: Fail
+// @description: 记录一次登录失败, 达到上限时锁定并通知管理员
+// @param: userID uint, account string, method string, ip string, userAgent string, reason string
+// @return: locked bool
+func (l *LockoutService) Fail(userID uint, account, method, ip, userAgent, reason string) (locked bool) {
+	blocked := l.blocked(account, ip)
+	var lockedFor []string
+	if len(account) > 0 {
+		if d, ok := l.incr(accountLockPrefix+account, global.GVA_CONFIG.Gaia.LoginMaxErrorLimit); ok {
+			lockedFor = append(lockedFor, fmt.Sprintf("账号 %s 锁定%s", html.EscapeString(account), lockDurationText(d)))
+		}
+	}
+	if len(ip) > 0 {
+		if d, ok := l.incr(ipLockPrefix+ip, global.GVA_CONFIG.Lockout.IpMaxErrorLimit); ok {
+			lockedFor = append(lockedFor, fmt.Sprintf("IP %s 锁定%s", html.EscapeString(ip), lockDurationText(d)))
+		}
+	}
+	locked = len(lockedFor) > 0
+	if blocked && !locked && l.throttled(account, ip) {
+		return false
+	}
+	l.record(system.SysLoginHistory{
+		UserID:    userID,
+		Account:   account,
+		Method:    method,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+		Locked:    locked,
+	})
+	if locked {
+		global.GVA_LOG.Warn("登录失败次数过多, 已锁定", zap.Strings("lock", lockedFor))
+		// 账号、User-Agent与失败原因来自登录请求, 写入邮件前转义
+		l.notify("登录锁定告警", fmt.Sprintf("%s<br/>登录方式: %s<br/>IP: %s<br/>User-Agent: %s<br/>最后一次失败原因: %s",
+			strings.Join(lockedFor, "<br/>"), html.EscapeString(method), html.EscapeString(ip),
+			html.EscapeString(userAgent), html.EscapeString(reason)))
+	}
+	return locked
+}
+
+// Record
+// @function: Record
+// @description: 记录不计入失败次数的登录失败, 如用户被禁用或已锁定, 同一账号与IP在窗口内只记录一条
+// @param: userID uint, account string, method string, ip string, userAgent string, reason string
+func (l *LockoutService) Record(userID uint, account, method, ip, userAgent, reason string) {
+	if l.throttled(account, ip) {
+		return
+	}
+	l.record(system.SysLoginHistory{
+		UserID:    userID,
+		Account:   account,
+		Method:    method,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+	})
+}
+
+// Succeed
+// @function: Succeed
+// @description: 登录成功, 清空账号失败计数并检测新IP/新设备
+// @param: user system.SysUser, method string, ip string, userAgent string
+func (l *LockoutService) Succeed(user system.SysUser, method, ip, userAgent string) {
+	if len(user.Email) > 0 {
+		global.GVA_REDIS.Del(context.Background(), accountLockPrefix+user.Email)
+	}
+	history := system.SysLoginHistory{
+		UserID:     user.ID,
+		Account:    user.Email,
+		Method:     method,
+		IP:         ip,
+		UserAgent:  userAgent,
+		DeviceHash: l.deviceHash(userAgent),
+		Success:    true,
+	}
+	var total, sameIP, sameDevice int64
+	db := global.GVA_DB.Model(&system.SysLoginHistory{}).Where("user_id = ? AND success = ?", user.ID, true)
+	db.Session(&gorm.Session{}).Count(&total)
+	if total > 0 {
+		db.Session(&gorm.Session{}).Where("ip = ?", ip).Count(&sameIP)
+		db.Session(&gorm.Session{}).Where("device_hash = ?", history.DeviceHash).Count(&sameDevice)
+		history.NewIP = sameIP == 0
+		history.NewDevice = sameDevice == 0
+	}
+	l.record(history)
+	if history.NewIP && history.NewDevice {
+		global.GVA_LOG.Warn("可疑登录: 新IP与新设备", zap.String("account", user.Email), zap.String("ip", ip))
+		l.notify("可疑登录提醒", fmt.Sprintf("用户 %s(%s) 使用新的IP与设备登录<br/>登录方式: %s<br/>IP: %s<br/>User-Agent: %s",
+			html.EscapeString(user.NickName), html.EscapeString(user.Email), html.EscapeString(method),
+			html.EscapeString(ip), html.EscapeString(userAgent)))
+	}
+}
+
+// Unlock
+// @function: Unlock
+// @description: 管理员解除账号或IP锁定, 同时重置锁定等级
+// @param: req systemReq.LoginUnlockReq
+// @return: err error
+func (l *LockoutService) Unlock(req systemReq.LoginUnlockReq) error {
+	if len(req.Account) == 0 && len(req.IP) == 0 {
+		return errors.New("请填写需要解锁的账号或IP")
+	}
+	ctx := context.Background()
+	if len(req.Account) > 0 {
+		account := l.AccountKey(req.Account)
+		// 被禁用的用户同样使用该键, 需先启用用户
+		var user system.SysUser
+		if err := global.GVA_DB.Where("email = ?", account).First(&user).Error; err == nil && user.Enable != system.UserActive {
+			return errors.New("用户已被禁用, 请先启用该用户")
+		}
+		global.GVA_REDIS.Del(ctx, accountLockPrefix+account, lockLevelPrefix+accountLockPrefix+account)
+	}
+	if len(req.IP) > 0 {
+		global.GVA_REDIS.Del(ctx, ipLockPrefix+req.IP, lockLevelPrefix+ipLockPrefix+req.IP)
+	}
+	return nil
+}
+
+// GetLockList
+// @function: GetLockList
+// @description: 获取当前生效的账号与IP锁定
+// @return: list []systemRes.LoginLock, err error
+func (l *LockoutService) GetLockList() (list []systemRes.LoginLock, err error) {
+	var accounts, ips []systemRes.LoginLock
+	if accounts, err = l.scan(LockTypeAccount, accountLockPrefix, global.GVA_CONFIG.Gaia.LoginMaxErrorLimit); err != nil {
+		return nil, err
+	}
+	if ips, err = l.scan(LockTypeIP, ipLockPrefix, global.GVA_CONFIG.Lockout.IpMaxErrorLimit); err != nil {
+		return nil, err
+	}
+	return append(accounts, ips...), nil
+}
+
+// GetLoginHistoryList
+// @function: GetLoginHistoryList
+// @description: 分页获取登录历史
+// @param: info systemReq.SysLoginHistorySearch
+// @return: list []system.SysLoginHistory, total int64, err error
+func (l *LockoutService) GetLoginHistoryList(info systemReq.SysLoginHistorySearch) (list []system.SysLoginHistory, total int64, err error) {
+	db := global.GVA_DB.Model(&system.SysLoginHistory{})
+	if info.UserID > 0 {
+		db = db.Where("user_id = ?", info.UserID)
+	}
+	if info.Account != "" {
+		db = db.Where("account LIKE ?", "%"+info.Account+"%")
+	}
+	if info.IP != "" {
+		db = db.Where("ip = ?", info.IP)
+	}
+	if info.Success != nil {
+		db = db.Where("success = ?", *info.Success)
+	}
+	if info.AnomalyOnly {
+		db = db.Where("new_ip = ? OR new_device = ? OR locked = ?", true, true, true)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// locked 计数达到上限即为锁定, 返回剩余时间
+func (l *LockoutService) locked(key string, limit int) (time.Duration, bool) {
+	if limit <= 0 {
+		return 0, false
+	}
+	ctx := context.Background()
+	count, err := global.GVA_REDIS.Get(ctx, key).Int()
+	if err != nil || count < limit {
+		return 0, false
+	}
+	ttl, _ := global.GVA_REDIS.TTL(ctx, key).Result()
+	return ttl, true
+}
+
+// incr 失败计数+1, 首次失败开始统计窗口, 达到上限时按锁定等级设置锁定时长
+func (l *LockoutService) incr(key string, limit int) (time.Duration, bool) {
+	if limit <= 0 {
+		return 0, false
+	}
+	ctx := context.Background()
+	count, err := global.GVA_REDIS.Incr(ctx, key).Result()
+	if err != nil {
+		global.GVA_LOG.Error("登录失败计数失败!", zap.Error(err))
+		return 0, false
+	}
+	if count == 1 {
+		global.GVA_REDIS.Expire(ctx, key, l.duration(global.GVA_CONFIG.Lockout.Window, 15*time.Minute))
+	}
+	if count != int64(limit) {
+		return 0, false
+	}
+	level, _ := global.GVA_REDIS.Incr(ctx, lockLevelPrefix+key).Result()
+	global.GVA_REDIS.Expire(ctx, lockLevelPrefix+key, lockLevelTTL)
+	base := l.duration(global.GVA_CONFIG.Lockout.Duration, 15*time.Minute)
+	maxDuration := l.duration(global.GVA_CONFIG.Lockout.MaxDuration, 24*time.Hour)
+	d := base
+	for i := int64(1); i < level && d < maxDuration; i++ {
+		d *= 2
+	}
+	d = min(d, maxDuration)
+	global.GVA_REDIS.Expire(ctx, key, d)
+	return d, true
+}
+
+// blocked 账号或IP已处于锁定中
+func (l *LockoutService) blocked(account, ip string) bool {
+	if _, ok := l.locked(accountLockPrefix+account, global.GVA_CONFIG.Gaia.LoginMaxErrorLimit); len(account) > 0 && ok {
+		return true
+	}
+	_, ok := l.locked(ipLockPrefix+ip, global.GVA_CONFIG.Lockout.IpMaxErrorLimit)
+	return len(ip) > 0 && ok
+}
+
+// throttled 窗口内已记录过同一账号与IP的失败时返回true, redis异常时照常记录
+func (l *LockoutService) throttled(account, ip string) bool {
+	ok, err := global.GVA_REDIS.SetNX(context.Background(), historyThrottlePrefix+account+"|"+ip, 1, historyThrottleWindow).Result()
+	return err == nil && !ok
+}
+
+func (l *LockoutService) scan(lockType, prefix string, limit int) (list []systemRes.LoginLock, err error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	ctx := context.Background()
+	iter := global.GVA_REDIS.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		count, cErr := global.GVA_REDIS.Get(ctx, key).Int()
+		if cErr != nil || count < limit {
+			continue
+		}
+		ttl, _ := global.GVA_REDIS.TTL(ctx, key).Result()
+		list = append(list, systemRes.LoginLock{
+			Type:     lockType,
+			Target:   strings.TrimPrefix(key, prefix),
+			Failures: count,
+			TTL:      int64(ttl / time.Second),
+		})
+	}
+	if err = iter.Err(); errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	return list, err
+}
+
+func (l *LockoutService) record(history system.SysLoginHistory) {
+	if len(history.DeviceHash) == 0 {
+		history.DeviceHash = l.deviceHash(history.UserAgent)
+	}
+	if err := global.GVA_DB.Create(&history).Error; err != nil {
+		global.GVA_LOG.Error("记录登录历史失败!", zap.Error(err))
+	}
+}
+
+func (l *LockoutService) deviceHash(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgentVersionRegexp.ReplaceAllString(strings.ToLower(userAgent), "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// lockDurationText 剩余锁定时间, 不足一分钟按一分钟计
+func lockDurationText(d time.Duration) string {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	if minutes < 60 {
+		return fmt.Sprintf("%d分钟", minutes)
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%d小时", minutes/60)
+	}
+	return fmt.Sprintf("%d小时%d分钟", minutes/60, minutes%60)
+}
+
+func (l *LockoutService) duration(value string, fallback time.Duration) time.Duration {
+	if d, err := utils.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// notify 邮件通知超级管理员, 未配置邮箱服务时跳过
+func (l *LockoutService) notify(subject, body string) {
+	if !global.GVA_CONFIG.Lockout.NotifyAdmin || len(global.GVA_CONFIG.Email.Host) == 0 {
+		return
+	}
+	var to []string
+	global.GVA_DB.Model(&system.SysUser{}).Where("authority_id = ? AND enable = ? AND email <> ''",
+		system.AdminAuthorityId, system.UserActive).Pluck("email", &to)
+	if len(to) == 0 && len(global.GVA_CONFIG.Email.To) > 0 {
+		to = append(to, global.GVA_CONFIG.Email.To)
+	}
+	if len(to) == 0 {
+		return
+	}
+	go func() {
+		if err := emailUtils.Email(strings.Join(to, ","), subject, body); err != nil {
+			global.GVA_LOG.Error("发送登录告警邮件失败!", zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,312 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/util"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+)
+
+type PermissionService struct{}
+
+var PermissionServiceApp = new(PermissionService)
+
+// Simulate
+// @function: Simulate
+// @description: 模拟用户或角色的有效权限: 接口是否放行及命中的规则, 可见菜单与按钮, 数据范围, 树形角色下的越权配置
+// @param: adminAuthorityID uint, req systemReq.PermissionSimulateReq
+// @return: res systemRes.PermissionSimulation, err error
+func (p *PermissionService) Simulate(adminAuthorityID uint, req systemReq.PermissionSimulateReq) (res systemRes.PermissionSimulation, err error) {
+	res.UseStrictAuth = global.GVA_CONFIG.System.UseStrictAuth
+
+	var authorityIds []uint
+	var current uint
+	var accountID string
+	grantEnd := make(map[uint]time.Time)
+	switch {
+	case req.UserID != 0:
+		var user system.SysUser
+		if err = global.GVA_DB.Preload("Authorities").Where("id = ?", req.UserID).First(&user).Error; err != nil {
+			return res, errors.New("用户不存在")
+		}
+		res.User = &systemRes.SimulatedUser{ID: user.ID, Username: user.Username, NickName: user.NickName, AuthorityId: user.AuthorityId}
+		current, accountID = user.AuthorityId, user.UUID.String()
+		authorityIds = append(authorityIds, current)
+		for _, authority := range user.Authorities {
+			if !slices.Contains(authorityIds, authority.AuthorityId) {
+				authorityIds = append(authorityIds, authority.AuthorityId)
+			}
+		}
+		var grants []system.SysAuthorityGrant
+		if err = global.GVA_DB.Where("user_id = ? AND status = ?", user.ID, system.GrantStatusActive).Find(&grants).Error; err != nil {
+			return
+		}
+		for _, grant := range grants {
+			grantEnd[grant.AuthorityId] = grant.EndAt
+		}
+	case req.AuthorityId != 0:
+		current = req.AuthorityId
+		authorityIds = []uint{current}
+	default:
+		return res, errors.New("请指定用户或角色")
+	}
+	for _, id := range authorityIds {
+		if err = AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, id); err != nil {
+			return
+		}
+	}
+
+	var all []system.SysAuthority
+	if err = global.GVA_DB.Find(&all).Error; err != nil {
+		return
+	}
+	authorities := make(map[uint]system.SysAuthority, len(all))
+	for _, authority := range all {
+		authorities[authority.AuthorityId] = authority
+	}
+	if _, ok := authorities[current]; !ok && req.UserID == 0 {
+		return res, errors.New("角色不存在")
+	}
+
+	path := strings.TrimPrefix(req.Path, global.GVA_CONFIG.System.RouterPrefix)
+	method := strings.ToUpper(req.Method)
+	if path != "" {
+		if method == "" {
+			return res, errors.New("请指定请求方法")
+		}
+		res.Api = &systemRes.SimulatedApi{Path: path, Method: method, AllowedBy: []uint{}}
+		var apis []system.SysApi
+		if err = global.GVA_DB.Where("method = ?", method).Find(&apis).Error; err != nil {
+			return
+		}
+		for _, api := range apis {
+			if util.KeyMatch2(path, api.Path) {
+				res.Api.Registered, res.Api.Description = true, api.Description
+				break
+			}
+		}
+	}
+	var menu *system.SysBaseMenu
+	if req.Menu != "" {
+		res.Menu = &systemRes.SimulatedMenuCheck{Query: req.Menu, VisibleIn: []uint{}}
+		var menus []system.SysBaseMenu
+		if err = global.GVA_DB.Where("name = ? OR path = ?", req.Menu, req.Menu).Find(&menus).Error; err != nil {
+			return
+		}
+		if len(menus) > 0 {
+			menu = &menus[0]
+		}
+	}
+
+	for _, id := range authorityIds {
+		var item systemRes.SimulatedAuthority
+		if item, err = p.simulateAuthority(authorities, id, path, method, menu, accountID); err != nil {
+			return
+		}
+		item.Current = id == current
+		if end, ok := grantEnd[id]; ok {
+			item.GrantEndAt = &end
+		}
+		if item.Api != nil && item.Api.Allowed {
+			res.Api.AllowedBy = append(res.Api.AllowedBy, id)
+		}
+		if item.Menu != nil && item.Menu.Visible {
+			res.Menu.VisibleIn = append(res.Menu.VisibleIn, id)
+		}
+		res.Authorities = append(res.Authorities, item)
+	}
+
+	if res.Api != nil {
+		res.Api.Allowed = slices.Contains(res.Api.AllowedBy, current)
+		switch {
+		case res.Api.Allowed:
+			res.Api.Reason = fmt.Sprintf("当前角色 %d 由规则 %s 放行", current, res.Authorities[0].Api.Rule)
+		case len(res.Api.AllowedBy) > 0:
+			res.Api.Reason = fmt.Sprintf("当前角色 %d 没有该接口权限, 切换到角色 %v 后可访问", current, res.Api.AllowedBy)
+		case !res.Api.Registered:
+			res.Api.Reason = "接口未在接口管理中登记, 也没有任何角色拥有该接口权限"
+		default:
+			res.Api.Reason = "没有任何角色拥有该接口权限"
+		}
+	}
+	if res.Menu != nil {
+		res.Menu.Visible = slices.Contains(res.Menu.VisibleIn, current)
+		switch {
+		case menu == nil:
+			res.Menu.Reason = "菜单不存在"
+		case res.Menu.Visible:
+			res.Menu.Reason = res.Authorities[0].Menu.Reason
+		case len(res.Menu.VisibleIn) > 0:
+			res.Menu.Reason = fmt.Sprintf("当前角色 %d: %s, 切换到角色 %v 后可见", current, res.Authorities[0].Menu.Reason, res.Menu.VisibleIn)
+		default:
+			res.Menu.Reason = res.Authorities[0].Menu.Reason
+		}
+	}
+	return res, nil
+}
+
+// simulateAuthority 解析单个角色的接口、菜单、按钮与数据范围
+func (p *PermissionService) simulateAuthority(authorities map[uint]system.SysAuthority, id uint, path, method string, menu *system.SysBaseMenu, accountID string) (item systemRes.SimulatedAuthority, err error) {
+	authority, ok := authorities[id]
+	item = systemRes.SimulatedAuthority{AuthorityId: id, Ancestors: []uint{}, Menus: []systemRes.SimulatedMenu{}}
+	if !ok {
+		item.Warnings = append(item.Warnings, "角色不存在, 用户仍关联该角色ID")
+	} else {
+		item.AuthorityName = authority.AuthorityName
+		if authority.ParentId != nil {
+			item.ParentId = *authority.ParentId
+		}
+	}
+	for parent := item.ParentId; parent != 0 && !slices.Contains(item.Ancestors, parent); parent = derefUint(authorities[parent].ParentId) {
+		item.Ancestors = append([]uint{parent}, item.Ancestors...)
+	}
+	strict := global.GVA_CONFIG.System.UseStrictAuth
+	if strict {
+		item.Manages = descendantAuthorities(authorities, id)
+	}
+
+	sub := strconv.Itoa(int(id))
+	e := CasbinServiceApp.Casbin()
+	policies, err := e.GetFilteredPolicy(0, sub)
+	if err != nil {
+		return
+	}
+	if path != "" {
+		item.Api = &systemRes.SimulatedApiRule{}
+		if item.Api.Allowed, err = e.Enforce(sub, path, method); err != nil {
+			return
+		}
+		for _, policy := range policies {
+			if len(policy) >= 3 && policy[2] == method && util.KeyMatch2(path, policy[1]) {
+				item.Api.Rule = strings.Join(policy[:3], " ")
+				break
+			}
+		}
+	}
+
+	menus, err := MenuServiceApp.GetMenuTree(id)
+	if err != nil {
+		return
+	}
+	item.Menus = flattenSimulatedMenus(item.Menus, menus)
+	var menuIds []string
+	if err = global.GVA_DB.Model(&system.SysAuthorityMenu{}).Where("sys_authority_authority_id = ?", id).
+		Pluck("sys_base_menu_id", &menuIds).Error; err != nil {
+		return
+	}
+	if menu != nil {
+		item.Menu = &systemRes.SimulatedMenuRule{}
+		switch {
+		case slices.ContainsFunc(item.Menus, func(m systemRes.SimulatedMenu) bool { return m.ID == menu.ID }):
+			item.Menu.Visible, item.Menu.Reason = true, "菜单可见"
+			if menu.Hidden {
+				item.Menu.Reason = "菜单已分配但设置为隐藏, 不在侧边栏显示, 可通过路由访问"
+			}
+		case slices.Contains(menuIds, strconv.Itoa(int(menu.ID))):
+			item.Menu.Reason = "菜单已分配, 但上级菜单未分配, 无法显示"
+		default:
+			item.Menu.Reason = "角色未分配该菜单"
+		}
+	}
+	if ok && !slices.ContainsFunc(item.Menus, func(m systemRes.SimulatedMenu) bool { return m.Name == authority.DefaultRouter }) {
+		item.Warnings = append(item.Warnings, fmt.Sprintf("默认路由 %s 不在可见菜单中, 登录后将跳转到 404", authority.DefaultRouter))
+	}
+
+	scope, err := DataScopeServiceApp.GetDataScope(id)
+	if err != nil {
+		return
+	}
+	item.DataScope = systemRes.SimulatedDataScope{Scope: scope.Scope, TenantIDs: []string{}}
+	if scope.Scope == system.DataScopeOwn && accountID == "" {
+		item.Warnings = append(item.Warnings, "数据范围为用户自己加入的工作空间, 指定用户后才能解析")
+	} else {
+		tenantScope, sErr := DataScopeServiceApp.TenantScope(id, accountID)
+		if sErr != nil {
+			return item, sErr
+		}
+		item.DataScope.All = tenantScope.All
+		if tenantScope.TenantIDs != nil {
+			item.DataScope.TenantIDs = tenantScope.TenantIDs
+		}
+	}
+
+	// 树形角色下子角色的接口与菜单应是父角色的子集, 超出部分说明配置绕过了层级限制
+	if strict && item.ParentId != 0 {
+		parentPolicies, pErr := e.GetFilteredPolicy(0, strconv.Itoa(int(item.ParentId)))
+		if pErr != nil {
+			return item, pErr
+		}
+		for _, policy := range policies {
+			if len(policy) >= 3 && !slices.ContainsFunc(parentPolicies, func(pp []string) bool {
+				return len(pp) >= 3 && pp[1] == policy[1] && pp[2] == policy[2]
+			}) {
+				item.Warnings = append(item.Warnings, fmt.Sprintf("接口 %s %s 不在父角色 %d 的权限中", policy[2], policy[1], item.ParentId))
+			}
+		}
+		var parentMenuIds []string
+		if err = global.GVA_DB.Model(&system.SysAuthorityMenu{}).Where("sys_authority_authority_id = ?", item.ParentId).
+			Pluck("sys_base_menu_id", &parentMenuIds).Error; err != nil {
+			return
+		}
+		for _, m := range item.Menus {
+			if !slices.Contains(parentMenuIds, strconv.Itoa(int(m.ID))) {
+				item.Warnings = append(item.Warnings, fmt.Sprintf("菜单 %s 不在父角色 %d 的权限中", m.Name, item.ParentId))
+			}
+		}
+	}
+	return item, nil
+}
+
+// flattenSimulatedMenus 按菜单树先序展开, 按钮按名称排序
+func flattenSimulatedMenus(list []systemRes.SimulatedMenu, menus []system.SysMenu) []systemRes.SimulatedMenu {
+	for _, m := range menus {
+		buttons := make([]string, 0, len(m.Btns))
+		for name := range m.Btns {
+			buttons = append(buttons, name)
+		}
+		slices.Sort(buttons)
+		list = append(list, systemRes.SimulatedMenu{
+			ID:       m.MenuId,
+			ParentId: m.ParentId,
+			Name:     m.Name,
+			Path:     m.Path,
+			Title:    m.Title,
+			Hidden:   m.Hidden,
+			Buttons:  buttons,
+		})
+		list = flattenSimulatedMenus(list, m.Children)
+	}
+	return list
+}
+
+// descendantAuthorities 角色的全部子孙角色
+func descendantAuthorities(authorities map[uint]system.SysAuthority, id uint) (list []uint) {
+	queue := []uint{id}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, authority := range authorities {
+			if derefUint(authority.ParentId) == parent && authority.AuthorityId != id && !slices.Contains(list, authority.AuthorityId) {
+				list = append(list, authority.AuthorityId)
+				queue = append(queue, authority.AuthorityId)
+			}
+		}
+	}
+	slices.Sort(list)
+	return list
+}
+
+func derefUint(v *uint) uint {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
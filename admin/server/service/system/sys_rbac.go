@@ -0,0 +1,763 @@
+package system
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 配置包文件大小上限
+const rbacMaxSize = 16 << 20
+
+// errRbacDryRun 预览时回滚事务
+var errRbacDryRun = errors.New("rbac dry run")
+
+type RbacService struct{}
+
+var RbacServiceApp = new(RbacService)
+
+// ExportRbac
+// @function: ExportRbac
+// @description: 导出当前环境的角色、菜单、按钮、接口、casbin 规则与角色数据范围
+// @return: bundle system.RbacBundle, err error
+func (r *RbacService) ExportRbac() (bundle system.RbacBundle, err error) {
+	if bundle, err = loadRbac(global.GVA_DB); err != nil {
+		return
+	}
+	bundle.ExportedAt = time.Now()
+	return bundle, nil
+}
+
+// EncodeRbac
+// @function: EncodeRbac
+// @description: 按格式序列化配置包, 支持 json 与 yaml
+// @param: bundle system.RbacBundle, format string
+// @return: data []byte, err error
+func (r *RbacService) EncodeRbac(bundle system.RbacBundle, format string) (data []byte, err error) {
+	switch format {
+	case "", "json":
+		return json.MarshalIndent(bundle, "", "  ")
+	case "yaml", "yml":
+		return yaml.Marshal(bundle)
+	}
+	return nil, fmt.Errorf("不支持的格式: %s", format)
+}
+
+// DecodeRbac
+// @function: DecodeRbac
+// @description: 解析上传的配置包, .yaml/.yml 按 yaml 解析, 其余按 json 解析
+// @param: file *multipart.FileHeader
+// @return: bundle system.RbacBundle, err error
+func (r *RbacService) DecodeRbac(file *multipart.FileHeader) (bundle system.RbacBundle, err error) {
+	if file.Size > rbacMaxSize {
+		return bundle, errors.New("配置包过大")
+	}
+	f, err := file.Open()
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, rbacMaxSize))
+	if err != nil {
+		return
+	}
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &bundle)
+	default:
+		err = json.Unmarshal(data, &bundle)
+	}
+	if err != nil {
+		return bundle, fmt.Errorf("配置包解析失败: %w", err)
+	}
+	return bundle, nil
+}
+
+// ImportRbac
+// @function: ImportRbac
+// @description: 在一个事务中导入配置包并返回与导入前的差异, dryRun 时只计算差异并回滚;
+// 配置包中的角色以配置包为准覆盖其菜单、按钮、资源权限与 casbin 规则, prune 时删除配置包中没有的角色、菜单与接口
+// @param: bundle system.RbacBundle, prune bool, dryRun bool
+// @return: diff systemRes.RbacDiff, err error
+func (r *RbacService) ImportRbac(bundle system.RbacBundle, prune, dryRun bool) (diff systemRes.RbacDiff, err error) {
+	normalizeRbac(&bundle)
+	if err = validateRbac(bundle); err != nil {
+		return
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		current, err := loadRbac(tx)
+		if err != nil {
+			return err
+		}
+		diff = diffRbac(current, bundle, prune)
+		if err = applyRbac(tx, bundle, prune); err != nil {
+			return err
+		}
+		if dryRun {
+			return errRbacDryRun
+		}
+		return nil
+	})
+	diff.DryRun, diff.Prune = dryRun, prune
+	if errors.Is(err, errRbacDryRun) {
+		return diff, nil
+	}
+	if err != nil {
+		return diff, err
+	}
+	return diff, CasbinServiceApp.FreshCasbin()
+}
+
+// loadRbac 读取当前环境的配置, 自增ID全部换成稳定键
+func loadRbac(db *gorm.DB) (bundle system.RbacBundle, err error) {
+	bundle.Version = system.RbacBundleVersion
+
+	var menus []system.SysBaseMenu
+	if err = db.Preload("Parameters").Preload("MenuBtn").Find(&menus).Error; err != nil {
+		return
+	}
+	menuNames := make(map[uint]string, len(menus))
+	btnNames := make(map[uint]string)
+	for _, m := range menus {
+		menuNames[m.ID] = m.Name
+		for _, b := range m.MenuBtn {
+			btnNames[b.ID] = b.Name
+		}
+	}
+	for _, m := range menus {
+		menu := system.RbacMenu{
+			Name:      m.Name,
+			Parent:    menuNames[m.ParentId],
+			Path:      m.Path,
+			Component: m.Component,
+			Hidden:    m.Hidden,
+			Sort:      m.Sort,
+			Meta:      system.RbacMenuMeta(m.Meta),
+		}
+		for _, p := range m.Parameters {
+			menu.Parameters = append(menu.Parameters, system.RbacMenuParameter{Type: p.Type, Key: p.Key, Value: p.Value})
+		}
+		for _, b := range m.MenuBtn {
+			menu.Buttons = append(menu.Buttons, system.RbacMenuButton{Name: b.Name, Desc: b.Desc})
+		}
+		bundle.Menus = append(bundle.Menus, menu)
+	}
+
+	var apis []system.SysApi
+	if err = db.Find(&apis).Error; err != nil {
+		return
+	}
+	for _, a := range apis {
+		bundle.Apis = append(bundle.Apis, system.RbacApi{Path: a.Path, Method: a.Method, ApiGroup: a.ApiGroup, Description: a.Description})
+	}
+
+	var authorities []system.SysAuthority
+	if err = db.Preload("DataAuthorityId").Find(&authorities).Error; err != nil {
+		return
+	}
+	var authorityMenus []system.SysAuthorityMenu
+	if err = db.Find(&authorityMenus).Error; err != nil {
+		return
+	}
+	var authorityBtns []system.SysAuthorityBtn
+	if err = db.Find(&authorityBtns).Error; err != nil {
+		return
+	}
+	var rules []gormadapter.CasbinRule
+	if err = db.Where("ptype = ?", "p").Find(&rules).Error; err != nil {
+		return
+	}
+	var dataScopes []system.SysAuthorityDataScope
+	if err = db.Find(&dataScopes).Error; err != nil {
+		return
+	}
+	scopes := make(map[uint]system.SysAuthorityDataScope, len(dataScopes))
+	for _, d := range dataScopes {
+		scopes[d.AuthorityId] = d
+	}
+	for _, a := range authorities {
+		id := strconv.Itoa(int(a.AuthorityId))
+		authority := system.RbacAuthority{
+			AuthorityId:   a.AuthorityId,
+			AuthorityName: a.AuthorityName,
+			DefaultRouter: a.DefaultRouter,
+			Buttons:       map[string][]string{},
+		}
+		if a.ParentId != nil {
+			authority.ParentId = *a.ParentId
+		}
+		for _, d := range a.DataAuthorityId {
+			authority.DataAuthorities = append(authority.DataAuthorities, d.AuthorityId)
+		}
+		for _, m := range authorityMenus {
+			menuID, _ := strconv.Atoi(m.MenuId)
+			if name, ok := menuNames[uint(menuID)]; ok && m.AuthorityId == id {
+				authority.Menus = append(authority.Menus, name)
+			}
+		}
+		for _, b := range authorityBtns {
+			menu, btn := menuNames[b.SysMenuID], btnNames[b.SysBaseMenuBtnID]
+			if b.AuthorityId == a.AuthorityId && menu != "" && btn != "" {
+				authority.Buttons[menu] = append(authority.Buttons[menu], btn)
+			}
+		}
+		for _, rule := range rules {
+			if rule.V0 == id {
+				authority.Policies = append(authority.Policies, system.RbacPolicy{Path: rule.V1, Method: rule.V2})
+			}
+		}
+		// 没有记录的角色为全部工作空间, 同样导出, 导入时才会覆盖目标环境的设置
+		authority.DataScope = &system.RbacDataScope{Scope: system.DataScopeAll}
+		if d, ok := scopes[a.AuthorityId]; ok {
+			authority.DataScope = &system.RbacDataScope{Scope: d.Scope, TenantIDs: d.TenantIDs}
+		}
+		bundle.Authorities = append(bundle.Authorities, authority)
+	}
+	normalizeRbac(&bundle)
+	return bundle, nil
+}
+
+// normalizeRbac 排序并去重, 使导出结果稳定, 差异比较与顺序无关
+func normalizeRbac(bundle *system.RbacBundle) {
+	slices.SortFunc(bundle.Authorities, func(a, b system.RbacAuthority) int { return cmp.Compare(a.AuthorityId, b.AuthorityId) })
+	for i := range bundle.Authorities {
+		a := &bundle.Authorities[i]
+		slices.Sort(a.DataAuthorities)
+		a.DataAuthorities = slices.Compact(a.DataAuthorities)
+		slices.Sort(a.Menus)
+		a.Menus = slices.Compact(a.Menus)
+		for menu, btns := range a.Buttons {
+			if len(btns) == 0 {
+				delete(a.Buttons, menu)
+				continue
+			}
+			slices.Sort(btns)
+			a.Buttons[menu] = slices.Compact(btns)
+		}
+		slices.SortFunc(a.Policies, func(x, y system.RbacPolicy) int {
+			return cmp.Or(cmp.Compare(x.Path, y.Path), cmp.Compare(x.Method, y.Method))
+		})
+		a.Policies = slices.Compact(a.Policies)
+		if a.DataScope != nil {
+			slices.Sort(a.DataScope.TenantIDs)
+			a.DataScope.TenantIDs = slices.Compact(a.DataScope.TenantIDs)
+		}
+	}
+	slices.SortFunc(bundle.Menus, func(a, b system.RbacMenu) int { return cmp.Compare(a.Name, b.Name) })
+	for i := range bundle.Menus {
+		m := &bundle.Menus[i]
+		slices.SortStableFunc(m.Parameters, func(x, y system.RbacMenuParameter) int {
+			return cmp.Or(cmp.Compare(x.Type, y.Type), cmp.Compare(x.Key, y.Key))
+		})
+		slices.SortFunc(m.Buttons, func(x, y system.RbacMenuButton) int { return cmp.Compare(x.Name, y.Name) })
+	}
+	slices.SortFunc(bundle.Apis, func(a, b system.RbacApi) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Method, b.Method))
+	})
+}
+
+// validateRbac 检查配置包自身的完整性, 引用当前环境的部分在写入时检查
+func validateRbac(bundle system.RbacBundle) error {
+	if bundle.Version != system.RbacBundleVersion {
+		return fmt.Errorf("不支持的配置包版本: %d", bundle.Version)
+	}
+	menus := make(map[string]system.RbacMenu, len(bundle.Menus))
+	for _, m := range bundle.Menus {
+		if m.Name == "" {
+			return errors.New("菜单name不能为空")
+		}
+		if _, ok := menus[m.Name]; ok {
+			return fmt.Errorf("菜单 %s 重复", m.Name)
+		}
+		menus[m.Name] = m
+		for i := 1; i < len(m.Buttons); i++ {
+			if m.Buttons[i].Name == m.Buttons[i-1].Name {
+				return fmt.Errorf("菜单 %s 的按钮 %s 重复", m.Name, m.Buttons[i].Name)
+			}
+		}
+	}
+	for _, m := range bundle.Menus {
+		// 沿父菜单向上, 步数超过菜单数即存在循环
+		for parent, depth := m.Parent, 0; parent != ""; depth++ {
+			if depth > len(bundle.Menus) {
+				return fmt.Errorf("菜单 %s 的父菜单存在循环", m.Name)
+			}
+			p, ok := menus[parent]
+			if !ok {
+				break
+			}
+			parent = p.Parent
+		}
+	}
+	for i, a := range bundle.Apis {
+		if a.Path == "" || a.Method == "" {
+			return errors.New("接口路径与方法不能为空")
+		}
+		if i > 0 && a.Path == bundle.Apis[i-1].Path && a.Method == bundle.Apis[i-1].Method {
+			return fmt.Errorf("接口 %s %s 重复", a.Method, a.Path)
+		}
+	}
+	for i, a := range bundle.Authorities {
+		if a.AuthorityId == 0 || a.AuthorityName == "" {
+			return errors.New("角色ID与角色名不能为空")
+		}
+		if i > 0 && a.AuthorityId == bundle.Authorities[i-1].AuthorityId {
+			return fmt.Errorf("角色 %d 重复", a.AuthorityId)
+		}
+		if a.ParentId == a.AuthorityId {
+			return fmt.Errorf("角色 %d 不能是自己的父角色", a.AuthorityId)
+		}
+		for _, p := range a.Policies {
+			if p.Path == "" || p.Method == "" {
+				return fmt.Errorf("角色 %d 的规则路径与方法不能为空", a.AuthorityId)
+			}
+		}
+		if a.DataScope != nil {
+			if !slices.Contains(system.DataScopes, a.DataScope.Scope) {
+				return fmt.Errorf("角色 %d 的数据范围 %s 不支持", a.AuthorityId, a.DataScope.Scope)
+			}
+			if a.DataScope.Scope == system.DataScopeCustom && len(a.DataScope.TenantIDs) == 0 {
+				return fmt.Errorf("角色 %d 的数据范围未指定工作空间", a.AuthorityId)
+			}
+		}
+	}
+	return nil
+}
+
+// diffRbac 按稳定键比较当前配置与配置包
+func diffRbac(current, bundle system.RbacBundle, prune bool) systemRes.RbacDiff {
+	return systemRes.RbacDiff{
+		Authorities: diffRbacSection(current.Authorities, bundle.Authorities, rbacAuthorityKey, prune),
+		Menus:       diffRbacSection(current.Menus, bundle.Menus, func(m system.RbacMenu) string { return m.Name }, prune),
+		Apis:        diffRbacSection(current.Apis, bundle.Apis, rbacApiKey, prune),
+	}
+}
+
+func rbacAuthorityKey(a system.RbacAuthority) string {
+	return strconv.Itoa(int(a.AuthorityId))
+}
+
+func rbacApiKey(a system.RbacApi) string {
+	return a.Method + " " + a.Path
+}
+
+func diffRbacSection[T any](current, incoming []T, key func(T) string, prune bool) (diff systemRes.RbacSectionDiff) {
+	old := make(map[string]T, len(current))
+	for _, v := range current {
+		old[key(v)] = v
+	}
+	seen := make(map[string]bool, len(incoming))
+	for _, v := range incoming {
+		k := key(v)
+		seen[k] = true
+		o, ok := old[k]
+		if !ok {
+			diff.Added = append(diff.Added, k)
+			continue
+		}
+		if fields := diffRbacFields(o, v); len(fields) > 0 {
+			diff.Updated = append(diff.Updated, systemRes.RbacChange{Key: k, Fields: fields})
+		}
+	}
+	if prune {
+		for _, v := range current {
+			if k := key(v); !seen[k] {
+				diff.Removed = append(diff.Removed, k)
+			}
+		}
+	}
+	return diff
+}
+
+// diffRbacFields 返回值不同的字段(json 名), 空切片与 nil 视为相同, b 中为 nil 的指针字段不比较
+func diffRbacFields(a, b any) (fields []string) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fa, fb := va.Field(i), vb.Field(i)
+		if k := fa.Kind(); (k == reflect.Slice || k == reflect.Map) && fa.Len() == 0 && fb.Len() == 0 {
+			continue
+		}
+		// 配置包中为空的指针字段导入时不修改
+		if fa.Kind() == reflect.Pointer && fb.IsNil() {
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// applyRbac 依次写入菜单、接口与角色, 角色的绑定依赖菜单与按钮已写入
+func applyRbac(tx *gorm.DB, bundle system.RbacBundle, prune bool) error {
+	menuIDs, err := applyRbacMenus(tx, bundle.Menus, prune)
+	if err != nil {
+		return err
+	}
+	if err = applyRbacApis(tx, bundle.Apis, prune); err != nil {
+		return err
+	}
+	return applyRbacAuthorities(tx, bundle.Authorities, menuIDs, prune)
+}
+
+// applyRbacMenus 按 name 新增或更新菜单, 参数整体替换, 按钮按 key 保留ID以免角色的按钮权限失效
+func applyRbacMenus(tx *gorm.DB, menus []system.RbacMenu, prune bool) (menuIDs map[string]uint, err error) {
+	var existing []system.SysBaseMenu
+	if err = tx.Find(&existing).Error; err != nil {
+		return
+	}
+	menuIDs = make(map[string]uint, len(existing)+len(menus))
+	for _, m := range existing {
+		menuIDs[m.Name] = m.ID
+	}
+	keep := make(map[string]bool, len(menus))
+	for _, m := range menus {
+		keep[m.Name] = true
+		menu := system.SysBaseMenu{
+			Path:      m.Path,
+			Name:      m.Name,
+			Hidden:    m.Hidden,
+			Component: m.Component,
+			Sort:      m.Sort,
+			Meta:      system.Meta(m.Meta),
+		}
+		if id, ok := menuIDs[m.Name]; ok {
+			err = tx.Model(&system.SysBaseMenu{}).Where("id = ?", id).
+				Select("path", "hidden", "component", "sort", "active_name", "keep_alive", "default_menu", "title", "icon", "close_tab").
+				Updates(&menu).Error
+		} else {
+			err = tx.Omit(clause.Associations).Create(&menu).Error
+			menuIDs[m.Name] = menu.ID
+		}
+		if err != nil {
+			return
+		}
+	}
+	for _, m := range menus {
+		id := menuIDs[m.Name]
+		var parentID uint
+		if m.Parent != "" {
+			var ok bool
+			if parentID, ok = menuIDs[m.Parent]; !ok || (prune && !keep[m.Parent]) {
+				return nil, fmt.Errorf("菜单 %s 的父菜单 %s 不存在", m.Name, m.Parent)
+			}
+		}
+		if err = tx.Model(&system.SysBaseMenu{}).Where("id = ?", id).Update("parent_id", parentID).Error; err != nil {
+			return
+		}
+		if err = tx.Unscoped().Delete(&system.SysBaseMenuParameter{}, "sys_base_menu_id = ?", id).Error; err != nil {
+			return
+		}
+		for _, p := range m.Parameters {
+			if err = tx.Create(&system.SysBaseMenuParameter{SysBaseMenuID: id, Type: p.Type, Key: p.Key, Value: p.Value}).Error; err != nil {
+				return
+			}
+		}
+		if err = applyRbacButtons(tx, id, m.Buttons); err != nil {
+			return
+		}
+	}
+	if !prune {
+		return menuIDs, nil
+	}
+	for _, m := range existing {
+		if keep[m.Name] {
+			continue
+		}
+		delete(menuIDs, m.Name)
+		if err = tx.Delete(&system.SysBaseMenu{}, "id = ?", m.ID).Error; err != nil {
+			return
+		}
+		if err = tx.Unscoped().Delete(&system.SysBaseMenuParameter{}, "sys_base_menu_id = ?", m.ID).Error; err != nil {
+			return
+		}
+		if err = tx.Unscoped().Delete(&system.SysBaseMenuBtn{}, "sys_base_menu_id = ?", m.ID).Error; err != nil {
+			return
+		}
+		if err = tx.Delete(&system.SysAuthorityBtn{}, "sys_menu_id = ?", m.ID).Error; err != nil {
+			return
+		}
+		if err = tx.Delete(&system.SysAuthorityMenu{}, "sys_base_menu_id = ?", m.ID).Error; err != nil {
+			return
+		}
+	}
+	return menuIDs, nil
+}
+
+// applyRbacButtons 按 key 同步菜单按钮, 删除的按钮同时移除角色的按钮权限
+func applyRbacButtons(tx *gorm.DB, menuID uint, buttons []system.RbacMenuButton) error {
+	var existing []system.SysBaseMenuBtn
+	if err := tx.Where("sys_base_menu_id = ?", menuID).Find(&existing).Error; err != nil {
+		return err
+	}
+	old := make(map[string]system.SysBaseMenuBtn, len(existing))
+	for _, b := range existing {
+		old[b.Name] = b
+	}
+	for _, b := range buttons {
+		if o, ok := old[b.Name]; ok {
+			delete(old, b.Name)
+			if o.Desc != b.Desc {
+				if err := tx.Model(&system.SysBaseMenuBtn{}).Where("id = ?", o.ID).Update("desc", b.Desc).Error; err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := tx.Create(&system.SysBaseMenuBtn{Name: b.Name, Desc: b.Desc, SysBaseMenuID: menuID}).Error; err != nil {
+			return err
+		}
+	}
+	for _, b := range old {
+		if err := tx.Unscoped().Delete(&system.SysBaseMenuBtn{}, "id = ?", b.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&system.SysAuthorityBtn{}, "sys_base_menu_btn_id = ?", b.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyRbacApis 按方法+路径新增或更新接口, prune 时删除多余接口及其 casbin 规则
+func applyRbacApis(tx *gorm.DB, apis []system.RbacApi, prune bool) error {
+	var existing []system.SysApi
+	if err := tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	old := make(map[string]system.SysApi, len(existing))
+	for _, a := range existing {
+		old[a.Method+" "+a.Path] = a
+	}
+	for _, a := range apis {
+		key := rbacApiKey(a)
+		o, ok := old[key]
+		delete(old, key)
+		if !ok {
+			if err := tx.Create(&system.SysApi{Path: a.Path, Method: a.Method, ApiGroup: a.ApiGroup, Description: a.Description}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if o.ApiGroup != a.ApiGroup || o.Description != a.Description {
+			if err := tx.Model(&system.SysApi{}).Where("id = ?", o.ID).
+				Updates(map[string]interface{}{"api_group": a.ApiGroup, "description": a.Description}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if !prune {
+		return nil
+	}
+	for _, a := range old {
+		if err := tx.Delete(&system.SysApi{}, "id = ?", a.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&gormadapter.CasbinRule{}, "v1 = ? AND v2 = ?", a.Path, a.Method).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyRbacAuthorities 按角色ID新增或更新角色, 并以配置包覆盖其菜单、按钮、资源权限、casbin 规则与数据范围
+func applyRbacAuthorities(tx *gorm.DB, authorities []system.RbacAuthority, menuIDs map[string]uint, prune bool) error {
+	var existing []system.SysAuthority
+	if err := tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	exists := make(map[uint]bool, len(existing))
+	for _, a := range existing {
+		exists[a.AuthorityId] = true
+	}
+	incoming := make(map[uint]bool, len(authorities))
+	for _, a := range authorities {
+		incoming[a.AuthorityId] = true
+	}
+	// 引用的角色须在配置包中, 不删除时也可以是当前环境已有的角色
+	known := func(id uint) bool {
+		return incoming[id] || (!prune && exists[id])
+	}
+	for _, a := range authorities {
+		if a.ParentId != 0 && !known(a.ParentId) {
+			return fmt.Errorf("角色 %d 的父角色 %d 不存在", a.AuthorityId, a.ParentId)
+		}
+		parentID := a.ParentId
+		var err error
+		if exists[a.AuthorityId] {
+			err = tx.Model(&system.SysAuthority{}).Where("authority_id = ?", a.AuthorityId).Updates(map[string]interface{}{
+				"authority_name": a.AuthorityName,
+				"parent_id":      parentID,
+				"default_router": a.DefaultRouter,
+			}).Error
+		} else {
+			err = tx.Omit(clause.Associations).Create(&system.SysAuthority{
+				AuthorityId:   a.AuthorityId,
+				AuthorityName: a.AuthorityName,
+				ParentId:      &parentID,
+				DefaultRouter: a.DefaultRouter,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var btns []system.SysBaseMenuBtn
+	if err := tx.Find(&btns).Error; err != nil {
+		return err
+	}
+	btnIDs := make(map[uint]map[string]uint)
+	for _, b := range btns {
+		if btnIDs[b.SysBaseMenuID] == nil {
+			btnIDs[b.SysBaseMenuID] = make(map[string]uint)
+		}
+		btnIDs[b.SysBaseMenuID][b.Name] = b.ID
+	}
+	for _, a := range authorities {
+		id := strconv.Itoa(int(a.AuthorityId))
+		data := make([]*system.SysAuthority, 0, len(a.DataAuthorities))
+		for _, d := range a.DataAuthorities {
+			if !known(d) {
+				return fmt.Errorf("角色 %d 的资源权限角色 %d 不存在", a.AuthorityId, d)
+			}
+			data = append(data, &system.SysAuthority{AuthorityId: d})
+		}
+		if err := tx.Model(&system.SysAuthority{AuthorityId: a.AuthorityId}).Association("DataAuthorityId").Replace(data); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&system.SysAuthorityMenu{}, "sys_authority_authority_id = ?", id).Error; err != nil {
+			return err
+		}
+		authorityMenus := make([]system.SysAuthorityMenu, 0, len(a.Menus))
+		for _, name := range a.Menus {
+			menuID, ok := menuIDs[name]
+			if !ok {
+				return fmt.Errorf("角色 %d 的菜单 %s 不存在", a.AuthorityId, name)
+			}
+			authorityMenus = append(authorityMenus, system.SysAuthorityMenu{MenuId: strconv.Itoa(int(menuID)), AuthorityId: id})
+		}
+		if len(authorityMenus) > 0 {
+			if err := tx.Create(&authorityMenus).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&system.SysAuthorityBtn{}, "authority_id = ?", a.AuthorityId).Error; err != nil {
+			return err
+		}
+		var authorityBtns []system.SysAuthorityBtn
+		for menu, names := range a.Buttons {
+			menuID := menuIDs[menu]
+			for _, name := range names {
+				btnID, ok := btnIDs[menuID][name]
+				if !ok {
+					return fmt.Errorf("角色 %d 的按钮 %s/%s 不存在", a.AuthorityId, menu, name)
+				}
+				authorityBtns = append(authorityBtns, system.SysAuthorityBtn{AuthorityId: a.AuthorityId, SysMenuID: menuID, SysBaseMenuBtnID: btnID})
+			}
+		}
+		if len(authorityBtns) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&authorityBtns).Error; err != nil {
+				return err
+			}
+		}
+
+		if a.DataScope != nil {
+			var tenantIDs []string
+			if a.DataScope.Scope == system.DataScopeCustom {
+				tenantIDs = a.DataScope.TenantIDs
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "authority_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"scope", "tenant_ids", "updated_at"}),
+			}).Create(&system.SysAuthorityDataScope{
+				AuthorityId: a.AuthorityId,
+				Scope:       a.DataScope.Scope,
+				TenantIDs:   tenantIDs,
+				UpdatedAt:   time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		rules := make([][]string, 0, len(a.Policies))
+		for _, p := range a.Policies {
+			rules = append(rules, []string{id, p.Path, p.Method})
+		}
+		if err := CasbinServiceApp.RemoveFilteredPolicy(tx, id); err != nil {
+			return err
+		}
+		if len(rules) > 0 {
+			if err := CasbinServiceApp.AddPolicies(tx, rules); err != nil {
+				return err
+			}
+		}
+	}
+	if !prune {
+		return nil
+	}
+	for _, a := range existing {
+		if incoming[a.AuthorityId] {
+			continue
+		}
+		if err := deleteRbacAuthority(tx, a.AuthorityId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteRbacAuthority 删除配置包中没有的角色, 仍有用户使用时拒绝
+func deleteRbacAuthority(tx *gorm.DB, authorityID uint) error {
+	var users int64
+	if err := tx.Model(&system.SysUserAuthority{}).Where("sys_authority_authority_id = ?", authorityID).Count(&users).Error; err != nil {
+		return err
+	}
+	if users == 0 {
+		if err := tx.Model(&system.SysUser{}).Where("authority_id = ?", authorityID).Count(&users).Error; err != nil {
+			return err
+		}
+	}
+	if users > 0 {
+		return fmt.Errorf("角色 %d 仍有用户使用, 不能删除", authorityID)
+	}
+	id := strconv.Itoa(int(authorityID))
+	if err := tx.Exec("DELETE FROM sys_data_authority_id WHERE sys_authority_authority_id = ? OR data_authority_id_authority_id = ?",
+		authorityID, authorityID).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&system.SysAuthorityMenu{}, "sys_authority_authority_id = ?", id).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&system.SysAuthorityBtn{}, "authority_id = ?", authorityID).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&system.SysAuthorityDataScope{}, "authority_id = ?", authorityID).Error; err != nil {
+		return err
+	}
+	if err := CasbinServiceApp.RemoveFilteredPolicy(tx, id); err != nil {
+		return err
+	}
+	return tx.Delete(&system.SysAuthority{}, "authority_id = ?", authorityID).Error
+}
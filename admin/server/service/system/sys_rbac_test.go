@@ -0,0 +1,57 @@
+package system
+
+import (
+	"testing"
+
+	adapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+// 角色数据范围随配置包导出与导入, 配置包中没有数据范围时不修改
+func TestRbacDataScope(t *testing.T) {
+	db := newTestDB(t, &system.SysAuthority{}, &system.SysBaseMenu{}, &system.SysBaseMenuBtn{}, &system.SysBaseMenuParameter{},
+		&system.SysAuthorityMenu{}, &system.SysAuthorityBtn{}, &system.SysApi{}, &adapter.CasbinRule{}, &system.SysAuthorityDataScope{})
+	for _, id := range []uint{9528, 9529} {
+		if err := db.Create(&system.SysAuthority{AuthorityId: id, AuthorityName: "运维"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Create(&system.SysAuthorityDataScope{AuthorityId: 9528, Scope: system.DataScopeCustom, TenantIDs: []string{"t2", "t1"}})
+	bundle, err := loadRbac(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes := map[uint]*system.RbacDataScope{}
+	for _, a := range bundle.Authorities {
+		scopes[a.AuthorityId] = a.DataScope
+	}
+	if s := scopes[9528]; s == nil || s.Scope != system.DataScopeCustom || len(s.TenantIDs) != 2 || s.TenantIDs[0] != "t1" {
+		t.Fatalf("导出 9528 数据范围 = %+v", s)
+	}
+	if s := scopes[9529]; s == nil || s.Scope != system.DataScopeAll {
+		t.Fatalf("导出 9529 数据范围 = %+v", s)
+	}
+
+	db.Model(&system.SysAuthorityDataScope{}).Where("authority_id = ?", 9528).Update("scope", system.DataScopeAll)
+	db.Create(&system.SysAuthorityDataScope{AuthorityId: 9529, Scope: system.DataScopeOwn})
+	bundle.Authorities[1].DataScope = nil
+	if err = validateRbac(bundle); err != nil {
+		t.Fatal(err)
+	}
+	if err = applyRbacAuthorities(db, bundle.Authorities, map[string]uint{}, false); err != nil {
+		t.Fatalf("applyRbacAuthorities() error = %v", err)
+	}
+	var got system.SysAuthorityDataScope
+	if db.Where("authority_id = ?", 9528).First(&got); got.Scope != system.DataScopeCustom || len(got.TenantIDs) != 2 {
+		t.Errorf("导入后 9528 数据范围 = %+v", got)
+	}
+	var unchanged system.SysAuthorityDataScope
+	if db.Where("authority_id = ?", 9529).First(&unchanged); unchanged.Scope != system.DataScopeOwn {
+		t.Errorf("配置包中没有数据范围时不应修改, 9529 = %+v", unchanged)
+	}
+
+	bundle.Authorities[0].DataScope = &system.RbacDataScope{Scope: system.DataScopeCustom}
+	if err = validateRbac(bundle); err == nil {
+		t.Errorf("validateRbac() 指定工作空间的数据范围须包含工作空间")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common"
@@ -38,23 +39,62 @@ var UserServiceApp = new(UserService)
 // @param: u *model.SysUser
 // @return: err error, userInter *model.SysUser
 func (userService *UserService) Register(u system.SysUser, token string) (userInter system.SysUser, err error) {
+	userInter, _, err = userService.register(u, token, false) // Extend: gaia账户已存在时返回冲突
+	return userInter, err
+}
+
+// Extend Start: Gaia Register User
+
+// RegisterLinked
+// @function: RegisterLinked
+// @description: 用户注册, 邮箱对应的gaia账户已存在时关联该账户而不是报错, 仅供身份已由钉钉、SCIM或可信的身份提供方确认的场景使用
+// @param: u system.SysUser, token string
+// @return: userInter system.SysUser, linked bool 是否关联了已存在的gaia账户, err error
+func (userService *UserService) RegisterLinked(u system.SysUser, token string) (userInter system.SysUser, linked bool, err error) {
+	if userInter, linked, err = userService.register(u, token, true); err == nil && linked {
+		global.GVA_LOG.Info("注册用户关联已存在的gaia账户", zap.String("email", u.Email), zap.String("uuid", userInter.UUID.String()))
+	}
+	return userInter, linked, err
+}
+
+// ErrGaiaAccountExists 注册时邮箱对应的gaia账户已存在
+var ErrGaiaAccountExists = errors.New("该邮箱已存在gaia账户, 请通过用户同步关联, 不能重复注册")
+
+// Extend Stop: Gaia Register User
+
+// register 用户注册, link 为 true 时关联邮箱对应的已存在gaia账户
+func (userService *UserService) register(u system.SysUser, token string, link bool) (userInter system.SysUser, linked bool, err error) {
 	var user system.SysUser
 	if !errors.Is(global.GVA_DB.Where("email = ?", u.Email).First(&user).Error, gorm.ErrRecordNotFound) {
-		return userInter, errors.New("用户名已注册")
+		return userInter, false, errors.New("用户名已注册")
 	}
 	global.GVA_LOG.Debug("注册用户信息:", zap.Any("1", 1))
 
 	// Extend Start: Gaia Register User
-	if err = serviceGaia.RegisterUser(u, token); err != nil {
-		return userInter, errors.New("gaia注册失败:" + err.Error())
+	if err = serviceGaia.RegisterUser(u, token); errors.Is(err, serviceGaia.ErrAccountExists) {
+		if !link {
+			return userInter, false, ErrGaiaAccountExists
+		}
+		linked = true
+	} else if err != nil {
+		return userInter, false, errors.New("gaia注册失败:" + err.Error())
 	}
 	// Extend Stop: Gaia Register User
 
-	// 否则 附加uuid 密码hash加密 注册
-	u.Password = utils.BcryptHash(u.Password)
+	// 否则 附加uuid 注册, 密码只保存在gaia账户中
+	u.Password = "" // Extend: unified password
 	u.UUID = uuid.Must(uuid.NewV4())
+	// Extend: two-way user sync, 以gaia账户ID作为UUID与账户关联
+	if account, aErr := u.GetAccount(); aErr == nil {
+		u.UUID = account.ID
+	}
+	// Extend Start: Gaia Register User
+	if linked && !errors.Is(global.GVA_DB.Where("uuid = ?", u.UUID).First(&user).Error, gorm.ErrRecordNotFound) {
+		return userInter, false, fmt.Errorf("gaia账户已关联后台用户 %s", user.Username)
+	}
+	// Extend Stop: Gaia Register User
 	err = global.GVA_DB.Create(&u).Error
-	return u, err
+	return u, linked, err
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -73,15 +113,8 @@ func (userService *UserService) Login(u *system.SysUser) (userInter *system.SysU
 	err = global.GVA_DB.Where("username = ? or email = ?", u.Username, u.Username).Preload("Authorities").Preload("Authority").First(&user).Error
 	if err == nil {
 		// Extend: Start 用户账号密码登录修改
-		var ok bool
-		var account gaia.Account
-		var pwd = serviceGaia.PasswdEncode{}
-		if account, err = user.GetAccount(); err != nil {
-			return nil, errors.New("无法在Gaia中找到相关用户, 请联系管理员到用户列表执行刷新操作")
-		}
-		// 判断密码是否正确
-		if ok, err = pwd.ComparePassword(u.Password, account.Password, account.PasswordSalt); err != nil || !ok {
-			return nil, errors.New("密码错误")
+		if err = serviceGaia.CredentialServiceApp.Verify(user, u.Password); err != nil {
+			return nil, err
 		}
 		// Extend: Stop 用户账号密码登录修改
 		MenuServiceApp.UserAuthorityDefaultRouter(&user)
@@ -100,11 +133,9 @@ func (userService *UserService) ChangePassword(u *system.SysUser, newPassword st
 	if err = global.GVA_DB.Where("id = ?", u.ID).First(&user).Error; err != nil {
 		return nil, err
 	}
-	if ok := utils.BcryptCheck(u.Password, user.Password); !ok {
-		return nil, errors.New("原密码错误")
-	}
-	user.Password = utils.BcryptHash(newPassword)
-	err = global.GVA_DB.Save(&user).Error
+	// Extend Start: unified password
+	err = serviceGaia.CredentialServiceApp.ChangePassword(user, u.Password, newPassword)
+	// Extend Stop: unified password
 	return &user, err
 
 }
@@ -260,6 +291,19 @@ func (userService *UserService) SetUserAuthorities(adminAuthorityID, id uint, au
 				SysUserId: id, SysAuthorityAuthorityId: v,
 			})
 		}
+		// Extend Start: 保留生效中的临时角色, 由到期任务收回
+		grantIds, TxErr := AuthorityGrantServiceApp.ActiveGrantAuthorities(tx, id)
+		if TxErr != nil {
+			return TxErr
+		}
+		for _, v := range grantIds {
+			if !slices.Contains(authorityIds, v) {
+				useAuthority = append(useAuthority, system.SysUserAuthority{
+					SysUserId: id, SysAuthorityAuthorityId: v,
+				})
+			}
+		}
+		// Extend Stop: 保留生效中的临时角色, 由到期任务收回
 		TxErr = tx.Create(&useAuthority).Error
 		if TxErr != nil {
 			return TxErr
@@ -280,6 +324,12 @@ func (userService *UserService) SetUserAuthorities(adminAuthorityID, id uint, au
 //@return: err error
 
 func (userService *UserService) DeleteUser(id int) (err error) {
+	// Extend: session registry
+	defer func() {
+		if err == nil {
+			_ = SessionServiceApp.RevokeUser(uint(id), system.SessionRevokeDeleted)
+		}
+	}()
 	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 		// 1. 获取用户信息
 		var user system.SysUser
@@ -336,6 +386,12 @@ func (userService *UserService) SetUserInfo(req system.SysUser, globalCodeType b
 	}
 	// switch user status
 	user.SyncGaiaStatus(req.Enable)
+	// Extend: session registry 禁用后立即注销在线会话
+	if req.Enable != system.UserActive {
+		if err = SessionServiceApp.RevokeUser(user.ID, system.SessionRevokeDisabled); err != nil {
+			return errors.New("SetUserInfo : revoke sessions error: " + err.Error())
+		}
+	}
 	// modify Gaia user information
 	if err = global.GVA_DB.Model(&gaia.Account{}).Where("id=?", account.ID).Updates(map[string]interface{}{
 		"updated_at": time.Now(),
@@ -453,24 +509,11 @@ func (userService *UserService) FindUserByUuid(uuid string) (user *system.SysUse
 // @param: ID uint
 // @return: err error
 func (userService *UserService) ResetPassword(id uint, passwd string) (err error) {
-	var s serviceGaia.PasswdEncode
 	var user system.SysUser
-	if err = global.GVA_DB.Where("id = ?", id).First(&user).Error; err == nil {
-		var passwordHashed, salt string
-		global.GVA_DB.Model(&system.SysUser{}).Where("id = ?", id).Updates(&map[string]string{
-			"password": utils.BcryptHash(passwd),
-		})
-		if passwordHashed, salt, err = s.EncodePassword(passwd); err != nil {
-			return
-		}
-		var account gaia.Account
-		if account, err = user.GetAccount(); err == nil {
-			account.PasswordSalt = salt
-			account.Password = passwordHashed
-			err = global.GVA_DB.Save(&account).Error
-		}
+	if err = global.GVA_DB.Where("id = ?", id).First(&user).Error; err != nil {
+		return err
 	}
-	return err
+	return serviceGaia.CredentialServiceApp.SetPassword(user, passwd)
 }
 
 // Extend Stop: update password
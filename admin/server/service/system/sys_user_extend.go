@@ -1,14 +1,19 @@
 package system
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/oidc"
 	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
 )
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -39,119 +44,193 @@ func (userService *UserExtendService) OaLogin(u *system.SysUser) (userInter *sys
 	return &user, err
 }
 
-// SyncUser
-// @author: [piexlmax](https://github.com/piexlmax)
-// @author: [SliverHorn](https://github.com/SliverHorn)
-// @function: SyncUser
-// @description: 用户同步
-// @param: u *model.SysUser
-// @return: err error, userInter *model.SysUser
-func (userService *UserExtendService) SyncUser() {
-	// init
-	var err error
-	var isInit = true
-	var user []system.SysUser
-	var accountList []gaia.Account
-	var mailDick = make(map[string]string)
-	if global.GVA_DB == nil {
-		global.GVA_LOG.Info("数据库未初始化，同步用户失败")
-		return
-	}
-	// 遍历后台用户表
-	if err = global.GVA_DB.Select("email", "username").Find(&user).Error; err != nil {
-		global.GVA_LOG.Error("SyncUser gaia表查询失败,原因: " + err.Error())
-		return
-	}
-	// 循环用户列表
-	if len(user) > 0 {
-		isInit = false
-	}
-	var emailList []string
-	for _, v := range user {
-		emailList = append(emailList, v.Email)
-		mailDick[v.Email] = v.Username
-	}
-	// 查询gaia用户表
-	db := global.GVA_DB
-	if len(emailList) > 0 {
-		db = db.Where("email NOT IN (?)", emailList)
-	}
-	if err = db.Where("status = ?", gaia.UserActive).Order("created_at ASC").Find(&accountList).Error; err != nil {
-		global.GVA_LOG.Error("SyncUser gaia表查询失败,原因: " + err.Error())
-		return
-	}
-	var adminAuthorities, userAuthorities []system.SysAuthority
-	userAuthorities = append(userAuthorities, system.SysAuthority{
-		AuthorityId: system.DefaultGroupID,
-	})
-	adminAuthorities = append(adminAuthorities, system.SysAuthority{
-		AuthorityId: system.AdminGroupID,
-	})
-	// 循环结果
-	for i, v := range accountList {
-		// 创建相关用户
-		var integrate gaia.AccountIntegrate
-		if username, ok := mailDick[v.Email]; ok {
-			if integrate, err = v.GetAccount(username); err != nil {
-				global.GVA_LOG.Debug("SyncUser GetAccount: " + err.Error())
-			}
+// OAuth2User
+// @function: OAuth2User
+// @description: 通过OAuth2/OIDC用户信息查找或注册后台用户, 并在 account_integrates 中记录关联;
+// 已有用户只通过 account_integrates 中的提供方与subject关联, 按邮箱关联要求邮箱已验证(或提供方声明信任其邮箱)
+// 且属于该提供方配置的域名, 其余情况由管理员通过 LinkIntegrate 手动关联
+// @param: info oidc.UserInfo, provider gaia.SystemIntegration 新用户默认角色取提供方配置, 为0时使用普通用户
+// @return: user system.SysUser, err error
+func (userService *UserExtendService) OAuth2User(info oidc.UserInfo, provider gaia.SystemIntegration) (user system.SysUser, err error) {
+	name := provider.ProviderName()
+	if len(info.ID) == 0 {
+		return user, errors.New("身份提供方未返回用户唯一标识")
+	}
+	// 1. 已关联的账户
+	var integrate gaia.AccountIntegrate
+	if err = global.GVA_DB.Where("provider = ? AND open_id = ?", name, info.ID).First(&integrate).Error; err == nil {
+		if err = global.GVA_DB.Where("uuid = ?", integrate.AccountID).First(&user).Error; err == nil {
+			return user, nil
 		}
-		// 是否配置了多余信息获取接口
-		var name = v.Name
-		var phone, ding string
-		if mailList := strings.Split(v.Email, "@"); len(integrate.OpenID) == 0 && len(mailList) == 2 {
-			integrate.OpenID = mailList[0]
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, err
 		}
-
-		// 创建用户
-		s := UserService{}
-		uuidStr, _ := uuid.NewV1()
-		if len(ding) > 0 {
-			if err = global.GVA_DB.Create(gaia.AccountDingTalkExtend{
-				ID:       v.ID,
-				DingTalk: ding,
-			}).Error; err != nil {
-				global.GVA_LOG.Error("SyncUser Create AccountDingTalkExtend: " + err.Error())
-			}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+	if len(info.Email) == 0 {
+		return user, errors.New("身份提供方未返回邮箱, 无法关联用户")
+	}
+	// 2. 邮箱匹配, 已有用户或gaia账户时须满足邮箱可信, 不存在则注册
+	trusted := provider.TrustEmail(info.Email) && (info.EmailVerified || provider.TrustIssuerEmail())
+	if err = global.GVA_DB.Where("email = ?", info.Email).First(&user).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		var count int64
+		if err = global.GVA_DB.Model(&gaia.Account{}).Where("email = ?", info.Email).Count(&count).Error; err != nil {
+			return user, err
 		}
-		// 注册
-		AuthorityId := system.DefaultGroupID
-		authorities := userAuthorities
-		if isInit && i == 0 {
-			// admin
-			AuthorityId = system.AdminGroupID
-			authorities = adminAuthorities
-
-			// 并设置管理员配置
-			if global.GVA_CONFIG.Gaia.SuperAdminTenantId == "" || global.GVA_CONFIG.Gaia.SuperAdminAccountId == "" {
-				global.GVA_CONFIG.Gaia.SuperAdminAccountId = v.ID.String()
-				// TODO 需要查询默认空间的id
-				var Tenant gaia.Tenants
-				global.GVA_CONFIG.Gaia.SuperAdminTenantId = Tenant.GetSuperAdminTenantId()
-				cs := utils.StructToMap(global.GVA_CONFIG)
-				for k, v := range cs {
-					global.GVA_VP.Set(k, v)
-				}
-				err = global.GVA_VP.WriteConfig()
-				if err != nil {
-					return
-				}
-			}
+		if count > 0 && !trusted {
+			return user, errOAuth2Untrusted
+		}
+		var username string
+		if username, err = userService.UsernameForEmail(info.Email); err != nil {
+			return user, err
+		}
+		if len(info.Name) == 0 {
+			info.Name = username
 		}
-		// Register
-		if _, err = s.Register(system.SysUser{
-			HeaderImg:   "",
-			UUID:        v.ID,
-			NickName:    name,
-			Phone:       phone,
-			Email:       v.Email,
-			AuthorityId: AuthorityId,
-			Authorities: authorities,
-			Username:    integrate.OpenID,
-			Password:    uuidStr.String(),
+		authorityId := provider.AuthorityId
+		if authorityId == 0 {
+			authorityId = system.NormalAuthorityId
+		}
+		s := UserService{}
+		// 未注册的邮箱或已通过上面的可信校验, 可以关联已存在的gaia账户
+		if user, _, err = s.RegisterLinked(system.SysUser{
+			Username:    username,
+			NickName:    info.Name,
+			HeaderImg:   info.Avatar,
+			AuthorityId: authorityId,
+			Authorities: []system.SysAuthority{{AuthorityId: authorityId}},
 			Enable:      system.UserActive,
+			Email:       info.Email,
+			Password:    utils.RandomString(16),
 		}, ""); err != nil {
-			global.GVA_LOG.Error("SyncUser Register system.SysUser: " + err.Error())
+			return user, err
+		}
+	} else if err != nil {
+		return user, err
+	} else if !trusted {
+		return user, errOAuth2Untrusted
+	}
+	// 3. 记录关联, 同一提供方已关联其他subject时拒绝覆盖
+	var linked gaia.AccountIntegrate
+	err = global.GVA_DB.Where("account_id = ? AND provider = ?", user.UUID, name).First(&linked).Error
+	if err == nil {
+		if linked.OpenID != info.ID {
+			return user, fmt.Errorf("该用户已关联身份提供方 %s 的其他账号, 请联系管理员", name)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+	if err = global.GVA_DB.Create(&gaia.AccountIntegrate{
+		ID:        uuid.Must(uuid.NewV4()),
+		AccountID: user.UUID,
+		Provider:  name,
+		OpenID:    info.ID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}).Error; err != nil {
+		global.GVA_LOG.Error("OAuth2User 记录关联失败: " + err.Error())
+	}
+	return user, nil
+}
+
+// errOAuth2Untrusted 邮箱对应已有用户, 但身份提供方未验证邮箱或邮箱不属于其配置的域名
+var errOAuth2Untrusted = errors.New("该邮箱已存在用户, 但身份提供方未验证该邮箱或邮箱域名不属于该提供方, 请联系管理员在用户管理中关联账号")
+
+// GetUserIntegrates
+// @function: GetUserIntegrates
+// @description: 获取用户关联的第三方身份, 不返回令牌
+// @param: id uint 用户ID
+// @return: list []gaia.AccountIntegrate, err error
+func (userService *UserExtendService) GetUserIntegrates(id uint) (list []gaia.AccountIntegrate, err error) {
+	var user system.SysUser
+	if err = global.GVA_DB.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if err = global.GVA_DB.Where("account_id = ?", user.UUID).Order("provider").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].EncryptedToken = ""
+	}
+	return list, nil
+}
+
+// LinkIntegrate
+// @function: LinkIntegrate
+// @description: 管理员将用户与第三方身份提供方的subject关联, 之后该subject登录时直接进入此用户;
+// subject已关联其他用户或该用户已关联同一提供方的其他subject时拒绝
+// @param: req systemReq.UserIntegrateReq
+// @return: err error
+func (userService *UserExtendService) LinkIntegrate(req systemReq.UserIntegrateReq) (err error) {
+	req.Provider, req.OpenID = strings.TrimSpace(req.Provider), strings.TrimSpace(req.OpenID)
+	if len(req.Provider) == 0 || len(req.OpenID) == 0 {
+		return errors.New("提供方与身份标识不能为空")
+	}
+	var user system.SysUser
+	if err = global.GVA_DB.Where("id = ?", req.ID).First(&user).Error; err != nil {
+		return err
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var list []gaia.AccountIntegrate
+		if err := tx.Where("provider = ? AND (open_id = ? OR account_id = ?)", req.Provider, req.OpenID, user.UUID).
+			Find(&list).Error; err != nil {
+			return err
+		}
+		for _, v := range list {
+			if v.AccountID != user.UUID {
+				return errors.New("该身份已关联其他用户, 请先解除关联")
+			}
+			if v.OpenID != req.OpenID {
+				return fmt.Errorf("该用户已关联身份提供方 %s 的其他账号, 请先解除关联", req.Provider)
+			}
+			return nil
 		}
+		return tx.Create(&gaia.AccountIntegrate{
+			ID:        uuid.Must(uuid.NewV4()),
+			AccountID: user.UUID,
+			Provider:  req.Provider,
+			OpenID:    req.OpenID,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}).Error
+	})
+}
+
+// UnlinkIntegrate
+// @function: UnlinkIntegrate
+// @description: 解除用户与第三方身份提供方的关联
+// @param: req systemReq.UserIntegrateReq
+// @return: err error
+func (userService *UserExtendService) UnlinkIntegrate(req systemReq.UserIntegrateReq) (err error) {
+	var user system.SysUser
+	if err = global.GVA_DB.Where("id = ?", req.ID).First(&user).Error; err != nil {
+		return err
+	}
+	return global.GVA_DB.Where("account_id = ? AND provider = ?", user.UUID, req.Provider).
+		Delete(&gaia.AccountIntegrate{}).Error
+}
+
+// UsernameForEmail
+// @function: UsernameForEmail
+// @description: 自动注册时由邮箱生成不冲突的用户名, 规则与用户同步一致: 邮箱前缀被占用时使用完整邮箱
+// @param: email string
+// @return: username string, err error
+func (userService *UserExtendService) UsernameForEmail(email string) (string, error) {
+	username, _, ok := usernameForEmail(email)
+	if !ok {
+		return "", fmt.Errorf("用户名 %s 与邮箱均已被占用, 请联系管理员", username)
 	}
+	return username, nil
+}
+
+// SyncUser
+// @author: [piexlmax](https://github.com/piexlmax)
+// @author: [SliverHorn](https://github.com/SliverHorn)
+// @function: SyncUser
+// @description: 用户同步, 以 accounts.id 与 sys_users.uuid 关联双向同步并记录冲突, 见 UserSyncService
+func (userService *UserExtendService) SyncUser() {
+	UserSyncServiceApp.Run() // Extend: two-way user sync
 }
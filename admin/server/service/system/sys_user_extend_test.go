@@ -0,0 +1,230 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/oidc"
+	"github.com/glebarez/sqlite"
+	"github.com/gofrs/uuid/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 使用内存sqlite替换全局数据库并迁移给定的表, 测试结束后恢复
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	oldDB, oldLog := global.GVA_DB, global.GVA_LOG
+	global.GVA_DB, global.GVA_LOG = db, zap.NewNop()
+	t.Cleanup(func() {
+		global.GVA_DB, global.GVA_LOG = oldDB, oldLog
+		if sqlDB, e := db.DB(); e == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestOAuth2User(t *testing.T) {
+	accountID := uuid.Must(uuid.NewV4())
+	victim := system.SysUser{UUID: accountID, Username: "admin", Email: "admin@corp.com", AuthorityId: system.AdminAuthorityId}
+	trustedIdP := gaia.SystemIntegration{Name: "corp", EmailDomains: "corp.com", Classify: gaia.SystemIntegrationOAuth2}
+	openIdP := gaia.SystemIntegration{Name: "open", Classify: gaia.SystemIntegrationOAuth2}
+	legacyIdP := gaia.SystemIntegration{Name: "oa", Classify: gaia.SystemIntegrationOAuth2, Config: `{"trust_issuer_email":true}`}
+	tests := []struct {
+		name     string
+		info     oidc.UserInfo
+		provider gaia.SystemIntegration
+		linked   *gaia.AccountIntegrate
+		wantErr  error
+		wantUser uint
+	}{
+		{
+			name:     "未配置域名的提供方不能按邮箱接管已有用户",
+			info:     oidc.UserInfo{ID: "attacker", Email: victim.Email, EmailVerified: true},
+			provider: openIdP,
+			wantErr:  errOAuth2Untrusted,
+		},
+		{
+			name:     "邮箱未验证不能接管已有用户",
+			info:     oidc.UserInfo{ID: "attacker", Email: victim.Email},
+			provider: trustedIdP,
+			wantErr:  errOAuth2Untrusted,
+		},
+		{
+			name:     "邮箱已验证且属于提供方域名时关联已有用户",
+			info:     oidc.UserInfo{ID: "sub-1", Email: victim.Email, EmailVerified: true},
+			provider: trustedIdP,
+			wantUser: 1,
+		},
+		{
+			name:     "声明信任邮箱的提供方不返回email_verified时也可关联已有用户",
+			info:     oidc.UserInfo{ID: "oa-admin", Email: victim.Email},
+			provider: legacyIdP,
+			wantUser: 1,
+		},
+		{
+			name:     "已有关联时按subject登录, 不依赖邮箱",
+			info:     oidc.UserInfo{ID: "sub-2", Email: "other@example.com"},
+			provider: openIdP,
+			linked:   &gaia.AccountIntegrate{Provider: "open", OpenID: "sub-2"},
+			wantUser: 1,
+		},
+		{
+			name:     "已关联其他subject时拒绝覆盖",
+			info:     oidc.UserInfo{ID: "sub-3", Email: victim.Email, EmailVerified: true},
+			provider: trustedIdP,
+			linked:   &gaia.AccountIntegrate{Provider: "corp", OpenID: "sub-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &system.SysUser{}, &gaia.Account{}, &gaia.AccountIntegrate{})
+			user := victim
+			if err := db.Create(&user).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.linked != nil {
+				tt.linked.ID, tt.linked.AccountID = uuid.Must(uuid.NewV4()), accountID
+				tt.linked.CreatedAt, tt.linked.UpdatedAt = time.Now(), time.Now()
+				if err := db.Create(tt.linked).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := (&UserExtendService{}).OAuth2User(tt.info, tt.provider)
+			if tt.wantUser == 0 {
+				if err == nil {
+					t.Fatalf("OAuth2User() 应拒绝关联, 实际返回用户 %d", got.ID)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("OAuth2User() error = %v, want %v", err, tt.wantErr)
+				}
+				var count int64
+				db.Model(&gaia.AccountIntegrate{}).Where("open_id = ?", tt.info.ID).Count(&count)
+				if count > 0 {
+					t.Fatalf("被拒绝的登录不应写入关联记录")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OAuth2User() error = %v", err)
+			}
+			if got.ID != tt.wantUser {
+				t.Fatalf("OAuth2User() user = %d, want %d", got.ID, tt.wantUser)
+			}
+			var link gaia.AccountIntegrate
+			if err = db.Where("provider = ? AND open_id = ?", tt.provider.ProviderName(), tt.info.ID).First(&link).Error; err != nil || link.AccountID != accountID {
+				t.Fatalf("关联记录错误: %+v, %v", link, err)
+			}
+		})
+	}
+}
+
+func TestLinkIntegrate(t *testing.T) {
+	db := newTestDB(t, &system.SysUser{}, &gaia.AccountIntegrate{})
+	users := []system.SysUser{
+		{UUID: uuid.Must(uuid.NewV4()), Username: "alice", Email: "alice@corp.com"},
+		{UUID: uuid.Must(uuid.NewV4()), Username: "bob", Email: "bob@corp.com"},
+	}
+	for i := range users {
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := &UserExtendService{}
+	if err := service.LinkIntegrate(systemReq.UserIntegrateReq{ID: users[0].ID, Provider: "oa", OpenID: "alice"}); err != nil {
+		t.Fatalf("LinkIntegrate() error = %v", err)
+	}
+	// 重复关联同一身份不报错
+	if err := service.LinkIntegrate(systemReq.UserIntegrateReq{ID: users[0].ID, Provider: "oa", OpenID: "alice"}); err != nil {
+		t.Fatalf("LinkIntegrate() error = %v", err)
+	}
+	if err := service.LinkIntegrate(systemReq.UserIntegrateReq{ID: users[1].ID, Provider: "oa", OpenID: "alice"}); err == nil {
+		t.Errorf("LinkIntegrate() 身份已关联其他用户时应拒绝")
+	}
+	if err := service.LinkIntegrate(systemReq.UserIntegrateReq{ID: users[0].ID, Provider: "oa", OpenID: "alice2"}); err == nil {
+		t.Errorf("LinkIntegrate() 已关联同一提供方的其他身份时应拒绝")
+	}
+	got, err := (&UserExtendService{}).OAuth2User(oidc.UserInfo{ID: "alice"}, gaia.SystemIntegration{Name: "oa"})
+	if err != nil || got.ID != users[0].ID {
+		t.Fatalf("OAuth2User() = %d, %v, want %d", got.ID, err, users[0].ID)
+	}
+	if err = service.UnlinkIntegrate(systemReq.UserIntegrateReq{ID: users[0].ID, Provider: "oa"}); err != nil {
+		t.Fatalf("UnlinkIntegrate() error = %v", err)
+	}
+	if list, _ := service.GetUserIntegrates(users[0].ID); len(list) != 0 {
+		t.Errorf("解除后仍有关联: %+v", list)
+	}
+}
+
+// 由旧 oa-login 迁移的提供方, OA不返回 email_verified, 已有用户仍可按OA邮箱登录并建立关联
+func TestOAuth2UserMigratedOa(t *testing.T) {
+	db := newTestDB(t, &system.SysUser{}, &gaia.Account{}, &gaia.AccountIntegrate{}, &gaia.SystemIntegration{})
+	oldConfig := global.GVA_CONFIG
+	global.GVA_CONFIG.JWT.SigningKey = "test"
+	global.GVA_CONFIG.OaLogin = config.OaLogin{Url: "http://oa.local", Oauth2ClientId: "client", Oauth2ClientSecret: "secret",
+		GetTokenByCodeApiPath: "/oauth/token", GetUserApiPath: "/oauth/user"}
+	t.Cleanup(func() { global.GVA_CONFIG = oldConfig })
+	user := system.SysUser{UUID: uuid.Must(uuid.NewV4()), Username: "bob", Email: "bob@corp.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	service := &serviceGaia.OAuth2Service{}
+	if err := service.MigrateOaLogin(); err != nil {
+		t.Fatalf("MigrateOaLogin() error = %v", err)
+	}
+	var provider gaia.SystemIntegration
+	if err := db.Where("name = ?", "oa").First(&provider).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 管理员补充授权地址后启用
+	provider.Config = strings.Replace(provider.Config, "{", `{"authorize_url":"/oauth/authorize",`, 1)
+	oidcConfig, err := serviceGaia.OIDCConfig(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := oidc.NewClient(context.Background(), oidcConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := client.MapUser(map[string]interface{}{"data": map[string]interface{}{"username": "bob.oa", "email": user.Email}})
+	got, err := (&UserExtendService{}).OAuth2User(info, provider)
+	if err != nil || got.ID != user.ID {
+		t.Fatalf("OAuth2User() = %d, %v, want %d", got.ID, err, user.ID)
+	}
+	var count int64
+	db.Model(&gaia.AccountIntegrate{}).Where("provider = ? AND open_id = ? AND account_id = ?", "oa", "bob.oa", user.UUID).Count(&count)
+	if count != 1 {
+		t.Errorf("未记录OA关联")
+	}
+
+	// 之前已迁移但未声明信任的配置在启动时补充
+	db.Model(&gaia.SystemIntegration{}).Where("id = ?", provider.Id).Update("config", `{"user_email_field":"data.email"}`)
+	if err = service.MigrateOaLogin(); err != nil {
+		t.Fatalf("MigrateOaLogin() error = %v", err)
+	}
+	db.Where("id = ?", provider.Id).First(&provider)
+	if !provider.TrustIssuerEmail() {
+		t.Errorf("已迁移的配置未补充邮箱信任: %s", provider.Config)
+	}
+}
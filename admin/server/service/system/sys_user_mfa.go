@@ -0,0 +1,345 @@
+package system
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/oidc"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+	mfaRecoveryCodeCount    = 10
+)
+
+var (
+	ErrMfaCodeIncorrect = errors.New("验证码错误")
+	ErrMfaNotEnabled    = errors.New("尚未开启二次验证")
+)
+
+type MfaService struct{}
+
+var MfaServiceApp = new(MfaService)
+
+// Required
+// @function: Required
+// @description: 用户的任一角色被要求强制开启二次验证, 超级管理员角色始终强制
+// @param: user system.SysUser
+// @return: bool
+func (m *MfaService) Required(user system.SysUser) bool {
+	required := append([]uint{system.AdminAuthorityId}, global.GVA_CONFIG.Mfa.RequiredAuthorities...)
+	if slices.Contains(required, user.AuthorityId) {
+		return true
+	}
+	for _, authority := range user.Authorities {
+		if slices.Contains(required, authority.AuthorityId) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled
+// @function: Enabled
+// @description: 用户是否已完成TOTP绑定
+// @param: userID uint
+// @return: bool
+func (m *MfaService) Enabled(userID uint) bool {
+	var count int64
+	global.GVA_DB.Model(&system.SysUserMfa{}).Where("user_id = ? AND enabled = ?", userID, true).Count(&count)
+	return count > 0
+}
+
+// Status
+// @function: Status
+// @description: 获取用户二次验证状态
+// @param: user system.SysUser
+// @return: res systemRes.MfaStatusResponse, err error
+func (m *MfaService) Status(user system.SysUser) (res systemRes.MfaStatusResponse, err error) {
+	res.Required = m.Required(user)
+	var mfa system.SysUserMfa
+	if err = global.GVA_DB.Where("user_id = ?", user.ID).First(&mfa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, nil
+		}
+		return res, err
+	}
+	res.Enabled = mfa.Enabled
+	res.EnabledAt = mfa.EnabledAt
+	res.RecoveryCodesLeft = len(m.recoveryHashes(mfa))
+	return res, nil
+}
+
+// Enroll
+// @function: Enroll
+// @description: 生成新的TOTP密钥与恢复码, 需调用 Activate 校验一次验证码后才生效
+// @param: user system.SysUser
+// @return: res systemRes.MfaEnrollResponse, err error
+func (m *MfaService) Enroll(user system.SysUser) (res systemRes.MfaEnrollResponse, err error) {
+	if m.Enabled(user.ID) {
+		return res, errors.New("已开启二次验证, 如需重新绑定请先关闭")
+	}
+	if res.Secret, err = totp.GenerateSecret(); err != nil {
+		return res, err
+	}
+	var secret string
+	if secret, err = utils.EncryptBlowfish([]byte(res.Secret), global.GVA_CONFIG.JWT.SigningKey); err != nil {
+		return res, errors.New("密钥加密失败")
+	}
+	var hashes string
+	if res.RecoveryCodes, hashes, err = m.newRecoveryCodes(); err != nil {
+		return res, err
+	}
+	issuer := global.GVA_CONFIG.Mfa.Issuer
+	if len(issuer) == 0 {
+		issuer = "Gaia Admin"
+	}
+	account := user.Email
+	if len(account) == 0 {
+		account = user.Username
+	}
+	res.URI = totp.URI(issuer, account, res.Secret)
+	mfa := system.SysUserMfa{UserID: user.ID}
+	err = global.GVA_DB.Where("user_id = ?", user.ID).Assign(map[string]interface{}{
+		"secret":         secret,
+		"enabled":        false,
+		"recovery_codes": hashes,
+		"last_step":      0,
+		"enabled_at":     nil,
+	}).FirstOrCreate(&mfa).Error
+	return res, err
+}
+
+// Activate
+// @function: Activate
+// @description: 校验绑定后的首个验证码并开启二次验证
+// @param: userID uint, code string
+// @return: err error
+func (m *MfaService) Activate(userID uint, code string) (err error) {
+	var mfa system.SysUserMfa
+	if err = global.GVA_DB.Where("user_id = ?", userID).First(&mfa).Error; err != nil {
+		return errors.New("请先获取绑定二维码")
+	}
+	if mfa.Enabled {
+		return errors.New("已开启二次验证")
+	}
+	if err = m.verifyTotp(mfa, code); err != nil {
+		return err
+	}
+	now := time.Now()
+	return global.GVA_DB.Model(&system.SysUserMfa{}).Where("id = ?", mfa.ID).Updates(map[string]interface{}{
+		"enabled":    true,
+		"enabled_at": &now,
+	}).Error
+}
+
+// Verify
+// @function: Verify
+// @description: 校验TOTP验证码或恢复码, 恢复码使用后即失效
+// @param: userID uint, code string
+// @return: err error
+func (m *MfaService) Verify(userID uint, code string) (err error) {
+	var mfa system.SysUserMfa
+	if err = global.GVA_DB.Where("user_id = ? AND enabled = ?", userID, true).First(&mfa).Error; err != nil {
+		return ErrMfaNotEnabled
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return m.verifyTotp(mfa, code)
+	}
+	return m.useRecoveryCode(mfa, code)
+}
+
+// Disable
+// @function: Disable
+// @description: 用户自行关闭二次验证, 强制开启的角色不允许关闭
+// @param: user system.SysUser, code string
+// @return: err error
+func (m *MfaService) Disable(user system.SysUser, code string) (err error) {
+	if m.Required(user) {
+		return errors.New("当前角色要求强制开启二次验证, 不允许关闭")
+	}
+	if err = m.Verify(user.ID, code); err != nil {
+		return err
+	}
+	return m.Reset(user.ID)
+}
+
+// RegenerateRecoveryCodes
+// @function: RegenerateRecoveryCodes
+// @description: 校验验证码后重新生成恢复码, 旧恢复码全部失效
+// @param: userID uint, code string
+// @return: codes []string, err error
+func (m *MfaService) RegenerateRecoveryCodes(userID uint, code string) (codes []string, err error) {
+	var mfa system.SysUserMfa
+	if err = global.GVA_DB.Where("user_id = ? AND enabled = ?", userID, true).First(&mfa).Error; err != nil {
+		return nil, ErrMfaNotEnabled
+	}
+	if err = m.verifyTotp(mfa, code); err != nil {
+		return nil, err
+	}
+	var hashes string
+	if codes, hashes, err = m.newRecoveryCodes(); err != nil {
+		return nil, err
+	}
+	err = global.GVA_DB.Model(&system.SysUserMfa{}).Where("id = ?", mfa.ID).Update("recovery_codes", hashes).Error
+	return codes, err
+}
+
+// Reset
+// @function: Reset
+// @description: 清除用户的二次验证绑定, 强制角色的用户下次登录时需重新绑定
+// @param: userID uint
+// @return: err error
+func (m *MfaService) Reset(userID uint) error {
+	return global.GVA_DB.Unscoped().Where("user_id = ?", userID).Delete(&system.SysUserMfa{}).Error
+}
+
+// MfaChallenge 二次验证凭证对应的用户与第一步的登录方式
+type MfaChallenge struct {
+	UserID uint   `json:"user_id"`
+	Method string `json:"method"`
+}
+
+// CreateChallenge
+// @function: CreateChallenge
+// @description: 密码或单点登录校验通过后生成短期的二次验证凭证
+// @param: userID uint, method string 第一步的登录方式
+// @return: token string, expiresAt time.Time, err error
+func (m *MfaService) CreateChallenge(userID uint, method string) (token string, expiresAt time.Time, err error) {
+	token = oidc.RandomString(32)
+	expiresAt = time.Now().Add(mfaChallengeTTL)
+	data, _ := json.Marshal(&MfaChallenge{UserID: userID, Method: method})
+	err = global.GVA_REDIS.Set(context.Background(), m.challengeKey(token), string(data), mfaChallengeTTL).Err()
+	return token, expiresAt, err
+}
+
+// ResolveChallenge
+// @function: ResolveChallenge
+// @description: 根据二次验证凭证获取用户ID与登录方式
+// @param: token string
+// @return: challenge MfaChallenge, err error
+func (m *MfaService) ResolveChallenge(token string) (challenge MfaChallenge, err error) {
+	var data string
+	if data, err = global.GVA_REDIS.Get(context.Background(), m.challengeKey(token)).Result(); err != nil {
+		return challenge, errors.New("二次验证已过期, 请重新登录")
+	}
+	if err = json.Unmarshal([]byte(data), &challenge); err != nil || challenge.UserID == 0 {
+		return challenge, errors.New("二次验证已过期, 请重新登录")
+	}
+	return challenge, nil
+}
+
+// FailChallenge
+// @function: FailChallenge
+// @description: 记录一次失败, 失败次数按账号计入登录锁定, 同一凭证超过次数或账号被锁定后凭证作废需重新登录
+// @param: token string, challenge MfaChallenge, user system.SysUser, ip string, userAgent string, reason string
+// @return: locked bool
+func (m *MfaService) FailChallenge(token string, challenge MfaChallenge, user system.SysUser, ip, userAgent, reason string) (locked bool) {
+	ctx := context.Background()
+	key := m.challengeKey(token) + ":attempts"
+	attempts, _ := global.GVA_REDIS.Incr(ctx, key).Result()
+	global.GVA_REDIS.Expire(ctx, key, mfaChallengeTTL)
+	locked = LockoutServiceApp.Fail(user.ID, LockoutServiceApp.AccountKey(user.Username), challenge.Method, ip, userAgent,
+		"二次验证失败: "+reason)
+	if locked || attempts >= mfaChallengeMaxAttempts {
+		global.GVA_REDIS.Del(ctx, m.challengeKey(token), key)
+	}
+	return locked
+}
+
+// FinishChallenge
+// @function: FinishChallenge
+// @description: 二次验证通过后作废凭证
+// @param: token string
+func (m *MfaService) FinishChallenge(token string) {
+	global.GVA_REDIS.Del(context.Background(), m.challengeKey(token), m.challengeKey(token)+":attempts")
+}
+
+func (m *MfaService) challengeKey(token string) string {
+	return "mfa_challenge:" + token
+}
+
+// verifyTotp 校验验证码并记录时间窗口, 同一窗口的验证码只能使用一次
+func (m *MfaService) verifyTotp(mfa system.SysUserMfa, code string) error {
+	secret, err := utils.DecryptBlowfish(mfa.Secret, global.GVA_CONFIG.JWT.SigningKey)
+	if err != nil {
+		return errors.New("密钥解析失败, 请联系管理员重置二次验证")
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok || step <= mfa.LastStep {
+		return ErrMfaCodeIncorrect
+	}
+	db := global.GVA_DB.Model(&system.SysUserMfa{}).Where("id = ? AND last_step < ?", mfa.ID, step).
+		Update("last_step", step)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return ErrMfaCodeIncorrect
+	}
+	return nil
+}
+
+// useRecoveryCode 校验并消费恢复码
+func (m *MfaService) useRecoveryCode(mfa system.SysUserMfa, code string) error {
+	hashes := m.recoveryHashes(mfa)
+	hash := m.hashRecoveryCode(code)
+	index := slices.Index(hashes, hash)
+	if index < 0 {
+		return ErrMfaCodeIncorrect
+	}
+	remain, _ := json.Marshal(slices.Delete(hashes, index, index+1))
+	db := global.GVA_DB.Model(&system.SysUserMfa{}).Where("id = ? AND recovery_codes = ?", mfa.ID, mfa.RecoveryCodes).
+		Update("recovery_codes", string(remain))
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return ErrMfaCodeIncorrect
+	}
+	return nil
+}
+
+func (m *MfaService) recoveryHashes(mfa system.SysUserMfa) (hashes []string) {
+	_ = json.Unmarshal([]byte(mfa.RecoveryCodes), &hashes)
+	return hashes
+}
+
+// newRecoveryCodes 生成恢复码, 返回明文与哈希后的JSON
+func (m *MfaService) newRecoveryCodes() (codes []string, hashes string, err error) {
+	list := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err = rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		code := hex.EncodeToString(b)
+		code = fmt.Sprintf("%s-%s", code[:5], code[5:])
+		codes = append(codes, code)
+		list = append(list, m.hashRecoveryCode(code))
+	}
+	data, _ := json.Marshal(list)
+	return codes, string(data), nil
+}
+
+func (m *MfaService) hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
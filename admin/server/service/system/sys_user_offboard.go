@@ -0,0 +1,333 @@
+package system
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// appTokenLetters Dify 应用密钥的字符集, 密钥格式为 app- 加 24 位字母数字
+const appTokenLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+type OffboardService struct{}
+
+var OffboardServiceApp = new(OffboardService)
+
+// offboardContext 预览与执行共用的数据
+type offboardContext struct {
+	user      system.SysUser
+	successor system.SysUser
+	from      gaia.Account
+	to        gaia.Account
+	preview   systemRes.OffboardPreview
+}
+
+// Preview
+// @function: Preview
+// @description: 预览离职交接将转移的工作空间、应用与密钥
+// @param: req systemReq.OffboardReq
+// @return: res systemRes.OffboardPreview, err error
+func (o *OffboardService) Preview(req systemReq.OffboardReq) (res systemRes.OffboardPreview, err error) {
+	ctx, err := o.prepare(req)
+	if err != nil {
+		return res, err
+	}
+	return ctx.preview, nil
+}
+
+// Execute
+// @function: Execute
+// @description: 执行离职交接: 转移工作空间所有权与应用, 处理应用密钥, 禁用账户并记录交接报告
+// @param: req systemReq.OffboardReq, operatorID uint, operator string
+// @return: res systemRes.OffboardResult, err error
+func (o *OffboardService) Execute(req systemReq.OffboardReq, operatorID uint, operator string) (res systemRes.OffboardResult, err error) {
+	ctx, err := o.prepare(req)
+	if err != nil {
+		return res, err
+	}
+	if req.TokenAction == "" {
+		req.TokenAction = system.OffboardTokenKeep
+	}
+	audit := gaia.TenantMemberAudit{
+		Source: gaia.TenantAuditSourceOffboard, Reason: req.Reason, OperatorID: operatorID, Operator: operator,
+	}
+	tenantsService := new(serviceGaia.TenantsService)
+	res.Report = system.SysUserOffboard{
+		UserID:        ctx.user.ID,
+		Username:      ctx.user.Username,
+		Email:         ctx.user.Email,
+		SuccessorID:   ctx.successor.ID,
+		SuccessorName: ctx.successor.Username,
+		TokenAction:   req.TokenAction,
+		Reason:        req.Reason,
+		Tenants:       ctx.preview.Tenants,
+		Apps:          ctx.preview.Apps,
+		OperatorID:    operatorID,
+		Operator:      operator,
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		for _, tenant := range ctx.preview.Tenants {
+			if err := tenantsService.HandoverTenant(tx, tenant.TenantID, ctx.from.ID, ctx.to.ID, audit); err != nil {
+				return fmt.Errorf("转移工作空间 %s 失败: %w", tenant.Name, err)
+			}
+		}
+		appIDs := make([]string, 0, len(ctx.preview.Apps))
+		for _, app := range ctx.preview.Apps {
+			appIDs = append(appIDs, app.AppID)
+			if err := tenantsService.EnsureMember(tx, app.TenantID, ctx.to.ID, gaia.TenantRoleEditor, audit); err != nil {
+				return fmt.Errorf("接任人加入工作空间 %s 失败: %w", app.TenantName, err)
+			}
+		}
+		if len(appIDs) > 0 {
+			if err := tx.Model(&gaia.Apps{}).Where("id IN ? AND created_by = ?", appIDs, ctx.from.ID).
+				Update("created_by", ctx.to.ID).Error; err != nil {
+				return err
+			}
+		}
+		tokens, newTokens, err := o.handleTokens(tx, appIDs, req.TokenAction)
+		if err != nil {
+			return err
+		}
+		res.Report.Tokens, res.NewTokens = tokens, newTokens
+		if err = tx.Model(&gaia.Account{}).Where("id = ?", ctx.from.ID).Updates(map[string]interface{}{
+			"status":     gaia.UserBanned,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if err = tx.Model(&system.SysUser{}).Where("id = ?", ctx.user.ID).Update("enable", system.UserDeactivate).Error; err != nil {
+			return err
+		}
+		if err = tx.Model(&system.SysAccessToken{}).Where("user_id = ? AND revoked_at IS NULL", ctx.user.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&res.Report).Error
+	})
+	if err != nil {
+		return res, err
+	}
+	ctx.user.SyncGaiaStatus(system.UserDeactivate)
+	err = SessionServiceApp.RevokeUser(ctx.user.ID, system.SessionRevokeDisabled)
+	return res, err
+}
+
+// SetActive
+// @function: SetActive
+// @description: 停用或恢复用户, 同步gaia账户状态, 停用时注销在线会话
+// @param: userID uint, active bool
+// @return: err error
+func (o *OffboardService) SetActive(userID uint, active bool) (err error) {
+	var user system.SysUser
+	if err = global.GVA_DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	account, err := user.GetAccount()
+	if err != nil {
+		return err
+	}
+	enable, status := system.UserActive, gaia.UserActive
+	if !active {
+		if account.ID.String() == global.GVA_CONFIG.Gaia.SuperAdminAccountId {
+			return errors.New("不能停用系统超级管理员")
+		}
+		enable, status = system.UserDeactivate, gaia.UserBanned
+	}
+	if err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&system.SysUser{}).Where("id = ?", user.ID).Update("enable", enable).Error; err != nil {
+			return err
+		}
+		return tx.Model(&gaia.Account{}).Where("id = ?", account.ID).Updates(map[string]interface{}{
+			"status":     status,
+			"updated_at": time.Now(),
+		}).Error
+	}); err != nil {
+		return err
+	}
+	user.SyncGaiaStatus(enable)
+	if !active {
+		return SessionServiceApp.RevokeUser(user.ID, system.SessionRevokeDisabled)
+	}
+	return nil
+}
+
+// GetOffboardList
+// @function: GetOffboardList
+// @description: 分页获取交接报告
+// @param: info systemReq.SysUserOffboardSearch
+// @return: list []system.SysUserOffboard, total int64, err error
+func (o *OffboardService) GetOffboardList(info systemReq.SysUserOffboardSearch) (list []system.SysUserOffboard, total int64, err error) {
+	db := global.GVA_DB.Model(&system.SysUserOffboard{})
+	if info.UserID > 0 {
+		db = db.Where("user_id = ?", info.UserID)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// prepare 校验请求并收集离职用户拥有的工作空间与应用
+func (o *OffboardService) prepare(req systemReq.OffboardReq) (ctx offboardContext, err error) {
+	switch req.TokenAction {
+	case "", system.OffboardTokenKeep, system.OffboardTokenRotate, system.OffboardTokenRevoke:
+	default:
+		return ctx, fmt.Errorf("不支持的密钥处理方式: %s", req.TokenAction)
+	}
+	if req.UserID == req.SuccessorID {
+		return ctx, errors.New("接任人不能是离职用户本人")
+	}
+	if err = global.GVA_DB.Where("id = ?", req.UserID).First(&ctx.user).Error; err != nil {
+		return ctx, errors.New("离职用户不存在")
+	}
+	if err = global.GVA_DB.Where("id = ?", req.SuccessorID).First(&ctx.successor).Error; err != nil {
+		return ctx, errors.New("接任人不存在")
+	}
+	if ctx.successor.Enable != system.UserActive {
+		return ctx, errors.New("接任人已被禁用")
+	}
+	if ctx.from, err = ctx.user.GetAccount(); err != nil {
+		return ctx, errors.New("离职用户没有对应的gaia账户")
+	}
+	if ctx.to, err = ctx.successor.GetAccount(); err != nil {
+		return ctx, errors.New("接任人没有对应的gaia账户")
+	}
+	if ctx.from.ID.String() == global.GVA_CONFIG.Gaia.SuperAdminAccountId {
+		return ctx, errors.New("不能对系统超级管理员执行离职交接")
+	}
+	ctx.preview = systemRes.OffboardPreview{
+		UserID:        ctx.user.ID,
+		Username:      ctx.user.Username,
+		SuccessorID:   ctx.successor.ID,
+		SuccessorName: ctx.successor.Username,
+		Tenants:       []system.OffboardTenant{},
+		Apps:          []system.OffboardApp{},
+		Warnings:      []string{},
+	}
+	// 接任人已加入的工作空间及角色
+	var successorJoins []gaia.TenantAccountJoins
+	if err = global.GVA_DB.Where("account_id = ?", ctx.to.ID).Find(&successorJoins).Error; err != nil {
+		return ctx, err
+	}
+	successorRoles := make(map[string]string, len(successorJoins))
+	for _, join := range successorJoins {
+		successorRoles[join.TenantID.String()] = join.Role
+	}
+	if err = global.GVA_DB.Table("tenant_account_joins AS j").
+		Select("j.tenant_id, t.name").
+		Joins("JOIN tenants AS t ON t.id = j.tenant_id").
+		Where("j.account_id = ? AND j.role = ?", ctx.from.ID, gaia.TenantRoleOwner).
+		Order("t.created_at").
+		Scan(&ctx.preview.Tenants).Error; err != nil {
+		return ctx, err
+	}
+	for i, tenant := range ctx.preview.Tenants {
+		ctx.preview.Tenants[i].SuccessorRole = successorRoles[tenant.TenantID]
+		if successorRoles[tenant.TenantID] == "" {
+			ctx.preview.Warnings = append(ctx.preview.Warnings,
+				fmt.Sprintf("接任人不是工作空间「%s」的成员, 交接时将加入并成为所有者", tenant.Name))
+		}
+	}
+	if err = global.GVA_DB.Table("apps AS a").
+		Select("a.id AS app_id, a.name, a.tenant_id, t.name AS tenant_name, "+
+			"(SELECT COUNT(1) FROM api_tokens AS k WHERE k.app_id = a.id) AS token_count").
+		Joins("JOIN tenants AS t ON t.id = a.tenant_id").
+		Where("a.created_by = ?", ctx.from.ID).
+		Order("a.created_at").
+		Scan(&ctx.preview.Apps).Error; err != nil {
+		return ctx, err
+	}
+	owned := make(map[string]bool, len(ctx.preview.Tenants))
+	for _, tenant := range ctx.preview.Tenants {
+		owned[tenant.TenantID] = true
+	}
+	warned := make(map[string]bool)
+	for i, app := range ctx.preview.Apps {
+		ctx.preview.Apps[i].SuccessorRole = successorRoles[app.TenantID]
+		ctx.preview.TokenCount += app.TokenCount
+		if successorRoles[app.TenantID] == "" && !owned[app.TenantID] && !warned[app.TenantID] {
+			warned[app.TenantID] = true
+			ctx.preview.Warnings = append(ctx.preview.Warnings,
+				fmt.Sprintf("接任人不是工作空间「%s」的成员, 交接时将以 editor 角色加入", app.TenantName))
+		}
+	}
+	if ctx.preview.TokenCount > 0 && req.TokenAction == system.OffboardTokenRevoke {
+		ctx.preview.Warnings = append(ctx.preview.Warnings,
+			fmt.Sprintf("将删除 %d 个应用密钥, 使用这些密钥的调用方会立即失效", ctx.preview.TokenCount))
+	}
+	if ctx.preview.TokenCount > 0 && req.TokenAction == system.OffboardTokenRotate {
+		ctx.preview.Warnings = append(ctx.preview.Warnings,
+			fmt.Sprintf("将轮换 %d 个应用密钥, 新密钥只在交接完成时显示一次", ctx.preview.TokenCount))
+	}
+	return ctx, nil
+}
+
+// handleTokens 按处理方式轮换或删除应用密钥, 返回脱敏后的记录与轮换后的新密钥
+func (o *OffboardService) handleTokens(tx *gorm.DB, appIDs []string, action string) (records, newTokens []system.OffboardToken, err error) {
+	if len(appIDs) == 0 || action == system.OffboardTokenKeep {
+		return nil, nil, nil
+	}
+	var tokens []gaia.ApiTokens
+	if err = tx.Where("app_id IN ?", appIDs).Find(&tokens).Error; err != nil {
+		return nil, nil, err
+	}
+	tokenIDs := make([]uuid.UUID, 0, len(tokens))
+	for _, token := range tokens {
+		tokenIDs = append(tokenIDs, token.ID)
+		record := system.OffboardToken{AppID: token.AppID.String(), TokenID: token.ID.String(), Token: o.maskToken(token.Token), Action: action}
+		if action == system.OffboardTokenRotate {
+			var value string
+			if value, err = o.newAppToken(); err != nil {
+				return nil, nil, err
+			}
+			if err = tx.Model(&gaia.ApiTokens{}).Where("id = ?", token.ID).Update("token", value).Error; err != nil {
+				return nil, nil, err
+			}
+			record.Token = o.maskToken(value)
+			newTokens = append(newTokens, system.OffboardToken{AppID: record.AppID, TokenID: record.TokenID, Token: value, Action: action})
+		}
+		records = append(records, record)
+	}
+	if action == system.OffboardTokenRevoke && len(tokenIDs) > 0 {
+		if err = tx.Where("id IN ?", tokenIDs).Delete(&gaia.ApiTokens{}).Error; err != nil {
+			return nil, nil, err
+		}
+		if err = tx.Model(&gaia.ApiTokenMoneyExtend{}).Where("app_token_id IN ?", tokenIDs).
+			Update("is_deleted", true).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return records, newTokens, nil
+}
+
+// newAppToken 生成与 Dify 格式一致的应用密钥
+func (o *OffboardService) newAppToken() (string, error) {
+	b := make([]byte, 24)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(appTokenLetters))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = appTokenLetters[n.Int64()]
+	}
+	return "app-" + string(b), nil
+}
+
+func (o *OffboardService) maskToken(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	return token[:4] + "****" + token[len(token)-4:]
+}
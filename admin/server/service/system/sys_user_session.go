@@ -0,0 +1,226 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionRevokedPrefix = "session_revoked:"
+	sessionSeenPrefix    = "session_seen:"
+	sessionActivePrefix  = "session_active:"
+	// 最后活跃时间的写库间隔
+	sessionTouchInterval = time.Minute
+	// 未使用redis时从数据库确认会话有效的本地缓存时长, 即其他实例注销会话后的最大生效延迟
+	sessionActiveCacheTTL = 10 * time.Second
+)
+
+type SessionService struct{}
+
+var SessionServiceApp = new(SessionService)
+
+func init() {
+	// 钉钉组织同步停用用户时注销其会话
+	serviceGaia.RevokeUserSessions = SessionServiceApp.RevokeUser
+}
+
+// Create
+// @function: Create
+// @description: 签发jwt后登记会话
+// @param: claims systemReq.CustomClaims, ip string, userAgent string
+// @return: err error
+func (s *SessionService) Create(claims systemReq.CustomClaims, ip, userAgent string) error {
+	if len(claims.RegisteredClaims.ID) == 0 {
+		return nil
+	}
+	now := time.Now()
+	return global.GVA_DB.Create(&system.SysUserSession{
+		SessionID:  claims.RegisteredClaims.ID,
+		UserID:     claims.BaseClaims.ID,
+		Username:   claims.Username,
+		IP:         ip,
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  claims.ExpiresAt.Time,
+	}).Error
+}
+
+// Active
+// @function: Active
+// @description: 会话是否有效, 未携带会话ID的旧令牌视为有效; 未使用redis时以数据库中的注销记录为准, 保证多实例部署下注销同样生效
+// @param: sessionID string
+// @return: bool
+func (s *SessionService) Active(sessionID string) bool {
+	if len(sessionID) == 0 {
+		return true
+	}
+	if _, ok := global.BlackCache.Get(sessionRevokedPrefix + sessionID); ok {
+		return false
+	}
+	if global.GVA_CONFIG.System.UseRedis || global.GVA_CONFIG.System.UseMultipoint {
+		if n, err := global.GVA_REDIS.Exists(context.Background(), sessionRevokedPrefix+sessionID).Result(); err == nil && n > 0 {
+			return false
+		}
+		return true
+	}
+	if _, ok := global.BlackCache.Get(sessionActivePrefix + sessionID); ok {
+		return true
+	}
+	var session system.SysUserSession
+	err := global.GVA_DB.Select("revoked_at", "expires_at").Where("session_id = ?", sessionID).First(&session).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		// 数据库异常时不阻断请求, 仍以本地注销记录为准
+		global.GVA_LOG.Error("查询会话状态失败!", zap.Error(err))
+		return true
+	}
+	if err != nil || session.RevokedAt != nil {
+		if ttl := time.Until(session.ExpiresAt); ttl > 0 {
+			global.BlackCache.Set(sessionRevokedPrefix+sessionID, struct{}{}, ttl)
+		}
+		return false
+	}
+	global.BlackCache.Set(sessionActivePrefix+sessionID, struct{}{}, sessionActiveCacheTTL)
+	return true
+}
+
+// Touch
+// @function: Touch
+// @description: 更新最后活跃时间与过期时间, 按间隔节流写库
+// @param: claims systemReq.CustomClaims
+func (s *SessionService) Touch(claims systemReq.CustomClaims) {
+	if len(claims.RegisteredClaims.ID) == 0 {
+		return
+	}
+	if _, ok := global.BlackCache.Get(sessionSeenPrefix + claims.RegisteredClaims.ID); ok {
+		return
+	}
+	global.BlackCache.Set(sessionSeenPrefix+claims.RegisteredClaims.ID, struct{}{}, sessionTouchInterval)
+	values := map[string]interface{}{"last_seen_at": time.Now()}
+	if claims.ExpiresAt != nil {
+		values["expires_at"] = claims.ExpiresAt.Time
+	}
+	if err := global.GVA_DB.Model(&system.SysUserSession{}).Where("session_id = ?", claims.RegisteredClaims.ID).
+		Updates(values).Error; err != nil {
+		global.GVA_LOG.Error("更新会话活跃时间失败!", zap.Error(err))
+	}
+}
+
+// Refresh
+// @function: Refresh
+// @description: 令牌刷新后立即更新会话过期时间
+// @param: claims systemReq.CustomClaims
+func (s *SessionService) Refresh(claims systemReq.CustomClaims) {
+	global.BlackCache.Delete(sessionSeenPrefix + claims.RegisteredClaims.ID)
+	s.Touch(claims)
+}
+
+// GetSessionList
+// @function: GetSessionList
+// @description: 分页获取会话
+// @param: info systemReq.SysUserSessionSearch
+// @return: list []system.SysUserSession, total int64, err error
+func (s *SessionService) GetSessionList(info systemReq.SysUserSessionSearch) (list []system.SysUserSession, total int64, err error) {
+	db := global.GVA_DB.Model(&system.SysUserSession{})
+	if info.UserID > 0 {
+		db = db.Where("user_id = ?", info.UserID)
+	}
+	if info.Username != "" {
+		db = db.Where("username LIKE ?", "%"+info.Username+"%")
+	}
+	if info.ActiveOnly {
+		db = db.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("last_seen_at desc").Find(&list).Error
+	return list, total, err
+}
+
+// GetUserSessions
+// @function: GetUserSessions
+// @description: 获取用户的在线会话
+// @param: userID uint, current string
+// @return: list []system.SysUserSession, err error
+func (s *SessionService) GetUserSessions(userID uint, current string) (list []system.SysUserSession, err error) {
+	err = global.GVA_DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").Find(&list).Error
+	for i := range list {
+		list[i].Current = list[i].SessionID == current
+	}
+	return list, err
+}
+
+// Revoke
+// @function: Revoke
+// @description: 注销会话, userID大于0时只允许注销该用户自己的会话
+// @param: sessionID string, userID uint, reason string
+// @return: err error
+func (s *SessionService) Revoke(sessionID string, userID uint, reason string) error {
+	var session system.SysUserSession
+	db := global.GVA_DB.Where("session_id = ?", sessionID)
+	if userID > 0 {
+		db = db.Where("user_id = ?", userID)
+	}
+	if err := db.First(&session).Error; err != nil {
+		return errors.New("会话不存在")
+	}
+	return s.revoke([]system.SysUserSession{session}, reason)
+}
+
+// RevokeUser
+// @function: RevokeUser
+// @description: 注销用户的全部在线会话
+// @param: userID uint, reason string
+// @return: err error
+func (s *SessionService) RevokeUser(userID uint, reason string) error {
+	var sessions []system.SysUserSession
+	if err := global.GVA_DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Find(&sessions).Error; err != nil {
+		return err
+	}
+	return s.revoke(sessions, reason)
+}
+
+func (s *SessionService) revoke(sessions []system.SysUserSession, reason string) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	now := time.Now()
+	ids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.SessionID)
+		global.BlackCache.Delete(sessionActivePrefix + session.SessionID)
+		ttl := time.Until(session.ExpiresAt)
+		if ttl <= 0 {
+			continue
+		}
+		global.BlackCache.Set(sessionRevokedPrefix+session.SessionID, struct{}{}, ttl)
+		if global.GVA_CONFIG.System.UseRedis || global.GVA_CONFIG.System.UseMultipoint {
+			global.GVA_REDIS.Set(context.Background(), sessionRevokedPrefix+session.SessionID, reason, ttl)
+		}
+	}
+	return global.GVA_DB.Model(&system.SysUserSession{}).Where("session_id IN ? AND revoked_at IS NULL", ids).
+		Updates(map[string]interface{}{"revoked_at": &now, "revoke_reason": reason}).Error
+}
+
+// LoadRevokedSessions 将未过期的已注销会话加载到 BlackCache
+func LoadRevokedSessions() {
+	var sessions []system.SysUserSession
+	if err := global.GVA_DB.Select("session_id", "expires_at").
+		Where("revoked_at IS NOT NULL AND expires_at > ?", time.Now()).Find(&sessions).Error; err != nil {
+		global.GVA_LOG.Error("加载已注销会话失败!", zap.Error(err))
+		return
+	}
+	for _, session := range sessions {
+		global.BlackCache.Set(sessionRevokedPrefix+session.SessionID, struct{}{}, time.Until(session.ExpiresAt))
+	}
+}
@@ -0,0 +1,50 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/songzhibin97/gkit/cache/local_cache"
+)
+
+// TestSessionActiveWithoutRedis 未使用redis时, 其他实例写入数据库的注销记录同样生效
+func TestSessionActiveWithoutRedis(t *testing.T) {
+	db := newTestDB(t, &system.SysUserSession{})
+	oldCache, oldConfig := global.BlackCache, global.GVA_CONFIG.System
+	global.BlackCache = local_cache.NewCache()
+	global.GVA_CONFIG.System.UseRedis, global.GVA_CONFIG.System.UseMultipoint = false, false
+	t.Cleanup(func() {
+		global.BlackCache, global.GVA_CONFIG.System = oldCache, oldConfig
+	})
+	expires := time.Now().Add(time.Hour)
+	for _, id := range []string{"a", "b"} {
+		if err := db.Create(&system.SysUserSession{SessionID: id, UserID: 1, ExpiresAt: expires}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &SessionService{}
+	if !s.Active("a") || !s.Active("b") {
+		t.Fatal("未注销的会话应有效")
+	}
+	if s.Active("missing") {
+		t.Fatal("不存在的会话应无效")
+	}
+	// 模拟其他实例注销: 只写数据库, 本地缓存过期后生效
+	now := time.Now()
+	if err := db.Model(&system.SysUserSession{}).Where("session_id = ?", "a").Update("revoked_at", &now).Error; err != nil {
+		t.Fatal(err)
+	}
+	global.BlackCache.Delete(sessionActivePrefix + "a")
+	if s.Active("a") {
+		t.Fatal("数据库中已注销的会话应无效")
+	}
+	// 本实例注销立即生效
+	if err := s.Revoke("b", 0, system.SessionRevokeManual); err != nil {
+		t.Fatal(err)
+	}
+	if s.Active("b") {
+		t.Fatal("注销后会话应立即无效")
+	}
+}
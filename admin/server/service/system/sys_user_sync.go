@@ -0,0 +1,644 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	serviceGaia "github.com/flipped-aurora/gin-vue-admin/server/service/gaia"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	userSyncName  = "gaia_account"
+	userSyncBatch = 500
+	// 游标回退一段时间, 避免遗漏同一时刻提交的修改, 重复处理是幂等的
+	userSyncOverlap = time.Minute
+)
+
+// errUserSyncSkip 当前记录存在冲突, 本次不处理
+var errUserSyncSkip = errors.New("user sync skipped")
+
+// 同步任务同一时间只运行一个, 定时任务与手动触发共用
+var (
+	userSyncLock    sync.Mutex
+	userSyncRunning atomic.Bool
+)
+
+// 各冲突类型支持的处理方式
+var userSyncResolveActions = map[string][]string{
+	system.SyncConflictDuplicateUsername: {system.SyncResolveRename, system.SyncResolveIgnore},
+	system.SyncConflictEmailChanged:      {system.SyncResolveUseGaia, system.SyncResolveUseAdmin, system.SyncResolveIgnore},
+	system.SyncConflictEmailTaken:        {system.SyncResolveIgnore},
+	system.SyncConflictAccountDeleted:    {system.SyncResolveDisable, system.SyncResolveIgnore},
+}
+
+type UserSyncService struct{}
+
+var UserSyncServiceApp = new(UserSyncService)
+
+// userSyncRun 单次同步的上下文
+type userSyncRun struct {
+	state      *system.SysUserSyncState
+	superAdmin string
+	// 本次已从gaia同步过的后台用户, 同一周期内双方均有修改时以gaia为准
+	handled map[uint]bool
+}
+
+// Run
+// @function: Run
+// @description: 双向同步gaia账户与后台用户, 以 accounts.id 与 sys_users.uuid 关联, 按 updated_at 增量同步,
+// 历史用户只在首次同步时按邮箱关联一次
+func (s *UserSyncService) Run() {
+	if global.GVA_DB == nil {
+		global.GVA_LOG.Info("数据库未初始化，同步用户失败")
+		return
+	}
+	if !userSyncLock.TryLock() {
+		return
+	}
+	defer userSyncLock.Unlock()
+	userSyncRunning.Store(true)
+	defer userSyncRunning.Store(false)
+
+	start := time.Now()
+	var state system.SysUserSyncState
+	if err := global.GVA_DB.Where(system.SysUserSyncState{Name: userSyncName}).FirstOrCreate(&state).Error; err != nil {
+		global.GVA_LOG.Error("SyncUser 获取同步状态失败!", zap.Error(err))
+		return
+	}
+	state.LastRunAt = start
+	state.LastScanned, state.LastCreated, state.LastPulled, state.LastPushed, state.LastConflicts = 0, 0, 0, 0, 0
+	run := &userSyncRun{state: &state, handled: make(map[uint]bool)}
+	run.superAdmin = s.resolveSuperAdmin(&state)
+
+	err := s.linkLegacyUsers(run)
+	if err == nil {
+		err = s.pullAccounts(run)
+	}
+	if err == nil {
+		err = s.pushUsers(run)
+	}
+	if err == nil {
+		err = s.detectDeleted(run)
+	}
+	state.Runs++
+	state.LastDuration = time.Since(start).Milliseconds()
+	state.LastError = ""
+	if err != nil {
+		state.LastError = err.Error()
+		global.GVA_LOG.Error("SyncUser 同步失败!", zap.Error(err))
+	}
+	if err = global.GVA_DB.Save(&state).Error; err != nil {
+		global.GVA_LOG.Error("SyncUser 保存同步状态失败!", zap.Error(err))
+	}
+}
+
+// Status
+// @function: Status
+// @description: 获取同步状态、最近一次运行统计与待处理冲突数
+// @return: res systemRes.UserSyncStatus, err error
+func (s *UserSyncService) Status() (res systemRes.UserSyncStatus, err error) {
+	err = global.GVA_DB.Where("name = ?", userSyncName).First(&res.State).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return res, err
+	}
+	res.Running = userSyncRunning.Load()
+	err = global.GVA_DB.Model(&system.SysUserSyncConflict{}).Where("status = ?", system.SyncConflictPending).
+		Count(&res.PendingConflicts).Error
+	return res, err
+}
+
+// GetConflictList
+// @function: GetConflictList
+// @description: 分页获取同步冲突
+// @param: info systemReq.SysUserSyncConflictSearch
+// @return: list []system.SysUserSyncConflict, total int64, err error
+func (s *UserSyncService) GetConflictList(info systemReq.SysUserSyncConflictSearch) (list []system.SysUserSyncConflict, total int64, err error) {
+	db := global.GVA_DB.Model(&system.SysUserSyncConflict{})
+	if info.Type != "" {
+		db = db.Where("type = ?", info.Type)
+	}
+	if info.Status != "" {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// ResolveConflict
+// @function: ResolveConflict
+// @description: 处理同步冲突
+// @param: req systemReq.SyncConflictResolveReq, operator string
+// @return: err error
+func (s *UserSyncService) ResolveConflict(req systemReq.SyncConflictResolveReq, operator string) error {
+	var conflict system.SysUserSyncConflict
+	if err := global.GVA_DB.Where("id = ?", req.ID).First(&conflict).Error; err != nil {
+		return errors.New("冲突记录不存在")
+	}
+	if conflict.Status != system.SyncConflictPending {
+		return errors.New("冲突已处理")
+	}
+	var allowed bool
+	for _, action := range userSyncResolveActions[conflict.Type] {
+		allowed = allowed || action == req.Action
+	}
+	if !allowed {
+		return fmt.Errorf("冲突类型 %s 不支持处理方式 %s", conflict.Type, req.Action)
+	}
+	var revoke bool
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var user system.SysUser
+		var account gaia.Account
+		if req.Action != system.SyncResolveIgnore {
+			if err := tx.Where("id = ?", conflict.UserID).First(&user).Error; err != nil {
+				return errors.New("后台用户不存在")
+			}
+		}
+		switch req.Action {
+		case system.SyncResolveUseGaia, system.SyncResolveUseAdmin:
+			if err := tx.Where("id = ?", conflict.AccountID).First(&account).Error; err != nil {
+				return errors.New("gaia账户不存在")
+			}
+		}
+		switch req.Action {
+		case system.SyncResolveRename:
+			req.Username = strings.TrimSpace(req.Username)
+			if req.Username == "" {
+				return errors.New("请填写新用户名")
+			}
+			if !errors.Is(tx.Where("username = ? AND id <> ?", req.Username, user.ID).First(&system.SysUser{}).Error, gorm.ErrRecordNotFound) {
+				return errors.New("用户名已被占用")
+			}
+			if err := tx.Model(&user).Update("username", req.Username).Error; err != nil {
+				return err
+			}
+		case system.SyncResolveUseGaia:
+			if !errors.Is(tx.Where("email = ? AND id <> ?", account.Email, user.ID).First(&system.SysUser{}).Error, gorm.ErrRecordNotFound) {
+				return errors.New("邮箱已被其他后台用户使用")
+			}
+			if err := tx.Model(&user).Update("email", account.Email).Error; err != nil {
+				return err
+			}
+		case system.SyncResolveUseAdmin:
+			if !errors.Is(tx.Where("email = ? AND id <> ?", user.Email, account.ID).First(&gaia.Account{}).Error, gorm.ErrRecordNotFound) {
+				return errors.New("邮箱已被其他gaia账户使用")
+			}
+			if err := tx.Model(&gaia.Account{}).Where("id = ?", account.ID).Updates(map[string]interface{}{
+				"email":      user.Email,
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		case system.SyncResolveDisable:
+			if err := tx.Model(&user).Update("enable", system.UserDeactivate).Error; err != nil {
+				return err
+			}
+			revoke = true
+		}
+		status := system.SyncConflictResolved
+		if req.Action == system.SyncResolveIgnore {
+			status = system.SyncConflictIgnored
+		}
+		now := time.Now()
+		return tx.Model(&conflict).Updates(map[string]interface{}{
+			"status":      status,
+			"resolution":  req.Action,
+			"resolved_by": operator,
+			"resolved_at": &now,
+		}).Error
+	})
+	if err == nil && revoke {
+		err = SessionServiceApp.RevokeUser(conflict.UserID, system.SessionRevokeDisabled)
+	}
+	return err
+}
+
+// resolveSuperAdmin 确定新建时授予超级管理员角色的账户, 只取配置文件中的账户或指定工作空间的所有者,
+// 均未配置时不授予任何账户, 结果记录在同步状态中
+func (s *UserSyncService) resolveSuperAdmin(state *system.SysUserSyncState) string {
+	cfg := global.GVA_CONFIG.Gaia
+	state.SuperAdmin = cfg.SuperAdminAccountId
+	if state.SuperAdmin == "" && cfg.SuperAdminTenantId != "" {
+		var join gaia.TenantAccountJoins
+		if err := global.GVA_DB.Where("tenant_id = ? AND role = ?", cfg.SuperAdminTenantId, gaia.TenantRoleOwner).
+			First(&join).Error; err == nil {
+			state.SuperAdmin = join.AccountID.String()
+		}
+	}
+	return state.SuperAdmin
+}
+
+// pullAccounts 将游标之后修改过的gaia账户同步到后台用户
+func (s *UserSyncService) pullAccounts(run *userSyncRun) error {
+	since := run.state.AccountCursor.Add(-userSyncOverlap)
+	var last *gaia.Account
+	for {
+		var accounts []gaia.Account
+		db := global.GVA_DB.Where("updated_at >= ?", since)
+		if last != nil {
+			db = db.Where("(updated_at, id) > (?, ?)", last.UpdatedAt, last.ID)
+		}
+		if err := db.Order("updated_at ASC, id ASC").Limit(userSyncBatch).Find(&accounts).Error; err != nil {
+			return err
+		}
+		for i := range accounts {
+			run.state.LastScanned++
+			if err := s.pullAccount(run, accounts[i]); err != nil {
+				global.GVA_LOG.Error("SyncUser 同步gaia账户失败!", zap.String("account", accounts[i].ID.String()), zap.Error(err))
+			}
+		}
+		if len(accounts) == 0 {
+			return nil
+		}
+		last = &accounts[len(accounts)-1]
+		if last.UpdatedAt.After(run.state.AccountCursor) {
+			run.state.AccountCursor = last.UpdatedAt
+		}
+		if len(accounts) < userSyncBatch {
+			return nil
+		}
+	}
+}
+
+// pullAccount 以gaia账户为准更新后台用户的昵称与状态, 关联不到后台用户时新建
+func (s *UserSyncService) pullAccount(run *userSyncRun, account gaia.Account) error {
+	user, err := s.linkedUser(run, account)
+	if errors.Is(err, errUserSyncSkip) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		if account.Status != gaia.UserActive {
+			return nil
+		}
+		return s.createUser(run, account)
+	}
+	run.handled[user.ID] = true
+	s.checkEmail(run, *user, account)
+
+	updates := make(map[string]interface{})
+	if account.Name != "" && account.Name != user.NickName {
+		updates["nick_name"] = account.Name
+	}
+	enable := user.Enable
+	switch account.Status {
+	case gaia.UserActive:
+		enable = system.UserActive
+	case gaia.UserBanned, gaia.UserClosed:
+		enable = system.UserDeactivate
+	}
+	if enable != user.Enable {
+		updates["enable"] = enable
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err = global.GVA_DB.Model(&system.SysUser{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	run.state.LastPulled++
+	if enable != user.Enable {
+		user.SyncGaiaStatus(enable)
+		if enable != system.UserActive {
+			return SessionServiceApp.RevokeUser(user.ID, system.SessionRevokeDisabled)
+		}
+	}
+	return nil
+}
+
+// linkedUser 获取gaia账户关联的后台用户, 只按 UUID 关联
+// 未关联的后台用户已使用该邮箱时记录冲突, 不按邮箱自动关联, 避免修改gaia邮箱即可接管后台用户
+func (s *UserSyncService) linkedUser(run *userSyncRun, account gaia.Account) (*system.SysUser, error) {
+	var user system.SysUser
+	err := global.GVA_DB.Where("uuid = ?", account.ID).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	err = global.GVA_DB.Where("email = ?", account.Email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.conflict(run, system.SysUserSyncConflict{
+		Type:       system.SyncConflictEmailTaken,
+		AccountID:  account.ID.String(),
+		UserID:     user.ID,
+		AdminValue: user.Username,
+		GaiaValue:  account.Email,
+		Detail:     fmt.Sprintf("邮箱 %s 已被未关联该账户的后台用户 %s 使用", account.Email, user.Username),
+	})
+	return nil, errUserSyncSkip
+}
+
+// linkLegacyUsers 历史数据中后台用户的UUID为随机值, 首次同步时通过邮箱关联一次并将UUID迁移为账户ID, 之后不再依赖邮箱
+func (s *UserSyncService) linkLegacyUsers(run *userSyncRun) error {
+	if run.state.LegacyLinked {
+		return nil
+	}
+	var lastID uint
+	for {
+		users, err := s.userLinks(lastID)
+		if err != nil {
+			return err
+		}
+		if users == nil {
+			break
+		}
+		lastID = users[len(users)-1].ID
+		for _, user := range users {
+			if user.Linked {
+				continue
+			}
+			if err = s.linkLegacyUser(run, user.SysUser); err != nil {
+				global.GVA_LOG.Error("SyncUser 关联gaia账户失败!", zap.Uint("user", user.ID), zap.Error(err))
+			}
+		}
+	}
+	run.state.LegacyLinked = true
+	return nil
+}
+
+// linkLegacyUser 同邮箱的账户未被其他用户关联时, 将后台用户的UUID迁移为账户ID并同步
+func (s *UserSyncService) linkLegacyUser(run *userSyncRun, user system.SysUser) error {
+	var account gaia.Account
+	if user.Email == "" || global.GVA_DB.Where("email = ?", user.Email).First(&account).Error != nil {
+		return nil
+	}
+	if !errors.Is(global.GVA_DB.Where("uuid = ?", account.ID).First(&system.SysUser{}).Error, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err := global.GVA_DB.Model(&system.SysUser{}).Where("id = ?", user.ID).Update("uuid", account.ID).Error; err != nil {
+		return err
+	}
+	// 在线会话中的UUID已失效, 需重新登录
+	if err := SessionServiceApp.RevokeUser(user.ID, system.SessionRevokeManual); err != nil {
+		global.GVA_LOG.Error("SyncUser 注销会话失败!", zap.Error(err))
+	}
+	return s.pullAccount(run, account)
+}
+
+// userSyncLink 后台用户及其UUID是否关联到存在的gaia账户
+type userSyncLink struct {
+	system.SysUser
+	Linked bool
+}
+
+// userLinks 按ID取下一批后台用户并标记关联的gaia账户是否存在, 没有更多用户时返回nil
+func (s *UserSyncService) userLinks(afterID uint) ([]userSyncLink, error) {
+	var users []system.SysUser
+	if err := global.GVA_DB.Where("id > ?", afterID).Order("id ASC").Limit(userSyncBatch).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.UUID.String())
+	}
+	var exists []string
+	if err := global.GVA_DB.Model(&gaia.Account{}).Where("id IN ?", ids).Pluck("id", &exists).Error; err != nil {
+		return nil, err
+	}
+	linked := make(map[string]bool, len(exists))
+	for _, id := range exists {
+		linked[strings.ToLower(id)] = true
+	}
+	list := make([]userSyncLink, 0, len(users))
+	for _, user := range users {
+		list = append(list, userSyncLink{SysUser: user, Linked: linked[user.UUID.String()]})
+	}
+	return list, nil
+}
+
+// createUser 为gaia账户新建后台用户, 用户名默认取邮箱前缀, 被占用时使用完整邮箱并记录冲突
+func (s *UserSyncService) createUser(run *userSyncRun, account gaia.Account) error {
+	username, renamed, ok := usernameForEmail(account.Email)
+	if !ok {
+		s.conflict(run, system.SysUserSyncConflict{
+			Type:      system.SyncConflictDuplicateUsername,
+			AccountID: account.ID.String(),
+			GaiaValue: account.Email,
+			Detail:    fmt.Sprintf("用户名 %s 与邮箱均已被占用, 未创建后台用户", username),
+		})
+		return nil
+	}
+	name, phone := account.Name, ""
+	// 钉钉组织同步已关联时使用钉钉的手机号与姓名
+	var ding gaia.AccountDingTalkExtend
+	if global.GVA_DB.Where("id = ?", account.ID).First(&ding).Error == nil {
+		phone = ding.Mobile
+		if len(ding.Name) > 0 {
+			name = ding.Name
+		}
+	}
+	authorityId := system.DefaultGroupID
+	if run.superAdmin != "" && account.ID.String() == run.superAdmin {
+		authorityId = system.AdminGroupID
+	}
+	// 账户已存在于gaia, 不经过 Register 重复注册
+	user := system.SysUser{
+		UUID:        account.ID,
+		Username:    username,
+		NickName:    name,
+		Phone:       phone,
+		Email:       account.Email,
+		AuthorityId: authorityId,
+		Authorities: []system.SysAuthority{{AuthorityId: authorityId}},
+		Enable:      system.UserActive,
+	}
+	if err := global.GVA_DB.Create(&user).Error; err != nil {
+		return err
+	}
+	run.state.LastCreated++
+	if renamed != "" {
+		s.conflict(run, system.SysUserSyncConflict{
+			Type:       system.SyncConflictDuplicateUsername,
+			AccountID:  account.ID.String(),
+			UserID:     user.ID,
+			AdminValue: username,
+			GaiaValue:  account.Email,
+			Detail:     fmt.Sprintf("用户名 %s 已被占用, 已使用邮箱作为用户名", renamed),
+		})
+	}
+	// 默认工作空间策略
+	if err := new(serviceGaia.TenantsService).ApplyDefaultTenants(account.ID); err != nil {
+		global.GVA_LOG.Error("SyncUser ApplyDefaultTenants: " + err.Error())
+	}
+	return nil
+}
+
+// pushUsers 将游标之后修改过的后台用户的昵称与状态同步到gaia账户
+func (s *UserSyncService) pushUsers(run *userSyncRun) error {
+	since := run.state.UserCursor.Add(-userSyncOverlap)
+	var last *system.SysUser
+	for {
+		var users []system.SysUser
+		db := global.GVA_DB.Where("updated_at >= ?", since)
+		if last != nil {
+			db = db.Where("(updated_at, id) > (?, ?)", last.UpdatedAt, last.ID)
+		}
+		if err := db.Order("updated_at ASC, id ASC").Limit(userSyncBatch).Find(&users).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			run.state.LastScanned++
+			if run.handled[user.ID] {
+				continue
+			}
+			if err := s.pushUser(run, user); err != nil {
+				global.GVA_LOG.Error("SyncUser 同步后台用户失败!", zap.Uint("user", user.ID), zap.Error(err))
+			}
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		last = &users[len(users)-1]
+		if last.UpdatedAt.After(run.state.UserCursor) {
+			run.state.UserCursor = last.UpdatedAt
+		}
+		if len(users) < userSyncBatch {
+			return nil
+		}
+	}
+}
+
+func (s *UserSyncService) pushUser(run *userSyncRun, user system.SysUser) error {
+	var account gaia.Account
+	if err := global.GVA_DB.Where("id = ?", user.UUID).First(&account).Error; err != nil {
+		// 未关联的用户由 detectDeleted 处理
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	s.checkEmail(run, user, account)
+	updates := make(map[string]interface{})
+	if user.NickName != "" && user.NickName != account.Name {
+		updates["name"] = user.NickName
+	}
+	// 只在 active 与 banned 之间切换, 不改变未激活或已注销的账户
+	if account.Status == gaia.UserActive && user.Enable != system.UserActive {
+		updates["status"] = gaia.UserBanned
+	} else if account.Status == gaia.UserBanned && user.Enable == system.UserActive {
+		updates["status"] = gaia.UserActive
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	updates["updated_at"] = time.Now()
+	if err := global.GVA_DB.Model(&gaia.Account{}).Where("id = ?", account.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	run.state.LastPushed++
+	return nil
+}
+
+// detectDeleted 检查关联的gaia账户已不存在的后台用户, 只记录冲突, 不自动停用
+// 账户删除不会更新 updated_at, 每次按ID游标检查一批后台用户, 检查完一轮后从头开始
+func (s *UserSyncService) detectDeleted(run *userSyncRun) error {
+	users, err := s.userLinks(run.state.DeletedCursor)
+	if err != nil {
+		return err
+	}
+	if len(users) < userSyncBatch {
+		run.state.DeletedCursor = 0
+	} else {
+		run.state.DeletedCursor = users[len(users)-1].ID
+	}
+	for _, user := range users {
+		run.state.LastScanned++
+		if user.Linked {
+			continue
+		}
+		s.conflict(run, system.SysUserSyncConflict{
+			Type:       system.SyncConflictAccountDeleted,
+			AccountID:  user.UUID.String(),
+			UserID:     user.ID,
+			AdminValue: user.Username,
+			Detail:     fmt.Sprintf("后台用户 %s 关联的gaia账户已不存在", user.Username),
+		})
+	}
+	return nil
+}
+
+// checkEmail 邮箱不一致时记录冲突, 不自动覆盖任何一方
+func (s *UserSyncService) checkEmail(run *userSyncRun, user system.SysUser, account gaia.Account) {
+	if strings.EqualFold(user.Email, account.Email) {
+		return
+	}
+	s.conflict(run, system.SysUserSyncConflict{
+		Type:       system.SyncConflictEmailChanged,
+		AccountID:  account.ID.String(),
+		UserID:     user.ID,
+		AdminValue: user.Email,
+		GaiaValue:  account.Email,
+		Detail:     "后台用户与gaia账户邮箱不一致",
+	})
+}
+
+func usernameTaken(username string) bool {
+	var count int64
+	global.GVA_DB.Model(&system.SysUser{}).Where("username = ?", username).Count(&count)
+	return count > 0
+}
+
+// usernameForEmail 由邮箱生成用户名, 默认取邮箱前缀, 被占用时使用完整邮箱, renamed 为被放弃的前缀
+// 前缀与完整邮箱均被占用时 ok 为 false, username 为被占用的前缀
+func usernameForEmail(email string) (username, renamed string, ok bool) {
+	username = email
+	if prefix, _, found := strings.Cut(email, "@"); found && prefix != "" {
+		username = prefix
+	}
+	if !usernameTaken(username) {
+		return username, "", true
+	}
+	if username == email || usernameTaken(email) {
+		return username, "", false
+	}
+	return email, username, true
+}
+
+// conflict 记录冲突, 同一对象的同类待处理冲突只保留一条
+func (s *UserSyncService) conflict(run *userSyncRun, c system.SysUserSyncConflict) {
+	c.Status = system.SyncConflictPending
+	c.LastSeenAt = time.Now()
+	var exist system.SysUserSyncConflict
+	err := global.GVA_DB.Where("type = ? AND status = ? AND account_id = ? AND user_id = ?",
+		c.Type, c.Status, c.AccountID, c.UserID).First(&exist).Error
+	if err == nil {
+		err = global.GVA_DB.Model(&exist).Updates(map[string]interface{}{
+			"admin_value":  c.AdminValue,
+			"gaia_value":   c.GaiaValue,
+			"detail":       c.Detail,
+			"last_seen_at": c.LastSeenAt,
+		}).Error
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err = global.GVA_DB.Create(&c).Error; err == nil {
+			run.state.LastConflicts++
+		}
+	}
+	if err != nil {
+		global.GVA_LOG.Error("SyncUser 记录冲突失败!", zap.String("type", c.Type), zap.Error(err))
+	}
+}
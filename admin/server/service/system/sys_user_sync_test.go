@@ -0,0 +1,221 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/gofrs/uuid/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/songzhibin97/gkit/cache/local_cache"
+	"gorm.io/gorm"
+)
+
+// newUserSyncTestDB 同步测试使用的表, tenant_account_joins 的默认值依赖postgres扩展, 手动建表
+func newUserSyncTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := newTestDB(t, &system.SysUser{}, &system.SysUserSession{}, &system.SysUserSyncConflict{},
+		&gaia.Account{}, &gaia.AccountDingTalkExtend{})
+	if err := db.Exec(`CREATE TABLE tenant_account_joins (id text, tenant_id text, account_id text, role text,
+		invited_by text, created_at datetime, updated_at datetime, current bool)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	oldGaia, oldRedis, oldCache := global.GVA_CONFIG.Gaia, global.GVA_REDIS, global.BlackCache
+	global.GVA_CONFIG.Gaia = config.Gaia{}
+	// 无可用redis, 只验证数据库中的结果
+	global.GVA_REDIS = redis.NewClient(&redis.Options{Addr: "127.0.0.1:0", MaxRetries: -1})
+	global.BlackCache = local_cache.NewCache()
+	t.Cleanup(func() {
+		global.GVA_CONFIG.Gaia, global.GVA_REDIS, global.BlackCache = oldGaia, oldRedis, oldCache
+	})
+	return db
+}
+
+func TestUserSyncResolveSuperAdmin(t *testing.T) {
+	owner, configured := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	tenantID := uuid.Must(uuid.NewV4())
+	tests := []struct {
+		name  string
+		cfg   config.Gaia
+		stale string
+		want  string
+	}{
+		{name: "未配置时不授予任何账户", stale: owner.String(), want: ""},
+		{name: "配置账户", cfg: config.Gaia{SuperAdminAccountId: configured.String()}, want: configured.String()},
+		{name: "配置工作空间时取其所有者", cfg: config.Gaia{SuperAdminTenantId: tenantID.String()}, want: owner.String()},
+		{name: "配置的工作空间没有所有者", cfg: config.Gaia{SuperAdminTenantId: uuid.Must(uuid.NewV4()).String()}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newUserSyncTestDB(t)
+			if err := db.Create(&gaia.TenantAccountJoins{
+				ID: uuid.Must(uuid.NewV4()), TenantID: tenantID, AccountID: owner, Role: gaia.TenantRoleOwner,
+			}).Error; err != nil {
+				t.Fatal(err)
+			}
+			global.GVA_CONFIG.Gaia = tt.cfg
+			state := system.SysUserSyncState{SuperAdmin: tt.stale}
+			if got := (&UserSyncService{}).resolveSuperAdmin(&state); got != tt.want || state.SuperAdmin != tt.want {
+				t.Errorf("resolveSuperAdmin() = %q, state %q, want %q", got, state.SuperAdmin, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserSyncPullAccount(t *testing.T) {
+	accountID := uuid.Must(uuid.NewV4())
+	account := gaia.Account{ID: accountID, Name: "Alice", Email: "alice@corp.com", Status: gaia.UserActive}
+	linked := &system.SysUser{UUID: accountID, Username: "alice", NickName: "Alice", Email: "alice@corp.com",
+		AuthorityId: system.DefaultGroupID, Enable: system.UserActive}
+	tests := []struct {
+		name          string
+		account       gaia.Account
+		existing      []system.SysUser
+		superAdmin    string
+		wantUser      bool
+		wantUsername  string
+		wantNick      string
+		wantAuthority uint
+		wantEnable    int
+		wantRevoked   bool
+		wantConflicts int64
+	}{
+		{
+			name: "新账户创建后台用户", account: account,
+			wantUser: true, wantUsername: "alice", wantNick: "Alice", wantAuthority: system.DefaultGroupID, wantEnable: system.UserActive,
+		},
+		{
+			name: "配置的超级管理员账户创建为管理员", account: account, superAdmin: accountID.String(),
+			wantUser: true, wantUsername: "alice", wantNick: "Alice", wantAuthority: system.AdminGroupID, wantEnable: system.UserActive,
+		},
+		{
+			name: "用户名被占用时使用邮箱并记录冲突", account: account,
+			existing: []system.SysUser{{UUID: uuid.Must(uuid.NewV4()), Username: "alice", Email: "other@corp.com"}},
+			wantUser: true, wantUsername: "alice@corp.com", wantNick: "Alice", wantAuthority: system.DefaultGroupID,
+			wantEnable: system.UserActive, wantConflicts: 1,
+		},
+		{
+			name: "未激活的账户不创建", account: gaia.Account{ID: accountID, Email: "alice@corp.com", Status: gaia.UserBanned},
+		},
+		{
+			name: "更新昵称", account: gaia.Account{ID: accountID, Name: "Alice Liu", Email: "alice@corp.com", Status: gaia.UserActive},
+			existing: []system.SysUser{*linked},
+			wantUser: true, wantUsername: "alice", wantNick: "Alice Liu", wantAuthority: system.DefaultGroupID, wantEnable: system.UserActive,
+		},
+		{
+			name: "账户被禁用时停用后台用户并注销会话", account: gaia.Account{ID: accountID, Name: "Alice", Email: "alice@corp.com", Status: gaia.UserBanned},
+			existing: []system.SysUser{*linked},
+			wantUser: true, wantUsername: "alice", wantNick: "Alice", wantAuthority: system.DefaultGroupID,
+			wantEnable: system.UserDeactivate, wantRevoked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newUserSyncTestDB(t)
+			if err := db.Create(&tt.account).Error; err != nil {
+				t.Fatal(err)
+			}
+			for i := range tt.existing {
+				user := tt.existing[i]
+				if err := db.Create(&user).Error; err != nil {
+					t.Fatal(err)
+				}
+				if err := db.Create(&system.SysUserSession{SessionID: user.UUID.String(), UserID: user.ID,
+					ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			run := &userSyncRun{state: &system.SysUserSyncState{}, superAdmin: tt.superAdmin, handled: map[uint]bool{}}
+			if err := (&UserSyncService{}).pullAccount(run, tt.account); err != nil {
+				t.Fatalf("pullAccount() error = %v", err)
+			}
+			var user system.SysUser
+			err := db.Where("uuid = ?", accountID).First(&user).Error
+			if !tt.wantUser {
+				if err == nil {
+					t.Fatalf("不应创建后台用户")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("未找到关联的后台用户: %v", err)
+			}
+			if user.Username != tt.wantUsername || user.NickName != tt.wantNick ||
+				user.AuthorityId != tt.wantAuthority || user.Enable != tt.wantEnable {
+				t.Errorf("user = {%s %s %d %d}, want {%s %s %d %d}", user.Username, user.NickName, user.AuthorityId, user.Enable,
+					tt.wantUsername, tt.wantNick, tt.wantAuthority, tt.wantEnable)
+			}
+			var revoked int64
+			db.Model(&system.SysUserSession{}).Where("user_id = ? AND revoked_at IS NOT NULL AND revoke_reason = ?",
+				user.ID, system.SessionRevokeDisabled).Count(&revoked)
+			if (revoked > 0) != tt.wantRevoked {
+				t.Errorf("会话注销 = %v, want %v", revoked > 0, tt.wantRevoked)
+			}
+			var conflicts int64
+			db.Model(&system.SysUserSyncConflict{}).Count(&conflicts)
+			if conflicts != tt.wantConflicts {
+				t.Errorf("冲突数 = %d, want %d", conflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+// 历史用户只在首次同步时按邮箱关联, 账户删除按ID游标分批检查
+func TestUserSyncLegacyLink(t *testing.T) {
+	db := newUserSyncTestDB(t)
+	alice, bob := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	for _, account := range []gaia.Account{
+		{ID: alice, Name: "Alice", Email: "alice@corp.com", Status: gaia.UserActive},
+		{ID: bob, Name: "Bob", Email: "bob@corp.com", Status: gaia.UserActive},
+	} {
+		if err := db.Create(&account).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy := system.SysUser{UUID: uuid.Must(uuid.NewV4()), Username: "alice", Email: "alice@corp.com", Enable: system.UserActive}
+	deleted := system.SysUser{UUID: uuid.Must(uuid.NewV4()), Username: "carol", Email: "carol@corp.com", Enable: system.UserActive}
+	for _, user := range []*system.SysUser{&legacy, &deleted} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := &UserSyncService{}
+	run := &userSyncRun{state: &system.SysUserSyncState{}, handled: map[uint]bool{}}
+	if err := service.linkLegacyUsers(run); err != nil {
+		t.Fatalf("linkLegacyUsers() error = %v", err)
+	}
+	var user system.SysUser
+	if err := db.Where("id = ?", legacy.ID).First(&user).Error; err != nil || user.UUID != alice || !run.state.LegacyLinked {
+		t.Fatalf("历史用户未关联: uuid = %s, legacyLinked = %v", user.UUID, run.state.LegacyLinked)
+	}
+
+	// 关联完成后, 同邮箱的后台用户不再自动关联, 只记录冲突
+	impostor := system.SysUser{UUID: uuid.Must(uuid.NewV4()), Username: "bob", Email: "bob@corp.com", Enable: system.UserActive}
+	if err := db.Create(&impostor).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := service.linkLegacyUsers(run); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.pullAccount(run, gaia.Account{ID: bob, Name: "Bob", Email: "bob@corp.com", Status: gaia.UserActive}); err != nil {
+		t.Fatalf("pullAccount() error = %v", err)
+	}
+	db.Where("id = ?", impostor.ID).First(&user)
+	var taken int64
+	db.Model(&system.SysUserSyncConflict{}).Where("type = ? AND user_id = ?", system.SyncConflictEmailTaken, impostor.ID).Count(&taken)
+	if user.UUID == bob || taken != 1 {
+		t.Errorf("同邮箱的后台用户 uuid = %s, 冲突 %d", user.UUID, taken)
+	}
+
+	if err := service.detectDeleted(run); err != nil {
+		t.Fatalf("detectDeleted() error = %v", err)
+	}
+	var missing []uint
+	db.Model(&system.SysUserSyncConflict{}).Where("type = ?", system.SyncConflictAccountDeleted).Order("user_id").Pluck("user_id", &missing)
+	if len(missing) != 2 || missing[0] != deleted.ID || missing[1] != impostor.ID || run.state.DeletedCursor != 0 {
+		t.Errorf("账户已删除的冲突 = %v, cursor = %d", missing, run.state.DeletedCursor)
+	}
+}
@@ -192,6 +192,16 @@ func (i *initApi) InitializeData(ctx context.Context) (context.Context, error) {
 		{ApiGroup: "tenants表", Method: "GET", Path: "/tenants/getAllTenants", Description: "获取所有工作区"},
 		{ApiGroup: "tenants表", Method: "GET", Path: "/tenants/getTenantsList", Description: "获取tenants表列表"},
 		{ApiGroup: "tenants表", Method: "GET", Path: "/tenants/findTenants", Description: "根据ID获取tenants表"},
+		// Extend Start: workspace management
+		{ApiGroup: "tenants表", Method: "PUT", Path: "/tenants/updateTenant", Description: "修改工作空间"},
+		{ApiGroup: "tenants表", Method: "GET", Path: "/tenants/getTenantMembers", Description: "获取工作空间成员"},
+		{ApiGroup: "tenants表", Method: "POST", Path: "/tenants/addTenantMember", Description: "添加工作空间成员"},
+		{ApiGroup: "tenants表", Method: "DELETE", Path: "/tenants/removeTenantMember", Description: "移除工作空间成员"},
+		{ApiGroup: "tenants表", Method: "PUT", Path: "/tenants/transferTenantOwner", Description: "转移工作空间所有权"},
+		{ApiGroup: "tenants表", Method: "GET", Path: "/tenants/getAccountTenants", Description: "获取账户加入的工作空间"},
+		{ApiGroup: "tenants表", Method: "PUT", Path: "/tenants/updateMemberRoles", Description: "批量修改成员角色"},
+		{ApiGroup: "tenants表", Method: "GET", Path: "/tenants/getMemberAuditList", Description: "获取成员变更审计"},
+		// Extend Stop: workspace management
 		{ApiGroup: "盖亚报表", Method: "GET", Path: "/gaia/dashboard/getAppTokenDailyQuotaData", Description: "获取每天密钥花费数据列表"},
 		{ApiGroup: "盖亚报表", Method: "GET", Path: "/gaia/dashboard/getAppTokenQuotaRankingData", Description: "分页获取【应用密钥】配额排名数据列表"},
 		{ApiGroup: "盖亚报表", Method: "GET", Path: "/gaia/dashboard/getAppQuotaRankingData", Description: "分页获取【应用】配额排名数据"},
@@ -201,12 +211,115 @@ func (i *initApi) InitializeData(ctx context.Context) (context.Context, error) {
 		// Extend Start: system integration
 		{ApiGroup: "应用集成配置", Method: "GET", Path: "/gaia/system/dingtalk", Description: "获取钉钉系统配置"},
 		{ApiGroup: "应用集成配置", Method: "POST", Path: "/gaia/system/dingtalk", Description: "设置钉钉系统配置"},
+		{ApiGroup: "应用集成配置", Method: "POST", Path: "/gaia/system/dingtalk/sync", Description: "同步钉钉组织架构"},
+		{ApiGroup: "应用集成配置", Method: "GET", Path: "/gaia/system/scim", Description: "获取SCIM用户预配配置"},
+		{ApiGroup: "应用集成配置", Method: "POST", Path: "/gaia/system/scim", Description: "设置SCIM用户预配配置"},
 		// Extend Stop: system integration
 
 		// Extend Start: oauth2
 		{ApiGroup: "应用集成配置", Method: "GET", Path: "/gaia/system/oauth2", Description: "设置OAuth2配置"},
 		{ApiGroup: "应用集成配置", Method: "POST", Path: "/gaia/system/oauth2", Description: "获取OAuth2集成配置"},
+		{ApiGroup: "应用集成配置", Method: "GET", Path: "/gaia/system/oauth2/providers", Description: "获取OAuth2提供方列表"},
+		{ApiGroup: "应用集成配置", Method: "POST", Path: "/gaia/system/oauth2/provider", Description: "新增或修改OAuth2提供方"},
+		{ApiGroup: "应用集成配置", Method: "DELETE", Path: "/gaia/system/oauth2/provider", Description: "删除OAuth2提供方"},
 		// Extend Stop: oauth2
+
+		// Extend Start: TOTP mfa
+		{ApiGroup: "系统用户", Method: "GET", Path: "/user/mfa", Description: "获取二次验证状态"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/mfa/enroll", Description: "绑定TOTP"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/mfa/activate", Description: "完成TOTP绑定"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/mfa/disable", Description: "关闭二次验证"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/mfa/recoveryCodes", Description: "重新生成恢复码"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/mfa/reset", Description: "重置用户二次验证"},
+		// Extend Stop: TOTP mfa
+
+		// Extend Start: oauth2 email trust
+		{ApiGroup: "系统用户", Method: "GET", Path: "/user/integrates", Description: "获取用户关联的第三方身份"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/integrate/link", Description: "关联第三方身份"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/integrate/unlink", Description: "解除第三方身份关联"},
+		// Extend Stop: oauth2 email trust
+
+		// Extend Start: session registry
+		{ApiGroup: "会话管理", Method: "GET", Path: "/session/mine", Description: "获取自己的在线会话"},
+		{ApiGroup: "会话管理", Method: "DELETE", Path: "/session/mine", Description: "注销自己的会话"},
+		{ApiGroup: "会话管理", Method: "POST", Path: "/session/getSessionList", Description: "分页获取全部会话"},
+		{ApiGroup: "会话管理", Method: "DELETE", Path: "/session/revoke", Description: "注销任意会话"},
+		{ApiGroup: "会话管理", Method: "DELETE", Path: "/session/revokeUser", Description: "注销用户的全部会话"},
+		// Extend Stop: session registry
+
+		// Extend Start: login lockout
+		{ApiGroup: "登录安全", Method: "GET", Path: "/loginSecurity/lockList", Description: "获取当前生效的登录锁定"},
+		{ApiGroup: "登录安全", Method: "POST", Path: "/loginSecurity/unlock", Description: "解除账号或IP锁定"},
+		{ApiGroup: "登录安全", Method: "POST", Path: "/loginSecurity/getLoginHistoryList", Description: "分页获取登录历史"},
+		{ApiGroup: "登录安全", Method: "GET", Path: "/loginSecurity/mine", Description: "获取自己的登录历史"},
+		// Extend Stop: login lockout
+
+		// Extend Start: personal access token
+		{ApiGroup: "访问令牌", Method: "GET", Path: "/accessToken/list", Description: "获取自己的个人访问令牌"},
+		{ApiGroup: "访问令牌", Method: "GET", Path: "/accessToken/scopes", Description: "获取可授予令牌的接口"},
+		{ApiGroup: "访问令牌", Method: "POST", Path: "/accessToken/create", Description: "创建个人访问令牌"},
+		{ApiGroup: "访问令牌", Method: "DELETE", Path: "/accessToken/revoke", Description: "吊销个人访问令牌"},
+		// Extend Stop: personal access token
+
+		// Extend Start: offboarding
+		{ApiGroup: "离职交接", Method: "POST", Path: "/offboard/preview", Description: "预览离职交接"},
+		{ApiGroup: "离职交接", Method: "POST", Path: "/offboard/execute", Description: "执行离职交接"},
+		{ApiGroup: "离职交接", Method: "POST", Path: "/offboard/deactivate", Description: "停用用户"},
+		{ApiGroup: "离职交接", Method: "POST", Path: "/offboard/reactivate", Description: "恢复用户"},
+		{ApiGroup: "离职交接", Method: "POST", Path: "/offboard/getOffboardList", Description: "分页获取交接报告"},
+		// Extend Stop: offboarding
+		// Extend Start: two-way user sync
+		{ApiGroup: "用户同步", Method: "GET", Path: "/userSync/status", Description: "获取用户同步状态"},
+		{ApiGroup: "用户同步", Method: "POST", Path: "/userSync/getConflictList", Description: "分页获取同步冲突"},
+		{ApiGroup: "用户同步", Method: "POST", Path: "/userSync/resolveConflict", Description: "处理同步冲突"},
+		// Extend Stop: two-way user sync
+		// Extend Start: end user directory
+		{ApiGroup: "终端用户", Method: "GET", Path: "/endUser/getEndUserList", Description: "分页获取终端用户"},
+		{ApiGroup: "终端用户", Method: "GET", Path: "/endUser/findEndUser", Description: "获取终端用户详情"},
+		{ApiGroup: "终端用户", Method: "POST", Path: "/endUser/blockEndUser", Description: "封禁终端用户"},
+		{ApiGroup: "终端用户", Method: "POST", Path: "/endUser/unblockEndUser", Description: "解封终端用户"},
+		// Extend Stop: end user directory
+		// Extend Start: export job queue
+		{ApiGroup: "导出任务", Method: "POST", Path: "/exportJob/createExportJob", Description: "创建导出任务"},
+		{ApiGroup: "导出任务", Method: "DELETE", Path: "/exportJob/deleteExportJob", Description: "删除导出任务"},
+		{ApiGroup: "导出任务", Method: "GET", Path: "/exportJob/getExportJobList", Description: "导出历史"},
+		{ApiGroup: "导出任务", Method: "GET", Path: "/exportJob/findExportJob", Description: "查询导出任务"},
+		{ApiGroup: "导出任务", Method: "GET", Path: "/exportJob/getDownloadLink", Description: "获取导出文件下载链接"},
+		// Extend Stop: export job queue
+		// Extend Start: scheduled report
+		{ApiGroup: "定时报表", Method: "POST", Path: "/exportSchedule/createExportSchedule", Description: "创建定时报表"},
+		{ApiGroup: "定时报表", Method: "PUT", Path: "/exportSchedule/updateExportSchedule", Description: "更新定时报表"},
+		{ApiGroup: "定时报表", Method: "DELETE", Path: "/exportSchedule/deleteExportSchedule", Description: "删除定时报表"},
+		{ApiGroup: "定时报表", Method: "POST", Path: "/exportSchedule/runExportSchedule", Description: "立即执行定时报表"},
+		{ApiGroup: "定时报表", Method: "GET", Path: "/exportSchedule/findExportSchedule", Description: "获取定时报表"},
+		{ApiGroup: "定时报表", Method: "GET", Path: "/exportSchedule/getExportScheduleList", Description: "获取定时报表列表"},
+		{ApiGroup: "定时报表", Method: "GET", Path: "/exportSchedule/getExportScheduleRunList", Description: "获取定时报表执行记录"},
+		// Extend Stop: scheduled report
+		// Extend Start: data scope
+		{ApiGroup: "角色", Method: "POST", Path: "/authority/setDataScope", Description: "设置角色数据范围"},
+		{ApiGroup: "角色", Method: "GET", Path: "/authority/getDataScope", Description: "获取角色数据范围"},
+		// Extend Stop: data scope
+		// Extend Start: rbac bundle
+		{ApiGroup: "权限配置包", Method: "GET", Path: "/rbac/exportRbac", Description: "导出权限配置包"},
+		{ApiGroup: "权限配置包", Method: "POST", Path: "/rbac/previewRbac", Description: "预览权限配置包差异"},
+		{ApiGroup: "权限配置包", Method: "POST", Path: "/rbac/importRbac", Description: "导入权限配置包"},
+		// Extend Stop: rbac bundle
+		// Extend Start: temporary authority grant
+		{ApiGroup: "临时角色", Method: "POST", Path: "/authorityGrant/createAuthorityGrant", Description: "临时授予角色"},
+		{ApiGroup: "临时角色", Method: "POST", Path: "/authorityGrant/revokeAuthorityGrant", Description: "提前收回临时角色"},
+		{ApiGroup: "临时角色", Method: "POST", Path: "/authorityGrant/approveAuthorityGrant", Description: "审批临时角色授权"},
+		{ApiGroup: "临时角色", Method: "GET", Path: "/authorityGrant/getAuthorityGrantList", Description: "获取临时角色授权列表"},
+		{ApiGroup: "临时角色", Method: "GET", Path: "/authorityGrant/getAuthorityGrantLogList", Description: "获取临时角色授权记录"},
+		// Extend Stop: temporary authority grant
+		{ApiGroup: "权限模拟", Method: "POST", Path: "/permission/simulate", Description: "模拟用户或角色的有效权限"}, // Extend: permission simulator
+		// Extend Start: gaia auto code
+		{ApiGroup: "代码生成器", Method: "POST", Path: "/autoCode/previewGaia", Description: "预览 gaia 表生成的代码"},
+		{ApiGroup: "代码生成器", Method: "POST", Path: "/autoCode/createGaia", Description: "按 gaia 表生成代码"},
+		// Extend Stop: gaia auto code
+		// Extend Start: export template sql safety
+		{ApiGroup: "导出数据库", Method: "USE", Path: "/exportTemplate/db/default", Description: "导出模板使用数据库 default"},
+		{ApiGroup: "导出数据库", Method: "USE", Path: "/exportTemplate/db/*", Description: "导出模板使用全部数据库"},
+		// Extend Stop: export template sql safety
 	}
 	if err := db.Create(&entities).Error; err != nil {
 		return ctx, errors.Wrap(err, sysModel.SysApi{}.TableName()+"表数据初始化失败!")
@@ -195,6 +195,16 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "888", V1: "/tenants/getAllTenants", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/tenants/getTenantsList", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/tenants/findTenants", V2: "GET"},
+		// Extend Start: workspace management
+		{Ptype: "p", V0: "888", V1: "/tenants/updateTenant", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/tenants/getTenantMembers", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/tenants/addTenantMember", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/tenants/removeTenantMember", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/tenants/transferTenantOwner", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/tenants/getAccountTenants", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/tenants/updateMemberRoles", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/tenants/getMemberAuditList", V2: "GET"},
+		// Extend Stop: workspace management
 		{Ptype: "p", V0: "888", V1: "/gaia/dashboard/getAppTokenDailyQuotaData", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/dashboard/getAppTokenQuotaRankingData", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/dashboard/getAppQuotaRankingData", V2: "GET"},
@@ -222,6 +232,26 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "8881", V1: "/menu/updateBaseMenu", V2: "POST"},
 		{Ptype: "p", V0: "8881", V1: "/menu/getBaseMenuById", V2: "POST"},
 		{Ptype: "p", V0: "8881", V1: "/user/changePassword", V2: "POST"},
+		// Extend Start: TOTP mfa
+		{Ptype: "p", V0: "8881", V1: "/user/mfa", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/user/mfa/enroll", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/user/mfa/activate", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/user/mfa/disable", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/user/mfa/recoveryCodes", V2: "POST"},
+		// Extend Stop: TOTP mfa
+		// Extend Start: session registry
+		{Ptype: "p", V0: "8881", V1: "/session/mine", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/session/mine", V2: "DELETE"},
+		// Extend Stop: session registry
+		// Extend Start: login lockout
+		{Ptype: "p", V0: "8881", V1: "/loginSecurity/mine", V2: "GET"},
+		// Extend Stop: login lockout
+		// Extend Start: personal access token
+		{Ptype: "p", V0: "8881", V1: "/accessToken/list", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/accessToken/scopes", V2: "GET"},
+		{Ptype: "p", V0: "8881", V1: "/accessToken/create", V2: "POST"},
+		{Ptype: "p", V0: "8881", V1: "/accessToken/revoke", V2: "DELETE"},
+		// Extend Stop: personal access token
 		{Ptype: "p", V0: "8881", V1: "/user/getUserList", V2: "POST"},
 		{Ptype: "p", V0: "8881", V1: "/user/setUserAuthority", V2: "POST"},
 		{Ptype: "p", V0: "8881", V1: "/fileUploadAndDownload/upload", V2: "POST"},
@@ -264,6 +294,26 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "9528", V1: "/menu/updateBaseMenu", V2: "POST"},
 		{Ptype: "p", V0: "9528", V1: "/menu/getBaseMenuById", V2: "POST"},
 		{Ptype: "p", V0: "9528", V1: "/user/changePassword", V2: "POST"},
+		// Extend Start: TOTP mfa
+		{Ptype: "p", V0: "9528", V1: "/user/mfa", V2: "GET"},
+		{Ptype: "p", V0: "9528", V1: "/user/mfa/enroll", V2: "POST"},
+		{Ptype: "p", V0: "9528", V1: "/user/mfa/activate", V2: "POST"},
+		{Ptype: "p", V0: "9528", V1: "/user/mfa/disable", V2: "POST"},
+		{Ptype: "p", V0: "9528", V1: "/user/mfa/recoveryCodes", V2: "POST"},
+		// Extend Stop: TOTP mfa
+		// Extend Start: session registry
+		{Ptype: "p", V0: "9528", V1: "/session/mine", V2: "GET"},
+		{Ptype: "p", V0: "9528", V1: "/session/mine", V2: "DELETE"},
+		// Extend Stop: session registry
+		// Extend Start: login lockout
+		{Ptype: "p", V0: "9528", V1: "/loginSecurity/mine", V2: "GET"},
+		// Extend Stop: login lockout
+		// Extend Start: personal access token
+		{Ptype: "p", V0: "9528", V1: "/accessToken/list", V2: "GET"},
+		{Ptype: "p", V0: "9528", V1: "/accessToken/scopes", V2: "GET"},
+		{Ptype: "p", V0: "9528", V1: "/accessToken/create", V2: "POST"},
+		{Ptype: "p", V0: "9528", V1: "/accessToken/revoke", V2: "DELETE"},
+		// Extend Stop: personal access token
 		{Ptype: "p", V0: "9528", V1: "/user/getUserList", V2: "POST"},
 		{Ptype: "p", V0: "9528", V1: "/user/setUserAuthority", V2: "POST"},
 		{Ptype: "p", V0: "9528", V1: "/fileUploadAndDownload/upload", V2: "POST"},
@@ -287,12 +337,109 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		// Extend Start: system integration
 		{Ptype: "p", V0: "888", V1: "/gaia/system/dingtalk", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/system/dingtalk", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/system/dingtalk/sync", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/system/scim", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/system/scim", V2: "POST"},
 		// Extend Stop: system integration
 
 		// Extend Start: oauth2
 		{Ptype: "p", V0: "888", V1: "/gaia/system/oauth2", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/gaia/system/oauth2", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/system/oauth2/providers", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia/system/oauth2/provider", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/gaia/system/oauth2/provider", V2: "DELETE"},
 		// Extend Stop: oauth2
+
+		// Extend Start: TOTP mfa
+		{Ptype: "p", V0: "888", V1: "/user/mfa", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/user/mfa/enroll", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/mfa/activate", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/mfa/disable", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/mfa/recoveryCodes", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/mfa/reset", V2: "POST"},
+		// Extend Stop: TOTP mfa
+		// Extend Start: oauth2 email trust
+		{Ptype: "p", V0: "888", V1: "/user/integrates", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/user/integrate/link", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/integrate/unlink", V2: "POST"},
+		// Extend Stop: oauth2 email trust
+		// Extend Start: session registry
+		{Ptype: "p", V0: "888", V1: "/session/mine", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/session/mine", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/session/getSessionList", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/session/revoke", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/session/revokeUser", V2: "DELETE"},
+		// Extend Stop: session registry
+		// Extend Start: login lockout
+		{Ptype: "p", V0: "888", V1: "/loginSecurity/lockList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/loginSecurity/unlock", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/loginSecurity/getLoginHistoryList", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/loginSecurity/mine", V2: "GET"},
+		// Extend Stop: login lockout
+		// Extend Start: personal access token
+		{Ptype: "p", V0: "888", V1: "/accessToken/list", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/accessToken/scopes", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/accessToken/create", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/accessToken/revoke", V2: "DELETE"},
+		// Extend Stop: personal access token
+		// Extend Start: offboarding
+		{Ptype: "p", V0: "888", V1: "/offboard/preview", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/offboard/execute", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/offboard/deactivate", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/offboard/reactivate", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/offboard/getOffboardList", V2: "POST"},
+		// Extend Stop: offboarding
+		// Extend Start: two-way user sync
+		{Ptype: "p", V0: "888", V1: "/userSync/status", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/userSync/getConflictList", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/userSync/resolveConflict", V2: "POST"},
+		// Extend Stop: two-way user sync
+		// Extend Start: end user directory
+		{Ptype: "p", V0: "888", V1: "/endUser/getEndUserList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/endUser/findEndUser", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/endUser/blockEndUser", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/endUser/unblockEndUser", V2: "POST"},
+		// Extend Stop: end user directory
+		// Extend Start: export job queue
+		{Ptype: "p", V0: "888", V1: "/exportJob/createExportJob", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/exportJob/deleteExportJob", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/exportJob/getExportJobList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/exportJob/findExportJob", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/exportJob/getDownloadLink", V2: "GET"},
+		// Extend Stop: export job queue
+		// Extend Start: scheduled report
+		{Ptype: "p", V0: "888", V1: "/exportSchedule/createExportSchedule", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/exportSchedule/updateExportSchedule", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/exportSchedule/deleteExportSchedule", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/exportSchedule/runExportSchedule", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/exportSchedule/findExportSchedule", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/exportSchedule/getExportScheduleList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/exportSchedule/getExportScheduleRunList", V2: "GET"},
+		// Extend Stop: scheduled report
+		// Extend Start: data scope
+		{Ptype: "p", V0: "888", V1: "/authority/setDataScope", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authority/getDataScope", V2: "GET"},
+		// Extend Stop: data scope
+		// Extend Start: rbac bundle
+		{Ptype: "p", V0: "888", V1: "/rbac/exportRbac", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/rbac/previewRbac", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/rbac/importRbac", V2: "POST"},
+		// Extend Stop: rbac bundle
+		// Extend Start: temporary authority grant
+		{Ptype: "p", V0: "888", V1: "/authorityGrant/createAuthorityGrant", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authorityGrant/revokeAuthorityGrant", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authorityGrant/approveAuthorityGrant", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authorityGrant/getAuthorityGrantList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/authorityGrant/getAuthorityGrantLogList", V2: "GET"},
+		// Extend Stop: temporary authority grant
+		// Extend Start: permission simulator
+		{Ptype: "p", V0: "888", V1: "/permission/simulate", V2: "POST"},
+		// Extend Stop: permission simulator
+		// Extend Start: gaia auto code
+		{Ptype: "p", V0: "888", V1: "/autoCode/previewGaia", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/autoCode/createGaia", V2: "POST"},
+		// Extend Stop: gaia auto code
+		{Ptype: "p", V0: "888", V1: "/exportTemplate/db/*", V2: "USE"}, // Extend: export template sql safety
 	}
 	if err := db.Create(&entities).Error; err != nil {
 		return ctx, errors.Wrap(err, "Casbin 表 ("+i.InitializerName()+") 数据初始化失败!")
@@ -14,7 +14,9 @@ import (
 	"strings"
 )
 
-type Base struct{}
+type Base struct {
+	fileSet *token.FileSet // Extend: 保留解析时的位置信息, 格式化时注释不错位
+}
 
 func (a *Base) Parse(filename string, writer io.Writer) (file *ast.File, err error) {
 	fileSet := token.NewFileSet()
@@ -26,6 +28,7 @@ func (a *Base) Parse(filename string, writer io.Writer) (file *ast.File, err err
 	if err != nil {
 		return nil, errors.Wrapf(err, "[filepath:%s]打开/解析文件失败!", filename)
 	}
+	a.fileSet = fileSet // Extend: 保留解析时的位置信息
 	return file, nil
 }
 
@@ -39,6 +42,12 @@ func (a *Base) Injection(file *ast.File) error {
 
 func (a *Base) Format(filename string, writer io.Writer, file *ast.File) error {
 	fileSet := token.NewFileSet()
+	// Extend Start: 使用解析时的位置信息, 否则行尾注释会被挪到下一行
+	if a.fileSet != nil {
+		fileSet = a.fileSet
+		fillPositions(fileSet, file)
+	}
+	// Extend Stop: 使用解析时的位置信息
 	if writer == nil {
 		open, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, 0666)
 		defer open.Close()
@@ -0,0 +1,66 @@
+package ast
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// fillPositions 注入的节点没有位置信息, 打印时前一行的行尾注释会被挪到注入节点之后,
+// 这里把注入节点的位置设为前一个节点所在行的行尾, 让注释先于注入节点输出
+func fillPositions(fileSet *token.FileSet, file *ast.File) {
+	var last token.Pos
+	ast.Inspect(file, func(node ast.Node) bool {
+		if node == nil {
+			return true
+		}
+		if node.Pos().IsValid() {
+			end := node.Pos()
+			switch node.(type) {
+			case *ast.Ident, *ast.BasicLit, *ast.CommentGroup:
+				end = node.End()
+			} // 只取叶子节点的结束位置, 父节点的结束位置会越过后面的兄弟节点
+			if end > last {
+				last = end
+			}
+			return true
+		}
+		if !last.IsValid() {
+			return true
+		}
+		pos := lineEnd(fileSet, last)
+		switch n := node.(type) {
+		case *ast.Ident:
+			n.NamePos = pos
+		case *ast.BasicLit:
+			n.ValuePos = pos
+		case *ast.CallExpr:
+			n.Lparen, n.Rparen = pos, pos
+		case *ast.AssignStmt:
+			n.TokPos = pos
+		case *ast.BlockStmt:
+			n.Lbrace, n.Rbrace = pos, pos
+		case *ast.CompositeLit:
+			n.Lbrace, n.Rbrace = pos, pos
+		case *ast.KeyValueExpr:
+			n.Colon = pos
+		case *ast.UnaryExpr:
+			n.OpPos = pos
+		case *ast.StarExpr:
+			n.Star = pos
+		}
+		return true
+	})
+}
+
+// lineEnd pos 所在行的行尾
+func lineEnd(fileSet *token.FileSet, pos token.Pos) token.Pos {
+	file := fileSet.File(pos)
+	if file == nil {
+		return pos
+	}
+	line := file.Line(pos)
+	if line < file.LineCount() {
+		return file.LineStart(line+1) - 1
+	}
+	return token.Pos(file.Base() + file.Size())
+}
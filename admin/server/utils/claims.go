@@ -59,6 +59,13 @@ func GetToken(c *gin.Context) string {
 }
 
 func GetClaims(c *gin.Context) (*systemReq.CustomClaims, error) {
+	// Extend Start: personal access token 个人访问令牌没有jwt, 使用中间件写入的claims
+	if claims, exists := c.Get("claims"); exists {
+		if waitUse, ok := claims.(*systemReq.CustomClaims); ok {
+			return waitUse, nil
+		}
+	}
+	// Extend Stop: personal access token
 	token := GetToken(c)
 	j := NewJWT()
 	claims, err := j.ParseToken(token)
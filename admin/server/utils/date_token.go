@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateRangeOpenEndKey 区间参数展开后额外写入 DateRangeOpenEndKey+key, 表示 endkey 不包含在区间内, 查询时应使用 >= start AND < end
+const DateRangeOpenEndKey = "openend"
+
+const (
+	dateTokenDatetime = "2006-01-02 15:04:05"
+	dateTokenDate     = "2006-01-02"
+)
+
+var (
+	// {{today}} {{today-7d}} {{month_start+1M|date}}
+	dateTokenPattern = regexp.MustCompile(`^\{\{\s*([a-z_]+)\s*(?:([+-])\s*(\d+)\s*([hdwMy]))?\s*(?:\|\s*(date|datetime))?\s*\}\}$`)
+	// {{last_7_days}} {{this_month}}
+	dateRangePattern = regexp.MustCompile(`^\{\{\s*(?:last_(\d+)_(hours|days|weeks|months)|(this|last)_(week|month|year))\s*(?:\|\s*(date|datetime))?\s*\}\}$`)
+)
+
+// ResolveDateTokens
+// @function: ResolveDateTokens
+// @description: 将查询参数中的相对日期替换为实际时间, 用于定时任务中按执行时间计算查询区间
+// 单个时间: {{now}} {{today}} {{yesterday}} {{week_start}} {{month_start}} {{year_start}}, 可带偏移 {{today-7d}}, 单位 h/d/w/M/y, 可加 |date 只输出日期
+// 时间区间: {{last_7_days}} {{last_24_hours}} {{last_2_weeks}} {{last_3_months}} {{this_week}} {{last_week}} {{this_month}} {{last_month}} {{this_year}} {{last_year}},
+// 参数 key 的区间会展开为 startkey 与 endkey, 对应导出模板的 BETWEEN 条件, 周从周一开始,
+// 区间为左闭右开, endkey 为下一区间的开始, 避免丢失结束前最后一秒内(含毫秒)的数据, 同时写入 DateRangeOpenEndKey+key 标记
+// @param: values url.Values, now time.Time
+// @return: url.Values, error
+func ResolveDateTokens(values url.Values, now time.Time) (url.Values, error) {
+	resolved := make(url.Values, len(values))
+	for key, list := range values {
+		for _, value := range list {
+			if !strings.Contains(value, "{{") {
+				resolved.Add(key, value)
+				continue
+			}
+			if m := dateRangePattern.FindStringSubmatch(value); m != nil {
+				start, end, err := dateRange(m, now)
+				if err != nil {
+					return nil, fmt.Errorf("参数 %s: %w", key, err)
+				}
+				layout := dateTokenLayout(m[5])
+				resolved.Set("start"+key, start.Format(layout))
+				resolved.Set("end"+key, end.Format(layout))
+				resolved.Set(DateRangeOpenEndKey+key, "1")
+				continue
+			}
+			m := dateTokenPattern.FindStringSubmatch(value)
+			if m == nil {
+				return nil, fmt.Errorf("参数 %s 的日期 %s 无法识别", key, value)
+			}
+			t, err := dateToken(m, now)
+			if err != nil {
+				return nil, fmt.Errorf("参数 %s: %w", key, err)
+			}
+			resolved.Add(key, t.Format(dateTokenLayout(m[5])))
+		}
+	}
+	return resolved, nil
+}
+
+func dateTokenLayout(format string) string {
+	if format == "date" {
+		return dateTokenDate
+	}
+	return dateTokenDatetime
+}
+
+// dateToken 解析单个时间, m 为 dateTokenPattern 的匹配结果
+func dateToken(m []string, now time.Time) (time.Time, error) {
+	today := startOfDay(now)
+	var t time.Time
+	switch m[1] {
+	case "now":
+		t = now
+	case "today":
+		t = today
+	case "yesterday":
+		t = today.AddDate(0, 0, -1)
+	case "week_start":
+		t = startOfWeek(now)
+	case "month_start":
+		t = today.AddDate(0, 0, 1-today.Day())
+	case "year_start":
+		t = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+	default:
+		return t, fmt.Errorf("不支持的日期 %s", m[1])
+	}
+	if m[2] == "" {
+		return t, nil
+	}
+	n, _ := strconv.Atoi(m[3])
+	if m[2] == "-" {
+		n = -n
+	}
+	switch m[4] {
+	case "h":
+		t = t.Add(time.Duration(n) * time.Hour)
+	case "d":
+		t = t.AddDate(0, 0, n)
+	case "w":
+		t = t.AddDate(0, 0, 7*n)
+	case "M":
+		t = t.AddDate(0, n, 0)
+	case "y":
+		t = t.AddDate(n, 0, 0)
+	}
+	return t, nil
+}
+
+// dateRange 解析左闭右开的时间区间, m 为 dateRangePattern 的匹配结果, last_N_xxx 不含当前的天、周、月, 小时区间截止到当前时间
+func dateRange(m []string, now time.Time) (start, end time.Time, err error) {
+	today := startOfDay(now)
+	monthStart := today.AddDate(0, 0, 1-today.Day())
+	yearStart := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+	if m[1] != "" {
+		n, _ := strconv.Atoi(m[1])
+		if n <= 0 {
+			return start, end, fmt.Errorf("区间长度必须大于0")
+		}
+		switch m[2] {
+		case "hours":
+			return now.Add(-time.Duration(n) * time.Hour), now, nil
+		case "days":
+			start, end = today.AddDate(0, 0, -n), today
+		case "weeks":
+			end = startOfWeek(now)
+			start = end.AddDate(0, 0, -7*n)
+		case "months":
+			start, end = monthStart.AddDate(0, -n, 0), monthStart
+		}
+		return start, end, nil
+	}
+	switch m[4] {
+	case "week":
+		start = startOfWeek(now)
+		end = start.AddDate(0, 0, 7)
+		if m[3] == "last" {
+			start, end = start.AddDate(0, 0, -7), start
+		}
+	case "month":
+		start, end = monthStart, monthStart.AddDate(0, 1, 0)
+		if m[3] == "last" {
+			start, end = monthStart.AddDate(0, -1, 0), monthStart
+		}
+	case "year":
+		start, end = yearStart, yearStart.AddDate(1, 0, 0)
+		if m[3] == "last" {
+			start, end = yearStart.AddDate(-1, 0, 0), yearStart
+		}
+	}
+	return start, end, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek 本周一
+func startOfWeek(t time.Time) time.Time {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return startOfDay(t).AddDate(0, 0, 1-weekday)
+}
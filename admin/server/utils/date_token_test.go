@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolveDateTokens(t *testing.T) {
+	// 2024-03-13 周三
+	now := time.Date(2024, 3, 13, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		values  url.Values
+		want    url.Values
+		wantErr bool
+	}{
+		{
+			name:   "plain",
+			values: url.Values{"name": {"abc"}},
+			want:   url.Values{"name": {"abc"}},
+		},
+		{
+			name:   "today offset",
+			values: url.Values{"day": {"{{today-7d}}"}, "at": {"{{ now + 2h }}"}, "d": {"{{month_start-1M|date}}"}},
+			want:   url.Values{"day": {"2024-03-06 00:00:00"}, "at": {"2024-03-13 12:30:00"}, "d": {"2024-02-01"}},
+		},
+		{
+			name:   "last 7 days",
+			values: url.Values{"created": {"{{last_7_days}}"}},
+			want:   url.Values{"startcreated": {"2024-03-06 00:00:00"}, "endcreated": {"2024-03-13 00:00:00"}, "openendcreated": {"1"}},
+		},
+		{
+			name:   "last week",
+			values: url.Values{"created": {"{{last_week|date}}"}},
+			want:   url.Values{"startcreated": {"2024-03-04"}, "endcreated": {"2024-03-11"}, "openendcreated": {"1"}},
+		},
+		{
+			name:   "this month",
+			values: url.Values{"created": {"{{this_month}}"}},
+			want:   url.Values{"startcreated": {"2024-03-01 00:00:00"}, "endcreated": {"2024-04-01 00:00:00"}, "openendcreated": {"1"}},
+		},
+		{
+			name:   "last 24 hours",
+			values: url.Values{"created": {"{{last_24_hours}}"}},
+			want:   url.Values{"startcreated": {"2024-03-12 10:30:00"}, "endcreated": {"2024-03-13 10:30:00"}, "openendcreated": {"1"}},
+		},
+		{
+			name:    "unknown",
+			values:  url.Values{"created": {"{{tomorrow}}"}},
+			wantErr: true,
+		},
+		{
+			name:    "zero range",
+			values:  url.Values{"created": {"{{last_0_days}}"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveDateTokens(tt.values, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ResolveDateTokens() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveDateTokens() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 区间为左闭右开时, 结束前最后一秒内的时间(含毫秒)属于区间, 下一区间的开始不属于区间
+func TestResolveDateTokensSubSecond(t *testing.T) {
+	now := time.Date(2024, 3, 13, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		name  string
+		token string
+		at    time.Time
+		want  bool
+	}{
+		{name: "区间最后一秒内的毫秒", token: "{{last_7_days}}", at: time.Date(2024, 3, 12, 23, 59, 59, 500e6, time.Local), want: true},
+		{name: "区间开始", token: "{{last_7_days}}", at: time.Date(2024, 3, 6, 0, 0, 0, 0, time.Local), want: true},
+		{name: "下一区间开始", token: "{{last_7_days}}", at: time.Date(2024, 3, 13, 0, 0, 0, 0, time.Local), want: false},
+		{name: "按日期输出时包含最后一天", token: "{{last_month|date}}", at: time.Date(2024, 2, 29, 23, 59, 59, 999e6, time.Local), want: true},
+		{name: "按日期输出时不含下月第一天", token: "{{last_month|date}}", at: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveDateTokens(url.Values{"created": {tt.token}}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Get(DateRangeOpenEndKey+"created") == "" {
+				t.Fatalf("缺少左闭右开标记: %v", got)
+			}
+			start, err := time.ParseInLocation(dateTokenLayout(layoutOf(tt.token)), got.Get("startcreated"), time.Local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			end, err := time.ParseInLocation(dateTokenLayout(layoutOf(tt.token)), got.Get("endcreated"), time.Local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if in := !tt.at.Before(start) && tt.at.Before(end); in != tt.want {
+				t.Errorf("%s 在区间 [%s, %s) 内 = %v, want %v", tt.at, start, end, in, tt.want)
+			}
+		})
+	}
+}
+
+func layoutOf(token string) string {
+	if m := dateRangePattern.FindStringSubmatch(token); m != nil {
+		return m[5]
+	}
+	return ""
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
 	jwt "github.com/golang-jwt/jwt/v4"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
@@ -43,6 +44,7 @@ func (j *JWT) CreateClaims(baseClaims request.BaseClaims) request.CustomClaims {
 			NotBefore: jwt.NewNumericDate(time.Now().Add(-1000)), // 签名生效时间
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ep)),    // 过期时间 1天  配置文件
 			Issuer:    global.GVA_CONFIG.JWT.Issuer,              // 签名的发行者
+			ID:        uuid.Must(uuid.NewV4()).String(),          // Extend: session registry 会话ID, 刷新令牌时沿用
 		},
 	}
 	return claims
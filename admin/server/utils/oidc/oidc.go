@@ -0,0 +1,525 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const discoveryTTL = time.Hour     // 发现文档缓存时长
+const jwksMinRefresh = time.Minute // 未知kid时刷新JWKS的最小间隔
+const defaultScope = "openid profile email"
+
+var (
+	ErrNonceMismatch   = errors.New("id_token nonce 不匹配")
+	ErrIssuerMismatch  = errors.New("id_token issuer 不匹配")
+	ErrAudienceInvalid = errors.New("id_token audience 不包含当前client_id")
+	ErrMissingIDToken  = errors.New("授权范围包含openid, 但令牌端点未返回id_token")
+	ErrUnknownKey      = errors.New("无法在JWKS中找到id_token签名密钥")
+)
+
+// Config OAuth2/OIDC 客户端配置
+type Config struct {
+	ServerURL       string // OAuth2 服务器地址, 与下方相对路径拼接
+	DiscoveryURL    string // OIDC 发现配置URL, 配置后优先使用发现文档中的端点
+	AuthorizeURL    string // 授权端点
+	TokenURL        string // 令牌端点
+	UserinfoURL     string // 用户信息端点
+	LogoutURL       string // 退出登录端点(end_session_endpoint)
+	ClientID        string
+	ClientSecret    string
+	Scope           string
+	TokenAuthMethod string // client_secret_post|client_secret_basic
+	RedirectURI     string
+	UserNameField   string
+	UserEmailField  string
+	UserIDField     string
+	UserAvatarField string
+	// 邮箱已验证标记字段, 支持 a.b 嵌套路径, 为空时读取 email_verified
+	UserEmailVerifiedField string
+	// 兼容旧OA系统: 用户信息端点的请求方式(GET|POST, 默认GET), 以及Authorization头是否省略Bearer前缀
+	UserinfoMethod   string
+	UserinfoRawToken bool
+	HTTPClient       *http.Client
+}
+
+// Discovery .well-known/openid-configuration 文档
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// Token 令牌端点返回
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+}
+
+// UserInfo 通过字段映射得到的用户信息
+type UserInfo struct {
+	ID            string                 `json:"id"`
+	Name          string                 `json:"name"`
+	Email         string                 `json:"email"`
+	EmailVerified bool                   `json:"email_verified"` // 提供方声明邮箱已验证
+	Avatar        string                 `json:"avatar"`
+	Claims        map[string]interface{} `json:"claims"`
+}
+
+// Client OAuth2/OIDC 客户端
+type Client struct {
+	config    Config
+	endpoints Discovery
+}
+
+type discoveryCache struct {
+	doc     Discovery
+	expires time.Time
+}
+
+type jwksCache struct {
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+var (
+	cacheLock      sync.Mutex
+	discoveryStore = make(map[string]discoveryCache)
+	jwksStore      = make(map[string]jwksCache)
+)
+
+// NewClient
+// @description: 创建客户端, 配置了DiscoveryURL时从发现文档加载端点
+// @param: ctx context.Context, config Config
+// @return: *Client, error
+func NewClient(ctx context.Context, config Config) (*Client, error) {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	if len(strings.TrimSpace(config.Scope)) == 0 {
+		config.Scope = defaultScope
+	}
+	c := &Client{config: config}
+	if len(config.DiscoveryURL) > 0 {
+		doc, err := c.discover(ctx, c.join(config.DiscoveryURL))
+		if err != nil {
+			return nil, err
+		}
+		c.endpoints = doc
+	}
+	// 手动配置的端点覆盖发现文档
+	if len(config.AuthorizeURL) > 0 {
+		c.endpoints.AuthorizationEndpoint = c.join(config.AuthorizeURL)
+	}
+	if len(config.TokenURL) > 0 {
+		c.endpoints.TokenEndpoint = c.join(config.TokenURL)
+	}
+	if len(config.UserinfoURL) > 0 {
+		c.endpoints.UserinfoEndpoint = c.join(config.UserinfoURL)
+	}
+	if len(config.LogoutURL) > 0 {
+		c.endpoints.EndSessionEndpoint = c.join(config.LogoutURL)
+	}
+	if len(c.endpoints.AuthorizationEndpoint) == 0 || len(c.endpoints.TokenEndpoint) == 0 {
+		return nil, errors.New("缺少授权端点或令牌端点配置")
+	}
+	return c, nil
+}
+
+// Endpoints 当前生效的端点
+func (c *Client) Endpoints() Discovery {
+	return c.endpoints
+}
+
+// IsOIDC 是否按OIDC流程校验id_token
+func (c *Client) IsOIDC() bool {
+	for _, v := range strings.Fields(c.config.Scope) {
+		if v == "openid" {
+			return true
+		}
+	}
+	return false
+}
+
+// join 相对路径与ServerURL拼接, 绝对地址原样返回
+func (c *Client) join(link string) string {
+	if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+		return link
+	}
+	return strings.TrimRight(c.config.ServerURL, "/") + "/" + strings.TrimLeft(link, "/")
+}
+
+// discover 获取并缓存发现文档
+func (c *Client) discover(ctx context.Context, link string) (doc Discovery, err error) {
+	cacheLock.Lock()
+	cached, ok := discoveryStore[link]
+	cacheLock.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.doc, nil
+	}
+	if err = c.getJson(ctx, link, &doc); err != nil {
+		return doc, fmt.Errorf("获取OIDC发现文档失败: %w", err)
+	}
+	if len(doc.Issuer) == 0 || len(doc.AuthorizationEndpoint) == 0 || len(doc.TokenEndpoint) == 0 {
+		return doc, errors.New("OIDC发现文档缺少issuer/authorization_endpoint/token_endpoint")
+	}
+	cacheLock.Lock()
+	discoveryStore[link] = discoveryCache{doc: doc, expires: time.Now().Add(discoveryTTL)}
+	cacheLock.Unlock()
+	return doc, nil
+}
+
+// AuthCodeURL
+// @description: 生成授权地址, 携带state、nonce与PKCE S256 challenge
+// @param: state string, nonce string, codeVerifier string
+// @return: string
+func (c *Client) AuthCodeURL(state, nonce, codeVerifier string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.config.ClientID)
+	params.Set("redirect_uri", c.config.RedirectURI)
+	params.Set("scope", c.config.Scope)
+	params.Set("state", state)
+	if len(nonce) > 0 {
+		params.Set("nonce", nonce)
+	}
+	if len(codeVerifier) > 0 {
+		params.Set("code_challenge", CodeChallenge(codeVerifier))
+		params.Set("code_challenge_method", "S256")
+	}
+	link := c.endpoints.AuthorizationEndpoint
+	if strings.Contains(link, "?") {
+		return link + "&" + params.Encode()
+	}
+	return link + "?" + params.Encode()
+}
+
+// Exchange
+// @description: 授权码换取令牌
+// @param: ctx context.Context, code string, codeVerifier string
+// @return: token Token, err error
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier string) (token Token, err error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.config.RedirectURI)
+	if len(codeVerifier) > 0 {
+		form.Set("code_verifier", codeVerifier)
+	}
+	useBasic := strings.ToLower(strings.TrimSpace(c.config.TokenAuthMethod)) == "client_secret_basic"
+	if !useBasic {
+		form.Set("client_id", c.config.ClientID)
+		form.Set("client_secret", c.config.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+	var body []byte
+	if body, err = c.do(req); err != nil {
+		return token, fmt.Errorf("令牌端点请求失败: %w", err)
+	}
+	if err = json.Unmarshal(body, &token); err != nil {
+		return token, fmt.Errorf("令牌端点返回解析失败: %w", err)
+	}
+	if len(token.AccessToken) == 0 && len(token.IDToken) == 0 {
+		return token, fmt.Errorf("令牌端点未返回令牌: %s", string(body))
+	}
+	return token, nil
+}
+
+// VerifyIDToken
+// @description: 使用JWKS校验id_token签名, 并校验iss、aud、exp与nonce
+// @param: ctx context.Context, raw string, nonce string
+// @return: claims jwt.MapClaims, err error
+func (c *Client) VerifyIDToken(ctx context.Context, raw, nonce string) (claims jwt.MapClaims, err error) {
+	if len(c.endpoints.JwksURI) == 0 {
+		return nil, errors.New("未配置jwks_uri, 无法校验id_token")
+	}
+	claims = jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256"}))
+	if _, err = parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, kid)
+	}); err != nil {
+		return nil, fmt.Errorf("id_token校验失败: %w", err)
+	}
+	if len(c.endpoints.Issuer) > 0 && !claims.VerifyIssuer(c.endpoints.Issuer, true) {
+		return nil, ErrIssuerMismatch
+	}
+	if !claims.VerifyAudience(c.config.ClientID, true) {
+		return nil, ErrAudienceInvalid
+	}
+	if got, _ := claims["nonce"].(string); len(nonce) > 0 && got != nonce {
+		return nil, ErrNonceMismatch
+	}
+	return claims, nil
+}
+
+// key 按kid从缓存的JWKS中取公钥, 未命中时刷新
+func (c *Client) key(ctx context.Context, kid string) (interface{}, error) {
+	link := c.endpoints.JwksURI
+	cacheLock.Lock()
+	cached, ok := jwksStore[link]
+	cacheLock.Unlock()
+	if ok {
+		if k := pickKey(cached.keys, kid); k != nil {
+			return k, nil
+		}
+		if time.Since(cached.fetchedAt) < jwksMinRefresh {
+			return nil, ErrUnknownKey
+		}
+	}
+	var set JSONWebKeySet
+	if err := c.getJson(ctx, link, &set); err != nil {
+		return nil, fmt.Errorf("获取JWKS失败: %w", err)
+	}
+	keys, err := set.PublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	cacheLock.Lock()
+	jwksStore[link] = jwksCache{keys: keys, fetchedAt: time.Now()}
+	cacheLock.Unlock()
+	if k := pickKey(keys, kid); k != nil {
+		return k, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// pickKey 没有kid且只有一个密钥时直接使用
+func pickKey(keys map[string]interface{}, kid string) interface{} {
+	if k, ok := keys[kid]; ok {
+		return k
+	}
+	if len(kid) == 0 && len(keys) == 1 {
+		for _, k := range keys {
+			return k
+		}
+	}
+	return nil
+}
+
+// UserInfo
+// @description: 请求用户信息端点
+// @param: ctx context.Context, accessToken string
+// @return: claims map[string]interface{}, err error
+func (c *Client) UserInfo(ctx context.Context, accessToken string) (claims map[string]interface{}, err error) {
+	if len(c.endpoints.UserinfoEndpoint) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	method := http.MethodGet
+	if strings.EqualFold(c.config.UserinfoMethod, http.MethodPost) {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoints.UserinfoEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.config.UserinfoRawToken {
+		req.Header.Set("Authorization", accessToken)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	body, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if err = json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("解析用户信息失败: %w", err)
+	}
+	return claims, nil
+}
+
+// Authenticate
+// @description: 完整的回调处理: 换取令牌、校验id_token、合并userinfo并映射用户字段
+// @param: ctx context.Context, code string, codeVerifier string, nonce string
+// @return: info UserInfo, token Token, err error
+func (c *Client) Authenticate(ctx context.Context, code, codeVerifier, nonce string) (info UserInfo, token Token, err error) {
+	if token, err = c.Exchange(ctx, code, codeVerifier); err != nil {
+		return info, token, err
+	}
+	claims := map[string]interface{}{}
+	if len(token.IDToken) > 0 {
+		var idClaims jwt.MapClaims
+		if idClaims, err = c.VerifyIDToken(ctx, token.IDToken, nonce); err != nil {
+			return info, token, err
+		}
+		for k, v := range idClaims {
+			claims[k] = v
+		}
+	} else if c.IsOIDC() && len(c.endpoints.JwksURI) > 0 {
+		return info, token, ErrMissingIDToken
+	}
+	if len(token.AccessToken) > 0 {
+		var userClaims map[string]interface{}
+		if userClaims, err = c.UserInfo(ctx, token.AccessToken); err != nil {
+			return info, token, err
+		}
+		// userinfo 的 sub 必须与 id_token 一致
+		if sub, ok := claims["sub"]; ok {
+			if userSub, has := userClaims["sub"]; has && userSub != sub {
+				return info, token, errors.New("userinfo 与 id_token 的 sub 不一致")
+			}
+		}
+		for k, v := range userClaims {
+			claims[k] = v
+		}
+	}
+	info = c.MapUser(claims)
+	if len(info.ID) == 0 {
+		return info, token, errors.New("无法从用户信息中获取用户唯一标识")
+	}
+	return info, token, nil
+}
+
+// MapUser
+// @description: 按字段映射从claims中提取用户信息, 字段支持 a.b 形式的嵌套路径
+// @param: claims map[string]interface{}
+// @return: UserInfo
+func (c *Client) MapUser(claims map[string]interface{}) UserInfo {
+	info := UserInfo{Claims: claims}
+	info.ID = Lookup(claims, c.config.UserIDField, "sub", "id", "user_id")
+	info.Name = Lookup(claims, c.config.UserNameField, "preferred_username", "name", "username")
+	info.Email = Lookup(claims, c.config.UserEmailField, "email")
+	info.Avatar = Lookup(claims, c.config.UserAvatarField, "picture", "avatar", "avatar_url")
+	// 部分提供方以字符串返回 email_verified
+	verifiedField := c.config.UserEmailVerifiedField
+	if len(verifiedField) == 0 {
+		verifiedField = "email_verified"
+	}
+	switch v := lookupValue(claims, verifiedField).(type) {
+	case bool:
+		info.EmailVerified = v
+	case string:
+		info.EmailVerified = strings.EqualFold(v, "true")
+	}
+	if len(info.Name) == 0 {
+		info.Name = strings.Split(info.Email, "@")[0]
+	}
+	return info
+}
+
+// LogoutURL
+// @description: 生成RP发起的退出登录地址, 未配置时返回空字符串
+// @param: idTokenHint string, postLogoutRedirectURI string
+// @return: string
+func (c *Client) LogoutURL(idTokenHint, postLogoutRedirectURI string) string {
+	if len(c.endpoints.EndSessionEndpoint) == 0 {
+		return ""
+	}
+	params := url.Values{}
+	params.Set("client_id", c.config.ClientID)
+	if len(idTokenHint) > 0 {
+		params.Set("id_token_hint", idTokenHint)
+	}
+	if len(postLogoutRedirectURI) > 0 {
+		params.Set("post_logout_redirect_uri", postLogoutRedirectURI)
+	}
+	link := c.endpoints.EndSessionEndpoint
+	if strings.Contains(link, "?") {
+		return link + "&" + params.Encode()
+	}
+	return link + "?" + params.Encode()
+}
+
+// getJson GET请求并解析json
+func (c *Client) getJson(ctx context.Context, link string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	body, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
+
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("状态码 %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// Lookup 依次按字段路径取第一个非空的字符串值
+func Lookup(claims map[string]interface{}, paths ...string) string {
+	for _, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		switch v := lookupValue(claims, path).(type) {
+		case string:
+			if len(v) > 0 {
+				return v
+			}
+		case float64:
+			return fmt.Sprintf("%.0f", v)
+		case json.Number:
+			return v.String()
+		}
+	}
+	return ""
+}
+
+// lookupValue 按 a.b 形式的嵌套路径读取claims中的原始值, 不存在时返回nil
+func lookupValue(claims map[string]interface{}, path string) interface{} {
+	var current interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// RandomString 生成url安全的随机字符串, 用于state、nonce与code_verifier
+func RandomString(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// CodeChallenge PKCE S256
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
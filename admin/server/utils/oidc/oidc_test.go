@@ -0,0 +1,240 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockIdP 本地模拟的OIDC身份提供方
+type mockIdP struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	signKey   *rsa.PrivateKey // 用于模拟签名密钥不匹配
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIdP{key: key, signKey: key}
+	mux := http.NewServeMux()
+	m.server = httptest.NewServer(mux)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Discovery{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			UserinfoEndpoint:      m.server.URL + "/userinfo",
+			JwksURI:               m.server.URL + "/jwks",
+			EndSessionEndpoint:    m.server.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(JSONWebKeySet{Keys: []JSONWebKey{{
+			Kty: "RSA", Kid: "test-key", Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if CodeChallenge(r.Form.Get("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":   m.server.URL,
+			"aud":   "client",
+			"sub":   "user-1",
+			"nonce": m.nonce,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+		})
+		token.Header["kid"] = "test-key"
+		raw, _ := token.SignedString(m.signKey)
+		_ = json.NewEncoder(w).Encode(Token{AccessToken: "access", IDToken: raw, TokenType: "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"sub":"user-1","profile":{"login":"alice"},"mail":"alice@example.com"}`))
+	})
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockIdP) client(t *testing.T) *Client {
+	c, err := NewClient(context.Background(), Config{
+		DiscoveryURL:   m.server.URL + "/.well-known/openid-configuration",
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		RedirectURI:    "http://localhost/callback",
+		UserNameField:  "profile.login",
+		UserEmailField: "mail",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// authorize 模拟浏览器跳转授权端点, 记录IdP收到的challenge与nonce
+func (m *mockIdP) authorize(t *testing.T, c *Client, nonce, verifier string) {
+	link, err := url.Parse(c.AuthCodeURL("state", nonce, verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("缺少PKCE参数: %s", link.String())
+	}
+	m.challenge = link.Query().Get("code_challenge")
+	m.nonce = link.Query().Get("nonce")
+}
+
+func TestAuthenticate(t *testing.T) {
+	m := newMockIdP(t)
+	c := m.client(t)
+	verifier := RandomString(32)
+	m.authorize(t, c, "nonce-1", verifier)
+
+	info, token, err := c.Authenticate(context.Background(), "good-code", verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if info.ID != "user-1" || info.Name != "alice" || info.Email != "alice@example.com" {
+		t.Errorf("MapUser() = %+v", info)
+	}
+	if !strings.HasPrefix(c.LogoutURL(token.IDToken, "http://localhost/"), m.server.URL+"/logout?") {
+		t.Errorf("LogoutURL() 未使用发现文档中的 end_session_endpoint")
+	}
+}
+
+func TestAuthenticateRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		nonce    string
+		verifier string
+		prepare  func(m *mockIdP)
+	}{
+		{name: "nonce不匹配", nonce: "other-nonce", verifier: "verifier-verifier-verifier-verifier-1"},
+		{name: "PKCE校验失败", nonce: "nonce-1", verifier: "wrong-verifier-wrong-verifier-wrong-1"},
+		{name: "签名密钥不匹配", nonce: "nonce-1", verifier: "verifier-verifier-verifier-verifier-1", prepare: func(m *mockIdP) {
+			m.signKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockIdP(t)
+			c := m.client(t)
+			m.authorize(t, c, "nonce-1", "verifier-verifier-verifier-verifier-1")
+			if tt.prepare != nil {
+				tt.prepare(m)
+			}
+			if _, _, err := c.Authenticate(context.Background(), "good-code", tt.verifier, tt.nonce); err == nil {
+				t.Errorf("Authenticate() 应当失败")
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	claims := map[string]interface{}{
+		"id":   float64(42),
+		"data": map[string]interface{}{"username": "bob"},
+	}
+	if got := Lookup(claims, "", "data.username"); got != "bob" {
+		t.Errorf("Lookup() = %s", got)
+	}
+	if got := Lookup(claims, "missing", "id"); got != "42" {
+		t.Errorf("Lookup() = %s", got)
+	}
+}
+
+// TestMapUserEmailVerified email_verified 缺失或为false时不视为已验证
+func TestMapUserEmailVerified(t *testing.T) {
+	c := &Client{}
+	for claim, want := range map[interface{}]bool{nil: false, false: false, true: true, "true": true, "false": false} {
+		claims := map[string]interface{}{"sub": "1", "email": "a@b.com"}
+		if claim != nil {
+			claims["email_verified"] = claim
+		}
+		if got := c.MapUser(claims).EmailVerified; got != want {
+			t.Errorf("MapUser(email_verified=%v) = %v, want %v", claim, got, want)
+		}
+	}
+	// 自定义的嵌套字段
+	c = &Client{config: Config{UserEmailField: "data.email", UserEmailVerifiedField: "data.email_verified"}}
+	info := c.MapUser(map[string]interface{}{"sub": "1", "email_verified": false,
+		"data": map[string]interface{}{"email": "a@b.com", "email_verified": true}})
+	if info.Email != "a@b.com" || !info.EmailVerified {
+		t.Errorf("MapUser() = %s, %v, want verified a@b.com", info.Email, info.EmailVerified)
+	}
+}
+
+// TestAuthenticateLegacyOA 旧OA系统: 非OIDC, 用户信息端点为POST且令牌不带Bearer前缀
+func TestAuthenticateLegacyOA(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("redirect_uri") != "http://localhost/callback" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"oa-token","expires_in":7200}`))
+	})
+	mux.HandleFunc("/oauth/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "oa-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0,"data":{"username":"bob","email":"bob@example.com"}}`))
+	})
+	c, err := NewClient(context.Background(), Config{
+		ServerURL:        server.URL,
+		AuthorizeURL:     "/oauth/authorize",
+		TokenURL:         "/oauth/token",
+		UserinfoURL:      "/oauth/user",
+		ClientID:         "client",
+		ClientSecret:     "secret",
+		RedirectURI:      "http://localhost/callback",
+		UserIDField:      "data.username",
+		UserNameField:    "data.username",
+		UserEmailField:   "data.email",
+		UserinfoMethod:   http.MethodPost,
+		UserinfoRawToken: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _, err := c.Authenticate(context.Background(), "code", "", "")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if info.ID != "bob" || info.Email != "bob@example.com" {
+		t.Errorf("MapUser() = %+v", info)
+	}
+}
@@ -0,0 +1,60 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B的SHA1测试向量, 取末6位
+func TestCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for ts, want := range cases {
+		got, err := Code(secret, Step(time.Unix(ts, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Code(%d) = %s, want %s", ts, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	code, _ := Code(secret, Step(now)-1)
+	step, ok := Validate(secret, code, now)
+	if !ok || step != Step(now)-1 {
+		t.Fatalf("previous window should be accepted, got %d %v", step, ok)
+	}
+	code, _ = Code(secret, Step(now)+2)
+	if _, ok = Validate(secret, code, now); ok {
+		t.Fatal("code outside skew should be rejected")
+	}
+	if _, ok = Validate(secret, "12345", now); ok {
+		t.Fatal("short code should be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Gaia Admin", "a@b.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Gaia%20Admin:a@b.com?") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Gaia+Admin") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+}
@@ -0,0 +1,53 @@
+package upload
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"os"
+)
+
+// 超过该大小的文件由 ReadForm 写入临时文件
+const localFileMaxMemory = 10 << 20
+
+// UploadLocalFile
+// @function: UploadLocalFile
+// @description: 将服务器本地生成的文件上传至配置的OSS, 文件通过 multipart 流转换, 不整体读入内存
+// @param: oss OSS, path string, filename string
+// @return: url string, key string, err error
+func UploadLocalFile(oss OSS, path, filename string) (string, string, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		err := writeFormFile(mw, path, filename)
+		if err == nil {
+			err = mw.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	form, err := multipart.NewReader(pr, mw.Boundary()).ReadForm(localFileMaxMemory)
+	_ = pr.Close()
+	if err != nil {
+		return "", "", err
+	}
+	defer form.RemoveAll()
+	files := form.File["file"]
+	if len(files) == 0 {
+		return "", "", errors.New("文件为空")
+	}
+	return oss.UploadFile(files[0])
+}
+
+func writeFormFile(mw *multipart.Writer, path, filename string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
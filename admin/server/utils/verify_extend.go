@@ -1,5 +1,18 @@
 package utils
 
 var (
-	OaLoginVerify = Rules{"AuthorizeCode": {NotEmpty()}} // 新增OA登录
+	OaLoginVerify             = Rules{"AuthorizeCode": {NotEmpty()}, "State": {NotEmpty()}}                      // 新增OA登录
+	DingTalkLoginVerify       = Rules{"AuthCode": {NotEmpty()}}                                                  // 钉钉扫码登录
+	OAuth2LoginVerify         = Rules{"Code": {NotEmpty()}, "State": {NotEmpty()}}                               // OAuth2/OIDC 登录
+	MfaCodeVerify             = Rules{"Code": {NotEmpty()}}                                                      // TOTP 二次验证
+	MfaChallengeVerify        = Rules{"MfaToken": {NotEmpty()}, "Code": {NotEmpty()}}                            // 登录二次验证
+	SessionUserVerify         = Rules{"UserID": {NotEmpty()}}                                                    // 注销用户会话
+	AccessTokenCreateVerify   = Rules{"Name": {NotEmpty()}, "ExpireDays": {NotEmpty()}}                          // 创建个人访问令牌
+	TenantUpdateVerify        = Rules{"ID": {NotEmpty()}}                                                        // 修改工作空间
+	TenantMemberVerify        = Rules{"TenantID": {NotEmpty()}, "Email": {NotEmpty()}}                           // 添加工作空间成员
+	TenantAccountVerify       = Rules{"TenantID": {NotEmpty()}, "AccountID": {NotEmpty()}}                       // 移除成员或转移所有权
+	TenantRoleVerify          = Rules{"TenantID": {NotEmpty()}, "AccountID": {NotEmpty()}, "Role": {NotEmpty()}} // 修改成员角色
+	OffboardVerify            = Rules{"UserID": {NotEmpty()}, "SuccessorID": {NotEmpty()}}                       // 离职交接
+	SyncConflictResolveVerify = Rules{"ID": {NotEmpty()}, "Action": {NotEmpty()}}                                // 处理同步冲突
+	EndUserBlockVerify        = Rules{"ID": {NotEmpty()}}                                                        // 封禁或解封终端用户
 )
@@ -151,6 +151,11 @@ def validate_app_token(view: Optional[Callable] = None, *, fetch_user_arg: Optio
                     )
                 # ---------------------二开部分End  额度限制，API调用计费 ---------------------
 
+                # ---------------------二开部分Begin  终端用户封禁 ---------------------
+                if kwargs.get("end_user") and redis_client.exists(f"end_user_blocked:{kwargs['end_user'].id}"):
+                    raise Forbidden("The end user has been blocked.")
+                # ---------------------二开部分End  终端用户封禁 ---------------------
+
             return view_func(*args, **kwargs)
 
         return decorated_view
@@ -2,10 +2,11 @@ from functools import wraps
 
 from flask import request
 from flask_restful import Resource  # type: ignore
-from werkzeug.exceptions import BadRequest, NotFound, Unauthorized
+from werkzeug.exceptions import BadRequest, Forbidden, NotFound, Unauthorized  # 二开部分  终端用户封禁, 新增Forbidden
 
 from controllers.web.error import WebSSOAuthRequiredError
 from extensions.ext_database import db
+from extensions.ext_redis import redis_client  # 二开部分  终端用户封禁
 from libs.passport import PassportService
 from models.model import App, EndUser, Site
 from services.enterprise.enterprise_service import EnterpriseService
@@ -56,6 +57,10 @@ def decode_jwt_token():
         end_user = db.session.query(EndUser).filter(EndUser.id == decoded["end_user_id"]).first()
         if not end_user:
             raise NotFound()
+        # ---------------------二开部分Begin  终端用户封禁 ---------------------
+        if redis_client.exists(f"end_user_blocked:{end_user.id}"):
+            raise Forbidden("The end user has been blocked.")
+        # ---------------------二开部分End  终端用户封禁 ---------------------
 
         _validate_web_sso_token(decoded, system_features, app_code)
 